@@ -4,21 +4,39 @@ import (
 	"context"
 	"fmt"
 	"path/filepath"
+	"slices"
+	"strings"
 
 	"github.com/genkit-migrate/genkit-migrate/internal/cli"
+	"github.com/genkit-migrate/genkit-migrate/internal/config"
 	"github.com/genkit-migrate/genkit-migrate/pkg/analyzer"
 	"github.com/genkit-migrate/genkit-migrate/pkg/generator"
+	"github.com/genkit-migrate/genkit-migrate/pkg/models"
+	"github.com/genkit-migrate/genkit-migrate/pkg/provider"
+	_ "github.com/genkit-migrate/genkit-migrate/pkg/provider/all"
+	"github.com/genkit-migrate/genkit-migrate/pkg/provider/aws"
+	"github.com/genkit-migrate/genkit-migrate/pkg/provider/azure"
+	"github.com/genkit-migrate/genkit-migrate/pkg/provider/ollama"
+	"github.com/genkit-migrate/genkit-migrate/pkg/rewrite"
 	"github.com/genkit-migrate/genkit-migrate/pkg/transformer"
+	"github.com/genkit-migrate/genkit-migrate/pkg/upgrade"
 	"github.com/spf13/cobra"
 )
 
 var (
-	sourcePath   string
-	targetPath   string
-	fromProvider string
-	toProvider   string
-	dryRun       bool
-	interactive  bool
+	sourcePath     string
+	targetPath     string
+	fromProvider   string
+	toProvider     string
+	dryRun         bool
+	interactive    bool
+	vectorStore    string
+	deployTarget   string
+	iac            string
+	ollamaModel    string
+	rulesFiles     []string
+	upgradeKit     bool
+	acceptBlockers bool
 )
 
 var migrateCmd = &cobra.Command{
@@ -42,10 +60,22 @@ func init() {
 
 	migrateCmd.Flags().StringVarP(&sourcePath, "source", "s", ".", "source project path")
 	migrateCmd.Flags().StringVarP(&targetPath, "target", "t", "", "target project path (default: source_aws)")
-	migrateCmd.Flags().StringVar(&fromProvider, "from", "gcp", "source cloud provider (gcp, aws, azure)")
-	migrateCmd.Flags().StringVar(&toProvider, "to", "aws", "target cloud provider (aws, gcp, azure)")
+	migrateCmd.Flags().StringVar(&fromProvider, "from", "gcp", fmt.Sprintf("source cloud provider (%s)", strings.Join(provider.Sources(), ", ")))
+	migrateCmd.Flags().StringVar(&toProvider, "to", "aws", fmt.Sprintf("target provider (%s)", strings.Join(provider.Names(), ", ")))
 	migrateCmd.Flags().BoolVar(&dryRun, "dry-run", false, "analyze and plan without making changes")
 	migrateCmd.Flags().BoolVarP(&interactive, "interactive", "i", true, "interactive mode with prompts")
+	migrateCmd.Flags().StringVar(&vectorStore, "vector-store", aws.VectorStores[0],
+		fmt.Sprintf("target vector store for Firestore/Vertex AI vector search (%s)", strings.Join(aws.VectorStores, ", ")))
+	migrateCmd.Flags().StringVar(&deployTarget, "deploy-target", "",
+		fmt.Sprintf("AWS compute to deploy to (%s; default: chosen from the source's Cloud Run, Cloud Functions or GKE config)", strings.Join(aws.DeployTargets, ", ")))
+	migrateCmd.Flags().StringVar(&iac, "iac", "terraform",
+		fmt.Sprintf("Infrastructure as code to generate for AWS (%s)", strings.Join(aws.IaCFormats, ", ")))
+	migrateCmd.Flags().StringVar(&ollamaModel, "ollama-model", "",
+		fmt.Sprintf("local model for --to=ollama (%s; default: config file, then %s)", strings.Join(ollama.Models, ", "), ollama.Models[0]))
+
+	migrateCmd.Flags().StringSliceVar(&rulesFiles, "rules", nil, "YAML file of custom rewrite rules, applied before the built-in rules (repeatable)")
+	migrateCmd.Flags().BoolVar(&upgradeKit, "upgrade-genkit", false, "also rewrite pre-1.0 GenKit API calls to GenKit 1.0")
+	migrateCmd.Flags().BoolVar(&acceptBlockers, "accept-blockers", false, "migrate even though the project uses source features the target has no equivalent of")
 
 	if err := migrateCmd.MarkFlagRequired("source"); err != nil {
 		// This should never fail with a valid flag name
@@ -76,11 +106,21 @@ func runMigrate(cmd *cobra.Command, args []string) error {
 	ui.Info(fmt.Sprintf("Source: %s (%s)", sourceAbs, fromProvider))
 	ui.Info(fmt.Sprintf("Target: %s (%s)", targetAbs, toProvider))
 
+	var rules []rewrite.Rule
+	for _, path := range rulesFiles {
+		loaded, err := rewrite.LoadRules(path)
+		if err != nil {
+			return fmt.Errorf("failed to load rules: %w", err)
+		}
+		rules = append(rules, loaded...)
+	}
+
 	ui.StartProgress("Analyzing source project...")
 
 	analyzer := analyzer.New(&analyzer.Config{
 		SourceProvider: fromProvider,
 		TargetProvider: toProvider,
+		Packages:       rewrite.RulePackages(rules),
 		Verbose:        verbose,
 	})
 
@@ -92,6 +132,53 @@ func runMigrate(cmd *cobra.Command, args []string) error {
 
 	ui.StopProgress()
 	ui.Success(fmt.Sprintf("Found %d flows, %d models", len(project.Flows), len(project.Models)))
+	if len(project.CloudServices) > 0 {
+		ui.Warning(fmt.Sprintf("Found %d Google Cloud SDK usages that must be migrated by hand (see MIGRATION.md)", len(project.CloudServices)))
+	}
+
+	if version := project.Dependencies[upgrade.GenkitModule]; upgrade.Legacy(version) {
+		if upgradeKit {
+			upgradeRules, err := upgrade.Rules()
+			if err != nil {
+				return err
+			}
+			rules = append(upgradeRules, rules...)
+			ui.Info(fmt.Sprintf("Upgrading GenKit %s to %s", version, upgrade.GenkitVersion))
+		} else {
+			ui.Warning(fmt.Sprintf("The project uses GenKit %s; pass --upgrade-genkit to rewrite its pre-1.0 API calls", version))
+		}
+	}
+
+	if len(project.DirectCalls) > 0 {
+		ui.Warning(fmt.Sprintf("Found %d direct model SDK calls outside GenKit; text generations are rewritten to genkit.Generate and the rest is flagged in MIGRATION.md", len(project.DirectCalls)))
+	}
+
+	if len(project.VectorStores) > 0 {
+		ui.Warning(fmt.Sprintf("Found %d vector store usages (Firestore vector search / Vertex AI Vector Search)", len(project.VectorStores)))
+
+		if toProvider == "aws" && interactive && !cmd.Flags().Changed("vector-store") {
+			vectorStore, err = ui.SelectProvider(aws.VectorStores, "Select the target vector store")
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	settings, err := config.Load(cfgFile)
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+	azureSettings := settings.GetAzureConfig()
+
+	if toProvider == "ollama" && ollamaModel == "" {
+		ollamaModel = settings.GetOllamaConfig().Model
+		if ollamaModel == "" && interactive {
+			ollamaModel, err = ui.SelectProvider(ollama.Models, "Select the local Ollama model")
+			if err != nil {
+				return err
+			}
+		}
+	}
 
 	if interactive && !dryRun {
 		confirmed, err := ui.Confirm("Continue with migration?")
@@ -104,6 +191,18 @@ func runMigrate(cmd *cobra.Command, args []string) error {
 		}
 	}
 
+	options := map[string]string{
+		ollama.ModelOption:        ollamaModel,
+		azure.SubscriptionOption:  azureSettings.SubscriptionID,
+		azure.ResourceGroupOption: azureSettings.ResourceGroup,
+		azure.LocationOption:      azureSettings.Location,
+	}
+	if toProvider == "aws" {
+		options[aws.VectorStoreOption] = vectorStore
+		options[aws.DeployTargetOption] = deployTarget
+		options[aws.IaCOption] = iac
+	}
+
 	ui.StartProgress("Transforming project...")
 
 	transformer := transformer.New(&transformer.Config{
@@ -111,6 +210,8 @@ func runMigrate(cmd *cobra.Command, args []string) error {
 		TargetProvider: toProvider,
 		TargetPath:     targetAbs,
 		DryRun:         dryRun,
+		Options:        options,
+		Rules:          rules,
 	})
 
 	migration, err := transformer.TransformProject(ctx, project)
@@ -122,6 +223,37 @@ func runMigrate(cmd *cobra.Command, args []string) error {
 	ui.StopProgress()
 	ui.Success("Project transformation complete")
 
+	if blockers := cli.Blockers(migration); len(blockers) > 0 {
+		for _, change := range blockers {
+			ui.Error(fmt.Sprintf("Blocker: %s (%s:%d)", change.Description, change.File, change.Line))
+		}
+		if !acceptBlockers && !dryRun {
+			return fmt.Errorf("migration blocked by %d uses of features with no %s equivalent; pass --accept-blockers to migrate anyway",
+				len(blockers), toProvider)
+		}
+	}
+
+	// Blockers were accepted above; confirm the other blocking changes.
+	blocking := slices.DeleteFunc(cli.BlockingChanges(migration), func(change *models.Change) bool {
+		return change.Type == "blocker"
+	})
+	if len(blocking) > 0 {
+		for _, change := range blocking {
+			ui.Warning(fmt.Sprintf("Blocking: %s", change.Description))
+		}
+
+		if interactive && !dryRun {
+			confirmed, err := ui.Confirm(fmt.Sprintf("The migration plan has %d blocking changes. Generate the project anyway?", len(blocking)))
+			if err != nil {
+				return err
+			}
+			if !confirmed {
+				ui.Info("Migration cancelled")
+				return nil
+			}
+		}
+	}
+
 	if !dryRun {
 		ui.StartProgress("Generating output files...")
 
@@ -0,0 +1,105 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+
+	"github.com/genkit-migrate/genkit-migrate/internal/cli"
+	"github.com/genkit-migrate/genkit-migrate/pkg/analyzer"
+	"github.com/genkit-migrate/genkit-migrate/pkg/upgrade"
+	"github.com/spf13/cobra"
+)
+
+var upgradeCmd = &cobra.Command{
+	Use:   "upgrade",
+	Short: "Upgrade a project from the pre-1.0 GenKit Go API",
+	Long: `Upgrade a GenKit project written against the pre-1.0 Go API to GenKit 1.0,
+without changing its cloud provider.
+
+This command will:
+1. Detect the GenKit version from go.mod
+2. Rewrite genkit.Init to return the *genkit.Genkit instance
+3. Pass the instance to flow, tool and generation calls
+4. Bump github.com/firebase/genkit/go in go.mod
+
+Example:
+  genkit-migrate upgrade --source=./my-genkit-app --dry-run`,
+	RunE: runUpgrade,
+}
+
+func init() {
+	rootCmd.AddCommand(upgradeCmd)
+
+	upgradeCmd.Flags().StringVarP(&sourcePath, "source", "s", ".", "source project path")
+	upgradeCmd.Flags().StringVarP(&targetPath, "target", "t", "", "target project path (default: upgrade in place)")
+	upgradeCmd.Flags().BoolVar(&dryRun, "dry-run", false, "plan the upgrade without making changes")
+
+	if err := upgradeCmd.MarkFlagRequired("source"); err != nil {
+		// This should never fail with a valid flag name
+		panic(fmt.Sprintf("failed to mark source flag as required: %v", err))
+	}
+}
+
+func runUpgrade(cmd *cobra.Command, args []string) error {
+	ctx := context.Background()
+
+	ui := cli.NewUI(false, verbose)
+
+	sourceAbs, err := filepath.Abs(sourcePath)
+	if err != nil {
+		return fmt.Errorf("invalid source path: %w", err)
+	}
+
+	targetAbs := sourceAbs
+	if targetPath != "" {
+		targetAbs, err = filepath.Abs(targetPath)
+		if err != nil {
+			return fmt.Errorf("invalid target path: %w", err)
+		}
+	}
+
+	ui.StartProgress("Analyzing source project...")
+
+	analyzer := analyzer.New(&analyzer.Config{
+		SourceProvider: "auto-detect",
+		Verbose:        verbose,
+	})
+
+	project, err := analyzer.AnalyzeProject(ctx, sourceAbs)
+	if err != nil {
+		ui.StopProgress()
+		return fmt.Errorf("analysis failed: %w", err)
+	}
+	ui.StopProgress()
+
+	version := project.Dependencies[upgrade.GenkitModule]
+	if !upgrade.Legacy(version) {
+		ui.Success(fmt.Sprintf("The project already uses GenKit %s; nothing to upgrade", version))
+		return nil
+	}
+	ui.Info(fmt.Sprintf("Upgrading GenKit %s to %s", version, upgrade.GenkitVersion))
+
+	upgrader := upgrade.New(&upgrade.Config{
+		TargetPath: targetAbs,
+		DryRun:     dryRun,
+	})
+
+	migration, err := upgrader.UpgradeProject(ctx, project)
+	if err != nil {
+		return fmt.Errorf("upgrade failed: %w", err)
+	}
+
+	if dryRun {
+		ui.Info("Dry run complete - no files were modified")
+		ui.PrintMigrationPlan(migration)
+		return nil
+	}
+
+	if err := upgrader.WriteProject(migration); err != nil {
+		return fmt.Errorf("failed to write project: %w", err)
+	}
+	ui.Success(fmt.Sprintf("Upgrade complete! Review the changes in %s and run go mod tidy", targetAbs))
+	ui.PrintMigrationPlan(migration)
+	return nil
+}
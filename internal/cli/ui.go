@@ -100,6 +100,14 @@ func (ui *UI) PrintMigrationPlan(migration *models.Migration) {
 	ui.Info("Migration Plan:")
 	fmt.Printf("\n")
 
+	if blocking := BlockingChanges(migration); len(blocking) > 0 {
+		ui.Warning("Blocking changes (must be resolved before the migrated app serves traffic):")
+		for _, change := range blocking {
+			fmt.Printf("  • %s: %s\n", change.Type, change.Description)
+		}
+		fmt.Printf("\n")
+	}
+
 	if len(migration.Changes) > 0 {
 		ui.Info("Changes:")
 		for _, change := range migration.Changes {
@@ -125,6 +133,28 @@ func (ui *UI) PrintMigrationPlan(migration *models.Migration) {
 	}
 }
 
+// Blockers returns the uses of source features the target has no equivalent
+// of, which stop the migration unless they are accepted.
+func Blockers(migration *models.Migration) []*models.Change {
+	blockers := make([]*models.Change, 0)
+	for _, change := range migration.Changes {
+		if change.Type == "blocker" {
+			blockers = append(blockers, change)
+		}
+	}
+	return blockers
+}
+
+func BlockingChanges(migration *models.Migration) []*models.Change {
+	blocking := make([]*models.Change, 0)
+	for _, change := range migration.Changes {
+		if change.Blocking {
+			blocking = append(blocking, change)
+		}
+	}
+	return blocking
+}
+
 func (ui *UI) PrintAnalysisTable(project *models.Project) {
 	headerStyle := lipgloss.NewStyle().
 		Foreground(lipgloss.Color("12")).
@@ -155,6 +185,108 @@ func (ui *UI) PrintAnalysisTable(project *models.Project) {
 		fmt.Printf("\n")
 	}
 
+	if len(project.Embedders) > 0 {
+		fmt.Printf("%s:\n", headerStyle.Render("Embedders"))
+		for _, embedder := range project.Embedders {
+			dimensions := "unknown"
+			if embedder.Dimensions > 0 {
+				dimensions = fmt.Sprintf("%d", embedder.Dimensions)
+			}
+			fmt.Printf("  • %s (%s dimensions) - %s:%d\n", embedder.Name, dimensions, embedder.Position.Filename, embedder.Position.Line)
+		}
+		fmt.Printf("\n")
+	}
+
+	if len(project.Indexers) > 0 {
+		fmt.Printf("%s:\n", headerStyle.Render("Indexers"))
+		for _, indexer := range project.Indexers {
+			fmt.Printf("  • %s/%s - %s:%d\n", indexer.Provider, indexer.Name, indexer.Position.Filename, indexer.Position.Line)
+		}
+		fmt.Printf("\n")
+	}
+
+	if len(project.VectorStores) > 0 {
+		fmt.Printf("%s:\n", headerStyle.Render("Vector Stores"))
+		for _, store := range project.VectorStores {
+			name := store.Kind
+			if store.Collection != "" {
+				name += " (" + store.Collection + ")"
+			}
+			fmt.Printf("  • %s - %s:%d\n", name, store.Position.Filename, store.Position.Line)
+		}
+		fmt.Printf("\n")
+	}
+
+	if len(project.DirectCalls) > 0 {
+		fmt.Printf("%s:\n", headerStyle.Render("Direct Model SDK Calls"))
+		for _, call := range project.DirectCalls {
+			name := call.Method
+			if call.Model != "" {
+				name += " (" + call.Model + ")"
+			}
+			fmt.Printf("  • %s %s - %s:%d:%d\n", call.Package, name, call.Position.Filename, call.Position.Line, call.Position.Column)
+		}
+		fmt.Printf("\n")
+	}
+
+	if len(project.SafetySettings) > 0 {
+		fmt.Printf("%s:\n", headerStyle.Render("Safety Settings"))
+		for _, setting := range project.SafetySettings {
+			fmt.Printf("  • %s: %s - %s:%d\n", setting.Category, setting.Threshold, setting.Position.Filename, setting.Position.Line)
+		}
+		fmt.Printf("\n")
+	}
+
+	if len(project.StructuredOutputs) > 0 {
+		fmt.Printf("%s:\n", headerStyle.Render("Structured Output"))
+		for _, output := range project.StructuredOutputs {
+			name := output.Method
+			if output.Type != "" {
+				name += " (" + output.Type + ")"
+			}
+			if output.Flow != "" {
+				name = output.Flow + ": " + name
+			}
+			fmt.Printf("  • %s - %s:%d\n", name, output.Position.Filename, output.Position.Line)
+		}
+		fmt.Printf("\n")
+	}
+
+	if len(project.Features) > 0 {
+		fmt.Printf("%s:\n", headerStyle.Render("Provider-Specific Features"))
+		for _, usage := range project.Features {
+			fmt.Printf("  • %s (%s) - %s:%d\n", usage.Title, usage.Detail, usage.Position.Filename, usage.Position.Line)
+		}
+		fmt.Printf("\n")
+	}
+
+	if deployment := project.Deployment; deployment != nil {
+		fmt.Printf("%s: %s (%s)\n", headerStyle.Render("Deployment"), deployment.Platform, deployment.File)
+		fmt.Printf("\n")
+	}
+
+	if len(project.Prompts) > 0 {
+		fmt.Printf("%s:\n", headerStyle.Render("Prompts"))
+		for _, prompt := range project.Prompts {
+			model := "default model"
+			if prompt.Model != nil {
+				model = prompt.Model.Name
+			}
+			fmt.Printf("  • %s (%s)\n", prompt.Path, model)
+		}
+		fmt.Printf("\n")
+	}
+
+	if len(project.CloudServices) > 0 {
+		fmt.Printf("%s:\n", headerStyle.Render("Google Cloud Services"))
+		for _, service := range project.CloudServices {
+			fmt.Printf("  • %s (%s) → %s [effort: %s, %d call sites] - %s:%d\n",
+				service.Service, service.Package, service.Alternative, service.Effort,
+				len(service.CallSites), service.Position.Filename, service.Position.Line)
+		}
+		fmt.Printf("\n")
+	}
+
 	if len(project.Dependencies) > 0 {
 		fmt.Printf("%s:\n", headerStyle.Render("Key Dependencies"))
 		for dep, version := range project.Dependencies {
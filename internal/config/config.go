@@ -16,9 +16,10 @@ type Config struct {
 }
 
 type Provider struct {
-	AWS   *AWSProvider   `yaml:"aws,omitempty"`
-	GCP   *GCPProvider   `yaml:"gcp,omitempty"`
-	Azure *AzureProvider `yaml:"azure,omitempty"`
+	AWS    *AWSProvider    `yaml:"aws,omitempty"`
+	GCP    *GCPProvider    `yaml:"gcp,omitempty"`
+	Azure  *AzureProvider  `yaml:"azure,omitempty"`
+	Ollama *OllamaProvider `yaml:"ollama,omitempty"`
 }
 
 type AWSProvider struct {
@@ -37,6 +38,10 @@ type AzureProvider struct {
 	Location       string `yaml:"location"`
 }
 
+type OllamaProvider struct {
+	Model string `yaml:"model"`
+}
+
 func Load(configPath string) (*Config, error) {
 	config := &Config{
 		DefaultSourceProvider: "gcp",
@@ -119,6 +124,20 @@ func (c *Config) GetAWSConfig() *AWSProvider {
 	}
 }
 
+func (c *Config) GetAzureConfig() *AzureProvider {
+	if provider, exists := c.Providers["azure"]; exists && provider.Azure != nil {
+		return provider.Azure
+	}
+	return &AzureProvider{}
+}
+
+func (c *Config) GetOllamaConfig() *OllamaProvider {
+	if provider, exists := c.Providers["ollama"]; exists && provider.Ollama != nil {
+		return provider.Ollama
+	}
+	return &OllamaProvider{}
+}
+
 func (c *Config) GetGCPConfig() *GCPProvider {
 	if provider, exists := c.Providers["gcp"]; exists && provider.GCP != nil {
 		return provider.GCP
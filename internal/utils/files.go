@@ -1,6 +1,7 @@
 package utils
 
 import (
+	"bytes"
 	"fmt"
 	"os"
 	"path/filepath"
@@ -46,3 +47,31 @@ func CopyFile(src, dst string) error {
 
 	return WriteFileWithDir(dst, string(data))
 }
+
+// SplitFrontmatter returns the "---" delimited header and the untouched body.
+func SplitFrontmatter(content []byte) (frontmatter, body []byte, ok bool) {
+	const delimiter = "---"
+
+	firstLine, rest, found := bytes.Cut(content, []byte("\n"))
+	if !found || string(bytes.TrimRight(firstLine, "\r")) != delimiter {
+		return nil, content, false
+	}
+
+	offset := 0
+	for offset <= len(rest) {
+		line, _, _ := bytes.Cut(rest[offset:], []byte("\n"))
+		if string(bytes.TrimRight(line, "\r")) == delimiter {
+			end := offset + len(line)
+			if end < len(rest) {
+				end++
+			}
+			return rest[:offset], rest[end:], true
+		}
+		if offset+len(line) >= len(rest) {
+			break
+		}
+		offset += len(line) + 1
+	}
+
+	return nil, content, false
+}
@@ -6,6 +6,8 @@ import (
 	"path/filepath"
 	"testing"
 
+	"github.com/genkit-migrate/genkit-migrate/pkg/models"
+	_ "github.com/genkit-migrate/genkit-migrate/pkg/provider/all"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -49,6 +51,9 @@ func TestDetectModelProvider(t *testing.T) {
 		{"vertexai/gemini-pro", "gcp"},
 		{"openai/gpt-4", "openai"},
 		{"anthropic/claude-3", "anthropic"},
+		{"bedrock/anthropic.claude-3-haiku-20240307-v1:0", "aws"},
+		{"anthropic.claude-3-sonnet-20240229-v1:0", "aws"},
+		{"amazon.nova-pro-v1:0", "aws"},
 		{"unknown-model", "unknown"},
 	}
 
@@ -58,6 +63,840 @@ func TestDetectModelProvider(t *testing.T) {
 	}
 }
 
+func TestAnalyzeBedrockProject(t *testing.T) {
+	testDir := t.TempDir()
+
+	goModContent := `module example.com/app
+
+go 1.23
+
+require (
+	github.com/firebase/genkit/go v1.0.2
+	github.com/scttfrdmn/genkit-aws v0.1.0
+)
+`
+	mainGoContent := `package main
+
+import (
+	"github.com/firebase/genkit/go/genkit"
+	"github.com/scttfrdmn/genkit-aws/pkg/bedrock"
+)
+
+func models(g *genkit.Genkit) {
+	_ = bedrock.Model(g, "anthropic.claude-3-haiku-20240307-v1:0")
+	_ = bedrock.Embedder(g, "amazon.titan-embed-text-v2:0")
+}
+`
+	err := os.WriteFile(filepath.Join(testDir, "go.mod"), []byte(goModContent), 0644)
+	require.NoError(t, err)
+	err = os.WriteFile(filepath.Join(testDir, "main.go"), []byte(mainGoContent), 0644)
+	require.NoError(t, err)
+
+	analyzer := New(&Config{SourceProvider: "aws", TargetProvider: "gcp"})
+
+	project, err := analyzer.AnalyzeProject(context.Background(), testDir)
+	require.NoError(t, err)
+
+	assert.Equal(t, map[string]string{
+		"github.com/firebase/genkit/go":   "v1.0.2",
+		"github.com/scttfrdmn/genkit-aws": "v0.1.0",
+	}, project.Dependencies)
+
+	require.Len(t, project.Models, 1)
+	assert.Equal(t, "bedrock/anthropic.claude-3-haiku-20240307-v1:0", project.Models[0].Name)
+	assert.Equal(t, "aws", project.Models[0].Provider)
+
+	require.Len(t, project.Embedders, 1)
+	assert.Equal(t, "bedrock/amazon.titan-embed-text-v2:0", project.Embedders[0].Name)
+	assert.Equal(t, 1024, project.Embedders[0].Dimensions)
+}
+
+func TestAnalyzeDependencies(t *testing.T) {
+	testDir := t.TempDir()
+
+	goModContent := `module example.com/app
+
+go 1.23
+
+require github.com/firebase/genkit/go v1.0.2
+
+require (
+	github.com/spf13/cobra v1.8.1
+)
+`
+	err := os.WriteFile(filepath.Join(testDir, "go.mod"), []byte(goModContent), 0644)
+	require.NoError(t, err)
+
+	analyzer := New(&Config{SourceProvider: "gcp", TargetProvider: "azure"})
+
+	project, err := analyzer.AnalyzeProject(context.Background(), testDir)
+	require.NoError(t, err)
+
+	assert.Equal(t, map[string]string{
+		"github.com/firebase/genkit/go": "v1.0.2",
+		"github.com/spf13/cobra":        "v1.8.1",
+	}, project.Dependencies)
+}
+
+func TestAnalyzeCloudServices(t *testing.T) {
+	testDir := createTestProject(t)
+	defer os.RemoveAll(testDir)
+
+	storeGoContent := `package main
+
+import (
+    "context"
+
+    "cloud.google.com/go/firestore"
+    secretmanager "cloud.google.com/go/secretmanager/apiv1"
+)
+
+func newClients(ctx context.Context) error {
+    if _, err := firestore.NewClient(ctx, "my-project"); err != nil {
+        return err
+    }
+    _, err := secretmanager.NewClient(ctx)
+    return err
+}
+`
+
+	err := os.WriteFile(filepath.Join(testDir, "store.go"), []byte(storeGoContent), 0644)
+	require.NoError(t, err)
+
+	analyzer := New(&Config{SourceProvider: "gcp", TargetProvider: "aws"})
+
+	project, err := analyzer.AnalyzeProject(context.Background(), testDir)
+	require.NoError(t, err)
+
+	assert.Len(t, project.Files, 2)
+	assert.False(t, project.Files["store.go"].HasGenKit)
+	require.Len(t, project.CloudServices, 2)
+
+	firestore := project.CloudServices[0]
+	assert.Equal(t, "Firestore", firestore.Service)
+	assert.Equal(t, "cloud.google.com/go/firestore", firestore.Package)
+	assert.Equal(t, "Amazon DynamoDB", firestore.Alternative)
+	assert.Equal(t, "high", firestore.Effort)
+	assert.Equal(t, "store.go", firestore.Position.Filename)
+	require.Len(t, firestore.CallSites, 1)
+	assert.Equal(t, "store.go", firestore.CallSites[0].Filename)
+
+	secrets := project.CloudServices[1]
+	assert.Equal(t, "Secret Manager", secrets.Service)
+	assert.Equal(t, "AWS Secrets Manager", secrets.Alternative)
+	assert.Equal(t, "low", secrets.Effort)
+	assert.Len(t, secrets.CallSites, 1)
+}
+
+func TestAnalyzeConfiguration(t *testing.T) {
+	testDir := createTestProject(t)
+	defer os.RemoveAll(testDir)
+
+	envContent := `# local settings
+GOOGLE_API_KEY=AIzaSySecretValue
+GCLOUD_LOCATION=europe-west1
+export LOG_LEVEL=debug
+DB_PASSWORD="hunter2"
+`
+
+	appContent := `runtime: go123
+service: summarizer
+instance_class: F2
+env_variables:
+  GOOGLE_CLOUD_PROJECT: my-project
+`
+
+	err := os.WriteFile(filepath.Join(testDir, ".env"), []byte(envContent), 0644)
+	require.NoError(t, err)
+	err = os.WriteFile(filepath.Join(testDir, "app.yaml"), []byte(appContent), 0644)
+	require.NoError(t, err)
+
+	analyzer := New(&Config{SourceProvider: "gcp", TargetProvider: "aws"})
+
+	project, err := analyzer.AnalyzeProject(context.Background(), testDir)
+	require.NoError(t, err)
+
+	require.Contains(t, project.ConfigFiles, ".env")
+	env := project.ConfigFiles[".env"]
+	assert.Equal(t, "env", env.Format)
+	require.Len(t, env.Settings, 4)
+
+	apiKey := env.Settings[0]
+	assert.Equal(t, "GOOGLE_API_KEY", apiKey.Key)
+	assert.True(t, apiKey.Secret)
+	assert.True(t, apiKey.GCP)
+	assert.Empty(t, apiKey.Value, "secret values must not be retained")
+	assert.Equal(t, 2, apiKey.Line)
+
+	location := env.Settings[1]
+	assert.Equal(t, "europe-west1", location.Value)
+	assert.True(t, location.GCP)
+	assert.False(t, location.Secret)
+
+	logLevel := env.Settings[2]
+	assert.Equal(t, "LOG_LEVEL", logLevel.Key)
+	assert.Equal(t, "debug", logLevel.Value)
+	assert.False(t, logLevel.GCP)
+
+	password := env.Settings[3]
+	assert.True(t, password.Secret)
+	assert.False(t, password.GCP)
+	assert.Empty(t, password.Value)
+
+	require.Contains(t, project.ConfigFiles, "app.yaml")
+	app := project.ConfigFiles["app.yaml"]
+	assert.Equal(t, "yaml", app.Format)
+	require.Len(t, app.Settings, 4)
+	assert.Equal(t, "runtime", app.Settings[0].Key)
+	assert.True(t, app.Settings[0].GCP)
+	assert.Equal(t, "env_variables.GOOGLE_CLOUD_PROJECT", app.Settings[3].Key)
+	assert.Equal(t, "my-project", app.Settings[3].Value)
+	assert.True(t, app.Settings[3].GCP)
+}
+
+func TestAnalyzeConfigurationSequenceSecrets(t *testing.T) {
+	testDir := createTestProject(t)
+	defer os.RemoveAll(testDir)
+
+	configContent := `api_keys:
+  - sk-first
+  - sk-second
+env:
+  - name: SLACK_TOKEN
+    value: xoxb-secret
+  - name: LOG_LEVEL
+    value: debug
+`
+	err := os.WriteFile(filepath.Join(testDir, "config.yaml"), []byte(configContent), 0644)
+	require.NoError(t, err)
+
+	analyzer := New(&Config{SourceProvider: "gcp", TargetProvider: "aws"})
+
+	project, err := analyzer.AnalyzeProject(context.Background(), testDir)
+	require.NoError(t, err)
+
+	require.Contains(t, project.ConfigFiles, "config.yaml")
+	settings := make(map[string]*models.ConfigSetting)
+	for _, setting := range project.ConfigFiles["config.yaml"].Settings {
+		settings[setting.Key] = setting
+	}
+
+	for _, key := range []string{"api_keys.0", "api_keys.1", "env.0.value"} {
+		require.Contains(t, settings, key)
+		assert.True(t, settings[key].Secret, key)
+		assert.Empty(t, settings[key].Value, key)
+	}
+	assert.False(t, settings["env.0.name"].Secret)
+	assert.False(t, settings["env.1.value"].Secret)
+	assert.Equal(t, "debug", settings["env.1.value"].Value)
+}
+
+func TestAnalyzePrompts(t *testing.T) {
+	testDir := createTestProject(t)
+	defer os.RemoveAll(testDir)
+
+	promptContent := `---
+model: googleai/gemini-1.5-flash
+config:
+  temperature: 0.4
+  maxOutputTokens: 512
+input:
+  schema:
+    topic: string
+---
+Write a haiku about {{topic}}.
+`
+
+	err := os.MkdirAll(filepath.Join(testDir, "prompts"), 0755)
+	require.NoError(t, err)
+	err = os.WriteFile(filepath.Join(testDir, "prompts", "haiku.prompt"), []byte(promptContent), 0644)
+	require.NoError(t, err)
+
+	analyzer := New(&Config{SourceProvider: "gcp", TargetProvider: "aws"})
+
+	project, err := analyzer.AnalyzeProject(context.Background(), testDir)
+	require.NoError(t, err)
+
+	require.Len(t, project.Prompts, 1)
+	prompt := project.Prompts[0]
+	assert.Equal(t, filepath.Join("prompts", "haiku.prompt"), prompt.Path)
+	assert.Equal(t, 0.4, prompt.Config["temperature"])
+	assert.Equal(t, 512, prompt.Config["maxOutputTokens"])
+
+	require.NotNil(t, prompt.Model)
+	assert.Equal(t, "googleai/gemini-1.5-flash", prompt.Model.Name)
+	assert.Equal(t, "gcp", prompt.Model.Provider)
+	assert.Equal(t, 2, prompt.Model.Position.Line)
+
+	assert.Len(t, project.Models, 2)
+	assert.Contains(t, project.Models, prompt.Model)
+}
+
+func TestAnalyzeEmbedders(t *testing.T) {
+	testDir := createTestProject(t)
+	defer os.RemoveAll(testDir)
+
+	ragContent := `package main
+
+import (
+	"context"
+
+	"github.com/firebase/genkit/go/ai"
+	"github.com/firebase/genkit/go/genkit"
+	"github.com/firebase/genkit/go/plugins/googleai"
+)
+
+func setupRAG(g *genkit.Genkit) {
+	embedder := googleai.Embedder(g, "text-embedding-004")
+	genkit.DefineIndexer(g, "pgvector", "docs", func(ctx context.Context, req *ai.IndexerRequest) error {
+		_, err := ai.Embed(ctx, embedder, ai.WithDocs(req.Documents...))
+		return err
+	})
+	_ = ai.WithEmbedderName("vertexai/text-multilingual-embedding-002")
+}
+`
+	err := os.WriteFile(filepath.Join(testDir, "rag.go"), []byte(ragContent), 0644)
+	require.NoError(t, err)
+
+	analyzer := New(&Config{SourceProvider: "gcp", TargetProvider: "aws"})
+
+	project, err := analyzer.AnalyzeProject(context.Background(), testDir)
+	require.NoError(t, err)
+
+	require.Len(t, project.Embedders, 2)
+	assert.Equal(t, "googleai/text-embedding-004", project.Embedders[0].Name)
+	assert.Equal(t, 768, project.Embedders[0].Dimensions)
+	assert.Equal(t, 12, project.Embedders[0].Position.Line)
+	assert.Equal(t, "vertexai/text-multilingual-embedding-002", project.Embedders[1].Name)
+
+	require.Len(t, project.Indexers, 1)
+	assert.Equal(t, "pgvector", project.Indexers[0].Provider)
+	assert.Equal(t, "docs", project.Indexers[0].Name)
+	assert.Equal(t, "main", project.Indexers[0].Package)
+}
+
+func TestAnalyzeVectorStores(t *testing.T) {
+	testDir := createTestProject(t)
+	defer os.RemoveAll(testDir)
+
+	retrieverContent := `package main
+
+import (
+	"context"
+
+	"cloud.google.com/go/firestore"
+	"github.com/firebase/genkit/go/genkit"
+	"github.com/firebase/genkit/go/plugins/firebase"
+)
+
+func defineRetriever(ctx context.Context, g *genkit.Genkit, client *firestore.Client) {
+	firebase.DefineRetriever(ctx, g, firebase.RetrieverOptions{
+		Name:         "menuRetriever",
+		Client:       client,
+		Collection:   "menu",
+		ContentField: "text",
+	})
+}
+`
+	searchContent := `package search
+
+import (
+	"context"
+
+	aiplatform "cloud.google.com/go/aiplatform/apiv1"
+)
+
+func newClient(ctx context.Context) {
+	client, _ := aiplatform.NewMatchClient(ctx)
+	_ = client
+}
+`
+	err := os.WriteFile(filepath.Join(testDir, "retriever.go"), []byte(retrieverContent), 0644)
+	require.NoError(t, err)
+	err = os.WriteFile(filepath.Join(testDir, "search.go"), []byte(searchContent), 0644)
+	require.NoError(t, err)
+
+	analyzer := New(&Config{SourceProvider: "gcp", TargetProvider: "aws"})
+
+	project, err := analyzer.AnalyzeProject(context.Background(), testDir)
+	require.NoError(t, err)
+
+	require.Len(t, project.VectorStores, 2)
+	retriever := project.VectorStores[0]
+	assert.Equal(t, "firestore", retriever.Kind)
+	assert.Equal(t, "menuRetriever", retriever.Name)
+	assert.Equal(t, "menu", retriever.Collection)
+	assert.Equal(t, "text", retriever.ContentField)
+	assert.Equal(t, 12, retriever.Position.Line)
+
+	assert.Equal(t, "vertex-vector-search", project.VectorStores[1].Kind)
+	assert.Contains(t, project.Files, "search.go")
+}
+
+func TestAnalyzeDirectCalls(t *testing.T) {
+	testDir := createTestProject(t)
+	defer os.RemoveAll(testDir)
+
+	genaiContent := `package main
+
+import (
+	"context"
+
+	"google.golang.org/genai"
+)
+
+func summarize(ctx context.Context, client *genai.Client, text string) (string, error) {
+	resp, err := client.Models.GenerateContent(ctx, "gemini-2.0-flash", genai.Text(text), nil)
+	if err != nil {
+		return "", err
+	}
+	return resp.Text(), nil
+}
+`
+	vertexContent := `package main
+
+import (
+	"context"
+
+	"cloud.google.com/go/vertexai/genai"
+)
+
+func newModel(ctx context.Context) (*genai.GenerativeModel, error) {
+	client, err := genai.NewClient(ctx, "my-project", "us-central1")
+	if err != nil {
+		return nil, err
+	}
+	return client.GenerativeModel("gemini-1.5-pro"), nil
+}
+`
+	err := os.WriteFile(filepath.Join(testDir, "summarize.go"), []byte(genaiContent), 0644)
+	require.NoError(t, err)
+	err = os.WriteFile(filepath.Join(testDir, "vertex.go"), []byte(vertexContent), 0644)
+	require.NoError(t, err)
+
+	analyzer := New(&Config{SourceProvider: "gcp", TargetProvider: "aws"})
+
+	project, err := analyzer.AnalyzeProject(context.Background(), testDir)
+	require.NoError(t, err)
+
+	assert.Contains(t, project.Files, "summarize.go")
+	assert.Empty(t, project.CloudServices)
+	require.Len(t, project.DirectCalls, 3)
+
+	generate := project.DirectCalls[0]
+	assert.Equal(t, "google.golang.org/genai", generate.Package)
+	assert.Equal(t, "GenerateContent", generate.Method)
+	assert.Equal(t, "googleai/gemini-2.0-flash", generate.Model)
+	assert.Equal(t, 10, generate.Position.Line)
+	assert.Equal(t, 15, generate.Position.Column)
+
+	assert.Equal(t, "NewClient", project.DirectCalls[1].Method)
+	assert.Equal(t, "vertexai/gemini-1.5-pro", project.DirectCalls[2].Model)
+
+	var names []string
+	for _, model := range project.Models {
+		names = append(names, model.Name)
+	}
+	assert.Contains(t, names, "googleai/gemini-2.0-flash")
+	assert.Contains(t, names, "vertexai/gemini-1.5-pro")
+}
+
+func TestAnalyzeSafetySettings(t *testing.T) {
+	testDir := createTestProject(t)
+	defer os.RemoveAll(testDir)
+
+	configContent := `package main
+
+import (
+	"github.com/firebase/genkit/go/genkit"
+	"google.golang.org/genai"
+)
+
+var config = &genai.GenerateContentConfig{
+	SafetySettings: []*genai.SafetySetting{
+		{Category: genai.HarmCategoryHateSpeech, Threshold: genai.HarmBlockThresholdBlockLowAndAbove},
+		{Category: "HARM_CATEGORY_CIVIC_INTEGRITY", Threshold: level},
+	},
+}
+`
+	handleContent := `package main
+
+import (
+	gemini "github.com/google/generative-ai-go/genai"
+)
+
+func configure(model *gemini.GenerativeModel) {
+	model.SafetySettings = append(model.SafetySettings, &gemini.SafetySetting{
+		Category:  gemini.HarmCategoryDangerousContent,
+		Threshold: gemini.HarmBlockOnlyHigh,
+	})
+}
+`
+	promptContent := `---
+model: googleai/gemini-1.5-flash
+config:
+  safetySettings:
+    - category: HARM_CATEGORY_HARASSMENT
+      threshold: BLOCK_MEDIUM_AND_ABOVE
+---
+Hello {{name}}
+`
+	err := os.WriteFile(filepath.Join(testDir, "config.go"), []byte(configContent), 0644)
+	require.NoError(t, err)
+	err = os.WriteFile(filepath.Join(testDir, "handle.go"), []byte(handleContent), 0644)
+	require.NoError(t, err)
+	err = os.WriteFile(filepath.Join(testDir, "hello.prompt"), []byte(promptContent), 0644)
+	require.NoError(t, err)
+
+	analyzer := New(&Config{SourceProvider: "gcp", TargetProvider: "aws"})
+
+	project, err := analyzer.AnalyzeProject(context.Background(), testDir)
+	require.NoError(t, err)
+
+	assert.Contains(t, project.Files, "handle.go")
+
+	var settings []string
+	lines := make(map[string]int)
+	for _, setting := range project.SafetySettings {
+		settings = append(settings, setting.Category+" "+setting.Threshold)
+		lines[setting.Category] = setting.Position.Line
+	}
+	assert.ElementsMatch(t, []string{
+		"HARM_CATEGORY_HATE_SPEECH BLOCK_LOW_AND_ABOVE",
+		"HARM_CATEGORY_CIVIC_INTEGRITY level",
+		"HARM_CATEGORY_DANGEROUS_CONTENT BLOCK_ONLY_HIGH",
+		"HARM_CATEGORY_HARASSMENT BLOCK_MEDIUM_AND_ABOVE",
+	}, settings)
+	assert.Equal(t, 10, lines["HARM_CATEGORY_HATE_SPEECH"])
+	assert.Equal(t, 8, lines["HARM_CATEGORY_DANGEROUS_CONTENT"])
+	assert.Equal(t, 5, lines["HARM_CATEGORY_HARASSMENT"])
+}
+
+func TestAnalyzeStructuredOutputs(t *testing.T) {
+	testDir := createTestProject(t)
+	defer os.RemoveAll(testDir)
+
+	flowsContent := `package main
+
+import (
+	"context"
+
+	"github.com/firebase/genkit/go/ai"
+	"github.com/firebase/genkit/go/genkit"
+	"google.golang.org/genai"
+)
+
+type Recipe struct {
+	Title string
+}
+
+func flows(g *genkit.Genkit) {
+	genkit.DefineFlow(g, "recipeFlow", func(ctx context.Context, dish string) (*Recipe, error) {
+		recipe, _, err := genkit.GenerateData[Recipe](ctx, g,
+			ai.WithModelName("googleai/gemini-2.0-flash"),
+			ai.WithPrompt("Recipe for %s", dish))
+		return recipe, err
+	})
+
+	genkit.DefineFlow(g, "titleFlow", func(ctx context.Context, dish string) (string, error) {
+		resp, err := genkit.Generate(ctx, g, ai.WithPrompt(dish), ai.WithOutputType(&Recipe{}))
+		if err != nil {
+			return "", err
+		}
+		return resp.Text(), nil
+	})
+}
+
+var config = &genai.GenerateContentConfig{
+	ResponseMIMEType: "application/json",
+	ResponseSchema:   &genai.Schema{Type: genai.TypeObject},
+}
+`
+	err := os.WriteFile(filepath.Join(testDir, "flows.go"), []byte(flowsContent), 0644)
+	require.NoError(t, err)
+
+	analyzer := New(&Config{SourceProvider: "gcp", TargetProvider: "aws"})
+
+	project, err := analyzer.AnalyzeProject(context.Background(), testDir)
+	require.NoError(t, err)
+
+	flows := make(map[string]bool)
+	for _, flow := range project.Flows {
+		flows[flow.Name] = flow.StructuredOutput
+		if flow.Name == "recipeFlow" {
+			assert.Equal(t, "string", flow.InputType)
+			assert.Equal(t, "*Recipe", flow.OutputType)
+		}
+	}
+	// The pre-1.0 flow of main.go generates plain text.
+	assert.Equal(t, map[string]bool{"recipeFlow": true, "titleFlow": true, "summarize": false}, flows)
+
+	require.Len(t, project.StructuredOutputs, 3)
+	generateData := project.StructuredOutputs[0]
+	assert.Equal(t, "recipeFlow", generateData.Flow)
+	assert.Equal(t, "GenerateData", generateData.Method)
+	assert.Equal(t, "Recipe", generateData.Type)
+	assert.Equal(t, "googleai/gemini-2.0-flash", generateData.Model)
+	assert.Equal(t, 17, generateData.Position.Line)
+
+	outputType := project.StructuredOutputs[1]
+	assert.Equal(t, "titleFlow", outputType.Flow)
+	assert.Equal(t, "WithOutputType", outputType.Method)
+	assert.Equal(t, "Recipe", outputType.Type)
+	assert.Empty(t, outputType.Model)
+
+	schema := project.StructuredOutputs[2]
+	assert.Empty(t, schema.Flow)
+	assert.Equal(t, "ResponseSchema", schema.Method)
+	assert.Equal(t, 34, schema.Position.Line)
+}
+
+func TestAnalyzeFeatures(t *testing.T) {
+	testDir := createTestProject(t)
+	defer os.RemoveAll(testDir)
+
+	searchContent := `package main
+
+import (
+	"context"
+
+	"google.golang.org/genai"
+)
+
+func search(ctx context.Context, client *genai.Client) (*genai.GenerateContentResponse, error) {
+	cache, err := client.Caches.Create(ctx, "gemini-2.0-flash", nil)
+	if err != nil {
+		return nil, err
+	}
+	return client.Models.GenerateContent(ctx, "gemini-2.0-flash", genai.Text("news"), &genai.GenerateContentConfig{
+		CachedContent: cache.Name,
+		Tools:         []*genai.Tool{{GoogleSearch: &genai.GoogleSearch{}}},
+	})
+}
+`
+	err := os.WriteFile(filepath.Join(testDir, "search.go"), []byte(searchContent), 0644)
+	require.NoError(t, err)
+
+	promptContent := `---
+model: googleai/gemini-2.0-flash
+config:
+  temperature: 0.2
+  codeExecution: true
+---
+Compute {{n}} factorial.
+`
+	err = os.WriteFile(filepath.Join(testDir, "factorial.prompt"), []byte(promptContent), 0644)
+	require.NoError(t, err)
+
+	envContent := "RAG_CORPUS=projects/demo/locations/us-central1/ragCorpora/1\nGOOGLE_SEARCH_GROUNDING=false\n"
+	err = os.WriteFile(filepath.Join(testDir, ".env"), []byte(envContent), 0644)
+	require.NoError(t, err)
+
+	analyzer := New(&Config{SourceProvider: "gcp", TargetProvider: "aws"})
+
+	project, err := analyzer.AnalyzeProject(context.Background(), testDir)
+	require.NoError(t, err)
+
+	// The file has no GenKit import but is kept for its features.
+	require.Contains(t, project.Files, "search.go")
+
+	require.Len(t, project.Features, 5)
+	usages := make(map[string]int)
+	for _, usage := range project.Features {
+		usages[usage.Feature+" "+usage.Detail] = usage.Position.Line
+	}
+	assert.Equal(t, map[string]int{
+		"context-caching Caches":               10,
+		"context-caching CachedContent":        15,
+		"google-search-grounding GoogleSearch": 16,
+		"code-execution codeExecution":         5,
+		"vertex-rag-engine RAG_CORPUS":         1,
+	}, usages)
+}
+
+func TestAnalyzeStreamingFlows(t *testing.T) {
+	testDir := createTestProject(t)
+	defer os.RemoveAll(testDir)
+
+	chatContent := `package main
+
+import (
+	"context"
+
+	"github.com/firebase/genkit/go/ai"
+	"github.com/firebase/genkit/go/genkit"
+)
+
+func chat(g *genkit.Genkit) {
+	genkit.DefineStreamingFlow(g, "chat", func(ctx context.Context, q string, cb func(context.Context, string) error) (string, error) {
+		return q, nil
+	})
+
+	genkit.DefineFlow(g, "story", func(ctx context.Context, topic string) (string, error) {
+		resp, err := genkit.Generate(ctx, g,
+			ai.WithModelName("googleai/gemini-1.5-flash"),
+			ai.WithStreaming(func(ctx context.Context, chunk *ai.ModelResponseChunk) error { return nil }))
+		if err != nil {
+			return "", err
+		}
+		return resp.Text(), nil
+	})
+}
+`
+	err := os.WriteFile(filepath.Join(testDir, "chat.go"), []byte(chatContent), 0644)
+	require.NoError(t, err)
+
+	analyzer := New(&Config{SourceProvider: "gcp", TargetProvider: "aws"})
+
+	project, err := analyzer.AnalyzeProject(context.Background(), testDir)
+	require.NoError(t, err)
+
+	streaming := make(map[string]bool)
+	for _, flow := range project.Flows {
+		streaming[flow.Name] = flow.Streaming
+	}
+	assert.Equal(t, map[string]bool{"chat": true, "story": true, "summarize": false}, streaming)
+
+	var names []string
+	for _, model := range project.Models {
+		names = append(names, model.Name)
+	}
+	assert.Contains(t, names, "googleai/gemini-1.5-flash")
+}
+
+func TestAnalyzeDeployment(t *testing.T) {
+	testDir := createTestProject(t)
+	defer os.RemoveAll(testDir)
+
+	analyzer := New(&Config{SourceProvider: "gcp", TargetProvider: "aws"})
+
+	project, err := analyzer.AnalyzeProject(context.Background(), testDir)
+	require.NoError(t, err)
+	assert.Nil(t, project.Deployment)
+
+	deployScript := `#!/bin/sh
+gcloud functions deploy summarize \
+  --gen2 --runtime=go122 \
+  --memory=1GiB --timeout=540s --max-instances 20
+`
+	err = os.WriteFile(filepath.Join(testDir, "deploy.sh"), []byte(deployScript), 0644)
+	require.NoError(t, err)
+
+	project, err = analyzer.AnalyzeProject(context.Background(), testDir)
+	require.NoError(t, err)
+	require.NotNil(t, project.Deployment)
+	assert.Equal(t, &models.Deployment{
+		Platform:     "cloud-functions",
+		File:         "deploy.sh",
+		Memory:       "1GiB",
+		Timeout:      "540s",
+		MaxInstances: 20,
+	}, project.Deployment)
+
+	// GKE manifests take precedence over deploy commands.
+	gkeContent := `apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: summarize
+spec:
+  replicas: 2
+  template:
+    spec:
+      containers:
+        - name: app
+          image: gcr.io/demo/summarize
+          ports:
+            - containerPort: 3400
+          resources:
+            requests:
+              cpu: 250m
+              memory: 256Mi
+            limits:
+              memory: 512Mi
+          readinessProbe:
+            httpGet:
+              path: /healthz
+              port: 3400
+            periodSeconds: 5
+---
+apiVersion: autoscaling/v2
+kind: HorizontalPodAutoscaler
+metadata:
+  name: summarize
+spec:
+  minReplicas: 3
+  maxReplicas: 12
+`
+	err = os.MkdirAll(filepath.Join(testDir, "k8s"), 0755)
+	require.NoError(t, err)
+	err = os.WriteFile(filepath.Join(testDir, "k8s", "app.yaml"), []byte(gkeContent), 0644)
+	require.NoError(t, err)
+
+	project, err = analyzer.AnalyzeProject(context.Background(), testDir)
+	require.NoError(t, err)
+	assert.Equal(t, &models.Deployment{
+		Platform:     "gke",
+		File:         filepath.Join("k8s", "app.yaml"),
+		CPU:          "250m",
+		Memory:       "512Mi",
+		MinInstances: 3,
+		MaxInstances: 12,
+		Port:         3400,
+		Container: &models.Container{
+			Resources: map[string]map[string]string{
+				"requests": {"cpu": "250m", "memory": "256Mi"},
+				"limits":   {"memory": "512Mi"},
+			},
+			ReadinessProbe: map[string]interface{}{
+				"httpGet":       map[string]interface{}{"path": "/healthz", "port": 3400},
+				"periodSeconds": 5,
+			},
+		},
+	}, project.Deployment)
+
+	// A Cloud Run service.yaml takes precedence over deploy commands.
+	serviceContent := `apiVersion: serving.knative.dev/v1
+kind: Service
+metadata:
+  name: summarize
+spec:
+  template:
+    metadata:
+      annotations:
+        autoscaling.knative.dev/minScale: "1"
+        autoscaling.knative.dev/maxScale: "5"
+    spec:
+      containerConcurrency: 40
+      timeoutSeconds: 900
+      containers:
+        - image: gcr.io/demo/summarize
+          ports:
+            - containerPort: 9090
+          resources:
+            limits:
+              cpu: "2"
+              memory: 2Gi
+`
+	err = os.MkdirAll(filepath.Join(testDir, "deploy"), 0755)
+	require.NoError(t, err)
+	err = os.WriteFile(filepath.Join(testDir, "deploy", "service.yaml"), []byte(serviceContent), 0644)
+	require.NoError(t, err)
+
+	project, err = analyzer.AnalyzeProject(context.Background(), testDir)
+	require.NoError(t, err)
+	assert.Equal(t, &models.Deployment{
+		Platform:     "cloud-run",
+		File:         filepath.Join("deploy", "service.yaml"),
+		CPU:          "2",
+		Memory:       "2Gi",
+		Timeout:      "900s",
+		MinInstances: 1,
+		MaxInstances: 5,
+		Concurrency:  40,
+		Port:         9090,
+	}, project.Deployment)
+}
+
 func createTestProject(t *testing.T) string {
 	tempDir, err := os.MkdirTemp("", "genkit-test-*")
 	require.NoError(t, err)
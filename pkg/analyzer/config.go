@@ -0,0 +1,189 @@
+package analyzer
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/genkit-migrate/genkit-migrate/pkg/models"
+	"gopkg.in/yaml.v3"
+)
+
+var gcpKeyPrefixes = []string{"GOOGLE_", "GCLOUD_", "GCP_", "GEMINI_", "FIREBASE_"}
+
+var gcpSections = map[string]bool{"GCP": true, "GOOGLE": true, "GCLOUD": true, "GOOGLEAI": true, "VERTEXAI": true}
+
+var secretKeyMarkers = []string{"KEY", "SECRET", "TOKEN", "PASSWORD", "CREDENTIAL", "PRIVATE"}
+
+func (a *Analyzer) parseConfigFile(filename, configPath string) (*models.ConfigFile, error) {
+	content, err := os.ReadFile(configPath)
+	if err != nil {
+		return nil, err
+	}
+
+	configFile := &models.ConfigFile{
+		Path:     configPath,
+		Settings: make([]*models.ConfigSetting, 0),
+	}
+
+	switch {
+	case filename == ".env":
+		configFile.Format = "env"
+		err = parseEnvSettings(content, configFile)
+	case strings.HasSuffix(filename, ".json"):
+		configFile.Format = "json"
+		err = parseYAMLSettings(content, configFile)
+	default:
+		configFile.Format = "yaml"
+		err = parseYAMLSettings(content, configFile)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	values := make(map[string]string, len(configFile.Settings))
+	for _, setting := range configFile.Settings {
+		values[setting.Key] = setting.Value
+	}
+
+	for _, setting := range configFile.Settings {
+		setting.Secret = isSecretKey(setting.Key) || isSecretEntry(setting.Key, values)
+		setting.GCP = isGCPSetting(filename, setting.Key)
+		if setting.Secret {
+			setting.Value = ""
+		}
+	}
+
+	return configFile, nil
+}
+
+func parseEnvSettings(content []byte, configFile *models.ConfigFile) error {
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	line := 0
+	for scanner.Scan() {
+		line++
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+
+		text = strings.TrimPrefix(text, "export ")
+		key, value, found := strings.Cut(text, "=")
+		if !found {
+			return fmt.Errorf("line %d: expected KEY=value", line)
+		}
+
+		configFile.Settings = append(configFile.Settings, &models.ConfigSetting{
+			Key:   strings.TrimSpace(key),
+			Value: strings.Trim(strings.TrimSpace(value), `"'`),
+			Line:  line,
+		})
+	}
+	return scanner.Err()
+}
+
+// JSON is a subset of YAML, so config.json goes through the same node walk,
+// which keeps key order and line numbers.
+func parseYAMLSettings(content []byte, configFile *models.ConfigFile) error {
+	var root yaml.Node
+	if err := yaml.Unmarshal(content, &root); err != nil {
+		return err
+	}
+
+	flattenNode("", &root, configFile)
+	return nil
+}
+
+func flattenNode(prefix string, node *yaml.Node, configFile *models.ConfigFile) {
+	switch node.Kind {
+	case yaml.DocumentNode:
+		for _, child := range node.Content {
+			flattenNode(prefix, child, configFile)
+		}
+	case yaml.MappingNode:
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			flattenNode(joinKey(prefix, node.Content[i].Value), node.Content[i+1], configFile)
+		}
+	case yaml.SequenceNode:
+		for i, child := range node.Content {
+			flattenNode(joinKey(prefix, fmt.Sprint(i)), child, configFile)
+		}
+	case yaml.AliasNode:
+		flattenNode(prefix, node.Alias, configFile)
+	case yaml.ScalarNode:
+		configFile.Settings = append(configFile.Settings, &models.ConfigSetting{
+			Key:   prefix,
+			Value: node.Value,
+			Line:  node.Line,
+		})
+	}
+}
+
+func joinKey(prefix, key string) string {
+	if prefix == "" {
+		return key
+	}
+	return prefix + "." + key
+}
+
+// lastKeySegment returns the upper-case name of a flattened key. Items of a
+// sequence are named after the sequence, so api_keys.0 is API_KEYS.
+func lastKeySegment(key string) string {
+	segments := strings.Split(key, ".")
+	for len(segments) > 1 && isIndex(segments[len(segments)-1]) {
+		segments = segments[:len(segments)-1]
+	}
+	return strings.ToUpper(segments[len(segments)-1])
+}
+
+func isIndex(segment string) bool {
+	_, err := strconv.Atoi(segment)
+	return err == nil
+}
+
+func isSecretKey(key string) bool {
+	name := lastKeySegment(key)
+	for _, marker := range secretKeyMarkers {
+		if strings.Contains(name, marker) {
+			return true
+		}
+	}
+	return false
+}
+
+// isSecretEntry reports whether key is the value of a name/value entry, as in
+// env: [{name: API_KEY, value: ...}], whose name is a secret key.
+func isSecretEntry(key string, values map[string]string) bool {
+	prefix, found := strings.CutSuffix(key, ".value")
+	if !found {
+		return false
+	}
+	name, exists := values[prefix+".name"]
+	return exists && isSecretKey(name)
+}
+
+func isGCPKey(key string) bool {
+	for _, segment := range strings.Split(strings.ToUpper(key), ".") {
+		if gcpSections[segment] {
+			return true
+		}
+		for _, prefix := range gcpKeyPrefixes {
+			if strings.HasPrefix(segment, prefix) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Everything in app.yaml apart from env_variables describes the App Engine
+// service itself, so it is GCP-specific by definition.
+func isGCPSetting(filename, key string) bool {
+	if filename == "app.yaml" && !strings.HasPrefix(key, "env_variables.") {
+		return true
+	}
+	return isGCPKey(key)
+}
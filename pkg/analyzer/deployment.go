@@ -0,0 +1,312 @@
+package analyzer
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"go/parser"
+	"go/token"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/genkit-migrate/genkit-migrate/pkg/models"
+	"gopkg.in/yaml.v3"
+)
+
+const functionsFrameworkPackage = "github.com/GoogleCloudPlatform/functions-framework-go/functions"
+
+// deployScripts are the files that usually hold the gcloud command deploying
+// the app.
+var deployScripts = []string{"cloudbuild.yaml", "cloudbuild.yml", "deploy.sh", "Makefile"}
+
+// knativeService is the part of a Cloud Run service.yaml that sizes and
+// scales the service.
+type knativeService struct {
+	APIVersion string `yaml:"apiVersion"`
+	Kind       string `yaml:"kind"`
+	Spec       struct {
+		Template struct {
+			Metadata struct {
+				Annotations map[string]string `yaml:"annotations"`
+			} `yaml:"metadata"`
+			Spec struct {
+				ContainerConcurrency int `yaml:"containerConcurrency"`
+				TimeoutSeconds       int `yaml:"timeoutSeconds"`
+				Containers           []struct {
+					Ports []struct {
+						ContainerPort int `yaml:"containerPort"`
+					} `yaml:"ports"`
+					Resources struct {
+						Limits map[string]string `yaml:"limits"`
+					} `yaml:"resources"`
+				} `yaml:"containers"`
+			} `yaml:"spec"`
+		} `yaml:"template"`
+	} `yaml:"spec"`
+}
+
+// kubernetesWorkload is the part of a GKE Deployment or
+// HorizontalPodAutoscaler manifest that sizes, scales and probes the app.
+type kubernetesWorkload struct {
+	APIVersion string `yaml:"apiVersion"`
+	Kind       string `yaml:"kind"`
+	Spec       struct {
+		Replicas    int `yaml:"replicas"`
+		MinReplicas int `yaml:"minReplicas"`
+		MaxReplicas int `yaml:"maxReplicas"`
+		Template    struct {
+			Spec struct {
+				Containers []struct {
+					Ports []struct {
+						ContainerPort int `yaml:"containerPort"`
+					} `yaml:"ports"`
+					Resources      map[string]map[string]string `yaml:"resources"`
+					ReadinessProbe map[string]interface{}       `yaml:"readinessProbe"`
+					LivenessProbe  map[string]interface{}       `yaml:"livenessProbe"`
+					StartupProbe   map[string]interface{}       `yaml:"startupProbe"`
+				} `yaml:"containers"`
+			} `yaml:"spec"`
+		} `yaml:"template"`
+	} `yaml:"spec"`
+}
+
+// analyzeDeployment finds how the source app is deployed: a Cloud Run
+// service.yaml first, then GKE manifests, then gcloud run or functions deploy
+// commands, then Cloud Functions registrations in code.
+func (a *Analyzer) analyzeDeployment(project *models.Project) error {
+	var deployment, gke *models.Deployment
+	var functions string
+
+	err := filepath.Walk(project.Path, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if strings.Contains(path, "vendor/") || strings.Contains(path, ".git/") || info.IsDir() {
+			return nil
+		}
+		relPath, _ := filepath.Rel(project.Path, path)
+
+		switch filepath.Ext(path) {
+		case ".yaml", ".yml":
+			if deployment == nil {
+				deployment = knativeDeployment(path, relPath)
+			}
+			gke = gkeDeployment(path, relPath, gke)
+		case ".go":
+			if functions != "" {
+				return nil
+			}
+			node, err := parser.ParseFile(token.NewFileSet(), path, nil, parser.ImportsOnly)
+			if err != nil {
+				return nil
+			}
+			for _, imp := range node.Imports {
+				if strings.Trim(imp.Path.Value, `"`) == functionsFrameworkPackage {
+					functions = relPath
+				}
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to walk project directory: %w", err)
+	}
+
+	if deployment == nil && gke != nil && gke.Container != nil {
+		deployment = gke
+	}
+	if deployment == nil {
+		for _, script := range deployScripts {
+			if deployment = gcloudDeployment(filepath.Join(project.Path, script), script); deployment != nil {
+				break
+			}
+		}
+	}
+	if deployment == nil && functions != "" {
+		deployment = &models.Deployment{Platform: "cloud-functions", File: functions}
+	}
+
+	project.Deployment = deployment
+	return nil
+}
+
+func knativeDeployment(path, relPath string) *models.Deployment {
+	content, err := os.ReadFile(path)
+	if err != nil || !bytes.Contains(content, []byte("serving.knative.dev")) {
+		return nil
+	}
+
+	decoder := yaml.NewDecoder(bytes.NewReader(content))
+	for {
+		var service knativeService
+		if err := decoder.Decode(&service); err != nil {
+			return nil
+		}
+		if !strings.HasPrefix(service.APIVersion, "serving.knative.dev/") || service.Kind != "Service" {
+			continue
+		}
+
+		template := service.Spec.Template
+		deployment := &models.Deployment{
+			Platform:    "cloud-run",
+			File:        relPath,
+			Concurrency: template.Spec.ContainerConcurrency,
+		}
+		if template.Spec.TimeoutSeconds > 0 {
+			deployment.Timeout = fmt.Sprintf("%ds", template.Spec.TimeoutSeconds)
+		}
+		deployment.MinInstances, _ = strconv.Atoi(template.Metadata.Annotations["autoscaling.knative.dev/minScale"])
+		deployment.MaxInstances, _ = strconv.Atoi(template.Metadata.Annotations["autoscaling.knative.dev/maxScale"])
+		if len(template.Spec.Containers) > 0 {
+			container := template.Spec.Containers[0]
+			deployment.CPU = container.Resources.Limits["cpu"]
+			deployment.Memory = container.Resources.Limits["memory"]
+			if len(container.Ports) > 0 {
+				deployment.Port = container.Ports[0].ContainerPort
+			}
+		}
+		return deployment
+	}
+}
+
+// gkeDeployment adds the Deployment and HorizontalPodAutoscaler manifests of
+// a file to what earlier files declared. Only the first Deployment is read.
+func gkeDeployment(path, relPath string, deployment *models.Deployment) *models.Deployment {
+	content, err := os.ReadFile(path)
+	if err != nil || !bytes.Contains(content, []byte("kind: Deployment")) && !bytes.Contains(content, []byte("kind: HorizontalPodAutoscaler")) {
+		return deployment
+	}
+
+	decoder := yaml.NewDecoder(bytes.NewReader(content))
+	for {
+		var workload kubernetesWorkload
+		if err := decoder.Decode(&workload); err != nil {
+			return deployment
+		}
+
+		switch {
+		case workload.APIVersion == "apps/v1" && workload.Kind == "Deployment":
+			if deployment != nil && deployment.Container != nil {
+				continue
+			}
+			containers := workload.Spec.Template.Spec.Containers
+			if len(containers) == 0 {
+				continue
+			}
+			if deployment == nil {
+				deployment = &models.Deployment{Platform: "gke"}
+			}
+			deployment.File = relPath
+			if deployment.MinInstances == 0 {
+				deployment.MinInstances = workload.Spec.Replicas
+				deployment.MaxInstances = workload.Spec.Replicas
+			}
+
+			container := containers[0]
+			for _, resources := range []string{"requests", "limits"} {
+				if cpu := container.Resources[resources]["cpu"]; cpu != "" {
+					deployment.CPU = cpu
+				}
+				if memory := container.Resources[resources]["memory"]; memory != "" {
+					deployment.Memory = memory
+				}
+			}
+			if len(container.Ports) > 0 {
+				deployment.Port = container.Ports[0].ContainerPort
+			}
+			deployment.Container = &models.Container{
+				Resources:      container.Resources,
+				ReadinessProbe: container.ReadinessProbe,
+				LivenessProbe:  container.LivenessProbe,
+				StartupProbe:   container.StartupProbe,
+			}
+		case strings.HasPrefix(workload.APIVersion, "autoscaling/") && workload.Kind == "HorizontalPodAutoscaler":
+			if deployment == nil {
+				deployment = &models.Deployment{Platform: "gke", File: relPath}
+			}
+			deployment.MinInstances = max(workload.Spec.MinReplicas, 1)
+			deployment.MaxInstances = workload.Spec.MaxReplicas
+		}
+	}
+}
+
+// gcloudDeployment reads the flags of a gcloud run deploy or gcloud
+// functions deploy command, from the args of a Cloud Build step or the lines
+// of a script.
+func gcloudDeployment(path, relPath string) *models.Deployment {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil
+	}
+
+	var commands [][]string
+	if strings.HasPrefix(relPath, "cloudbuild.") {
+		var build struct {
+			Steps []struct {
+				Args []string `yaml:"args"`
+			} `yaml:"steps"`
+		}
+		if err := yaml.Unmarshal(content, &build); err != nil {
+			return nil
+		}
+		for _, step := range build.Steps {
+			commands = append(commands, step.Args)
+		}
+	} else {
+		scanner := bufio.NewScanner(bytes.NewReader(content))
+		var line string
+		for scanner.Scan() {
+			line += scanner.Text()
+			if strings.HasSuffix(line, `\`) {
+				line = strings.TrimSuffix(line, `\`) + " "
+				continue
+			}
+			commands = append(commands, strings.Fields(line))
+			line = ""
+		}
+	}
+
+	for _, args := range commands {
+		platform := ""
+		for i := 0; i+1 < len(args); i++ {
+			switch {
+			case args[i] == "run" && args[i+1] == "deploy":
+				platform = "cloud-run"
+			case args[i] == "functions" && args[i+1] == "deploy":
+				platform = "cloud-functions"
+			}
+		}
+		if platform == "" {
+			continue
+		}
+
+		deployment := &models.Deployment{Platform: platform, File: relPath}
+		for i, arg := range args {
+			flag, value, found := strings.Cut(arg, "=")
+			if !found && i+1 < len(args) {
+				value = args[i+1]
+			}
+			value = strings.Trim(value, `"'`)
+			switch flag {
+			case "--cpu":
+				deployment.CPU = value
+			case "--memory":
+				deployment.Memory = value
+			case "--timeout":
+				deployment.Timeout = value
+			case "--min-instances":
+				deployment.MinInstances, _ = strconv.Atoi(value)
+			case "--max-instances":
+				deployment.MaxInstances, _ = strconv.Atoi(value)
+			case "--concurrency":
+				deployment.Concurrency, _ = strconv.Atoi(value)
+			case "--port":
+				deployment.Port, _ = strconv.Atoi(value)
+			}
+		}
+		return deployment
+	}
+	return nil
+}
@@ -0,0 +1,109 @@
+package analyzer
+
+import (
+	"go/ast"
+	"go/token"
+	"slices"
+	"strings"
+
+	"github.com/genkit-migrate/genkit-migrate/pkg/models"
+	"github.com/genkit-migrate/genkit-migrate/pkg/provider"
+	"github.com/genkit-migrate/genkit-migrate/pkg/rewrite"
+)
+
+// clientSources returns the model SDKs of every source provider, keyed by
+// import path.
+func clientSources() map[string]provider.ClientSource {
+	clients := make(map[string]provider.ClientSource)
+	for _, name := range provider.Sources() {
+		source, err := provider.Get(name)
+		if err != nil {
+			continue
+		}
+		for _, client := range source.Source().Clients {
+			clients[client.Path] = client
+		}
+	}
+	return clients
+}
+
+// extractDirectCalls finds calls into model SDKs that bypass GenKit, such as
+// client.Models.GenerateContent(ctx, "gemini-2.0-flash", ...).
+func (a *Analyzer) extractDirectCalls(node *ast.File, fset *token.FileSet) []*models.DirectCall {
+	calls := make([]*models.DirectCall, 0)
+	sources := clientSources()
+
+	clients := make(map[string]provider.ClientSource)
+	for _, imp := range node.Imports {
+		importPath := strings.Trim(imp.Path.Value, `"`)
+		client, exists := sources[importPath]
+		if !exists {
+			continue
+		}
+		localName := rewrite.DefaultImportName(importPath)
+		if imp.Name != nil {
+			localName = imp.Name.Name
+		}
+		clients[localName] = client
+	}
+	if len(clients) == 0 {
+		return calls
+	}
+
+	ast.Inspect(node, func(n ast.Node) bool {
+		call, ok := n.(*ast.CallExpr)
+		if !ok {
+			return true
+		}
+		sel, ok := call.Fun.(*ast.SelectorExpr)
+		if !ok {
+			return true
+		}
+
+		if ident, ok := sel.X.(*ast.Ident); ok {
+			if client, imported := clients[ident.Name]; imported {
+				if sel.Sel.Name == "NewClient" {
+					calls = append(calls, &models.DirectCall{
+						Package:  client.Path,
+						Method:   sel.Sel.Name,
+						Position: fset.Position(call.Pos()),
+					})
+				}
+				return true
+			}
+		}
+
+		for _, client := range clients {
+			if !slices.Contains(client.Methods, sel.Sel.Name) {
+				continue
+			}
+			direct := &models.DirectCall{
+				Package:  client.Path,
+				Method:   sel.Sel.Name,
+				Position: fset.Position(call.Pos()),
+			}
+			if name, ok := directCallModel(client, sel.Sel.Name, call); ok {
+				direct.Model = client.Prefix + name
+			}
+			calls = append(calls, direct)
+			break
+		}
+		return true
+	})
+
+	return calls
+}
+
+// directCallModel returns the model a call names: the handle constructors
+// take it first, and per-call clients take it after the context.
+func directCallModel(client provider.ClientSource, method string, call *ast.CallExpr) (string, bool) {
+	switch {
+	case method == "GenerativeModel" || method == "EmbeddingModel":
+		if len(call.Args) > 0 {
+			return stringArg(call.Args[0])
+		}
+	case !client.Handles && len(call.Args) > 1:
+		return stringArg(call.Args[1])
+	}
+	return "", false
+}
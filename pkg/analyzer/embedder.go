@@ -0,0 +1,125 @@
+package analyzer
+
+import (
+	"go/ast"
+	"go/token"
+	"strconv"
+	"strings"
+
+	"github.com/genkit-migrate/genkit-migrate/pkg/models"
+)
+
+// Default output dimensions of the Google and Bedrock embedding models.
+var embedderDimensions = map[string]int{
+	"googleai/text-embedding-004":              768,
+	"googleai/embedding-001":                   768,
+	"googleai/gemini-embedding-001":            3072,
+	"vertexai/text-embedding-004":              768,
+	"vertexai/text-embedding-005":              768,
+	"vertexai/text-multilingual-embedding-002": 768,
+	"vertexai/textembedding-gecko@003":         768,
+	"vertexai/gemini-embedding-001":            3072,
+	"bedrock/amazon.titan-embed-text-v2:0":     1024,
+	"bedrock/amazon.titan-embed-text-v1":       1536,
+	"bedrock/cohere.embed-english-v3":          1024,
+	"bedrock/cohere.embed-multilingual-v3":     1024,
+}
+
+// Plugin helpers that resolve an embedder by its unprefixed name.
+var embedderConstructors = map[string]string{
+	"googleai.Embedder":            "googleai/",
+	"vertexai.Embedder":            "vertexai/",
+	"googlegenai.Embedder":         "googleai/",
+	"googlegenai.GoogleAIEmbedder": "googleai/",
+	"googlegenai.VertexAIEmbedder": "vertexai/",
+	"bedrock.Embedder":             "bedrock/",
+}
+
+func (a *Analyzer) extractEmbedder(call *ast.CallExpr, fset *token.FileSet) *models.Embedder {
+	sel, ok := call.Fun.(*ast.SelectorExpr)
+	if !ok || len(call.Args) == 0 {
+		return nil
+	}
+	pkg, ok := sel.X.(*ast.Ident)
+	if !ok {
+		return nil
+	}
+
+	name, ok := stringArg(call.Args[len(call.Args)-1])
+	if !ok {
+		return nil
+	}
+
+	if prefix, exists := embedderConstructors[pkg.Name+"."+sel.Sel.Name]; exists {
+		name = prefix + name
+	} else if !isEmbedderName(name) {
+		return nil
+	}
+
+	return &models.Embedder{
+		Name:       name,
+		Provider:   a.detectModelProvider(name),
+		Dimensions: embedderDimensions[name],
+		Position:   fset.Position(call.Pos()),
+	}
+}
+
+func isEmbedderName(name string) bool {
+	if _, known := embedderDimensions[name]; known {
+		return true
+	}
+	if strings.HasPrefix(name, "bedrock/") {
+		return strings.Contains(name, "embed")
+	}
+	return (strings.HasPrefix(name, "googleai/") || strings.HasPrefix(name, "vertexai/")) &&
+		strings.Contains(name, "embedding")
+}
+
+// extractIndexer recognises genkit.DefineIndexer(g, provider, name, fn) as
+// well as plugin helpers such as localvec.DefineIndexerAndRetriever(g, name, cfg).
+func (a *Analyzer) extractIndexer(call *ast.CallExpr, fset *token.FileSet, packageName string) *models.Indexer {
+	sel, ok := call.Fun.(*ast.SelectorExpr)
+	if !ok || !strings.HasPrefix(sel.Sel.Name, "DefineIndexer") {
+		return nil
+	}
+	pkg, ok := sel.X.(*ast.Ident)
+	if !ok {
+		return nil
+	}
+
+	names := make([]string, 0, 2)
+	for _, arg := range call.Args {
+		if value, ok := stringArg(arg); ok {
+			names = append(names, value)
+		}
+	}
+
+	indexer := &models.Indexer{
+		Package:  packageName,
+		Position: fset.Position(call.Pos()),
+	}
+	switch len(names) {
+	case 0:
+		return nil
+	case 1:
+		indexer.Provider = pkg.Name
+		indexer.Name = names[0]
+	default:
+		indexer.Provider = names[0]
+		indexer.Name = names[1]
+	}
+
+	return indexer
+}
+
+func stringArg(expr ast.Expr) (string, bool) {
+	lit, ok := expr.(*ast.BasicLit)
+	if !ok || lit.Kind != token.STRING {
+		return "", false
+	}
+	value, err := strconv.Unquote(lit.Value)
+	if err != nil {
+		return "", false
+	}
+	return value, true
+}
@@ -0,0 +1,135 @@
+package analyzer
+
+import (
+	"fmt"
+	"go/ast"
+	"go/token"
+	"slices"
+	"strings"
+
+	"github.com/genkit-migrate/genkit-migrate/pkg/models"
+	"github.com/genkit-migrate/genkit-migrate/pkg/provider"
+	"gopkg.in/yaml.v3"
+)
+
+// sourceFeatures returns the provider-specific features of every source
+// provider.
+func sourceFeatures() []provider.Feature {
+	features := make([]provider.Feature, 0)
+	for _, name := range provider.Sources() {
+		source, err := provider.Get(name)
+		if err != nil {
+			continue
+		}
+		features = append(features, source.Source().Features...)
+	}
+	return features
+}
+
+// normalizeSetting compares setting names in upper case without separators,
+// so that googleSearchRetrieval and GOOGLE_SEARCH_RETRIEVAL agree.
+func normalizeSetting(name string) string {
+	return strings.ToUpper(strings.NewReplacer("_", "", "-", "").Replace(name))
+}
+
+func settingFeature(name string) (provider.Feature, bool) {
+	for _, feature := range sourceFeatures() {
+		if slices.Contains(feature.Settings, normalizeSetting(name)) {
+			return feature, true
+		}
+	}
+	return provider.Feature{}, false
+}
+
+// extractFeatures finds the identifiers of provider-specific features, such
+// as genai.Tool{GoogleSearch: ...} or client.Caches.Create, in files that
+// import their packages.
+func (a *Analyzer) extractFeatures(node *ast.File, fset *token.FileSet) []*models.FeatureUsage {
+	usages := make([]*models.FeatureUsage, 0)
+
+	imported := make(map[string]bool)
+	for _, imp := range node.Imports {
+		imported[strings.Trim(imp.Path.Value, `"`)] = true
+	}
+
+	identifiers := make(map[string]provider.Feature)
+	for _, feature := range sourceFeatures() {
+		if !slices.ContainsFunc(feature.Packages, func(pkg string) bool { return imported[pkg] }) {
+			continue
+		}
+		for _, identifier := range feature.Identifiers {
+			identifiers[identifier] = feature
+		}
+	}
+	if len(identifiers) == 0 {
+		return usages
+	}
+
+	// Uses such as GoogleSearch: &genai.GoogleSearch{} count once per line.
+	seen := make(map[string]bool)
+	add := func(ident *ast.Ident) {
+		feature, exists := identifiers[ident.Name]
+		position := fset.Position(ident.Pos())
+		key := fmt.Sprintf("%s:%d", feature.Name, position.Line)
+		if exists && !seen[key] {
+			seen[key] = true
+			usages = append(usages, &models.FeatureUsage{
+				Feature:  feature.Name,
+				Title:    feature.Title,
+				Detail:   ident.Name,
+				Position: position,
+			})
+		}
+	}
+
+	ast.Inspect(node, func(n ast.Node) bool {
+		switch node := n.(type) {
+		case *ast.SelectorExpr:
+			add(node.Sel)
+		case *ast.KeyValueExpr:
+			if key, ok := node.Key.(*ast.Ident); ok {
+				add(key)
+			}
+		}
+		return true
+	})
+
+	return usages
+}
+
+// promptFeatures reads the provider-specific options of a Dotprompt config.
+func promptFeatures(config *yaml.Node, filePath string) []*models.FeatureUsage {
+	usages := make([]*models.FeatureUsage, 0)
+	for i := 0; i+1 < len(config.Content); i += 2 {
+		key := config.Content[i]
+		if feature, exists := settingFeature(key.Value); exists {
+			usages = append(usages, &models.FeatureUsage{
+				Feature: feature.Name,
+				Title:   feature.Title,
+				Detail:  key.Value,
+				// Frontmatter starts on the line after the opening delimiter.
+				Position: token.Position{Filename: filePath, Line: key.Line + 1, Column: key.Column},
+			})
+		}
+	}
+	return usages
+}
+
+// configFeatures finds the settings that enable provider-specific features,
+// such as GOOGLE_SEARCH_GROUNDING=true in .env.
+func configFeatures(configFile *models.ConfigFile) []*models.FeatureUsage {
+	usages := make([]*models.FeatureUsage, 0)
+	for _, setting := range configFile.Settings {
+		feature, exists := settingFeature(lastKeySegment(setting.Key))
+		if !exists || strings.EqualFold(setting.Value, "false") {
+			continue
+		}
+		usages = append(usages, &models.FeatureUsage{
+			Feature:  feature.Name,
+			Title:    feature.Title,
+			Detail:   setting.Key,
+			Position: token.Position{Filename: configFile.Path, Line: setting.Line},
+		})
+	}
+	return usages
+}
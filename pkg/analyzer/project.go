@@ -8,9 +8,11 @@ import (
 	"go/token"
 	"os"
 	"path/filepath"
+	"slices"
 	"strings"
 
 	"github.com/genkit-migrate/genkit-migrate/pkg/models"
+	"github.com/genkit-migrate/genkit-migrate/pkg/provider"
 )
 
 type Analyzer struct {
@@ -20,7 +22,10 @@ type Analyzer struct {
 type Config struct {
 	SourceProvider string
 	TargetProvider string
-	Verbose        bool
+	// Packages are further import paths whose users need migrating, such as
+	// internal wrappers that user rewrite rules target.
+	Packages []string
+	Verbose  bool
 }
 
 func New(config *Config) *Analyzer {
@@ -29,14 +34,24 @@ func New(config *Config) *Analyzer {
 
 func (a *Analyzer) AnalyzeProject(ctx context.Context, projectPath string) (*models.Project, error) {
 	project := &models.Project{
-		Path:           projectPath,
-		SourceProvider: a.config.SourceProvider,
-		TargetProvider: a.config.TargetProvider,
-		Files:          make(map[string]*models.SourceFile),
-		Dependencies:   make(map[string]string),
-		Flows:          make([]*models.Flow, 0),
-		Models:         make([]*models.Model, 0),
-		Configuration:  make(map[string]interface{}),
+		Path:              projectPath,
+		SourceProvider:    a.config.SourceProvider,
+		TargetProvider:    a.config.TargetProvider,
+		Files:             make(map[string]*models.SourceFile),
+		Dependencies:      make(map[string]string),
+		Flows:             make([]*models.Flow, 0),
+		Models:            make([]*models.Model, 0),
+		Configuration:     make(map[string]interface{}),
+		CloudServices:     make([]*models.CloudService, 0),
+		ConfigFiles:       make(map[string]*models.ConfigFile),
+		Prompts:           make([]*models.Prompt, 0),
+		Embedders:         make([]*models.Embedder, 0),
+		Indexers:          make([]*models.Indexer, 0),
+		VectorStores:      make([]*models.VectorStore, 0),
+		DirectCalls:       make([]*models.DirectCall, 0),
+		SafetySettings:    make([]*models.SafetySetting, 0),
+		StructuredOutputs: make([]*models.StructuredOutput, 0),
+		Features:          make([]*models.FeatureUsage, 0),
 	}
 
 	err := filepath.Walk(projectPath, func(path string, info os.FileInfo, err error) error {
@@ -44,9 +59,29 @@ func (a *Analyzer) AnalyzeProject(ctx context.Context, projectPath string) (*mod
 			return err
 		}
 
-		if !strings.HasSuffix(path, ".go") ||
-			strings.Contains(path, "vendor/") ||
-			strings.Contains(path, ".git/") {
+		if strings.Contains(path, "vendor/") || strings.Contains(path, ".git/") {
+			return nil
+		}
+
+		if strings.HasSuffix(path, ".prompt") {
+			prompt, err := a.parsePromptFile(path, projectPath)
+			if err != nil {
+				if a.config.Verbose {
+					fmt.Printf("Warning: failed to parse %s: %v\n", path, err)
+				}
+				return nil
+			}
+
+			project.Prompts = append(project.Prompts, prompt)
+			if prompt.Model != nil {
+				project.Models = append(project.Models, prompt.Model)
+			}
+			project.SafetySettings = append(project.SafetySettings, prompt.SafetySettings...)
+			project.Features = append(project.Features, prompt.Features...)
+			return nil
+		}
+
+		if !strings.HasSuffix(path, ".go") {
 			return nil
 		}
 
@@ -61,9 +96,25 @@ func (a *Analyzer) AnalyzeProject(ctx context.Context, projectPath string) (*mod
 		if sourceFile != nil {
 			relPath, _ := filepath.Rel(projectPath, path)
 			project.Files[relPath] = sourceFile
+			// Cloud services are reported rather than rewritten, at paths
+			// relative to the project like the rest of the report.
+			for _, service := range sourceFile.CloudServices {
+				service.Position.Filename = relPath
+				for i := range service.CallSites {
+					service.CallSites[i].Filename = relPath
+				}
+			}
 
 			project.Flows = append(project.Flows, sourceFile.Flows...)
 			project.Models = append(project.Models, sourceFile.Models...)
+			project.CloudServices = append(project.CloudServices, sourceFile.CloudServices...)
+			project.Embedders = append(project.Embedders, sourceFile.Embedders...)
+			project.Indexers = append(project.Indexers, sourceFile.Indexers...)
+			project.VectorStores = append(project.VectorStores, sourceFile.VectorStores...)
+			project.DirectCalls = append(project.DirectCalls, sourceFile.DirectCalls...)
+			project.SafetySettings = append(project.SafetySettings, sourceFile.SafetySettings...)
+			project.StructuredOutputs = append(project.StructuredOutputs, sourceFile.StructuredOutputs...)
+			project.Features = append(project.Features, sourceFile.Features...)
 		}
 
 		return nil
@@ -83,6 +134,11 @@ func (a *Analyzer) AnalyzeProject(ctx context.Context, projectPath string) (*mod
 		return nil, fmt.Errorf("failed to analyze configuration: %w", err)
 	}
 
+	err = a.analyzeDeployment(project)
+	if err != nil {
+		return nil, fmt.Errorf("failed to analyze deployment: %w", err)
+	}
+
 	return project, nil
 }
 
@@ -99,6 +155,8 @@ func (a *Analyzer) parseGoFile(filePath, projectRoot string) (*models.SourceFile
 		Imports:     make([]string, 0),
 		Flows:       make([]*models.Flow, 0),
 		Models:      make([]*models.Model, 0),
+		Embedders:   make([]*models.Embedder, 0),
+		Indexers:    make([]*models.Indexer, 0),
 		HasGenKit:   false,
 	}
 
@@ -111,9 +169,35 @@ func (a *Analyzer) parseGoFile(filePath, projectRoot string) (*models.SourceFile
 			strings.Contains(importPath, "genkit/go/plugins") {
 			sourceFile.HasGenKit = true
 		}
+		for _, pkg := range a.config.Packages {
+			if importPath == pkg {
+				sourceFile.HasGenKit = true
+			}
+		}
+	}
+
+	sourceFile.CloudServices = a.extractCloudServices(node, fset)
+	sourceFile.VectorStores = a.extractVectorStores(node, fset)
+	sourceFile.DirectCalls = a.extractDirectCalls(node, fset)
+	sourceFile.SafetySettings = a.extractSafetySettings(node, fset)
+	sourceFile.StructuredOutputs = a.extractStructuredOutputs(node, fset)
+	sourceFile.Features = a.extractFeatures(node, fset)
+
+	for _, call := range sourceFile.DirectCalls {
+		if call.Model != "" && !strings.HasPrefix(call.Method, "Embed") {
+			sourceFile.Models = append(sourceFile.Models, &models.Model{
+				Name:     call.Model,
+				Provider: a.detectModelProvider(call.Model),
+				Position: call.Position,
+			})
+		}
 	}
 
 	if !sourceFile.HasGenKit {
+		if len(sourceFile.CloudServices) > 0 || len(sourceFile.VectorStores) > 0 || len(sourceFile.DirectCalls) > 0 ||
+			len(sourceFile.SafetySettings) > 0 || len(sourceFile.StructuredOutputs) > 0 || len(sourceFile.Features) > 0 {
+			return sourceFile, nil
+		}
 		return nil, nil
 	}
 
@@ -127,27 +211,59 @@ func (a *Analyzer) parseGoFile(filePath, projectRoot string) (*models.SourceFile
 			if model := a.extractModel(node, fset); model != nil {
 				sourceFile.Models = append(sourceFile.Models, model)
 			}
+
+			if embedder := a.extractEmbedder(node, fset); embedder != nil {
+				sourceFile.Embedders = append(sourceFile.Embedders, embedder)
+			}
+
+			if indexer := a.extractIndexer(node, fset, sourceFile.PackageName); indexer != nil {
+				sourceFile.Indexers = append(sourceFile.Indexers, indexer)
+			}
 		}
 		return true
 	})
 
+	for _, flow := range sourceFile.Flows {
+		flow.StructuredOutput = slices.ContainsFunc(sourceFile.StructuredOutputs, func(output *models.StructuredOutput) bool {
+			return output.Flow == flow.Name
+		})
+	}
+
 	return sourceFile, nil
 }
 
 func (a *Analyzer) extractFlow(call *ast.CallExpr, fset *token.FileSet) *models.Flow {
-	if sel, ok := call.Fun.(*ast.SelectorExpr); ok {
-		if sel.Sel.Name == "DefineFlow" && len(call.Args) >= 2 {
-			if lit, ok := call.Args[0].(*ast.BasicLit); ok && lit.Kind == token.STRING {
-				flowName := strings.Trim(lit.Value, `"`)
+	name, fn, ok := flowCall(call)
+	if !ok {
+		return nil
+	}
 
-				return &models.Flow{
-					Name:     flowName,
-					Position: fset.Position(call.Pos()),
-				}
+	flow := &models.Flow{
+		Name:      name,
+		Position:  fset.Position(call.Pos()),
+		Streaming: call.Fun.(*ast.SelectorExpr).Sel.Name == "DefineStreamingFlow",
+	}
+	if fn != nil {
+		flow.InputType, flow.OutputType = flowTypes(fn.Type)
+		// Flows that stream their generations with ai.WithStreaming.
+		ast.Inspect(fn.Body, func(n ast.Node) bool {
+			if sel, ok := n.(*ast.SelectorExpr); ok && sel.Sel.Name == "WithStreaming" {
+				flow.Streaming = true
 			}
-		}
+			return !flow.Streaming
+		})
 	}
-	return nil
+	return flow
+}
+
+// Plugin helpers that resolve a model by its unprefixed name, such as
+// bedrock.Model(g, "anthropic.claude-3-haiku-20240307-v1:0").
+var modelConstructors = map[string]string{
+	"googleai.Model":            "googleai/",
+	"vertexai.Model":            "vertexai/",
+	"googlegenai.GoogleAIModel": "googleai/",
+	"googlegenai.VertexAIModel": "vertexai/",
+	"bedrock.Model":             "bedrock/",
 }
 
 func (a *Analyzer) extractModel(call *ast.CallExpr, fset *token.FileSet) *models.Model {
@@ -165,25 +281,35 @@ func (a *Analyzer) extractModel(call *ast.CallExpr, fset *token.FileSet) *models
 				}
 			}
 		}
+
+		// Models named in generation options, as in ai.WithModelName("googleai/gemini-2.0-flash").
+		if sel.Sel.Name == "WithModelName" && len(call.Args) == 1 {
+			if name, ok := stringArg(call.Args[0]); ok {
+				return &models.Model{
+					Name:     name,
+					Provider: a.detectModelProvider(name),
+					Position: fset.Position(call.Pos()),
+				}
+			}
+		}
+
+		if pkg, ok := sel.X.(*ast.Ident); ok && len(call.Args) >= 1 {
+			prefix, exists := modelConstructors[pkg.Name+"."+sel.Sel.Name]
+			name, isString := stringArg(call.Args[len(call.Args)-1])
+			if exists && isString {
+				return &models.Model{
+					Name:     prefix + name,
+					Provider: a.detectModelProvider(prefix + name),
+					Position: fset.Position(call.Pos()),
+				}
+			}
+		}
 	}
 	return nil
 }
 
 func (a *Analyzer) detectModelProvider(modelName string) string {
-	switch {
-	case strings.Contains(modelName, "googleai/") || strings.Contains(modelName, "vertexai/"):
-		return "gcp"
-	case strings.Contains(modelName, "openai/") || strings.Contains(modelName, "gpt-"):
-		return "openai"
-	case strings.Contains(modelName, "anthropic/") || strings.Contains(modelName, "claude-"):
-		return "anthropic"
-	case strings.Contains(modelName, "ollama/"):
-		return "ollama"
-	case strings.Contains(modelName, "bedrock/") || strings.Contains(modelName, "amazon.") || strings.Contains(modelName, "anthropic."):
-		return "aws"
-	default:
-		return "unknown"
-	}
+	return provider.DetectModel(modelName)
 }
 
 func (a *Analyzer) analyzeDependencies(project *models.Project) error {
@@ -201,12 +327,11 @@ func (a *Analyzer) analyzeDependencies(project *models.Project) error {
 			(strings.Contains(line, " v") && !strings.HasPrefix(line, "module") && !strings.HasPrefix(line, "go ")) {
 
 			parts := strings.Fields(line)
-			if len(parts) >= 2 {
-				dep := strings.TrimPrefix(parts[0], "require")
-				dep = strings.TrimSpace(dep)
-				version := parts[1]
-
-				project.Dependencies[dep] = version
+			if len(parts) > 0 && parts[0] == "require" {
+				parts = parts[1:]
+			}
+			if len(parts) >= 2 && parts[0] != "(" {
+				project.Dependencies[parts[0]] = parts[1]
 			}
 		}
 	}
@@ -219,9 +344,21 @@ func (a *Analyzer) analyzeConfiguration(project *models.Project) error {
 
 	for _, filename := range configFiles {
 		configPath := filepath.Join(project.Path, filename)
-		if _, err := os.Stat(configPath); err == nil {
-			project.Configuration[filename] = configPath
+		if _, err := os.Stat(configPath); err != nil {
+			continue
+		}
+
+		project.Configuration[filename] = configPath
+
+		configFile, err := a.parseConfigFile(filename, configPath)
+		if err != nil {
+			if a.config.Verbose {
+				fmt.Printf("Warning: failed to parse %s: %v\n", configPath, err)
+			}
+			continue
 		}
+		project.ConfigFiles[filename] = configFile
+		project.Features = append(project.Features, configFeatures(configFile)...)
 	}
 
 	return nil
@@ -0,0 +1,67 @@
+package analyzer
+
+import (
+	"fmt"
+	"go/token"
+	"os"
+	"path/filepath"
+
+	"github.com/genkit-migrate/genkit-migrate/internal/utils"
+	"github.com/genkit-migrate/genkit-migrate/pkg/models"
+	"gopkg.in/yaml.v3"
+)
+
+func (a *Analyzer) parsePromptFile(filePath, projectRoot string) (*models.Prompt, error) {
+	content, err := os.ReadFile(filePath)
+	if err != nil {
+		return nil, err
+	}
+
+	relPath, err := filepath.Rel(projectRoot, filePath)
+	if err != nil {
+		return nil, err
+	}
+
+	prompt := &models.Prompt{
+		Path:     relPath,
+		Position: token.Position{Filename: filePath, Line: 1},
+	}
+
+	frontmatter, _, ok := utils.SplitFrontmatter(content)
+	if !ok {
+		return prompt, nil
+	}
+
+	var root yaml.Node
+	if err := yaml.Unmarshal(frontmatter, &root); err != nil {
+		return nil, fmt.Errorf("invalid frontmatter: %w", err)
+	}
+	if len(root.Content) == 0 || root.Content[0].Kind != yaml.MappingNode {
+		return prompt, nil
+	}
+
+	mapping := root.Content[0]
+	for i := 0; i+1 < len(mapping.Content); i += 2 {
+		key, value := mapping.Content[i], mapping.Content[i+1]
+
+		switch key.Value {
+		case "model":
+			prompt.Model = &models.Model{
+				Name:     value.Value,
+				Provider: a.detectModelProvider(value.Value),
+				// Frontmatter starts on the line after the opening delimiter.
+				Position: token.Position{Filename: filePath, Line: value.Line + 1, Column: value.Column},
+			}
+		case "config":
+			config := make(map[string]interface{})
+			if err := value.Decode(&config); err != nil {
+				return nil, fmt.Errorf("invalid config: %w", err)
+			}
+			prompt.Config = config
+			prompt.SafetySettings = promptSafetySettings(value, filePath)
+			prompt.Features = promptFeatures(value, filePath)
+		}
+	}
+
+	return prompt, nil
+}
@@ -0,0 +1,200 @@
+package analyzer
+
+import (
+	"go/ast"
+	"go/token"
+	"go/types"
+	"strconv"
+	"strings"
+
+	"github.com/genkit-migrate/genkit-migrate/pkg/models"
+	"github.com/genkit-migrate/genkit-migrate/pkg/rewrite"
+	"gopkg.in/yaml.v3"
+)
+
+// Harm categories and block thresholds, keyed by their constant names in
+// upper case without underscores or the HarmCategory/HarmBlock prefixes, so
+// that genai.HarmCategoryHateSpeech and "HARM_CATEGORY_HATE_SPEECH" agree.
+var harmCategories = map[string]string{
+	"HARASSMENT":       "HARM_CATEGORY_HARASSMENT",
+	"HATESPEECH":       "HARM_CATEGORY_HATE_SPEECH",
+	"SEXUALLYEXPLICIT": "HARM_CATEGORY_SEXUALLY_EXPLICIT",
+	"DANGEROUSCONTENT": "HARM_CATEGORY_DANGEROUS_CONTENT",
+	"CIVICINTEGRITY":   "HARM_CATEGORY_CIVIC_INTEGRITY",
+}
+
+var harmThresholds = map[string]string{
+	"LOWANDABOVE":    "BLOCK_LOW_AND_ABOVE",
+	"MEDIUMANDABOVE": "BLOCK_MEDIUM_AND_ABOVE",
+	"ONLYHIGH":       "BLOCK_ONLY_HIGH",
+	"NONE":           "BLOCK_NONE",
+	"OFF":            "OFF",
+	"UNSPECIFIED":    "HARM_BLOCK_THRESHOLD_UNSPECIFIED",
+}
+
+func normalizeHarm(name string, values map[string]string, prefixes ...string) (string, bool) {
+	key := strings.ToUpper(strings.ReplaceAll(name, "_", ""))
+	for _, prefix := range prefixes {
+		key = strings.TrimPrefix(key, prefix)
+	}
+	value, exists := values[key]
+	return value, exists
+}
+
+func harmCategory(name string) string {
+	if category, ok := normalizeHarm(name, harmCategories, "HARMCATEGORY"); ok {
+		return category
+	}
+	return name
+}
+
+func harmThreshold(name string) string {
+	if threshold, ok := normalizeHarm(name, harmThresholds, "HARMBLOCKTHRESHOLD", "HARMBLOCK", "BLOCK"); ok {
+		return threshold
+	}
+	return name
+}
+
+// extractSafetySettings finds genai.SafetySetting literals, whether they are
+// part of a plugin config, a GenerateContentConfig or set on a model handle.
+func (a *Analyzer) extractSafetySettings(node *ast.File, fset *token.FileSet) []*models.SafetySetting {
+	settings := make([]*models.SafetySetting, 0)
+	sources := clientSources()
+
+	packages := make(map[string]bool)
+	for _, imp := range node.Imports {
+		importPath := strings.Trim(imp.Path.Value, `"`)
+		if _, exists := sources[importPath]; !exists {
+			continue
+		}
+		localName := rewrite.DefaultImportName(importPath)
+		if imp.Name != nil {
+			localName = imp.Name.Name
+		}
+		packages[localName] = true
+	}
+	if len(packages) == 0 {
+		return settings
+	}
+
+	isSafetySetting := func(expr ast.Expr) bool {
+		if star, ok := expr.(*ast.StarExpr); ok {
+			expr = star.X
+		}
+		sel, ok := expr.(*ast.SelectorExpr)
+		if !ok || sel.Sel.Name != "SafetySetting" {
+			return false
+		}
+		ident, ok := sel.X.(*ast.Ident)
+		return ok && packages[ident.Name]
+	}
+
+	seen := make(map[*ast.CompositeLit]bool)
+	add := func(lit *ast.CompositeLit) {
+		if seen[lit] {
+			return
+		}
+		seen[lit] = true
+		if setting := safetySetting(lit); setting != nil {
+			setting.Position = fset.Position(lit.Pos())
+			settings = append(settings, setting)
+		}
+	}
+
+	ast.Inspect(node, func(n ast.Node) bool {
+		lit, ok := n.(*ast.CompositeLit)
+		if !ok {
+			return true
+		}
+
+		if array, ok := lit.Type.(*ast.ArrayType); ok && isSafetySetting(array.Elt) {
+			// Elements of []*genai.SafetySetting{{...}} omit their type.
+			for _, elt := range lit.Elts {
+				if unary, ok := elt.(*ast.UnaryExpr); ok && unary.Op == token.AND {
+					elt = unary.X
+				}
+				if element, ok := elt.(*ast.CompositeLit); ok {
+					add(element)
+				}
+			}
+		} else if isSafetySetting(lit.Type) {
+			add(lit)
+		}
+		return true
+	})
+
+	return settings
+}
+
+func safetySetting(lit *ast.CompositeLit) *models.SafetySetting {
+	setting := &models.SafetySetting{}
+	for _, elt := range lit.Elts {
+		kv, ok := elt.(*ast.KeyValueExpr)
+		if !ok {
+			continue
+		}
+		key, ok := kv.Key.(*ast.Ident)
+		if !ok {
+			continue
+		}
+
+		switch key.Name {
+		case "Category":
+			setting.Category = harmCategory(harmName(kv.Value))
+		case "Threshold":
+			setting.Threshold = harmThreshold(harmName(kv.Value))
+		}
+	}
+	if setting.Category == "" {
+		return nil
+	}
+	return setting
+}
+
+// harmName returns the constant or string a value names, or its source text.
+func harmName(expr ast.Expr) string {
+	switch value := expr.(type) {
+	case *ast.SelectorExpr:
+		return value.Sel.Name
+	case *ast.BasicLit:
+		if name, err := strconv.Unquote(value.Value); err == nil {
+			return name
+		}
+	case *ast.CallExpr:
+		// Conversions such as genai.HarmCategory("HARM_CATEGORY_HARASSMENT").
+		if len(value.Args) == 1 {
+			if lit, ok := value.Args[0].(*ast.BasicLit); ok {
+				return harmName(lit)
+			}
+		}
+	}
+	return types.ExprString(expr)
+}
+
+// promptSafetySettings reads the safetySettings of a Dotprompt config.
+func promptSafetySettings(config *yaml.Node, filePath string) []*models.SafetySetting {
+	settings := make([]*models.SafetySetting, 0)
+	for i := 0; i+1 < len(config.Content); i += 2 {
+		key, value := config.Content[i], config.Content[i+1]
+		if key.Value != "safetySettings" || value.Kind != yaml.SequenceNode {
+			continue
+		}
+
+		for _, item := range value.Content {
+			var setting struct {
+				Category  string `yaml:"category"`
+				Threshold string `yaml:"threshold"`
+			}
+			if err := item.Decode(&setting); err != nil || setting.Category == "" {
+				continue
+			}
+			settings = append(settings, &models.SafetySetting{
+				Category:  harmCategory(setting.Category),
+				Threshold: harmThreshold(setting.Threshold),
+				// Frontmatter starts on the line after the opening delimiter.
+				Position: token.Position{Filename: filePath, Line: item.Line + 1, Column: item.Column},
+			})
+		}
+	}
+	return settings
+}
@@ -0,0 +1,113 @@
+package analyzer
+
+import (
+	"go/ast"
+	"go/token"
+	"strings"
+
+	"github.com/genkit-migrate/genkit-migrate/pkg/models"
+	"github.com/genkit-migrate/genkit-migrate/pkg/rewrite"
+)
+
+type cloudServiceInfo struct {
+	service     string
+	alternative string
+	effort      string
+}
+
+const gcpSDKPrefix = "cloud.google.com/go/"
+
+var gcpCloudServices = map[string]cloudServiceInfo{
+	"firestore":        {"Firestore", "Amazon DynamoDB", "high"},
+	"datastore":        {"Datastore", "Amazon DynamoDB", "high"},
+	"storage":          {"Cloud Storage", "Amazon S3", "low"},
+	"secretmanager":    {"Secret Manager", "AWS Secrets Manager", "low"},
+	"pubsub":           {"Pub/Sub", "Amazon SQS/SNS", "medium"},
+	"logging":          {"Cloud Logging", "Amazon CloudWatch Logs", "low"},
+	"bigquery":         {"BigQuery", "Amazon Athena/Redshift", "high"},
+	"spanner":          {"Cloud Spanner", "Amazon Aurora", "high"},
+	"cloudtasks":       {"Cloud Tasks", "Amazon SQS", "medium"},
+	"kms":              {"Cloud KMS", "AWS KMS", "medium"},
+	"compute/metadata": {"Compute metadata", "EC2/ECS instance metadata", "low"},
+}
+
+func (a *Analyzer) extractCloudServices(node *ast.File, fset *token.FileSet) []*models.CloudService {
+	services := make([]*models.CloudService, 0)
+	clients := clientSources()
+
+	for _, imp := range node.Imports {
+		importPath := strings.Trim(imp.Path.Value, `"`)
+		if !strings.HasPrefix(importPath, gcpSDKPrefix) {
+			continue
+		}
+		// Model SDKs are reported as direct calls and rewritten.
+		if _, exists := clients[importPath]; exists {
+			continue
+		}
+
+		info := lookupCloudService(importPath)
+
+		localName := rewrite.DefaultImportName(importPath)
+		if imp.Name != nil {
+			localName = imp.Name.Name
+		}
+
+		service := &models.CloudService{
+			Service:     info.service,
+			Package:     importPath,
+			Position:    fset.Position(imp.Pos()),
+			CallSites:   make([]token.Position, 0),
+			Alternative: info.alternative,
+		}
+
+		ast.Inspect(node, func(n ast.Node) bool {
+			call, ok := n.(*ast.CallExpr)
+			if !ok {
+				return true
+			}
+			if sel, ok := call.Fun.(*ast.SelectorExpr); ok {
+				if ident, ok := sel.X.(*ast.Ident); ok && ident.Name == localName {
+					service.CallSites = append(service.CallSites, fset.Position(call.Pos()))
+				}
+			}
+			return true
+		})
+
+		service.Effort = scaleEffort(info.effort, len(service.CallSites))
+		services = append(services, service)
+	}
+
+	return services
+}
+
+func lookupCloudService(importPath string) cloudServiceInfo {
+	rest := strings.TrimPrefix(importPath, gcpSDKPrefix)
+
+	for key, info := range gcpCloudServices {
+		if rest == key || strings.HasPrefix(rest, key+"/") {
+			return info
+		}
+	}
+
+	name := strings.Split(rest, "/")[0]
+	return cloudServiceInfo{
+		service:     name,
+		alternative: "No direct mapping (manual review)",
+		effort:      "high",
+	}
+}
+
+func scaleEffort(effort string, callSites int) string {
+	if callSites <= 10 {
+		return effort
+	}
+
+	switch effort {
+	case "low":
+		return "medium"
+	case "medium":
+		return "high"
+	default:
+		return effort
+	}
+}
@@ -0,0 +1,236 @@
+package analyzer
+
+import (
+	"go/ast"
+	"go/token"
+	"go/types"
+	"slices"
+	"strings"
+
+	"github.com/genkit-migrate/genkit-migrate/pkg/models"
+	"github.com/genkit-migrate/genkit-migrate/pkg/provider"
+	"github.com/genkit-migrate/genkit-migrate/pkg/rewrite"
+)
+
+const (
+	genkitPackage   = "github.com/firebase/genkit/go/genkit"
+	genkitAIPackage = "github.com/firebase/genkit/go/ai"
+)
+
+// generationSources returns the generation configs of every source provider.
+func generationSources() []*provider.Generation {
+	generations := make([]*provider.Generation, 0)
+	for _, name := range provider.Sources() {
+		source, err := provider.Get(name)
+		if err != nil || source.Source().Generation == nil {
+			continue
+		}
+		generations = append(generations, source.Source().Generation)
+	}
+	return generations
+}
+
+// flowCall returns the name and function of a DefineFlow or
+// DefineStreamingFlow call, in both the DefineFlow(g, name, fn) form and the
+// pre-1.0 DefineFlow(name, fn) form.
+func flowCall(call *ast.CallExpr) (string, *ast.FuncLit, bool) {
+	sel, ok := call.Fun.(*ast.SelectorExpr)
+	if !ok || (sel.Sel.Name != "DefineFlow" && sel.Sel.Name != "DefineStreamingFlow") {
+		return "", nil, false
+	}
+	for i := 0; i < 2 && i+1 < len(call.Args); i++ {
+		if name, ok := stringArg(call.Args[i]); ok {
+			fn, _ := call.Args[i+1].(*ast.FuncLit)
+			return name, fn, true
+		}
+	}
+	return "", nil, false
+}
+
+// flowTypes returns the input and output types of a flow function, which
+// takes a context and the input and returns the output and an error.
+func flowTypes(fn *ast.FuncType) (string, string) {
+	var params []ast.Expr
+	if fn.Params != nil {
+		for _, field := range fn.Params.List {
+			for range max(len(field.Names), 1) {
+				params = append(params, field.Type)
+			}
+		}
+	}
+
+	var input, output string
+	if len(params) > 1 {
+		input = types.ExprString(params[1])
+	}
+	if fn.Results != nil && len(fn.Results.List) > 0 {
+		output = types.ExprString(fn.Results.List[0].Type)
+	}
+	return input, output
+}
+
+// extractStructuredOutputs finds generations that ask for JSON matching a
+// schema: ai.WithOutputType options, genkit.GenerateData calls and response
+// schemas set on a source provider's generation config.
+func (a *Analyzer) extractStructuredOutputs(node *ast.File, fset *token.FileSet) []*models.StructuredOutput {
+	outputs := make([]*models.StructuredOutput, 0)
+
+	imports := make(map[string]string)
+	imported := make(map[string]bool)
+	for _, imp := range node.Imports {
+		importPath := strings.Trim(imp.Path.Value, `"`)
+		localName := rewrite.DefaultImportName(importPath)
+		if imp.Name != nil {
+			localName = imp.Name.Name
+		}
+		imports[localName] = importPath
+		imported[importPath] = true
+	}
+
+	isFunc := func(expr ast.Expr, importPath, name string) bool {
+		sel, ok := expr.(*ast.SelectorExpr)
+		if !ok || sel.Sel.Name != name {
+			return false
+		}
+		ident, ok := sel.X.(*ast.Ident)
+		return ok && imports[ident.Name] == importPath
+	}
+
+	generations := generationSources()
+	isConfig := func(expr ast.Expr) bool {
+		sel, ok := expr.(*ast.SelectorExpr)
+		if !ok {
+			return false
+		}
+		ident, ok := sel.X.(*ast.Ident)
+		if !ok {
+			return false
+		}
+		for _, generation := range generations {
+			if slices.Contains(generation.Packages, imports[ident.Name]) && slices.Contains(generation.Types, sel.Sel.Name) {
+				return true
+			}
+		}
+		return false
+	}
+	// The schema fields of the generation configs this file imports.
+	schemaFields := make(map[string]bool)
+	for _, generation := range generations {
+		if !slices.ContainsFunc(generation.Packages, func(pkg string) bool { return imported[pkg] }) {
+			continue
+		}
+		for _, option := range generation.Options {
+			if option.Schema {
+				schemaFields[option.Field] = true
+			}
+		}
+	}
+
+	type flowScope struct {
+		name string
+		fn   *ast.FuncLit
+	}
+	var flows []flowScope
+	ast.Inspect(node, func(n ast.Node) bool {
+		if call, ok := n.(*ast.CallExpr); ok {
+			if name, fn, ok := flowCall(call); ok && fn != nil {
+				flows = append(flows, flowScope{name: name, fn: fn})
+			}
+		}
+		return true
+	})
+
+	// enclosingFlow returns the innermost flow whose function contains pos.
+	enclosingFlow := func(pos token.Pos) string {
+		name := ""
+		var from token.Pos
+		for _, flow := range flows {
+			if flow.fn.Pos() <= pos && pos < flow.fn.End() && flow.fn.Pos() >= from {
+				name, from = flow.name, flow.fn.Pos()
+			}
+		}
+		return name
+	}
+
+	add := func(method, typeName, model string, at ast.Node) {
+		outputs = append(outputs, &models.StructuredOutput{
+			Flow:     enclosingFlow(at.Pos()),
+			Method:   method,
+			Type:     typeName,
+			Model:    model,
+			Position: fset.Position(at.Pos()),
+		})
+	}
+
+	// generateModel returns the model a generation names with
+	// ai.WithModelName.
+	generateModel := func(call *ast.CallExpr) string {
+		for _, arg := range call.Args {
+			option, ok := arg.(*ast.CallExpr)
+			if ok && isFunc(option.Fun, genkitAIPackage, "WithModelName") && len(option.Args) == 1 {
+				if name, ok := stringArg(option.Args[0]); ok {
+					return name
+				}
+			}
+		}
+		return ""
+	}
+
+	ast.Inspect(node, func(n ast.Node) bool {
+		switch node := n.(type) {
+		case *ast.CallExpr:
+			if index, ok := node.Fun.(*ast.IndexExpr); ok && isFunc(index.X, genkitPackage, "GenerateData") {
+				add("GenerateData", types.ExprString(index.Index), generateModel(node), node)
+				return true
+			}
+			for _, arg := range node.Args {
+				option, ok := arg.(*ast.CallExpr)
+				if ok && isFunc(option.Fun, genkitAIPackage, "WithOutputType") && len(option.Args) == 1 {
+					add("WithOutputType", outputTypeName(option.Args[0]), generateModel(node), option)
+				}
+			}
+		case *ast.CompositeLit:
+			if !isConfig(node.Type) {
+				return true
+			}
+			for _, elt := range node.Elts {
+				kv, ok := elt.(*ast.KeyValueExpr)
+				if !ok {
+					continue
+				}
+				if key, ok := kv.Key.(*ast.Ident); ok && schemaFields[key.Name] {
+					add(key.Name, "", "", kv)
+				}
+			}
+		case *ast.AssignStmt:
+			// Schemas set on a model handle, as in model.ResponseSchema = ...
+			for _, lhs := range node.Lhs {
+				if sel, ok := lhs.(*ast.SelectorExpr); ok && schemaFields[sel.Sel.Name] {
+					add(sel.Sel.Name, "", "", node)
+				}
+			}
+		}
+		return true
+	})
+
+	return outputs
+}
+
+// outputTypeName returns the type of the value passed to ai.WithOutputType,
+// such as Recipe for Recipe{}, &Recipe{} or new(Recipe).
+func outputTypeName(expr ast.Expr) string {
+	if unary, ok := expr.(*ast.UnaryExpr); ok && unary.Op == token.AND {
+		expr = unary.X
+	}
+	switch value := expr.(type) {
+	case *ast.CompositeLit:
+		if value.Type != nil {
+			return types.ExprString(value.Type)
+		}
+	case *ast.CallExpr:
+		if ident, ok := value.Fun.(*ast.Ident); ok && ident.Name == "new" && len(value.Args) == 1 {
+			return types.ExprString(value.Args[0])
+		}
+	}
+	return ""
+}
@@ -0,0 +1,137 @@
+package analyzer
+
+import (
+	"go/ast"
+	"go/token"
+	"strings"
+
+	"github.com/genkit-migrate/genkit-migrate/pkg/models"
+	"github.com/genkit-migrate/genkit-migrate/pkg/rewrite"
+)
+
+const (
+	firebasePluginPackage = "github.com/firebase/genkit/go/plugins/firebase"
+	firestorePackage      = "cloud.google.com/go/firestore"
+	aiplatformPackage     = "cloud.google.com/go/aiplatform/apiv1"
+)
+
+// Calls that identify a vector store, keyed by import path.
+var vectorStoreCalls = map[string]map[string]string{
+	firebasePluginPackage: {
+		"DefineRetriever": "firestore",
+	},
+	aiplatformPackage: {
+		"NewMatchClient":         "vertex-vector-search",
+		"NewIndexEndpointClient": "vertex-vector-search",
+	},
+	aiplatformPackage + "beta1": {
+		"NewMatchClient":         "vertex-vector-search",
+		"NewIndexEndpointClient": "vertex-vector-search",
+	},
+}
+
+// Methods that issue a vector query on a client of the given package.
+var vectorStoreMethods = map[string]map[string]string{
+	firestorePackage: {
+		"FindNearest": "firestore",
+	},
+	aiplatformPackage: {
+		"FindNeighbors": "vertex-vector-search",
+	},
+	aiplatformPackage + "beta1": {
+		"FindNeighbors": "vertex-vector-search",
+	},
+}
+
+func (a *Analyzer) extractVectorStores(node *ast.File, fset *token.FileSet) []*models.VectorStore {
+	stores := make([]*models.VectorStore, 0)
+
+	imports := make(map[string]string)
+	for _, imp := range node.Imports {
+		importPath := strings.Trim(imp.Path.Value, `"`)
+		localName := rewrite.DefaultImportName(importPath)
+		if imp.Name != nil {
+			localName = imp.Name.Name
+		}
+		imports[localName] = importPath
+	}
+
+	ast.Inspect(node, func(n ast.Node) bool {
+		call, ok := n.(*ast.CallExpr)
+		if !ok {
+			return true
+		}
+		sel, ok := call.Fun.(*ast.SelectorExpr)
+		if !ok {
+			return true
+		}
+
+		if ident, ok := sel.X.(*ast.Ident); ok {
+			if importPath, imported := imports[ident.Name]; imported {
+				if kind, exists := vectorStoreCalls[importPath][sel.Sel.Name]; exists {
+					store := &models.VectorStore{
+						Kind:     kind,
+						Package:  importPath,
+						Position: fset.Position(call.Pos()),
+					}
+					if kind == "firestore" {
+						extractRetrieverOptions(store, call.Args)
+					}
+					stores = append(stores, store)
+				}
+				return true
+			}
+		}
+
+		for _, importPath := range imports {
+			if kind, exists := vectorStoreMethods[importPath][sel.Sel.Name]; exists {
+				stores = append(stores, &models.VectorStore{
+					Kind:     kind,
+					Package:  importPath,
+					Position: fset.Position(call.Pos()),
+				})
+			}
+		}
+		return true
+	})
+
+	return stores
+}
+
+func extractRetrieverOptions(store *models.VectorStore, args []ast.Expr) {
+	for _, arg := range args {
+		if unary, ok := arg.(*ast.UnaryExpr); ok && unary.Op == token.AND {
+			arg = unary.X
+		}
+		lit, ok := arg.(*ast.CompositeLit)
+		if !ok {
+			continue
+		}
+
+		for _, elt := range lit.Elts {
+			kv, ok := elt.(*ast.KeyValueExpr)
+			if !ok {
+				continue
+			}
+			key, ok := kv.Key.(*ast.Ident)
+			if !ok {
+				continue
+			}
+			value, ok := stringArg(kv.Value)
+			if !ok {
+				continue
+			}
+
+			switch key.Name {
+			case "Name":
+				store.Name = value
+			case "Collection":
+				store.Collection = value
+			case "ContentField":
+				store.ContentField = value
+			case "VectorField":
+				store.VectorField = value
+			}
+		}
+	}
+}
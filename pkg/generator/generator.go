@@ -7,6 +7,7 @@ import (
 	"path/filepath"
 
 	"github.com/genkit-migrate/genkit-migrate/pkg/models"
+	"github.com/genkit-migrate/genkit-migrate/pkg/provider"
 	"github.com/otiai10/copy"
 )
 
@@ -130,80 +131,77 @@ This project has been migrated from %s to %s using genkit-migrate.
 	)
 
 	for _, change := range migration.Changes {
-		content += fmt.Sprintf("- **%s**: %s (in %s)\n", change.Type, change.Description, change.File)
+		content += fmt.Sprintf("- **%s**: %s", change.Type, change.Description)
+		switch {
+		case change.File != "" && change.Line > 0:
+			content += fmt.Sprintf(" (in %s:%d)", change.File, change.Line)
+		case change.File != "":
+			content += fmt.Sprintf(" (in %s)", change.File)
+		}
+		if change.Blocking {
+			content += " - **blocking**"
+		} else if change.ManualReview {
+			content += " - needs manual review"
+		}
+		content += "\n"
 	}
 
-	if g.config.TargetProvider == "aws" {
-		content += `
-
-## AWS Deployment
-
-### Prerequisites
-
-1. AWS CLI configured with appropriate credentials
-2. Terraform installed (>= 1.0)
-3. Docker installed (for containerization)
-
-### Deploy with Terraform
+	content += g.generateBlockingSection(migration)
+	content += g.generateCloudServicesSection(migration.Project)
 
-` + "```bash" + `
-cd terraform
-terraform init
-terraform plan
-terraform apply
-` + "```" + `
+	if len(migration.Commands) > 0 {
+		content += "\n## Commands to Run\n\n```bash\n"
+		for _, command := range migration.Commands {
+			content += command + "\n"
+		}
+		content += "```\n"
+	}
 
-### Build and Deploy Docker Container
+	if target, err := provider.Get(g.config.TargetProvider); err == nil {
+		content += target.Guide(migration)
+	}
 
-` + "```bash" + `
-docker build -t genkit-app .
-docker tag genkit-app:latest <your-ecr-repo>:latest
-docker push <your-ecr-repo>:latest
-` + "```" + `
+	return content
+}
 
-### Configuration
+func (g *Generator) generateBlockingSection(migration *models.Migration) string {
+	content := ""
+	for _, change := range migration.Changes {
+		if change.Blocking {
+			content += fmt.Sprintf("- %s (in %s)\n", change.Description, change.File)
+		}
+	}
+	if content == "" {
+		return ""
+	}
 
-Update the ` + "`config.yaml`" + ` file with your specific AWS settings:
+	return `
+## Blocking Changes
 
-- AWS region
-- Bedrock model preferences
-- CloudWatch configuration
-- Environment variables
+The migrated application must not serve traffic until these are resolved:
 
-## Next Steps
+` + content
+}
 
-1. Review all generated files
-2. Test the application locally
-3. Update any hardcoded values in configuration
-4. Deploy to your AWS environment
-5. Set up monitoring and logging
-6. Test all GenKit flows work correctly
+func (g *Generator) generateCloudServicesSection(project *models.Project) string {
+	if len(project.CloudServices) == 0 {
+		return ""
+	}
 
-## Model Mappings Applied
+	content := `
+## Google Cloud Services
 
-The following model mappings were applied during migration:
+These Google Cloud SDK packages are used outside of GenKit and are not migrated
+automatically. Each one needs to be ported to the suggested counterpart:
 
+| Service | Package | Suggested Replacement | Effort | Call Sites | Location |
+|---------|---------|-----------------------|--------|------------|----------|
 `
 
-		modelMappings := map[string]string{
-			"googleai/gemini-1.5-flash": "anthropic.claude-3-haiku-20240307-v1:0",
-			"googleai/gemini-1.5-pro":   "anthropic.claude-3-sonnet-20240229-v1:0",
-			"vertexai/gemini-pro":       "anthropic.claude-3-sonnet-20240229-v1:0",
-		}
-
-		for oldModel, newModel := range modelMappings {
-			content += fmt.Sprintf("- `%s` → `%s`\n", oldModel, newModel)
-		}
-
-		content += `
-## Support
-
-For issues with the migration tool, please visit:
-https://github.com/genkit-migrate/genkit-migrate/issues
-
-For GenKit framework support, please visit:
-https://firebase.google.com/docs/genkit
-`
+	for _, service := range project.CloudServices {
+		content += fmt.Sprintf("| %s | `%s` | %s | %s | %d | %s:%d |\n",
+			service.Service, service.Package, service.Alternative, service.Effort,
+			len(service.CallSites), service.Position.Filename, service.Position.Line)
 	}
 
 	return content
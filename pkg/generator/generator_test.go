@@ -7,6 +7,7 @@ import (
 	"testing"
 
 	"github.com/genkit-migrate/genkit-migrate/pkg/models"
+	_ "github.com/genkit-migrate/genkit-migrate/pkg/provider/all"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	"go/token"
@@ -103,7 +104,9 @@ func TestGenerateReadme(t *testing.T) {
 		},
 		Changes: []*models.Change{
 			{Type: "dependency", Description: "Updated dependencies", File: "go.mod"},
-			{Type: "model", Description: "Mapped model", File: "main.go"},
+			{Type: "model", Description: "Mapped model", File: "main.go", OldValue: "googleai/gemini-1.5-pro", NewValue: "anthropic.claude-3-sonnet-20240229-v1:0"},
+			{Type: "config", Description: "Dropped generation option Seed", File: "main.go", Line: 12, ManualReview: true},
+			{Type: "deploy", Description: "Deploying to lambda"},
 		},
 	}
 
@@ -114,8 +117,42 @@ func TestGenerateReadme(t *testing.T) {
 	assert.Contains(t, readme, "Target Provider**: aws")
 	assert.Contains(t, readme, "Flows Found**: 2")
 	assert.Contains(t, readme, "Models Found**: 1")
-	assert.Contains(t, readme, "Changes Applied**: 2")
+	assert.Contains(t, readme, "Changes Applied**: 4")
+	assert.Contains(t, readme, "Dropped generation option Seed (in main.go:12) - needs manual review")
+	assert.Contains(t, readme, "Deploying to lambda\n")
+	assert.NotContains(t, readme, "(in )")
 	assert.Contains(t, readme, "AWS Deployment")
 	assert.Contains(t, readme, "terraform init")
 	assert.Contains(t, readme, "Model Mappings Applied")
+	// Only the mappings the migration applied are listed.
+	assert.Contains(t, readme, "- `googleai/gemini-1.5-pro` → `anthropic.claude-3-sonnet-20240229-v1:0`\n")
+	assert.NotContains(t, readme, "vertexai/gemini-pro")
+}
+
+func TestGenerateReadmeCloudServices(t *testing.T) {
+	generator := New(&Config{
+		TargetProvider: "aws",
+	})
+
+	migration := &models.Migration{
+		Project: &models.Project{
+			SourceProvider: "gcp",
+			TargetProvider: "aws",
+			CloudServices: []*models.CloudService{
+				{
+					Service:     "Firestore",
+					Package:     "cloud.google.com/go/firestore",
+					Position:    token.Position{Filename: "store.go", Line: 5},
+					CallSites:   []token.Position{{Filename: "store.go", Line: 10}},
+					Alternative: "Amazon DynamoDB",
+					Effort:      "high",
+				},
+			},
+		},
+	}
+
+	readme := generator.generateReadme(migration)
+
+	assert.Contains(t, readme, "## Google Cloud Services")
+	assert.Contains(t, readme, "| Firestore | `cloud.google.com/go/firestore` | Amazon DynamoDB | high | 1 | store.go:5 |")
 }
@@ -9,9 +9,14 @@ type Migration struct {
 }
 
 type Change struct {
-	Type        string `json:"type"` // "dependency", "import", "model", "config"
-	Description string `json:"description"`
-	File        string `json:"file"`
-	OldValue    string `json:"old_value,omitempty"`
-	NewValue    string `json:"new_value,omitempty"`
+	Type         string `json:"type"` // "dependency", "import", "model", "embedder", "vectorstore", "config", "service", "rule", "test", "blocker"
+	Description  string `json:"description"`
+	File         string `json:"file"`
+	Line         int    `json:"line,omitempty"`
+	Column       int    `json:"column,omitempty"`
+	OldValue     string `json:"old_value,omitempty"`
+	NewValue     string `json:"new_value,omitempty"`
+	ManualReview bool   `json:"manual_review,omitempty"`
+	Blocking     bool   `json:"blocking,omitempty"` // must be resolved before the migrated app can serve traffic
+	Rule         string `json:"rule,omitempty"`     // rewrite rule that made the change
 }
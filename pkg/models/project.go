@@ -3,31 +3,52 @@ package models
 import "go/token"
 
 type Project struct {
-	Path           string                 `json:"path"`
-	SourceProvider string                 `json:"source_provider"`
-	TargetProvider string                 `json:"target_provider"`
-	Files          map[string]*SourceFile `json:"files"`
-	Dependencies   map[string]string      `json:"dependencies"`
-	Flows          []*Flow                `json:"flows"`
-	Models         []*Model               `json:"models"`
-	Configuration  map[string]interface{} `json:"configuration"`
+	Path              string                 `json:"path"`
+	SourceProvider    string                 `json:"source_provider"`
+	TargetProvider    string                 `json:"target_provider"`
+	Files             map[string]*SourceFile `json:"files"`
+	Dependencies      map[string]string      `json:"dependencies"`
+	Flows             []*Flow                `json:"flows"`
+	Models            []*Model               `json:"models"`
+	Configuration     map[string]interface{} `json:"configuration"`
+	CloudServices     []*CloudService        `json:"cloud_services"`
+	ConfigFiles       map[string]*ConfigFile `json:"config_files"`
+	Prompts           []*Prompt              `json:"prompts"`
+	Embedders         []*Embedder            `json:"embedders"`
+	Indexers          []*Indexer             `json:"indexers"`
+	VectorStores      []*VectorStore         `json:"vector_stores"`
+	DirectCalls       []*DirectCall          `json:"direct_calls"`
+	SafetySettings    []*SafetySetting       `json:"safety_settings"`
+	StructuredOutputs []*StructuredOutput    `json:"structured_outputs"`
+	Features          []*FeatureUsage        `json:"features"`
+	Deployment        *Deployment            `json:"deployment,omitempty"`
 }
 
 type SourceFile struct {
-	Path        string   `json:"path"`
-	PackageName string   `json:"package_name"`
-	Imports     []string `json:"imports"`
-	Flows       []*Flow  `json:"flows"`
-	Models      []*Model `json:"models"`
-	HasGenKit   bool     `json:"has_genkit"`
+	Path              string              `json:"path"`
+	PackageName       string              `json:"package_name"`
+	Imports           []string            `json:"imports"`
+	Flows             []*Flow             `json:"flows"`
+	Models            []*Model            `json:"models"`
+	CloudServices     []*CloudService     `json:"cloud_services,omitempty"`
+	Embedders         []*Embedder         `json:"embedders,omitempty"`
+	Indexers          []*Indexer          `json:"indexers,omitempty"`
+	VectorStores      []*VectorStore      `json:"vector_stores,omitempty"`
+	DirectCalls       []*DirectCall       `json:"direct_calls,omitempty"`
+	SafetySettings    []*SafetySetting    `json:"safety_settings,omitempty"`
+	StructuredOutputs []*StructuredOutput `json:"structured_outputs,omitempty"`
+	Features          []*FeatureUsage     `json:"features,omitempty"`
+	HasGenKit         bool                `json:"has_genkit"`
 }
 
 type Flow struct {
-	Name        string         `json:"name"`
-	Position    token.Position `json:"position"`
-	InputType   string         `json:"input_type,omitempty"`
-	OutputType  string         `json:"output_type,omitempty"`
-	Description string         `json:"description,omitempty"`
+	Name             string         `json:"name"`
+	Position         token.Position `json:"position"`
+	InputType        string         `json:"input_type,omitempty"`
+	OutputType       string         `json:"output_type,omitempty"`
+	Description      string         `json:"description,omitempty"`
+	StructuredOutput bool           `json:"structured_output,omitempty"`
+	Streaming        bool           `json:"streaming,omitempty"`
 }
 
 type Model struct {
@@ -35,3 +56,121 @@ type Model struct {
 	Provider string         `json:"provider"`
 	Position token.Position `json:"position"`
 }
+
+type Embedder struct {
+	Name       string         `json:"name"`
+	Provider   string         `json:"provider"`
+	Dimensions int            `json:"dimensions,omitempty"`
+	Position   token.Position `json:"position"`
+}
+
+type Indexer struct {
+	Provider string         `json:"provider"`
+	Name     string         `json:"name"`
+	Package  string         `json:"package"`
+	Position token.Position `json:"position"`
+}
+
+type VectorStore struct {
+	Kind         string         `json:"kind"` // "firestore", "vertex-vector-search"
+	Package      string         `json:"package"`
+	Name         string         `json:"name,omitempty"`
+	Collection   string         `json:"collection,omitempty"`
+	ContentField string         `json:"content_field,omitempty"`
+	VectorField  string         `json:"vector_field,omitempty"`
+	Position     token.Position `json:"position"`
+}
+
+// DirectCall is a call into a model SDK made outside of GenKit.
+type DirectCall struct {
+	Package  string         `json:"package"`
+	Method   string         `json:"method"`
+	Model    string         `json:"model,omitempty"`
+	Position token.Position `json:"position"`
+}
+
+// SafetySetting is a Gemini harm category and the threshold content in it is
+// blocked at. Values not written as constants keep their source text.
+type SafetySetting struct {
+	Category  string         `json:"category"`  // e.g. "HARM_CATEGORY_HARASSMENT"
+	Threshold string         `json:"threshold"` // e.g. "BLOCK_LOW_AND_ABOVE"
+	Position  token.Position `json:"position"`
+}
+
+// StructuredOutput is a generation that asks the model for JSON matching a
+// schema, through ai.WithOutputType, genkit.GenerateData or a Gemini response
+// schema.
+type StructuredOutput struct {
+	Flow     string         `json:"flow,omitempty"` // enclosing flow, if any
+	Method   string         `json:"method"`         // e.g. "WithOutputType", "GenerateData", "ResponseSchema"
+	Type     string         `json:"type,omitempty"` // Go type of the output, when known
+	Model    string         `json:"model,omitempty"`
+	Position token.Position `json:"position"`
+}
+
+// FeatureUsage is a use of a provider-specific feature, such as grounding
+// with Google Search, in code, a prompt or a config file.
+type FeatureUsage struct {
+	Feature  string         `json:"feature"` // e.g. "google-search-grounding"
+	Title    string         `json:"title"`
+	Detail   string         `json:"detail"` // identifier or setting that uses it
+	Position token.Position `json:"position"`
+}
+
+type CloudService struct {
+	Service     string           `json:"service"`
+	Package     string           `json:"package"`
+	Position    token.Position   `json:"position"`
+	CallSites   []token.Position `json:"call_sites,omitempty"`
+	Alternative string           `json:"alternative"`
+	Effort      string           `json:"effort"`
+}
+
+type Prompt struct {
+	Path           string                 `json:"path"`
+	Model          *Model                 `json:"model,omitempty"`
+	Config         map[string]interface{} `json:"config,omitempty"`
+	SafetySettings []*SafetySetting       `json:"safety_settings,omitempty"`
+	Features       []*FeatureUsage        `json:"features,omitempty"`
+	Position       token.Position         `json:"position"`
+}
+
+// Deployment is how the source app is deployed, read from its Cloud Run
+// service, GKE manifests, gcloud deploy commands or Cloud Functions
+// registration. Settings keep their source format, e.g. CPU "1" or "500m",
+// Memory "512Mi".
+type Deployment struct {
+	Platform     string `json:"platform"` // "cloud-run", "cloud-functions", "gke"
+	File         string `json:"file"`
+	CPU          string `json:"cpu,omitempty"`
+	Memory       string `json:"memory,omitempty"`
+	Timeout      string `json:"timeout,omitempty"` // e.g. "300s"
+	MinInstances int    `json:"min_instances,omitempty"`
+	MaxInstances int    `json:"max_instances,omitempty"`
+	Concurrency  int    `json:"concurrency,omitempty"`
+	Port         int    `json:"port,omitempty"`
+	// Container is the app container of a GKE Deployment, whose resources
+	// and probes carry over to Kubernetes targets as written.
+	Container *Container `json:"container,omitempty"`
+}
+
+type Container struct {
+	Resources      map[string]map[string]string `json:"resources,omitempty"` // requests, limits
+	ReadinessProbe map[string]interface{}       `json:"readiness_probe,omitempty"`
+	LivenessProbe  map[string]interface{}       `json:"liveness_probe,omitempty"`
+	StartupProbe   map[string]interface{}       `json:"startup_probe,omitempty"`
+}
+
+type ConfigFile struct {
+	Path     string           `json:"path"`
+	Format   string           `json:"format"` // "yaml", "json", "env"
+	Settings []*ConfigSetting `json:"settings"`
+}
+
+type ConfigSetting struct {
+	Key    string `json:"key"`
+	Value  string `json:"value,omitempty"`
+	Secret bool   `json:"secret,omitempty"`
+	GCP    bool   `json:"gcp,omitempty"`
+	Line   int    `json:"line,omitempty"`
+}
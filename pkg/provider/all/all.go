@@ -0,0 +1,10 @@
+// Package all registers the built-in providers.
+package all
+
+import (
+	_ "github.com/genkit-migrate/genkit-migrate/pkg/provider/api"
+	_ "github.com/genkit-migrate/genkit-migrate/pkg/provider/aws"
+	_ "github.com/genkit-migrate/genkit-migrate/pkg/provider/azure"
+	_ "github.com/genkit-migrate/genkit-migrate/pkg/provider/gcp"
+	_ "github.com/genkit-migrate/genkit-migrate/pkg/provider/ollama"
+)
@@ -0,0 +1,310 @@
+// Package api migrates GenKit projects to model APIs used directly rather
+// than through a cloud provider: OpenAI and Anthropic.
+package api
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"text/template"
+
+	"github.com/genkit-migrate/genkit-migrate/pkg/models"
+	"github.com/genkit-migrate/genkit-migrate/pkg/provider"
+	"github.com/genkit-migrate/genkit-migrate/pkg/provider/azure"
+)
+
+const (
+	openAIPluginPackage    = "github.com/firebase/genkit/go/plugins/compat_oai/openai"
+	anthropicPluginPackage = "github.com/firebase/genkit/go/plugins/compat_oai/anthropic"
+)
+
+func init() {
+	provider.Register(&apiProvider{
+		name:     "openai",
+		title:    "OpenAI",
+		keyEnv:   "OPENAI_API_KEY",
+		prefixes: []string{"openai/", "gpt-"},
+		catalog:  azure.OpenAICatalog,
+		plugin: &provider.Plugin{
+			Name:           "openai",
+			Path:           openAIPluginPackage,
+			Imports:        [][2]string{{"os", "os"}, {"openai", openAIPluginPackage}},
+			Expr:           `&openai.OpenAI{APIKey: os.Getenv("OPENAI_API_KEY")}`,
+			Lookup:         "genkit",
+			LookupPath:     "github.com/firebase/genkit/go/genkit",
+			LookupProvider: "openai",
+		},
+	})
+	provider.Register(&apiProvider{
+		name:     "anthropic",
+		title:    "Anthropic",
+		keyEnv:   "ANTHROPIC_API_KEY",
+		prefixes: []string{"anthropic/", "claude-"},
+		catalog:  anthropicCatalog,
+		plugin: &provider.Plugin{
+			Name: "anthropic",
+			Path: anthropicPluginPackage,
+			Imports: [][2]string{
+				{"os", "os"},
+				{"anthropic", anthropicPluginPackage},
+				{"option", "github.com/openai/openai-go/option"},
+			},
+			Expr:           `&anthropic.Anthropic{Opts: []option.RequestOption{option.WithAPIKey(os.Getenv("ANTHROPIC_API_KEY"))}}`,
+			Lookup:         "genkit",
+			LookupPath:     "github.com/firebase/genkit/go/genkit",
+			LookupProvider: "anthropic",
+		},
+		requires: [][2]string{{"github.com/openai/openai-go", "v1.8.2"}},
+	})
+}
+
+// apiProvider describes a model API. The provider name doubles as the GenKit
+// plugin name.
+type apiProvider struct {
+	name     string
+	title    string
+	keyEnv   string
+	prefixes []string
+	catalog  func(source string) *provider.Catalog
+	plugin   *provider.Plugin
+	requires [][2]string
+}
+
+func (p *apiProvider) Name() string {
+	return p.name
+}
+
+func (p *apiProvider) Title() string {
+	return p.title
+}
+
+func (p *apiProvider) DetectModel(ref string) bool {
+	for _, prefix := range p.prefixes {
+		if strings.HasPrefix(ref, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+func (p *apiProvider) Source() *provider.Source {
+	return nil
+}
+
+func (p *apiProvider) Catalog(ctx provider.Context) *provider.Catalog {
+	return p.catalog(ctx.Source())
+}
+
+var anthropicModelMappings = map[string]map[string]string{
+	"gcp": {
+		"googleai/gemini-1.5-flash":    "claude-3-5-haiku-20241022",
+		"googleai/gemini-1.5-flash-8b": "claude-3-5-haiku-20241022",
+		"googleai/gemini-1.5-pro":      "claude-3-5-sonnet-20241022",
+		"googleai/gemini-2.0-flash":    "claude-3-5-sonnet-20241022",
+		"vertexai/gemini-pro":          "claude-3-5-sonnet-20241022",
+		"vertexai/gemini-1.5-pro":      "claude-3-5-sonnet-20241022",
+		"vertexai/gemini-1.5-flash":    "claude-3-5-haiku-20241022",
+	},
+	// Bedrock serves the Anthropic models under versioned IDs; Claude 3
+	// Sonnet is retired on the Anthropic API, so it moves to 3.5 Sonnet.
+	"aws": {
+		"bedrock/anthropic.claude-3-haiku-20240307-v1:0":    "claude-3-haiku-20240307",
+		"bedrock/anthropic.claude-3-sonnet-20240229-v1:0":   "claude-3-5-sonnet-20241022",
+		"bedrock/anthropic.claude-3-opus-20240229-v1:0":     "claude-3-opus-20240229",
+		"bedrock/anthropic.claude-3-5-sonnet-20240620-v1:0": "claude-3-5-sonnet-20240620",
+		"bedrock/anthropic.claude-3-5-sonnet-20241022-v2:0": "claude-3-5-sonnet-20241022",
+		"bedrock/amazon.nova-micro-v1:0":                    "claude-3-5-haiku-20241022",
+		"bedrock/amazon.nova-lite-v1:0":                     "claude-3-5-haiku-20241022",
+		"bedrock/amazon.nova-pro-v1:0":                      "claude-3-5-sonnet-20241022",
+	},
+}
+
+// anthropicCatalog has no embedders; Anthropic does not serve embedding
+// models.
+func anthropicCatalog(source string) *provider.Catalog {
+	return &provider.Catalog{
+		Models: anthropicModelMappings[source],
+		Prefix: "anthropic/",
+	}
+}
+
+func (p *apiProvider) Rewrite(ctx provider.Context) *provider.Rewrite {
+	return &provider.Rewrite{
+		Plugin:   p.plugin,
+		Requires: p.requires,
+	}
+}
+
+// Settings turns API keys into the API's key and drops everything tied to
+// the cloud account. There is no secret store; the app reads its key from
+// the environment.
+func (p *apiProvider) Settings(ctx provider.Context) *provider.Settings {
+	apiKey := provider.SettingKey{
+		Key:   p.keyEnv,
+		Value: func(string) string { return "" },
+		Note:  fmt.Sprintf("set it to an %s API key", p.title),
+	}
+	noAccount := provider.SettingKey{Note: fmt.Sprintf("the %s API is not tied to a cloud account", p.title)}
+
+	settings := &provider.Settings{
+		Keys: map[string]provider.SettingKey{
+			"GOOGLE_CLOUD_PROJECT":           noAccount,
+			"GCLOUD_PROJECT":                 noAccount,
+			"GCP_PROJECT":                    noAccount,
+			"PROJECT_ID":                     noAccount,
+			"GCLOUD_LOCATION":                noAccount,
+			"GOOGLE_CLOUD_LOCATION":          noAccount,
+			"GOOGLE_CLOUD_REGION":            noAccount,
+			"LOCATION":                       noAccount,
+			"REGION":                         noAccount,
+			"GOOGLE_APPLICATION_CREDENTIALS": noAccount,
+			"GOOGLE_API_KEY":                 apiKey,
+			"GOOGLE_GENAI_API_KEY":           apiKey,
+			"GEMINI_API_KEY":                 apiKey,
+		},
+		Reserved: []string{p.name, "environment"},
+	}
+	if ctx.Source() == "aws" {
+		settings.Keys = map[string]provider.SettingKey{
+			"AWS_REGION":            noAccount,
+			"AWS_DEFAULT_REGION":    noAccount,
+			"AWS_PROFILE":           noAccount,
+			"AWS_ACCESS_KEY_ID":     apiKey,
+			"AWS_SECRET_ACCESS_KEY": noAccount,
+			"AWS_SESSION_TOKEN":     noAccount,
+		}
+	}
+	return settings
+}
+
+// usedModels lists the API models the migrated code references.
+func (p *apiProvider) usedModels(ctx provider.Context) []string {
+	mappings := ctx.Catalog().Models
+
+	names := make(map[string]bool)
+	for _, model := range ctx.Project().Models {
+		if newModel, exists := mappings[model.Name]; exists {
+			names[newModel] = true
+		}
+	}
+	for _, change := range ctx.Migration().Changes {
+		if change.Type == "model" && change.NewValue != "" {
+			names[strings.TrimPrefix(change.NewValue, p.name+"/")] = true
+		}
+	}
+
+	apiModels := make([]string, 0, len(names))
+	for name := range names {
+		apiModels = append(apiModels, name)
+	}
+	sort.Strings(apiModels)
+
+	return apiModels
+}
+
+func (p *apiProvider) Configure(ctx provider.Context) error {
+	configTemplate := `# {{ .Title }} Configuration for GenKit
+{{ .Name }}:
+  models:
+{{- range .Models }}
+    - {{ . }}
+{{- end }}
+
+# Environment variables
+environment:
+  - GENKIT_ENV=production
+  - {{ .KeyEnv }}=<{{ .Title }} API key>
+`
+
+	tmpl, err := template.New("config").Parse(configTemplate)
+	if err != nil {
+		return err
+	}
+
+	var content strings.Builder
+	err = tmpl.Execute(&content, map[string]interface{}{
+		"Title":  p.title,
+		"Name":   p.name,
+		"KeyEnv": p.keyEnv,
+		"Models": p.usedModels(ctx),
+	})
+	if err != nil {
+		return err
+	}
+
+	return ctx.WriteConfig(content.String())
+}
+
+func (p *apiProvider) Deploy(ctx provider.Context) error {
+	provider.WriteDockerfile(ctx.Migration())
+	generateContainerCI(ctx.Migration())
+	return nil
+}
+
+// generateContainerCI tests the app and publishes its image to the GitHub
+// container registry; where the image runs is up to the team.
+func generateContainerCI(migration *models.Migration) {
+	workflow := `name: CI
+
+on:
+  push:
+    branches: [ main ]
+  pull_request:
+
+jobs:
+  test:
+    runs-on: ubuntu-latest
+    steps:
+    - uses: actions/checkout@v4
+    - uses: actions/setup-go@v5
+      with:
+        go-version: '1.23'
+    - run: go test -v ./...
+
+  image:
+    needs: test
+    if: github.ref == 'refs/heads/main'
+    runs-on: ubuntu-latest
+    permissions:
+      contents: read
+      packages: write
+    steps:
+    - uses: actions/checkout@v4
+    - uses: docker/login-action@v3
+      with:
+        registry: ghcr.io
+        username: ${{ github.actor }}
+        password: ${{ secrets.GITHUB_TOKEN }}
+    - uses: docker/build-push-action@v6
+      with:
+        push: true
+        tags: ghcr.io/${{ github.repository }}:${{ github.sha }}
+`
+
+	migration.NewFiles[".github/workflows/ci.yml"] = workflow
+}
+
+func (p *apiProvider) Guide(migration *models.Migration) string {
+	content := `
+
+## Container Deployment
+
+The app calls the model API directly and is not tied to a cloud provider.
+It reads its API key from ` + "`" + p.keyEnv + "`" + `.
+
+### Run the Container
+
+` + "```bash" + `
+docker build -t genkit-app .
+docker run -p 8080:8080 -e ` + p.keyEnv + ` genkit-app
+` + "```" + `
+
+### Continuous Integration
+
+` + "`.github/workflows/ci.yml`" + ` runs the tests and pushes the image to the
+GitHub container registry on every push to main. Provide ` + "`" + p.keyEnv + "`" + `
+to whichever platform runs the image, through its secret store.
+`
+
+	return content + provider.ModelMappingsGuide(migration)
+}
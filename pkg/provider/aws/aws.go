@@ -0,0 +1,823 @@
+// Package aws migrates GenKit projects to and from Amazon Bedrock through
+// the genkit-aws plugin, deployed to Lambda, ECS Fargate, App Runner or EKS
+// with Terraform, CDK, SAM or Pulumi.
+package aws
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"text/template"
+
+	"github.com/genkit-migrate/genkit-migrate/pkg/models"
+	"github.com/genkit-migrate/genkit-migrate/pkg/provider"
+	"github.com/genkit-migrate/genkit-migrate/pkg/rewrite"
+)
+
+const (
+	genkitAWSPackage = "github.com/scttfrdmn/genkit-aws/pkg/genkit-aws"
+	bedrockPackage   = "github.com/scttfrdmn/genkit-aws/pkg/bedrock"
+)
+
+func init() {
+	provider.Register(awsProvider{})
+}
+
+type awsProvider struct{}
+
+func (awsProvider) Name() string {
+	return "aws"
+}
+
+func (awsProvider) Title() string {
+	return "AWS"
+}
+
+func (awsProvider) DetectModel(ref string) bool {
+	ref = foundationModel(ref)
+	return strings.HasPrefix(ref, "bedrock/") || strings.HasPrefix(ref, "amazon.") || strings.HasPrefix(ref, "anthropic.")
+}
+
+var source = &provider.Source{
+	Plugins: []provider.PluginSource{
+		{Path: genkitAWSPackage, Prefixes: []string{"bedrock/"}, Constructors: []string{"New"}, Init: true},
+		{Path: bedrockPackage, Prefixes: []string{"bedrock/"}},
+	},
+	SDKs:     []string{"firebase", "genkit-aws", "aws-sdk-go"},
+	Sections: []string{"AWS"},
+	Owns: func(name string, known bool, setting *models.ConfigSetting) bool {
+		return known || strings.HasPrefix(name, "AWS_")
+	},
+	Generation: bedrockGeneration,
+}
+
+func (awsProvider) Source() *provider.Source {
+	return source
+}
+
+var bedrockGeneration = &provider.Generation{
+	Title:    "Bedrock",
+	Packages: []string{bedrockPackage},
+	Types:    []string{"GenerationConfig"},
+	Package:  bedrockPackage,
+	Name:     "bedrock",
+	Type:     "GenerationConfig",
+	Options: []provider.GenerationOption{
+		{Field: "Temperature", Prompt: "temperature", Common: "Temperature", Min: 0, Max: 1, Limited: true},
+		{Field: "MaxTokens", Prompt: "maxTokens", Common: "MaxOutputTokens", Min: 1, Max: 4096, Limited: true},
+		{Field: "TopP", Prompt: "topP", Common: "TopP", Min: 0, Max: 1, Limited: true},
+		{Field: "TopK", Prompt: "topK", Common: "TopK", Min: 0, Max: 500, Limited: true},
+		{Field: "StopSequences", Prompt: "stopSequences", Common: "StopSequences"},
+	},
+	Unsupported: map[string]string{
+		"CandidateCount":   "Bedrock always returns a single candidate",
+		"ResponseMIMEType": "use schema-constrained output instead of a MIME type",
+		"ResponseSchema":   "use schema-constrained output instead of a response schema",
+		"SafetySettings":   "Bedrock enforces content filters through Guardrails",
+		"PresencePenalty":  "not supported by Bedrock models",
+		"FrequencyPenalty": "not supported by Bedrock models",
+		"Seed":             "not supported by Bedrock models",
+	},
+	StructuredOutput: bedrockStructuredOutput,
+}
+
+// bedrockStructuredOutput has genkit-aws force a tool call whose input schema
+// is the requested output type. Bedrock has no native JSON mode, so only
+// models with tool use support it.
+var bedrockStructuredOutput = &provider.StructuredOutput{
+	Title:   "Bedrock tool-use JSON mode",
+	Field:   "OutputMode",
+	Expr:    "bedrock.OutputModeToolUse",
+	Imports: [][2]string{{"bedrock", bedrockPackage}},
+	Models: withInferenceProfiles(
+		"anthropic.claude-3",
+		"anthropic.claude-sonnet-4",
+		"anthropic.claude-opus-4",
+		"amazon.nova-",
+		"mistral.mistral-large",
+		"cohere.command-r",
+		"meta.llama3-1",
+	),
+}
+
+var alternatives = map[string]string{
+	"google-search-grounding": "ground answers with a Bedrock Knowledge Base using a web crawler data source, or call a search API from a Lambda tool",
+	"context-caching":         "use Bedrock prompt caching by adding cache points to the long, repeated prefix of the prompt",
+	"code-execution":          "run the code in a Lambda function exposed to the model as a tool",
+	"vertex-rag-engine":       "move the corpus to a Bedrock Knowledge Base and retrieve from it with RetrieveAndGenerate",
+}
+
+const titanDimensionsNote = "Titan Text Embeddings V2 produces 256, 512 or 1024 dimensions"
+
+func (awsProvider) Catalog(ctx provider.Context) *provider.Catalog {
+	if ctx.Source() != "gcp" {
+		return &provider.Catalog{Prefix: "bedrock/"}
+	}
+
+	titan := provider.Embedder{Target: "amazon.titan-embed-text-v2:0", Dimensions: 1024, Note: titanDimensionsNote}
+	catalog := &provider.Catalog{
+		Models: map[string]string{
+			"googleai/gemini-1.5-flash": "anthropic.claude-3-haiku-20240307-v1:0",
+			"googleai/gemini-1.5-pro":   "anthropic.claude-3-sonnet-20240229-v1:0",
+			"googleai/gemini-2.0-flash": "anthropic.claude-3-5-sonnet-20241022-v2:0",
+			"vertexai/gemini-pro":       "anthropic.claude-3-sonnet-20240229-v1:0",
+			"vertexai/gemini-1.5-pro":   "anthropic.claude-3-sonnet-20240229-v1:0",
+			"vertexai/gemini-1.5-flash": "anthropic.claude-3-haiku-20240307-v1:0",
+			// Map some models to Amazon Nova for variety
+			"googleai/gemini-1.5-flash-8b": "amazon.nova-lite-v1:0",
+			"googleai/text-bison":          "amazon.nova-micro-v1:0",
+		},
+		Embedders: map[string]provider.Embedder{
+			"googleai/text-embedding-004":              titan,
+			"googleai/embedding-001":                   titan,
+			"googleai/gemini-embedding-001":            titan,
+			"vertexai/text-embedding-004":              titan,
+			"vertexai/text-embedding-005":              titan,
+			"vertexai/textembedding-gecko@003":         titan,
+			"vertexai/gemini-embedding-001":            titan,
+			"vertexai/text-multilingual-embedding-002": {Target: "cohere.embed-multilingual-v3", Dimensions: 1024},
+		},
+		Prefix:       "bedrock/",
+		Alternatives: alternatives,
+	}
+
+	// Models served only through inference profiles in the app's region
+	// are invoked by profile ID.
+	for name, model := range catalog.Models {
+		catalog.Models[name] = invocationID(model, region(ctx))
+	}
+	return catalog
+}
+
+func (awsProvider) Rewrite(ctx provider.Context) *provider.Rewrite {
+	config := pluginConfig(ctx)
+	rules := make([]rewrite.Rule, 0)
+	if _, backend, err := selectedVectorStore(ctx); err == nil {
+		rules = append(rules, &vectorStoreRule{backend: backend})
+	}
+	requires := [][2]string{{"github.com/scttfrdmn/genkit-aws", "v0.1.0"}}
+	// Prompts are rewritten without a project.
+	if ctx.Project() != nil {
+		if key, _, err := selectedDeployTarget(ctx); err == nil && key == "lambda" {
+			rules = append(rules, &lambdaHandlerRule{})
+			// Required by the generated serve_flows_lambda.go.
+			requires = append(requires,
+				[2]string{"github.com/aws/aws-lambda-go", "v1.47.0"},
+				[2]string{"github.com/awslabs/aws-lambda-go-api-proxy", "v0.16.2"})
+		}
+	}
+	if migration := ctx.Migration(); migration != nil && len(converseDirs(migration)) > 0 {
+		requires = append(requires, converseRequires...)
+	}
+
+	return &provider.Rewrite{
+		Plugin: &provider.Plugin{
+			Name: "genkit-aws",
+			Path: genkitAWSPackage,
+			Imports: [][2]string{
+				{"genkitaws", genkitAWSPackage},
+				{"bedrock", bedrockPackage},
+			},
+			Init:       "genkitaws.Init",
+			InitConfig: config,
+			Expr:       "genkitaws.New(" + config + ")",
+			Lookup:     "bedrock",
+			LookupPath: bedrockPackage,
+		},
+		Requires:   requires,
+		Generation: generation(ctx),
+		Client:     converseClient,
+		Rules:      rules,
+	}
+}
+
+func pluginConfig(ctx provider.Context) string {
+	quoted := make([]string, 0)
+	for _, model := range bedrockModels(ctx) {
+		quoted = append(quoted, strconv.Quote(model))
+	}
+
+	return fmt.Sprintf(`&genkitaws.Config{Region: %q, Bedrock: &bedrock.Config{Models: []string{%s}}}`,
+		region(ctx), strings.Join(quoted, ", "))
+}
+
+// bedrockModels returns the Bedrock models the project's models map to,
+// falling back to Claude 3 Sonnet when none were detected.
+func bedrockModels(ctx provider.Context) []string {
+	mappings := ctx.Catalog().Models
+
+	seen := make(map[string]bool)
+	targets := make([]string, 0)
+	if project := ctx.Project(); project != nil {
+		for _, model := range project.Models {
+			if newModel, exists := mappings[model.Name]; exists && !seen[newModel] {
+				seen[newModel] = true
+				targets = append(targets, newModel)
+			}
+		}
+	}
+	if len(targets) == 0 {
+		targets = append(targets, "anthropic.claude-3-sonnet-20240229-v1:0")
+	}
+	sort.Strings(targets)
+	return targets
+}
+
+func region(ctx provider.Context) string {
+	if region, exists := ctx.Setting("AWS_REGION"); exists {
+		return region
+	}
+	return "us-east-1"
+}
+
+var gcpRegions = map[string]string{
+	"us-central1":          "us-east-1",
+	"us-east1":             "us-east-1",
+	"us-east4":             "us-east-1",
+	"us-west1":             "us-west-2",
+	"us-west2":             "us-west-1",
+	"europe-west1":         "eu-west-1",
+	"europe-west2":         "eu-west-2",
+	"europe-west3":         "eu-central-1",
+	"europe-west4":         "eu-west-1",
+	"asia-northeast1":      "ap-northeast-1",
+	"asia-southeast1":      "ap-southeast-1",
+	"australia-southeast1": "ap-southeast-2",
+}
+
+func translateRegion(region string) string {
+	if awsRegion, exists := gcpRegions[region]; exists {
+		return awsRegion
+	}
+	return "us-east-1"
+}
+
+var gcpSettingKeys = map[string]provider.SettingKey{
+	"GOOGLE_CLOUD_PROJECT":           {Key: "PROJECT_NAME"},
+	"GCLOUD_PROJECT":                 {Key: "PROJECT_NAME"},
+	"GCP_PROJECT":                    {Key: "PROJECT_NAME"},
+	"PROJECT_ID":                     {Key: "PROJECT_NAME"},
+	"PROJECT":                        {Key: "PROJECT_NAME"},
+	"GCLOUD_LOCATION":                {Key: "AWS_REGION", Value: translateRegion},
+	"GOOGLE_CLOUD_LOCATION":          {Key: "AWS_REGION", Value: translateRegion},
+	"GOOGLE_CLOUD_REGION":            {Key: "AWS_REGION", Value: translateRegion},
+	"LOCATION":                       {Key: "AWS_REGION", Value: translateRegion},
+	"REGION":                         {Key: "AWS_REGION", Value: translateRegion},
+	"GOOGLE_APPLICATION_CREDENTIALS": {Key: "AWS_PROFILE", Value: func(string) string { return "default" }},
+	"GOOGLE_API_KEY":                 {Note: "Bedrock authenticates with IAM credentials"},
+	"GOOGLE_GENAI_API_KEY":           {Note: "Bedrock authenticates with IAM credentials"},
+	"GEMINI_API_KEY":                 {Note: "Bedrock authenticates with IAM credentials"},
+}
+
+func (awsProvider) Settings(ctx provider.Context) *provider.Settings {
+	settings := &provider.Settings{
+		Reserved: []string{"region", "profile", "bedrock", "cloudwatch", "environment"},
+		Secret: func(key, value string) *provider.Secret {
+			name := ctx.ModuleName() + "/" + key
+			// Neither Lambda nor the app resolves a reference in its
+			// config, so the app reads the secret itself.
+			return &provider.Secret{
+				Name:    name,
+				Store:   "AWS Secrets Manager secret",
+				Command: fmt.Sprintf("aws secretsmanager create-secret --name %s --secret-string \"%s\"", name, value),
+			}
+		},
+	}
+	if ctx.Source() == "gcp" {
+		settings.Keys = gcpSettingKeys
+	}
+	return settings
+}
+
+func (awsProvider) Configure(ctx provider.Context) error {
+	configTemplate := `# AWS Configuration for GenKit
+region: {{ .Region }}
+profile: default
+
+bedrock:
+  models:
+{{- range .Models }}
+    - {{ . }}
+{{- end }}
+
+cloudwatch:
+  namespace: "GenKit/{{ .ProjectName }}"
+  enabled: true
+
+# Environment variables
+environment:
+  - GENKIT_ENV=production
+  - AWS_REGION={{ .Region }}
+`
+
+	tmpl, err := template.New("config").Parse(configTemplate)
+	if err != nil {
+		return err
+	}
+
+	var content strings.Builder
+	err = tmpl.Execute(&content, map[string]interface{}{
+		"ProjectName": ctx.ProjectName(),
+		"Region":      region(ctx),
+		"Models":      bedrockModels(ctx),
+	})
+	if err != nil {
+		return err
+	}
+
+	if err := ctx.WriteConfig(content.String()); err != nil {
+		return err
+	}
+
+	transformAppEngineSettings(ctx.Migration())
+	return nil
+}
+
+var appEngineSettings = map[string]string{
+	"runtime":              "Lambda runtime provided.al2 (compiled Go binary)",
+	"service":              "Lambda function name (var.function_name)",
+	"instance_class":       "Lambda memory_size",
+	"automatic_scaling":    "Lambda reserved/provisioned concurrency",
+	"basic_scaling":        "Lambda reserved/provisioned concurrency",
+	"manual_scaling":       "Lambda provisioned concurrency",
+	"handlers":             "API Gateway routes",
+	"entrypoint":           "Dockerfile CMD",
+	"vpc_access_connector": "Lambda vpc_config",
+}
+
+func transformAppEngineSettings(migration *models.Migration) {
+	appFile, exists := migration.Project.ConfigFiles["app.yaml"]
+	if !exists {
+		return
+	}
+
+	seen := make(map[string]bool)
+	for _, setting := range appFile.Settings {
+		section := strings.Split(setting.Key, ".")[0]
+		if section == "env_variables" || seen[section] {
+			continue
+		}
+		seen[section] = true
+
+		target, known := appEngineSettings[section]
+		if !known {
+			target = "no AWS equivalent, review manually"
+		}
+
+		change := &models.Change{
+			Type:        "config",
+			Description: fmt.Sprintf("App Engine setting %s -> %s", section, target),
+			File:        "app.yaml",
+		}
+		if setting.Key == section {
+			change.OldValue = setting.Value
+		}
+		migration.Changes = append(migration.Changes, change)
+	}
+}
+
+func (awsProvider) Deploy(ctx provider.Context) error {
+	if err := transformVectorStores(ctx); err != nil {
+		return fmt.Errorf("failed to transform vector stores: %w", err)
+	}
+
+	if err := transformSafetySettings(ctx); err != nil {
+		return fmt.Errorf("failed to transform safety settings: %w", err)
+	}
+
+	if err := generateConverse(ctx.Migration()); err != nil {
+		return fmt.Errorf("failed to generate the Converse client: %w", err)
+	}
+
+	return generateDeployment(ctx)
+}
+
+func generateGitHubActions(migration *models.Migration, d *deployment) error {
+	workflow := `name: Deploy to AWS
+
+on:
+  push:
+    branches: [ main ]
+
+jobs:
+  test:
+    runs-on: ubuntu-latest
+    steps:
+    - uses: actions/checkout@v4
+    - uses: actions/setup-go@v4
+      with:
+        go-version: '1.23'
+    - run: go test -v ./...
+{{- if eq .IaC "cdk-go" }}
+    - run: go test -v ./...
+      working-directory: cdk
+{{- end }}
+{{- if eq .Target "eks" }}
+    - uses: azure/setup-helm@v4
+    - run: helm lint charts/{{ .ProjectName }} --set image.repository=lint --set serviceAccount.roleArn=lint
+{{- end }}
+
+  # Each environment deploys after the previous one; add required reviewers
+  # to the staging and prod GitHub environments to gate promotion.
+{{- $needs := "test" }}
+{{- range .Environments }}
+{{- if ne $needs "test" }}
+{{ end }}
+{{- if ne $.IaC "terraform" }}
+  deploy-{{ . }}:
+    needs: {{ $needs }}
+    runs-on: ubuntu-latest
+    environment: {{ . }}
+{{- if eq $.IaC "pulumi-go" }}
+    env:
+      PULUMI_ACCESS_TOKEN: ${{ "{{ secrets.PULUMI_ACCESS_TOKEN }}" }}
+{{- end }}
+    steps:
+    - uses: actions/checkout@v4
+    - uses: aws-actions/configure-aws-credentials@v4
+      with:
+        aws-access-key-id: ${{ "{{ secrets.AWS_ACCESS_KEY_ID }}" }}
+        aws-secret-access-key: ${{ "{{ secrets.AWS_SECRET_ACCESS_KEY }}" }}
+        aws-region: {{ $.Region }}
+    - uses: actions/setup-go@v4
+      with:
+        go-version: '1.23'
+{{- if eq $.IaC "cdk-go" }}
+    - uses: actions/setup-node@v4
+      with:
+        node-version: '20'
+    - run: npm install -g aws-cdk
+{{- else if eq $.IaC "pulumi-go" }}
+    - uses: pulumi/actions@v6
+{{- else }}
+    - uses: aws-actions/setup-sam@v2
+      with:
+        use-installer: true
+{{- end }}
+{{- if $.Inputs }}
+
+    # The stack reads the guardrail and vector store from the outputs of
+    # terraform/envs/{{ . }}, empty until its first apply.
+    - name: Read the Terraform outputs
+      working-directory: terraform/envs/{{ . }}
+      run: |
+        terraform init -backend-config=backend.hcl
+{{- range $.Inputs }}
+        echo "{{ .Name }}=$(terraform output -raw {{ .Output }} 2>/dev/null)" >> "$GITHUB_ENV"
+{{- end }}
+{{- end }}
+{{- if eq $.Target "lambda" }}
+
+    - name: Build the function
+      run: |
+        GOOS=linux GOARCH=amd64 CGO_ENABLED=0 go build -tags lambda,lambda.norpc -o build/bootstrap .
+{{- end }}
+{{- if eq $.IaC "pulumi-go" }}
+
+    - name: Select the {{ . }} stack
+      working-directory: pulumi
+      run: pulumi stack select --create {{ . }}
+{{- if ne $.Target "lambda" }}
+
+    - name: Create the ECR repository
+      working-directory: pulumi
+      run: pulumi up --stack {{ . }} --yes --target 'urn:pulumi:{{ . }}::{{ $.ProjectName }}::aws:ecr/repository:Repository::app'
+
+    - uses: aws-actions/amazon-ecr-login@v2
+
+    - name: Build and push the image
+      run: |
+        REPOSITORY=$(cd pulumi && pulumi stack output ecrRepositoryUrl --stack {{ . }})
+        docker build -t $REPOSITORY:${{ "{{ github.sha }}" }} .
+        docker push $REPOSITORY:${{ "{{ github.sha }}" }}
+{{- end }}
+{{- end }}
+{{- $name := "SAM" }}
+{{- $deploy := printf "sam deploy --config-env %s --no-fail-on-empty-changeset" . }}
+{{- if eq $.IaC "cdk-go" }}
+{{- $name = "CDK" }}
+{{- $deploy = printf "cdk deploy --require-approval never -c environment=%s" . }}
+{{- else if eq $.IaC "pulumi-go" }}
+{{- $name = "Pulumi" }}
+{{- $deploy = printf "pulumi up --stack %s --yes" . }}
+{{- if ne $.Target "lambda" }}
+{{- $deploy = printf "%s --config imageTag=${{ github.sha }}" $deploy }}
+{{- end }}
+{{- end }}
+{{- $deploy = printf "%s%s" $deploy ($.InputArgs .) }}
+
+    - name: Deploy with {{ $name }}
+{{- if eq $.IaC "cdk-go" }}
+      working-directory: cdk
+{{- else if eq $.IaC "pulumi-go" }}
+      working-directory: pulumi
+{{- end }}
+      run: {{ $deploy }}
+{{- if $.Inputs }}
+
+    # The first apply grants the role the stack created; the stack is then
+    # redeployed with the outputs.
+    - name: Apply the guardrail and vector store
+      working-directory: terraform/envs/{{ . }}
+      run: |
+        terraform apply -auto-approve
+{{- range $.Inputs }}
+        echo "{{ .Name }}=$(terraform output -raw {{ .Output }})" >> "$GITHUB_ENV"
+{{- end }}
+
+    - name: Redeploy with the Terraform outputs
+{{- if eq $.IaC "cdk-go" }}
+      working-directory: cdk
+{{- else if eq $.IaC "pulumi-go" }}
+      working-directory: pulumi
+{{- end }}
+      run: {{ $deploy }}
+{{- end }}
+{{- else }}
+  deploy-{{ . }}:
+    needs: {{ $needs }}
+    runs-on: ubuntu-latest
+    environment: {{ . }}
+    defaults:
+      run:
+        working-directory: terraform/envs/{{ . }}
+    steps:
+    - uses: actions/checkout@v4
+    - uses: aws-actions/configure-aws-credentials@v4
+      with:
+        aws-access-key-id: ${{ "{{ secrets.AWS_ACCESS_KEY_ID }}" }}
+        aws-secret-access-key: ${{ "{{ secrets.AWS_SECRET_ACCESS_KEY }}" }}
+        aws-region: {{ $.Region }}
+    - run: terraform init -backend-config=backend.hcl
+{{- if eq $.Target "lambda" }}
+    - uses: actions/setup-go@v4
+      with:
+        go-version: '1.23'
+
+    - name: Build the function
+      working-directory: .
+      run: |
+        GOOS=linux GOARCH=amd64 CGO_ENABLED=0 go build -tags lambda,lambda.norpc -o build/bootstrap .
+        cd build && zip ../terraform/genkit-app.zip bootstrap
+
+    - name: Deploy with Terraform
+      run: terraform apply -auto-approve
+{{- else }}
+
+    - name: Create the ECR repository
+      run: terraform apply -auto-approve -target=module.app.aws_ecr_repository.app{{ if eq $.Target "eks" }} -var eks_cluster_name=${{ "{{ vars.EKS_CLUSTER_NAME }}" }}{{ end }}
+
+    - uses: aws-actions/amazon-ecr-login@v2
+
+    - name: Build and push the image
+      run: |
+        REPOSITORY=$(terraform output -raw ecr_repository_url)
+        docker build -t $REPOSITORY:${{ "{{ github.sha }}" }} ../../..
+        docker push $REPOSITORY:${{ "{{ github.sha }}" }}
+{{- if eq $.Target "eks" }}
+
+    - name: Deploy with Terraform
+      run: terraform apply -auto-approve -var eks_cluster_name=${{ "{{ vars.EKS_CLUSTER_NAME }}" }}
+
+    - uses: azure/setup-helm@v4
+
+    - name: Deploy to EKS
+      run: |
+        aws eks update-kubeconfig --name ${{ "{{ vars.EKS_CLUSTER_NAME }}" }}
+        helm upgrade --install {{ $.ProjectName }} ../../../charts/{{ $.ProjectName }} \
+          --namespace {{ . }} --create-namespace --wait \
+          --set image.repository=$(terraform output -raw ecr_repository_url) \
+          --set image.tag=${{ "{{ github.sha }}" }} \
+          --set serviceAccount.roleArn=$(terraform output -raw app_role_arn) \
+          --set-json env="$(terraform output -json app_environment)"
+{{- else }}
+
+    - name: Deploy with Terraform
+      run: terraform apply -auto-approve -var image_tag=${{ "{{ github.sha }}" }}
+{{- end }}
+{{- end }}
+{{- end }}
+{{- $needs = printf "deploy-%s" . }}
+{{- end }}
+`
+
+	tmpl, err := template.New("deploy.yml").Parse(workflow)
+	if err != nil {
+		return err
+	}
+
+	var content strings.Builder
+	if err := tmpl.Execute(&content, d); err != nil {
+		return err
+	}
+
+	migration.NewFiles[".github/workflows/deploy.yml"] = content.String()
+	return nil
+}
+
+// deployGuide describes how to build and deploy the generated artifacts: a
+// zipped binary for Lambda, an ECR image otherwise, installed with Helm on
+// EKS.
+func deployGuide(migration *models.Migration) string {
+	if _, sam := migration.NewFiles["samconfig.toml"]; sam {
+		return `2. AWS SAM CLI installed
+3. Docker installed, for ` + "`sam local`" + `
+
+### Deploy with SAM
+
+` + "```bash" + `
+GOOS=linux GOARCH=amd64 CGO_ENABLED=0 go build -tags lambda,lambda.norpc -o build/bootstrap .
+sam validate --lint
+sam local start-api              # serves POST /<flow> on http://127.0.0.1:3000
+sam deploy --config-env dev      # or staging, prod
+` + "```" + `
+` + stackResourcesGuide(migration)
+	}
+	if _, cdk := migration.NewFiles["cdk/cdk.json"]; cdk {
+		guide := `2. Node.js and the AWS CDK CLI (` + "`npm install -g aws-cdk`" + `)
+`
+		if _, container := migration.NewFiles["Dockerfile"]; container {
+			guide += `3. Docker installed
+`
+		}
+		guide += `
+### Deploy with CDK
+
+` + "```bash" + `
+`
+		if _, container := migration.NewFiles["Dockerfile"]; !container {
+			guide += `GOOS=linux GOARCH=amd64 CGO_ENABLED=0 go build -tags lambda,lambda.norpc -o build/bootstrap .
+`
+		}
+		return guide + `cd cdk
+go mod tidy && go test ./...
+cdk bootstrap
+cdk deploy -c environment=dev
+` + "```" + `
+` + stackResourcesGuide(migration)
+	}
+	if project, pulumi := migration.NewFiles["pulumi/Pulumi.yaml"]; pulumi {
+		name, _, _ := strings.Cut(strings.TrimPrefix(project, "name: "), "\n")
+		guide := `2. The Pulumi CLI installed and logged in (` + "`pulumi login`" + `)
+`
+		_, container := migration.NewFiles["Dockerfile"]
+		if container {
+			guide += `3. Docker installed
+`
+		}
+		guide += `
+### Deploy with Pulumi
+
+` + "```bash" + `
+`
+		if !container {
+			return guide + `GOOS=linux GOARCH=amd64 CGO_ENABLED=0 go build -tags lambda,lambda.norpc -o build/bootstrap .
+cd pulumi
+go mod tidy
+pulumi stack select --create dev   # or staging, prod
+pulumi up
+` + "```" + `
+` + stackResourcesGuide(migration)
+		}
+		return guide + `cd pulumi
+go mod tidy
+pulumi stack select --create dev   # or staging, prod
+pulumi up --target urn:pulumi:dev::` + name + `::aws:ecr/repository:Repository::app
+REPOSITORY=$(pulumi stack output ecrRepositoryUrl)
+aws ecr get-login-password | docker login --username AWS --password-stdin ${REPOSITORY%%/*}
+docker build -t $REPOSITORY:latest .. && docker push $REPOSITORY:latest
+pulumi up
+` + "```" + `
+` + stackResourcesGuide(migration)
+	}
+	if _, container := migration.NewFiles["Dockerfile"]; !container {
+		return `2. Terraform installed (>= 1.0)
+
+### Deploy with Terraform
+
+` + "```bash" + `
+GOOS=linux GOARCH=amd64 CGO_ENABLED=0 go build -tags lambda,lambda.norpc -o build/bootstrap .
+(cd build && zip ../terraform/genkit-app.zip bootstrap)
+cd terraform/envs/dev            # or staging, prod
+terraform init -backend-config=backend.hcl
+terraform apply
+` + "```" + `
+` + terraformEnvironmentsGuide
+	}
+
+	guide := `2. Terraform installed (>= 1.0)
+3. Docker installed
+
+### Build the Image and Deploy with Terraform
+
+` + "```bash" + `
+cd terraform/envs/dev            # or staging, prod
+terraform init -backend-config=backend.hcl
+terraform apply -target=module.app.aws_ecr_repository.app
+REPOSITORY=$(terraform output -raw ecr_repository_url)
+aws ecr get-login-password | docker login --username AWS --password-stdin ${REPOSITORY%%/*}
+docker build -t $REPOSITORY:latest ../../.. && docker push $REPOSITORY:latest
+`
+	var chart string
+	for path := range migration.NewFiles {
+		if strings.HasPrefix(path, "charts/") && strings.HasSuffix(path, "/Chart.yaml") {
+			chart = strings.TrimSuffix(strings.TrimPrefix(path, "charts/"), "/Chart.yaml")
+		}
+	}
+	if chart != "" {
+		return guide + `terraform apply -var eks_cluster_name=<cluster>
+` + "```" + `
+
+### Deploy to EKS
+
+` + "```bash" + `
+aws eks update-kubeconfig --name <cluster>
+helm upgrade --install ` + chart + ` ../../../charts/` + chart + ` --namespace dev --create-namespace \
+  --set image.repository=$REPOSITORY --set image.tag=latest \
+  --set serviceAccount.roleArn=$(terraform output -raw app_role_arn) \
+  --set-json env="$(terraform output -json app_environment)"
+` + "```" + `
+
+The chart in ` + "`charts/" + chart + "`" + ` exposes the image, replica count and autoscaling, resources, probes,
+the Bedrock models written to the ` + "`config.yaml`" + ` ConfigMap, and ` + "`config.yaml`" + ` itself as values.
+` + terraformEnvironmentsGuide
+	}
+	return guide + `terraform apply
+` + "```" + `
+` + terraformEnvironmentsGuide
+}
+
+// stackResourcesGuide describes applying the guardrail and vector store
+// Terraform between two deploys of a CDK, SAM or Pulumi stack.
+func stackResourcesGuide(migration *models.Migration) string {
+	if _, exists := migration.NewFiles["terraform/main.tf"]; !exists {
+		return ""
+	}
+	return `
+### Guardrail and Vector Store
+
+The stack takes the guardrail and vector store from ` + "`terraform/`" + `, a module applied by the roots
+in ` + "`terraform/envs`" + `, which grants the app role the stack creates. After the first deploy of an
+environment, apply its root and deploy again with its outputs, as each job of the workflow does:
+
+` + "```bash" + `
+cd terraform/envs/dev            # or staging, prod
+terraform init -backend-config=backend.hcl
+terraform apply
+terraform output
+` + "```" + `
+`
+}
+
+// terraformEnvironmentsGuide describes the Terraform layout of terraform/envs.
+const terraformEnvironmentsGuide = `
+### Environments
+
+` + "`terraform/`" + ` is a module applied by the roots in ` + "`terraform/envs/dev`" + `, ` + "`staging`" + ` and ` + "`prod`" + `. Each keeps its
+state in the S3 bucket and DynamoDB lock table named in its ` + "`backend.hcl`" + `, which must exist before
+the first ` + "`terraform init`" + `, and sets its variables in ` + "`terraform.tfvars`" + `. Resources are named
+` + "`<project>-<environment>`" + `. The workflow deploys dev, then staging, then prod; add required reviewers
+to the staging and prod GitHub environments to gate each promotion.
+`
+
+func (awsProvider) Guide(migration *models.Migration) string {
+	content := `
+
+## AWS Deployment
+
+### Prerequisites
+
+1. AWS CLI configured with appropriate credentials
+` + deployGuide(migration) + lambdaGuide(migration) + `
+### Configuration
+
+Update the ` + "`config.yaml`" + ` file with your specific AWS settings:
+
+- AWS region
+- Bedrock model preferences
+- CloudWatch configuration
+- Environment variables
+
+## Next Steps
+
+1. Review all generated files
+2. Test the application locally
+3. Update any hardcoded values in configuration
+4. Deploy to your AWS environment
+5. Set up monitoring and logging
+6. Test all GenKit flows work correctly
+` + provider.ModelMappingsGuide(migration)
+
+	content += `
+## Support
+
+For issues with the migration tool, please visit:
+https://github.com/genkit-migrate/genkit-migrate/issues
+
+For GenKit framework support, please visit:
+https://firebase.google.com/docs/genkit
+`
+
+	return content
+}
@@ -0,0 +1,547 @@
+package aws
+
+import (
+	"encoding/json"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"path"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/genkit-migrate/genkit-migrate/pkg/models"
+	"github.com/genkit-migrate/genkit-migrate/pkg/provider"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v3"
+)
+
+// testContext implements provider.Context for a migration from GCP.
+type testContext struct {
+	project   *models.Project
+	migration *models.Migration
+	options   map[string]string
+	settings  map[string]string
+}
+
+func newTestContext(project *models.Project, options map[string]string) *testContext {
+	return &testContext{
+		project: project,
+		migration: &models.Migration{
+			Project:  project,
+			Changes:  make([]*models.Change, 0),
+			NewFiles: make(map[string]string),
+		},
+		options:  options,
+		settings: make(map[string]string),
+	}
+}
+
+func (c *testContext) Source() string               { return "gcp" }
+func (c *testContext) Project() *models.Project     { return c.project }
+func (c *testContext) Migration() *models.Migration { return c.migration }
+func (c *testContext) Option(name string) string    { return c.options[name] }
+func (c *testContext) Catalog() *provider.Catalog   { return awsProvider{}.Catalog(c) }
+func (c *testContext) ModuleName() string           { return "example.com/genkit-app" }
+func (c *testContext) ProjectName() string          { return "genkit-app" }
+func (c *testContext) Setting(key string) (string, bool) {
+	value, exists := c.settings[key]
+	return value, exists
+}
+
+func (c *testContext) WriteConfig(header string) error {
+	c.migration.NewFiles["config.yaml"] = header
+	return nil
+}
+
+// testProject returns a project with a flow, a Gemini model and, when
+// vectorStore is set, a Firestore vector store.
+func testProject(vectorStore bool) *models.Project {
+	project := &models.Project{
+		Path: "/src/genkit-app",
+		Flows: []*models.Flow{
+			{Name: "summarize"},
+		},
+		Models: []*models.Model{
+			{Name: "googleai/gemini-1.5-flash", Provider: "googleai"},
+		},
+	}
+	if vectorStore {
+		project.VectorStores = []*models.VectorStore{
+			{Kind: "firestore", Package: firebasePluginPackage, Collection: "menu"},
+		}
+	}
+	return project
+}
+
+func deploy(t *testing.T, project *models.Project, options map[string]string) *models.Migration {
+	t.Helper()
+	ctx := newTestContext(project, options)
+	require.NoError(t, awsProvider{}.Configure(ctx))
+	require.NoError(t, awsProvider{}.Deploy(ctx))
+	return ctx.migration
+}
+
+var (
+	terraformBlock     = regexp.MustCompile(`(?m)^(resource|data) "([^"]+)" "([^"]+)"`)
+	terraformNamed     = regexp.MustCompile(`(?m)^(variable|output|module|provider) "([^"]+)"`)
+	terraformLocals    = regexp.MustCompile(`(?ms)^locals \{\n(.*?)^\}`)
+	terraformLocal     = regexp.MustCompile(`(?m)^  ([a-z0-9_]+)\s+=`)
+	terraformVarRef    = regexp.MustCompile(`\bvar\.([a-z0-9_]+)`)
+	terraformLocalRef  = regexp.MustCompile(`\blocal\.([a-z0-9_]+)`)
+	terraformDataRef   = regexp.MustCompile(`\bdata\.([a-z0-9_]+)\.([a-z0-9_]+)`)
+	terraformModuleRef = regexp.MustCompile(`\bmodule\.([a-z0-9_]+)\.([a-z0-9_]+)`)
+	// Resource references, but not the types in data references or in
+	// the quoted resource headers.
+	terraformResourceRef = regexp.MustCompile(`(?:^|[^.\w"])((?:aws|opensearch)_[a-z0-9_]+)\.([a-z0-9_]+)`)
+)
+
+// terraformModules groups the generated .tf files by directory.
+func terraformModules(files map[string]string) map[string]string {
+	modules := make(map[string]string)
+	paths := make([]string, 0)
+	for filePath := range files {
+		if path.Ext(filePath) == ".tf" {
+			paths = append(paths, filePath)
+		}
+	}
+	sort.Strings(paths)
+	for _, filePath := range paths {
+		modules[path.Dir(filePath)] += files[filePath] + "\n"
+	}
+	return modules
+}
+
+// checkTerraform stands in for terraform validate, which is not available
+// in tests: every directory of .tf files must declare each block once,
+// have a single required_providers block, and declare what it references.
+// Module outputs are checked against the module the roots apply.
+func checkTerraform(t *testing.T, files map[string]string) {
+	t.Helper()
+
+	modules := terraformModules(files)
+	require.NotEmpty(t, modules, "no Terraform was generated")
+
+	for dir, content := range modules {
+		assert.LessOrEqual(t, strings.Count(content, "required_providers {"), 1, "%s: duplicate required_providers", dir)
+
+		declared := make(map[string]bool)
+		for _, match := range terraformBlock.FindAllStringSubmatch(content, -1) {
+			key := match[1] + "." + match[2] + "." + match[3]
+			assert.False(t, declared[key], "%s: duplicate %s %q %q", dir, match[1], match[2], match[3])
+			declared[key] = true
+		}
+		for _, match := range terraformNamed.FindAllStringSubmatch(content, -1) {
+			key := match[1] + "." + match[2]
+			assert.False(t, declared[key], "%s: duplicate %s %q", dir, match[1], match[2])
+			declared[key] = true
+		}
+		for _, block := range terraformLocals.FindAllStringSubmatch(content, -1) {
+			for _, match := range terraformLocal.FindAllStringSubmatch(block[1], -1) {
+				key := "local." + match[1]
+				assert.False(t, declared[key], "%s: duplicate local %q", dir, match[1])
+				declared[key] = true
+			}
+		}
+
+		for _, match := range terraformVarRef.FindAllStringSubmatch(content, -1) {
+			assert.True(t, declared["variable."+match[1]], "%s: undeclared variable %q", dir, match[1])
+		}
+		for _, match := range terraformLocalRef.FindAllStringSubmatch(content, -1) {
+			assert.True(t, declared["local."+match[1]], "%s: undeclared local %q", dir, match[1])
+		}
+		for _, match := range terraformDataRef.FindAllStringSubmatch(content, -1) {
+			assert.True(t, declared["data."+match[1]+"."+match[2]], "%s: undeclared data source %s.%s", dir, match[1], match[2])
+		}
+		for _, match := range terraformResourceRef.FindAllStringSubmatch(content, -1) {
+			assert.True(t, declared["resource."+match[1]+"."+match[2]], "%s: undeclared resource %s.%s", dir, match[1], match[2])
+		}
+		for _, match := range terraformModuleRef.FindAllStringSubmatch(content, -1) {
+			source := regexp.MustCompile(`(?s)module "` + match[1] + `" \{\s*source\s*=\s*"([^"]+)"`).FindStringSubmatch(content)
+			require.NotNil(t, source, "%s: undeclared module %q", dir, match[1])
+			assert.Contains(t, modules[path.Join(dir, source[1])], `output "`+match[2]+`"`, "%s: module %s has no output %q", dir, match[1], match[2])
+		}
+	}
+}
+
+func TestTerraformVectorStores(t *testing.T) {
+	for _, store := range VectorStores {
+		t.Run(store, func(t *testing.T) {
+			migration := deploy(t, testProject(store != ""), map[string]string{VectorStoreOption: store})
+
+			checkTerraform(t, migration.NewFiles)
+			assert.NotContains(t, migration.NewFiles["terraform/vectorstore.tf"], "provider ")
+
+			root := migration.NewFiles["terraform/envs/prod/main.tf"]
+			if store == "bedrock-kb" {
+				assert.Contains(t, migration.NewFiles["terraform/main.tf"], `source  = "opensearch-project/opensearch"`)
+				assert.Contains(t, root, `source  = "opensearch-project/opensearch"`)
+				assert.Contains(t, root, "url         = module.app.knowledge_base_collection_endpoint")
+				return
+			}
+			assert.NotContains(t, migration.NewFiles["terraform/main.tf"], "opensearch-project")
+			assert.NotContains(t, root, `provider "opensearch"`)
+		})
+	}
+}
+
+func TestTerraformDeployTargets(t *testing.T) {
+	for _, target := range DeployTargets {
+		for _, store := range append([]string{""}, VectorStores...) {
+			t.Run(target+"/"+store, func(t *testing.T) {
+				options := map[string]string{DeployTargetOption: target, VectorStoreOption: store}
+				migration := deploy(t, testProject(store != ""), options)
+
+				checkTerraform(t, migration.NewFiles)
+				assert.Equal(t, 1, strings.Count(migration.NewFiles["terraform/main.tf"], `data "aws_caller_identity" "current"`))
+			})
+		}
+	}
+}
+
+func TestInferenceProfiles(t *testing.T) {
+	project := testProject(false)
+	project.Models = []*models.Model{
+		{Name: "googleai/gemini-2.0-flash", Provider: "googleai"},
+	}
+	profile := "us.anthropic.claude-3-5-sonnet-20241022-v2:0"
+	model := "anthropic.claude-3-5-sonnet-20241022-v2:0"
+
+	for _, iac := range []string{"terraform", "cdk-go", "sam", "pulumi-go"} {
+		t.Run(iac, func(t *testing.T) {
+			migration := deploy(t, project, map[string]string{IaCOption: iac})
+
+			assert.Contains(t, migration.NewFiles["config.yaml"], "    - "+profile+"\n")
+			main := migration.NewFiles[iacFormats[iac].main]
+			switch iac {
+			case "terraform":
+				assert.Contains(t, migration.NewFiles["terraform/main.tf"], `inference_profile_id = "`+profile+`"`)
+				checkTerraform(t, migration.NewFiles)
+			case "sam":
+				assert.Contains(t, main, "inference-profile/"+profile)
+				for _, region := range profileRegions["us"] {
+					assert.Contains(t, main, "bedrock:"+region+"::foundation-model/"+model)
+				}
+			default:
+				assert.Contains(t, main, `{"`+profile+`", "`+model+`", []string{"us-east-1", "us-east-2", "us-west-2"}}`)
+			}
+		})
+	}
+
+	// Where the model is served on demand, it is invoked by its ID.
+	ctx := newTestContext(project, map[string]string{})
+	ctx.settings["AWS_REGION"] = "us-west-2"
+	policy := newBedrockPolicy(ctx)
+	assert.Equal(t, []string{model}, policy.Models)
+	assert.Empty(t, policy.Profiles)
+}
+
+// TestIaCResources checks that every infrastructure format deploys the
+// guardrail and vector store: formats other than Terraform apply them as a
+// Terraform module of their own and take its outputs as inputs.
+func TestIaCResources(t *testing.T) {
+	for _, store := range VectorStores {
+		project := testProject(store != "")
+		project.SafetySettings = []*models.SafetySetting{
+			{Category: "HARM_CATEGORY_HARASSMENT", Threshold: "BLOCK_LOW_AND_ABOVE"},
+		}
+
+		resources := make(map[string][]string)
+		for _, iac := range IaCFormats {
+			t.Run(store+"/"+iac, func(t *testing.T) {
+				migration := deploy(t, project, map[string]string{VectorStoreOption: store, IaCOption: iac})
+				checkTerraform(t, migration.NewFiles)
+
+				for _, file := range []string{"terraform/guardrail.tf", "terraform/vectorstore.tf"} {
+					for _, match := range terraformBlock.FindAllStringSubmatch(migration.NewFiles[file], -1) {
+						if match[1] == "resource" {
+							resources[iac] = append(resources[iac], match[2]+"."+match[3])
+						}
+					}
+				}
+				assert.Contains(t, resources[iac], "aws_bedrock_guardrail.genkit")
+
+				if iac == "terraform" {
+					return
+				}
+				main := migration.NewFiles["terraform/main.tf"]
+				assert.Contains(t, main, `data "aws_iam_role" "app_role"`)
+				assert.NotContains(t, main, `resource "aws_iam_role" "app_role"`)
+
+				stack := migration.NewFiles[iacFormats[iac].main]
+				outputs := migration.NewFiles["terraform/envs/prod/outputs.tf"]
+				inputs := 0
+				for _, match := range regexp.MustCompile(`"([A-Z_]+)",\s+// output ([a-z_]+)`).FindAllStringSubmatch(stack, -1) {
+					inputs++
+					assert.Contains(t, outputs, `output "`+match[2]+`"`, "%s is not an output", match[1])
+				}
+				if iac == "sam" {
+					for _, match := range regexp.MustCompile(`Description: ([A-Z_]+), output ([a-z_]+) of`).FindAllStringSubmatch(stack, -1) {
+						inputs++
+						assert.Contains(t, outputs, `output "`+match[2]+`"`, "%s is not an output", match[1])
+					}
+				}
+				assert.Equal(t, 3, inputs, "guardrail ID and version, and the vector store")
+			})
+		}
+		for _, iac := range IaCFormats {
+			assert.ElementsMatch(t, resources["terraform"], resources[iac], "%s/%s", store, iac)
+		}
+	}
+}
+
+func TestPolicyActions(t *testing.T) {
+	project := testProject(false)
+	project.Flows[0].Streaming = true
+	project.SafetySettings = []*models.SafetySetting{
+		{Category: "HARM_CATEGORY_HARASSMENT", Threshold: "BLOCK_LOW_AND_ABOVE"},
+	}
+	action := regexp.MustCompile(`\b(?:bedrock|cloudwatch):[A-Z]\w+`)
+
+	actions := make(map[string][]string)
+	for _, iac := range IaCFormats {
+		migration := deploy(t, project, map[string]string{IaCOption: iac, DeployTargetOption: "lambda"})
+		stack := migration.NewFiles[iacFormats[iac].main]
+		actions[iac] = slices.Compact(slices.Sorted(slices.Values(action.FindAllString(stack, -1))))
+		// Metrics are only published to the app's namespace.
+		assert.Contains(t, stack, "GenKit/genkit-app", iac)
+	}
+
+	assert.Equal(t, []string{"bedrock:ApplyGuardrail", "bedrock:InvokeModel", "bedrock:InvokeModelWithResponseStream", "cloudwatch:PutMetricData"}, actions["terraform"])
+	for _, iac := range IaCFormats {
+		assert.Equal(t, actions["terraform"], actions[iac], iac)
+	}
+}
+
+func TestDeployWorkflow(t *testing.T) {
+	project := testProject(true)
+	project.SafetySettings = []*models.SafetySetting{
+		{Category: "HARM_CATEGORY_HARASSMENT", Threshold: "BLOCK_LOW_AND_ABOVE"},
+	}
+
+	for _, iac := range IaCFormats {
+		for _, target := range iacFormats[iac].targets {
+			t.Run(iac+"/"+target, func(t *testing.T) {
+				migration := deploy(t, project, map[string]string{IaCOption: iac, DeployTargetOption: target})
+
+				var workflow struct {
+					Jobs map[string]struct {
+						Needs       string
+						Environment string
+						Steps       []struct {
+							Name             string
+							Run              string
+							WorkingDirectory string `yaml:"working-directory"`
+						}
+					}
+				}
+				require.NoError(t, yaml.Unmarshal([]byte(migration.NewFiles[".github/workflows/deploy.yml"]), &workflow))
+				assert.Len(t, workflow.Jobs, 4, "test and a job per environment")
+
+				needs := "test"
+				for _, environment := range []string{"dev", "staging", "prod"} {
+					job, ok := workflow.Jobs["deploy-"+environment]
+					require.True(t, ok, "no deploy-%s job", environment)
+					assert.Equal(t, needs, job.Needs)
+					assert.Equal(t, environment, job.Environment)
+					needs = "deploy-" + environment
+
+					var applied bool
+					for _, step := range job.Steps {
+						if strings.Contains(step.Run, "terraform apply") {
+							applied = true
+							if iac != "terraform" {
+								assert.Equal(t, "terraform/envs/"+environment, step.WorkingDirectory)
+							}
+						}
+						assert.NotContains(t, step.Run, "--no-confirm-changeset")
+						for _, other := range []string{"dev", "staging", "prod"} {
+							if other != environment {
+								assert.NotContains(t, step.Run, "--stack "+other, "step %q", step.Name)
+								assert.NotContains(t, step.Run, "environment="+other, "step %q", step.Name)
+								assert.NotContains(t, step.Run, "--config-env "+other, "step %q", step.Name)
+							}
+						}
+					}
+					assert.True(t, applied, "deploy-%s applies no Terraform", environment)
+				}
+			})
+		}
+	}
+
+	migration := deploy(t, project, map[string]string{IaCOption: "sam"})
+	assert.NotContains(t, migration.NewFiles["samconfig.toml"], "confirm_changeset = true")
+}
+
+func TestEnvironmentRoots(t *testing.T) {
+	project := testProject(true)
+	project.SafetySettings = []*models.SafetySetting{
+		{Category: "HARM_CATEGORY_HARASSMENT", Threshold: "BLOCK_LOW_AND_ABOVE"},
+	}
+
+	for _, iac := range IaCFormats {
+		t.Run(iac, func(t *testing.T) {
+			migration := deploy(t, project, map[string]string{IaCOption: iac})
+			checkTerraform(t, migration.NewFiles)
+
+			states := make(map[string]bool)
+			for _, environment := range []string{"dev", "staging", "prod"} {
+				dir := "terraform/envs/" + environment + "/"
+				for _, file := range []string{"main.tf", "variables.tf", "outputs.tf", "backend.hcl", "terraform.tfvars"} {
+					assert.Contains(t, migration.NewFiles, dir+file)
+				}
+				assert.Equal(t, migration.NewFiles["terraform/variables.tf"], migration.NewFiles[dir+"variables.tf"])
+				assert.Contains(t, migration.NewFiles[dir+"main.tf"], `source = "../.."`)
+				assert.Contains(t, migration.NewFiles[dir+"terraform.tfvars"], `environment  = "`+environment+`"`)
+
+				key := regexp.MustCompile(`key\s+= "([^"]+)"`).FindStringSubmatch(migration.NewFiles[dir+"backend.hcl"])
+				require.NotNil(t, key, "%s has no state key", dir)
+				assert.False(t, states[key[1]], "%s shares its state %s", dir, key[1])
+				states[key[1]] = true
+			}
+		})
+	}
+}
+
+// parseGo parses a generated Go file, failing the test if it does not
+// compile as a package main.
+func parseGo(t *testing.T, files map[string]string, name string) *ast.File {
+	t.Helper()
+	content, exists := files[name]
+	require.True(t, exists, "%s was not generated", name)
+	file, err := parser.ParseFile(token.NewFileSet(), name, content, parser.ParseComments)
+	require.NoError(t, err)
+	assert.Equal(t, "main", file.Name.Name, name)
+	return file
+}
+
+func TestConverse(t *testing.T) {
+	project := testProject(false)
+	project.Files = map[string]*models.SourceFile{
+		"classify/classify.go": {PackageName: "classify"},
+		"main.go":              {PackageName: "main"},
+	}
+	project.DirectCalls = []*models.DirectCall{
+		{Package: "google.golang.org/genai", Method: "GenerateContent", Model: "googleai/gemini-1.5-pro"},
+	}
+	ctx := newTestContext(project, nil)
+	ctx.migration.NewFiles["classify/classify.go"] = `resp, err := converseText(ctx, "anthropic.claude-3-sonnet-20240229-v1:0", text)`
+	ctx.migration.NewFiles["main.go"] = "package main\n"
+	require.NoError(t, awsProvider{}.Configure(ctx))
+	require.NoError(t, awsProvider{}.Deploy(ctx))
+
+	files := ctx.migration.NewFiles
+	assert.NotContains(t, files, "converse.go")
+	file, err := parser.ParseFile(token.NewFileSet(), "converse.go", files["classify/converse.go"], 0)
+	require.NoError(t, err)
+	assert.Equal(t, "classify", file.Name.Name)
+
+	requires := awsProvider{}.Rewrite(ctx).Requires
+	for _, require := range converseRequires {
+		assert.Contains(t, requires, require)
+	}
+
+	// The directly called model is granted alongside the GenKit one.
+	policy := newBedrockPolicy(ctx)
+	assert.Contains(t, policy.Models, "anthropic.claude-3-sonnet-20240229-v1:0")
+	assert.Contains(t, policy.Models, "anthropic.claude-3-haiku-20240307-v1:0")
+}
+
+func TestCDKApp(t *testing.T) {
+	for _, target := range iacFormats["cdk-go"].targets {
+		t.Run(target, func(t *testing.T) {
+			migration := deploy(t, testProject(false), map[string]string{IaCOption: "cdk-go", DeployTargetOption: target})
+
+			var config struct {
+				App     string
+				Context map[string]string
+			}
+			require.NoError(t, json.Unmarshal([]byte(migration.NewFiles["cdk/cdk.json"]), &config))
+			assert.Equal(t, "go mod download && go run app.go", config.App)
+			assert.Equal(t, "dev", config.Context["environment"])
+			assert.True(t, strings.HasPrefix(migration.NewFiles["cdk/go.mod"], "module genkit-app-cdk\n"))
+
+			app := parseGo(t, migration.NewFiles, "cdk/app.go")
+			parseGo(t, migration.NewFiles, "cdk/app_test.go")
+			assert.Equal(t, target == "lambda", app.Scope.Lookup("flows") != nil, "flows are routed by API Gateway")
+			assert.NotNil(t, app.Scope.Lookup("contextEnvironment"))
+		})
+	}
+}
+
+func TestPulumiProgram(t *testing.T) {
+	for _, target := range iacFormats["pulumi-go"].targets {
+		t.Run(target, func(t *testing.T) {
+			migration := deploy(t, testProject(false), map[string]string{IaCOption: "pulumi-go", DeployTargetOption: target})
+
+			var project struct {
+				Name    string
+				Runtime string
+			}
+			require.NoError(t, yaml.Unmarshal([]byte(migration.NewFiles["pulumi/Pulumi.yaml"]), &project))
+			assert.Equal(t, "genkit-app", project.Name)
+			assert.Equal(t, "go", project.Runtime)
+
+			for _, environment := range []string{"dev", "staging", "prod"} {
+				var stack struct {
+					Config map[string]string
+				}
+				name := "pulumi/Pulumi." + environment + ".yaml"
+				require.NoError(t, yaml.Unmarshal([]byte(migration.NewFiles[name]), &stack), name)
+				assert.Equal(t, "us-east-1", stack.Config["aws:region"], name)
+				assert.Equal(t, "genkit-app", stack.Config[project.Name+":projectName"], name)
+			}
+
+			program := parseGo(t, migration.NewFiles, "pulumi/main.go")
+			assert.Equal(t, target == "lambda", program.Scope.Lookup("flows") != nil, "flows are routed by API Gateway")
+			assert.NotNil(t, program.Scope.Lookup("configEnvironment"))
+		})
+	}
+}
+
+func TestHelmChart(t *testing.T) {
+	migration := deploy(t, testProject(false), map[string]string{DeployTargetOption: "eks"})
+	chart := "charts/genkit-app/"
+
+	var metadata struct {
+		APIVersion string `yaml:"apiVersion"`
+		Name       string
+	}
+	require.NoError(t, yaml.Unmarshal([]byte(migration.NewFiles[chart+"Chart.yaml"]), &metadata))
+	assert.Equal(t, "v2", metadata.APIVersion)
+	assert.Equal(t, "genkit-app", metadata.Name)
+
+	var values struct {
+		ReplicaCount int `yaml:"replicaCount"`
+		Autoscaling  struct {
+			MinReplicas int `yaml:"minReplicas"`
+			MaxReplicas int `yaml:"maxReplicas"`
+		}
+		ServiceAccount struct {
+			Name string
+		} `yaml:"serviceAccount"`
+		ContainerPort  int                          `yaml:"containerPort"`
+		Resources      map[string]map[string]string `yaml:"resources"`
+		LivenessProbe  map[string]interface{}       `yaml:"livenessProbe"`
+		ReadinessProbe map[string]interface{}       `yaml:"readinessProbe"`
+		Models         []string
+	}
+	require.NoError(t, yaml.Unmarshal([]byte(migration.NewFiles[chart+"values.yaml"]), &values))
+	assert.Equal(t, values.Autoscaling.MinReplicas, values.ReplicaCount)
+	assert.LessOrEqual(t, values.Autoscaling.MinReplicas, values.Autoscaling.MaxReplicas)
+	assert.Equal(t, "genkit-app", values.ServiceAccount.Name)
+	assert.Equal(t, 8080, values.ContainerPort)
+	assert.NotEmpty(t, values.Resources["requests"])
+	assert.NotEmpty(t, values.Resources["limits"])
+	assert.NotEmpty(t, values.LivenessProbe)
+	assert.NotEmpty(t, values.ReadinessProbe)
+	assert.NotEmpty(t, values.Models)
+
+	for name := range helmTemplates {
+		assert.Contains(t, migration.NewFiles, chart+"templates/"+name)
+	}
+	assert.Contains(t, migration.NewFiles["terraform/envs/prod/terraform.tfvars"], `namespace    = "prod"`)
+}
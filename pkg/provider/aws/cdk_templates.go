@@ -0,0 +1,376 @@
+package aws
+
+const cdkJSON = `{
+  "app": "go mod download && go run app.go",
+  "context": {
+    "environment": "dev"
+  }
+}
+`
+
+const cdkGoMod = `module {{ .Deployment.ProjectName }}-cdk
+
+go 1.23
+
+require (
+	github.com/aws/aws-cdk-go/awscdk/v2 v2.170.0
+	github.com/aws/constructs-go/constructs/v10 v10.4.2
+	github.com/aws/jsii-runtime-go v1.104.0
+)
+`
+
+const cdkApp = `// Code generated by genkit-migrate. Review before deploying.
+
+// Command cdk defines the AWS infrastructure of {{ .Deployment.ProjectName }}. Deploy an
+// environment with
+//
+//	cdk deploy -c environment=<name>
+//
+// Every environment gets its own stack and resource names.
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/aws/aws-cdk-go/awscdk/v2"
+{{- if eq .Deployment.Target "lambda" }}
+	"github.com/aws/aws-cdk-go/awscdk/v2/awsapigatewayv2"
+	"github.com/aws/aws-cdk-go/awscdk/v2/awsapigatewayv2integrations"
+{{- else }}
+	"github.com/aws/aws-cdk-go/awscdk/v2/awsapplicationautoscaling"
+	"github.com/aws/aws-cdk-go/awscdk/v2/awsec2"
+	"github.com/aws/aws-cdk-go/awscdk/v2/awsecs"
+	"github.com/aws/aws-cdk-go/awscdk/v2/awsecspatterns"
+	"github.com/aws/aws-cdk-go/awscdk/v2/awselasticloadbalancingv2"
+{{- end }}
+	"github.com/aws/aws-cdk-go/awscdk/v2/awsiam"
+{{- if eq .Deployment.Target "lambda" }}
+	"github.com/aws/aws-cdk-go/awscdk/v2/awslambda"
+{{- end }}
+	"github.com/aws/aws-cdk-go/awscdk/v2/awslogs"
+	"github.com/aws/constructs-go/constructs/v10"
+	"github.com/aws/jsii-runtime-go"
+)
+
+// Bedrock foundation models the app invokes.
+var foundationModels = []string{
+{{- range .Deployment.Policy.Models }}
+	"{{ . }}",
+{{- end }}
+}
+
+// Cross-region inference profiles the app invokes, and the foundation model
+// each routes to in the regions of its geography.
+var inferenceProfiles = []struct {
+	ID, Model string
+	Regions   []string
+}{
+{{- range .Deployment.Policy.Profiles }}
+	{"{{ .ID }}", "{{ .Model }}", []string{ {{- range $i, $region := .Regions }}{{ if $i }}, {{ end }}"{{ $region }}"{{ end }}}},
+{{- end }}
+}
+{{- if eq .Deployment.Target "lambda" }}
+
+// Flows API Gateway routes to the function as POST /<flow>.
+var flows = []string{
+{{- range .Deployment.Flows }}
+	"{{ . }}",
+{{- end }}
+}
+{{- end }}
+
+// Environment variables pointing at the guardrail and vector store, outputs
+// of terraform/envs/<environment> passed with cdk deploy -c NAME=value.
+var contextEnvironment = []string{
+{{- range .Deployment.Inputs }}
+	"{{ .Name }}", // output {{ .Output }}
+{{- end }}
+}
+
+type GenKitStackProps struct {
+	awscdk.StackProps
+	// Environment names the stack's resources, such as dev or prod.
+	Environment string
+{{- if eq .Deployment.Target "lambda" }}
+	// Code is the directory holding the bootstrap and app binaries.
+	Code string
+{{- end }}
+}
+
+func NewGenKitStack(scope constructs.Construct, id string, props *GenKitStackProps) awscdk.Stack {
+	stack := awscdk.NewStack(scope, &id, &props.StackProps)
+	awscdk.Tags_Of(stack).Add(jsii.String("environment"), jsii.String(props.Environment), nil)
+	name := "{{ .Deployment.ProjectName }}-" + props.Environment
+
+	environment := map[string]*string{
+		"GENKIT_ENV": jsii.String("production"),
+	}
+	for _, key := range contextEnvironment {
+		if value, ok := stack.Node().TryGetContext(jsii.String(key)).(string); ok {
+			environment[key] = jsii.String(value)
+		}
+	}
+
+	// IAM role the app runs as
+	role := awsiam.NewRole(stack, jsii.String("AppRole"), &awsiam.RoleProps{
+		RoleName:  jsii.String(name + "-app-role"),
+		AssumedBy: awsiam.NewServicePrincipal(jsii.String("{{ .Principal }}"), nil),
+	})
+	grantBedrock(stack, role, environment)
+	role.AddToPolicy(awsiam.NewPolicyStatement(&awsiam.PolicyStatementProps{
+		Actions:   jsii.Strings("cloudwatch:PutMetricData"),
+		Resources: jsii.Strings("*"),
+		Conditions: &map[string]interface{}{
+			"StringEquals": map[string]interface{}{
+				"cloudwatch:namespace": "{{ .Deployment.MetricsNamespace }}",
+			},
+		},
+	}))
+
+	logGroup := awslogs.NewLogGroup(stack, jsii.String("LogGroup"), &awslogs.LogGroupProps{
+		LogGroupName:  jsii.String("{{ if eq .Deployment.Target "lambda" }}/aws/lambda/{{ else }}/ecs/{{ end }}" + name),
+		Retention:     awslogs.RetentionDays_TWO_WEEKS,
+		RemovalPolicy: awscdk.RemovalPolicy_DESTROY,
+	})
+{{- if eq .Deployment.Target "lambda" }}
+
+	// Lambda function for GenKit app
+	role.AddManagedPolicy(awsiam.ManagedPolicy_FromAwsManagedPolicyName(jsii.String("service-role/AWSLambdaBasicExecutionRole")))
+	function := awslambda.NewFunction(stack, jsii.String("Function"), &awslambda.FunctionProps{
+		FunctionName: jsii.String(name),
+		Runtime:      awslambda.Runtime_PROVIDED_AL2023(),
+		Handler:      jsii.String("bootstrap"),
+		Code:         awslambda.Code_FromAsset(jsii.String(props.Code), nil),
+		Role:         role,
+		MemorySize:   jsii.Number({{ .Deployment.Memory }}),
+		Timeout:      awscdk.Duration_Seconds(jsii.Number({{ .Deployment.Timeout }})),
+{{- if ge .Deployment.ReservedConcurrency 0 }}
+		ReservedConcurrentExecutions: jsii.Number({{ .Deployment.ReservedConcurrency }}),
+{{- end }}
+		Environment: &environment,
+		LogGroup:    logGroup,
+	})
+
+	// HTTP API routing POST /<flow> to the function
+	integration := awsapigatewayv2integrations.NewHttpLambdaIntegration(jsii.String("Integration"), function, nil)
+	api := awsapigatewayv2.NewHttpApi(stack, jsii.String("Api"), &awsapigatewayv2.HttpApiProps{
+		ApiName: jsii.String(name + "-api"),
+	})
+	for _, flow := range flows {
+		api.AddRoutes(&awsapigatewayv2.AddRoutesOptions{
+			Path:        jsii.String("/" + flow),
+			Methods:     &[]awsapigatewayv2.HttpMethod{awsapigatewayv2.HttpMethod_POST},
+			Integration: integration,
+		})
+	}
+
+	awscdk.NewCfnOutput(stack, jsii.String("ApiUrl"), &awscdk.CfnOutputProps{Value: api.Url()})
+	awscdk.NewCfnOutput(stack, jsii.String("FunctionName"), &awscdk.CfnOutputProps{Value: function.FunctionName()})
+	awscdk.NewCfnOutput(stack, jsii.String("FunctionArn"), &awscdk.CfnOutputProps{Value: function.FunctionArn()})
+{{- else }}
+
+	// Public subnets only, as in the default VPC: tasks pull the image and
+	// reach Bedrock through a public IP instead of a NAT gateway.
+	vpc := awsec2.NewVpc(stack, jsii.String("Vpc"), &awsec2.VpcProps{
+		MaxAzs:      jsii.Number(2),
+		NatGateways: jsii.Number(0),
+		SubnetConfiguration: &[]*awsec2.SubnetConfiguration{
+			{Name: jsii.String("public"), SubnetType: awsec2.SubnetType_PUBLIC},
+		},
+	})
+
+	// Fargate service behind an Application Load Balancer
+	service := awsecspatterns.NewApplicationLoadBalancedFargateService(stack, jsii.String("Service"), &awsecspatterns.ApplicationLoadBalancedFargateServiceProps{
+		ServiceName:        jsii.String(name),
+		Vpc:                vpc,
+		Cpu:                jsii.Number({{ .Deployment.CPU }}),
+		MemoryLimitMiB:     jsii.Number({{ .Deployment.Memory }}),
+		DesiredCount:       jsii.Number({{ .Deployment.MinCount }}),
+		AssignPublicIp:     jsii.Bool(true),
+		TaskSubnets:        &awsec2.SubnetSelection{SubnetType: awsec2.SubnetType_PUBLIC},
+		PublicLoadBalancer: jsii.Bool(true),
+		// Requests may run as long as they did on the source.
+		IdleTimeout: awscdk.Duration_Seconds(jsii.Number({{ .Deployment.Timeout }})),
+		TaskImageOptions: &awsecspatterns.ApplicationLoadBalancedTaskImageOptions{
+			Image: awsecs.ContainerImage_FromAsset(jsii.String(".."), &awsecs.AssetImageProps{
+				Exclude: jsii.Strings(".git", "build", "cdk", "terraform"),
+			}),
+			ContainerPort: jsii.Number({{ .Deployment.Port }}),
+			TaskRole:      role,
+			Environment:   &environment,
+			LogDriver: awsecs.LogDrivers_AwsLogs(&awsecs.AwsLogDriverProps{
+				StreamPrefix: jsii.String("app"),
+				LogGroup:     logGroup,
+			}),
+		},
+	})
+	// {{ httpHealthCheck }}
+	service.TargetGroup().ConfigureHealthCheck(&awselasticloadbalancingv2.HealthCheck{
+		Path:             jsii.String("/"),
+		HealthyHttpCodes: jsii.String("200-499"),
+	})
+
+	scaling := service.Service().AutoScaleTaskCount(&awsapplicationautoscaling.EnableScalingProps{
+		MinCapacity: jsii.Number({{ .Deployment.MinCount }}),
+		MaxCapacity: jsii.Number({{ .Deployment.MaxCount }}),
+	})
+	scaling.ScaleOnCpuUtilization(jsii.String("CpuScaling"), &awsecs.CpuUtilizationScalingProps{
+		TargetUtilizationPercent: jsii.Number(70),
+	})
+
+	awscdk.NewCfnOutput(stack, jsii.String("ServiceUrl"), &awscdk.CfnOutputProps{
+		Value: jsii.String("http://" + *service.LoadBalancer().LoadBalancerDnsName()),
+	})
+	awscdk.NewCfnOutput(stack, jsii.String("ClusterName"), &awscdk.CfnOutputProps{Value: service.Cluster().ClusterName()})
+	awscdk.NewCfnOutput(stack, jsii.String("ServiceName"), &awscdk.CfnOutputProps{Value: service.Service().ServiceName()})
+{{- end }}
+	awscdk.NewCfnOutput(stack, jsii.String("LogGroupName"), &awscdk.CfnOutputProps{Value: logGroup.LogGroupName()})
+
+	return stack
+}
+
+// grantBedrock lets the role invoke only the models the app uses.
+// InvokeModel also authorizes Converse{{ if .Deployment.Policy.Streaming }}, and InvokeModelWithResponseStream
+// ConverseStream{{ end }}.
+func grantBedrock(stack awscdk.Stack, role awsiam.Role, environment map[string]*string) {
+	resources := make([]*string, 0)
+	for _, model := range foundationModels {
+		resources = append(resources, jsii.String(fmt.Sprintf("arn:%s:bedrock:%s::foundation-model/%s", *stack.Partition(), *stack.Region(), model)))
+	}
+	for _, profile := range inferenceProfiles {
+		resources = append(resources, jsii.String(fmt.Sprintf("arn:%s:bedrock:%s:%s:inference-profile/%s", *stack.Partition(), *stack.Region(), *stack.Account(), profile.ID)))
+		for _, region := range profile.Regions {
+			resources = append(resources, jsii.String(fmt.Sprintf("arn:%s:bedrock:%s::foundation-model/%s", *stack.Partition(), region, profile.Model)))
+		}
+	}
+	if len(resources) > 0 {
+		role.AddToPolicy(awsiam.NewPolicyStatement(&awsiam.PolicyStatementProps{
+			Actions:   jsii.Strings("bedrock:InvokeModel"{{ if .Deployment.Policy.Streaming }}, "bedrock:InvokeModelWithResponseStream"{{ end }}),
+			Resources: &resources,
+		}))
+	}
+
+	if guardrail, ok := environment["BEDROCK_GUARDRAIL_ID"]; ok {
+		role.AddToPolicy(awsiam.NewPolicyStatement(&awsiam.PolicyStatementProps{
+			Actions:   jsii.Strings("bedrock:ApplyGuardrail"),
+			Resources: jsii.Strings(fmt.Sprintf("arn:%s:bedrock:%s:%s:guardrail/%s", *stack.Partition(), *stack.Region(), *stack.Account(), *guardrail)),
+		}))
+	}
+}
+
+func main() {
+	defer jsii.Close()
+
+	app := awscdk.NewApp(nil)
+	environment, ok := app.Node().TryGetContext(jsii.String("environment")).(string)
+	if !ok || environment == "" {
+		environment = "dev"
+	}
+
+	NewGenKitStack(app, "{{ .Deployment.ProjectName }}-"+environment, &GenKitStackProps{
+		StackProps: awscdk.StackProps{
+			Env: &awscdk.Environment{
+				Account: jsii.String(os.Getenv("CDK_DEFAULT_ACCOUNT")),
+				Region:  jsii.String("{{ .Deployment.Region }}"),
+			},
+		},
+		Environment: environment,
+{{- if eq .Deployment.Target "lambda" }}
+		Code:        "../build",
+{{- end }}
+	})
+
+	app.Synth(nil)
+}
+`
+
+const cdkAppTest = `// Code generated by genkit-migrate. Review before deploying.
+
+package main
+
+import (
+	"encoding/json"
+	"os"
+{{- if eq .Deployment.Target "lambda" }}
+	"path/filepath"
+{{- end }}
+	"regexp"
+	"testing"
+
+	"github.com/aws/aws-cdk-go/awscdk/v2"
+	"github.com/aws/aws-cdk-go/awscdk/v2/assertions"
+	"github.com/aws/jsii-runtime-go"
+)
+
+// snapshot is the synthesized template the stack is compared with. It is
+// recorded on the first run; rerun with UPDATE_SNAPSHOT=1 to accept a change.
+const snapshot = "testdata/stack.snapshot.json"
+
+// Asset hashes change whenever the app is rebuilt.
+var assetHash = regexp.MustCompile(` + "`" + `[0-9a-f]{64}` + "`" + `)
+
+func TestGenKitStack(t *testing.T) {
+{{- if eq .Deployment.Target "lambda" }}
+	code := t.TempDir()
+	if err := os.WriteFile(filepath.Join(code, "bootstrap"), nil, 0o755); err != nil {
+		t.Fatal(err)
+	}
+{{ end }}
+	app := awscdk.NewApp(nil)
+	stack := NewGenKitStack(app, "GenKitTest", &GenKitStackProps{
+		StackProps: awscdk.StackProps{
+			Env: &awscdk.Environment{
+				Account: jsii.String("123456789012"),
+				Region:  jsii.String("{{ .Deployment.Region }}"),
+			},
+		},
+		Environment: "test",
+{{- if eq .Deployment.Target "lambda" }}
+		Code:        code,
+{{- end }}
+	})
+	template := assertions.Template_FromStack(stack, nil)
+
+	template.ResourceCountIs(jsii.String("{{ if eq .Deployment.Target "lambda" }}AWS::Lambda::Function{{ else }}AWS::ECS::Service{{ end }}"), jsii.Number(1))
+{{- if not .Deployment.Policy.Empty }}
+	template.HasResourceProperties(jsii.String("AWS::IAM::Policy"), map[string]interface{}{
+		"PolicyDocument": map[string]interface{}{
+			"Statement": assertions.Match_ArrayWith(&[]interface{}{
+				assertions.Match_ObjectLike(&map[string]interface{}{
+{{- if .Deployment.Policy.Streaming }}
+					"Action": []interface{}{"bedrock:InvokeModel", "bedrock:InvokeModelWithResponseStream"},
+{{- else }}
+					"Action": "bedrock:InvokeModel",
+{{- end }}
+				}),
+			}),
+		},
+	})
+{{- end }}
+
+	content, err := json.MarshalIndent(template.ToJSON(), "", "  ")
+	if err != nil {
+		t.Fatal(err)
+	}
+	content = assetHash.ReplaceAll(content, []byte("ASSET_HASH"))
+
+	expected, err := os.ReadFile(snapshot)
+	if os.IsNotExist(err) || os.Getenv("UPDATE_SNAPSHOT") != "" {
+		if err := os.MkdirAll("testdata", 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(snapshot, content, 0o644); err != nil {
+			t.Fatal(err)
+		}
+		t.Logf("recorded %s", snapshot)
+		return
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(content) != string(expected) {
+		t.Errorf("synthesized template differs from %s; rerun with UPDATE_SNAPSHOT=1 if the change is intended", snapshot)
+	}
+}
+`
@@ -0,0 +1,130 @@
+package aws
+
+import (
+	"fmt"
+	"go/format"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/genkit-migrate/genkit-migrate/pkg/models"
+	"github.com/genkit-migrate/genkit-migrate/pkg/provider"
+)
+
+// converseFunc replaces direct genai generations that have no
+// *genkit.Genkit in scope.
+const converseFunc = "converseText"
+
+var converseClient = &provider.Client{Title: "Bedrock Converse", Func: converseFunc}
+
+// converseRequires are the modules the generated converse.go imports.
+var converseRequires = [][2]string{
+	{"github.com/aws/aws-sdk-go-v2", "v1.36.3"},
+	{"github.com/aws/aws-sdk-go-v2/config", "v1.29.14"},
+	{"github.com/aws/aws-sdk-go-v2/service/bedrockruntime", "v1.30.0"},
+}
+
+// converseSource follows the package clause of the generated converse.go.
+const converseSource = `
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/bedrockruntime"
+	"github.com/aws/aws-sdk-go-v2/service/bedrockruntime/types"
+)
+
+// bedrockClient is created on first use from the default AWS configuration,
+// which reads AWS_REGION and the credentials of the environment or role.
+var bedrockClient = sync.OnceValues(func() (*bedrockruntime.Client, error) {
+	cfg, err := config.LoadDefaultConfig(context.Background())
+	if err != nil {
+		return nil, fmt.Errorf("failed to load AWS config: %w", err)
+	}
+	return bedrockruntime.NewFromConfig(cfg), nil
+})
+
+// converseResponse is the reply of a Converse call, read through Text like
+// the genai response it replaces.
+type converseResponse struct {
+	*bedrockruntime.ConverseOutput
+}
+
+// Text returns the text blocks of the reply.
+func (r *converseResponse) Text() string {
+	message, ok := r.Output.(*types.ConverseOutputMemberMessage)
+	if !ok {
+		return ""
+	}
+	var text strings.Builder
+	for _, block := range message.Value.Content {
+		if part, ok := block.(*types.ContentBlockMemberText); ok {
+			text.WriteString(part.Value)
+		}
+	}
+	return text.String()
+}
+
+// converseText sends prompt to a Bedrock model through the Converse API.
+func converseText(ctx context.Context, modelID, prompt string) (*converseResponse, error) {
+	client, err := bedrockClient()
+	if err != nil {
+		return nil, err
+	}
+	output, err := client.Converse(ctx, &bedrockruntime.ConverseInput{
+		ModelId: aws.String(modelID),
+		Messages: []types.Message{{
+			Role:    types.ConversationRoleUser,
+			Content: []types.ContentBlock{&types.ContentBlockMemberText{Value: prompt}},
+		}},
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &converseResponse{output}, nil
+}
+`
+
+// converseDirs returns the packages of the migrated files that call
+// converseText, keyed by directory.
+func converseDirs(migration *models.Migration) map[string]string {
+	dirs := make(map[string]string)
+	for filePath, sourceFile := range migration.Project.Files {
+		content, migrated := migration.NewFiles[filePath]
+		if migrated && strings.Contains(content, converseFunc+"(") {
+			dirs[path.Dir(filePath)] = sourceFile.PackageName
+		}
+	}
+	return dirs
+}
+
+// generateConverse writes converseText into each package calling it.
+func generateConverse(migration *models.Migration) error {
+	packages := converseDirs(migration)
+	dirs := make([]string, 0, len(packages))
+	for dir := range packages {
+		dirs = append(dirs, dir)
+	}
+	sort.Strings(dirs)
+
+	for _, dir := range dirs {
+		source, err := format.Source([]byte("package " + packages[dir] + converseSource))
+		if err != nil {
+			return fmt.Errorf("failed to format converse.go: %w", err)
+		}
+
+		filePath := path.Join(dir, "converse.go")
+		migration.NewFiles[filePath] = string(source)
+		migration.Changes = append(migration.Changes, &models.Change{
+			Type:        "service",
+			Description: fmt.Sprintf("Generated %s, which calls Bedrock Converse with the default AWS configuration", converseFunc),
+			File:        filePath,
+		})
+	}
+	return nil
+}
@@ -0,0 +1,875 @@
+package aws
+
+import (
+	"fmt"
+	"go/format"
+	"math"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"text/template"
+
+	"github.com/genkit-migrate/genkit-migrate/pkg/models"
+	"github.com/genkit-migrate/genkit-migrate/pkg/provider"
+	"gopkg.in/yaml.v3"
+)
+
+// DeployTargetOption selects the AWS compute the app runs on; it is one of
+// DeployTargets, chosen from the source deployment when empty.
+const DeployTargetOption = "deploy-target"
+
+var DeployTargets = []string{"lambda", "ecs-fargate", "app-runner", "eks"}
+
+type deployTarget struct {
+	name string
+	// principal is the service that assumes the app role; EKS pods assume
+	// it through IRSA instead.
+	principal string
+	container bool // runs the Dockerfile image pushed to ECR
+	terraform string
+	variables string
+	outputs   string
+}
+
+var deployTargets = map[string]*deployTarget{
+	"lambda": {
+		name:      "AWS Lambda behind API Gateway",
+		principal: "lambda.amazonaws.com",
+		terraform: lambdaTerraform,
+		variables: lambdaVariables,
+		outputs:   lambdaOutputs,
+	},
+	"ecs-fargate": {
+		name:      "Amazon ECS on Fargate behind an Application Load Balancer",
+		principal: "ecs-tasks.amazonaws.com",
+		container: true,
+		terraform: fargateTerraform,
+		variables: fargateVariables,
+		outputs:   fargateOutputs,
+	},
+	"app-runner": {
+		name:      "AWS App Runner",
+		principal: "tasks.apprunner.amazonaws.com",
+		container: true,
+		terraform: appRunnerTerraform,
+		variables: appRunnerVariables,
+		outputs:   appRunnerOutputs,
+	},
+	"eks": {
+		name:      "Amazon EKS",
+		container: true,
+		terraform: eksTerraform,
+		variables: eksVariables,
+		outputs:   eksOutputs,
+	},
+}
+
+const (
+	// API Gateway HTTP APIs time out integrations after 30 seconds, and App
+	// Runner requests after 120.
+	apiGatewayTimeout = 30
+	appRunnerTimeout  = 120
+	lambdaMaxTimeout  = 900
+	// Cloud Run's default request timeout, when the source does not set one.
+	cloudRunTimeout = 300
+)
+
+// IaCOption selects the infrastructure-as-code format the deployment is
+// generated in; it is one of IaCFormats, terraform when empty.
+const IaCOption = "iac"
+
+var IaCFormats = []string{"terraform", "cdk-go", "sam", "pulumi-go"}
+
+type iacFormat struct {
+	name    string
+	targets []string // deploy targets the format generates
+	main    string   // file defining the app's resources
+	// inputs describes how the stack is passed the environment variables
+	// of the Terraform resources.
+	inputs string
+}
+
+var iacFormats = map[string]*iacFormat{
+	"terraform": {name: "Terraform", targets: DeployTargets, main: "terraform/main.tf"},
+	"cdk-go":    {name: "AWS CDK", targets: []string{"lambda", "ecs-fargate"}, main: "cdk/app.go", inputs: "cdk deploy -c NAME=value"},
+	"sam":       {name: "AWS SAM", targets: []string{"lambda"}, main: "template.yaml", inputs: "sam deploy --parameter-overrides Name=value"},
+	"pulumi-go": {name: "Pulumi", targets: []string{"lambda", "ecs-fargate"}, main: "pulumi/main.go", inputs: "pulumi config set NAME value"},
+}
+
+func selectedIaC(ctx provider.Context) (string, *iacFormat, error) {
+	key := ctx.Option(IaCOption)
+	if key == "" {
+		key = "terraform"
+	}
+	format, exists := iacFormats[key]
+	if !exists {
+		return "", nil, fmt.Errorf("unsupported infrastructure format %q (supported: %s)", key, strings.Join(IaCFormats, ", "))
+	}
+	return key, format, nil
+}
+
+// selectedDeployTarget returns the deploy target and why it was chosen.
+// Cloud Functions map to Lambda and Cloud Run services to App Runner, unless
+// requests outlive what those allow or flows stream, which call for Fargate.
+// Fargate, or the format's only target, stands in for targets the
+// infrastructure format lacks.
+func selectedDeployTarget(ctx provider.Context) (string, string, error) {
+	_, format, err := selectedIaC(ctx)
+	if err != nil {
+		return "", "", err
+	}
+
+	if key := ctx.Option(DeployTargetOption); key != "" {
+		if _, exists := deployTargets[key]; !exists {
+			return "", "", fmt.Errorf("unsupported deploy target %q (supported: %s)", key, strings.Join(DeployTargets, ", "))
+		}
+		if !slices.Contains(format.targets, key) {
+			return "", "", fmt.Errorf("deploy target %q cannot be generated with %s (supported: %s)", key, format.name, strings.Join(format.targets, ", "))
+		}
+		return key, "selected with --deploy-target", nil
+	}
+
+	key, reason := sourceDeployTarget(ctx.Project())
+	if !slices.Contains(format.targets, key) {
+		reason = fmt.Sprintf("%s, but %s is not generated with %s", reason, deployTargets[key].name, format.name)
+		if slices.Contains(format.targets, "ecs-fargate") {
+			return "ecs-fargate", reason, nil
+		}
+		return format.targets[0], reason, nil
+	}
+	return key, reason, nil
+}
+
+func sourceDeployTarget(project *models.Project) (string, string) {
+	streaming := slices.ContainsFunc(project.Flows, func(flow *models.Flow) bool { return flow.Streaming })
+	deployment := project.Deployment
+	if deployment == nil {
+		if streaming {
+			return "ecs-fargate", "streaming flows need long-lived connections"
+		}
+		return "lambda", "no Cloud Run, Cloud Functions or GKE configuration found"
+	}
+
+	timeout := parseSeconds(deployment.Timeout)
+	switch {
+	case deployment.Platform == "gke":
+		return "eks", "the source runs on GKE"
+	case streaming:
+		return "ecs-fargate", "streaming flows need long-lived connections"
+	case deployment.Platform == "cloud-functions" && timeout <= lambdaMaxTimeout:
+		return "lambda", "the source is a Cloud Function"
+	case deployment.Platform == "cloud-run" && timeout > 0 && timeout <= appRunnerTimeout:
+		return "app-runner", "the source is a Cloud Run service"
+	}
+	return "ecs-fargate", fmt.Sprintf("the source %s allows requests longer than Lambda or App Runner do", deployment.Platform)
+}
+
+// deployment is the deployment model every infrastructure template renders:
+// the compute target, its sizing and scaling, and the app's permissions.
+type deployment struct {
+	Target      string
+	IaC         string
+	ProjectName string
+	Region      string
+	Environment map[string]string
+	// VectorStore is the selected vector store backend, empty when the app
+	// has no vector store.
+	VectorStore string
+	Guardrail   bool
+	Policy      *bedrockPolicy
+	Port        int
+	CPU         int // CPU units, 1024 per vCPU
+	Memory      int // MiB
+	Timeout     int // seconds
+	MinCount    int
+	MaxCount    int
+	Concurrency int
+	// ReservedConcurrency caps concurrent Lambda executions, -1 for none.
+	ReservedConcurrency int
+	// Flows are the names API Gateway routes to the Lambda function.
+	Flows []string
+	// MetricsNamespace is the CloudWatch namespace the app publishes to.
+	MetricsNamespace string
+	// Environments are the stages the app is deployed to, in promotion order.
+	Environments []string
+	// Resources and Probes configure the EKS pods, keyed as in a container
+	// spec; the source's GKE Deployment sets them when there is one.
+	Resources map[string]map[string]string
+	Probes    map[string]map[string]interface{}
+	// Inputs are the Terraform outputs a CDK, SAM or Pulumi stack takes as
+	// environment variables, none for Terraform.
+	Inputs []stackInput
+}
+
+// chartConfig returns the migrated config.yaml as the config value of the
+// Helm chart, nil when there is none. bedrock.models is left out; the chart
+// fills it in from its models value.
+func chartConfig(migration *models.Migration) (*yaml.Node, error) {
+	content, exists := migration.NewFiles["config.yaml"]
+	if !exists {
+		return nil, nil
+	}
+	var document yaml.Node
+	if err := yaml.Unmarshal([]byte(content), &document); err != nil {
+		return nil, fmt.Errorf("failed to parse config.yaml: %w", err)
+	}
+	if len(document.Content) == 0 || document.Content[0].Kind != yaml.MappingNode {
+		return nil, nil
+	}
+	config := document.Content[0]
+	for i := 0; i+1 < len(config.Content); i += 2 {
+		if bedrock := config.Content[i+1]; config.Content[i].Value == "bedrock" && bedrock.Kind == yaml.MappingNode {
+			for j := 0; j+1 < len(bedrock.Content); j += 2 {
+				if bedrock.Content[j].Value == "models" {
+					bedrock.Content = append(bedrock.Content[:j], bedrock.Content[j+2:]...)
+					break
+				}
+			}
+			if len(bedrock.Content) == 0 {
+				config.Content = append(config.Content[:i], config.Content[i+2:]...)
+			}
+			break
+		}
+	}
+	return config, nil
+}
+
+// toYAML marshals value as YAML indented by indent spaces, for nesting it
+// under a key.
+func toYAML(indent int, value interface{}) (string, error) {
+	var out strings.Builder
+	encoder := yaml.NewEncoder(&out)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(value); err != nil {
+		return "", err
+	}
+	lines := strings.Split(strings.TrimRight(out.String(), "\n"), "\n")
+	for i, line := range lines {
+		if line != "" {
+			lines[i] = strings.Repeat(" ", indent) + line
+		}
+	}
+	return strings.Join(lines, "\n"), nil
+}
+
+// CPUMillis returns the CPU in Kubernetes millicores.
+func (d *deployment) CPUMillis() int {
+	return d.CPU * 1000 / 1024
+}
+
+// InputArgs returns the flags passing the stack inputs, exported by the
+// workflow as environment variables, to the stack's deploy command.
+func (d *deployment) InputArgs(environment string) string {
+	if len(d.Inputs) == 0 {
+		return ""
+	}
+	args := make([]string, 0, len(d.Inputs))
+	switch d.IaC {
+	case "cdk-go":
+		for _, input := range d.Inputs {
+			args = append(args, fmt.Sprintf(`-c %s="$%s"`, input.Name, input.Name))
+		}
+	case "sam":
+		overrides := []string{"Environment=" + environment}
+		for _, input := range d.Inputs {
+			overrides = append(overrides, fmt.Sprintf("%s=$%s", logicalID(input.Name), input.Name))
+		}
+		args = append(args, fmt.Sprintf(`--parameter-overrides "%s"`, strings.Join(overrides, " ")))
+	case "pulumi-go":
+		for _, input := range d.Inputs {
+			args = append(args, fmt.Sprintf(`--config %s="$%s"`, input.Name, input.Name))
+		}
+	}
+	return " " + strings.Join(args, " ")
+}
+
+func newDeployment(ctx provider.Context, key, iac string) *deployment {
+	environment := vectorStoreEnvironment(ctx)
+	for key, value := range guardrailEnvironment(ctx) {
+		environment[key] = value
+	}
+
+	d := &deployment{
+		Target:              key,
+		IaC:                 iac,
+		ProjectName:         resourceName(ctx.ModuleName()),
+		Region:              region(ctx),
+		Environment:         environment,
+		Guardrail:           len(ctx.Project().SafetySettings) > 0,
+		Policy:              newBedrockPolicy(ctx),
+		Port:                8080,
+		CPU:                 1024,
+		Memory:              512,
+		Timeout:             cloudRunTimeout,
+		MinCount:            1,
+		MaxCount:            10,
+		Concurrency:         80,
+		ReservedConcurrency: -1,
+		Flows:               make([]string, 0),
+		MetricsNamespace:    "GenKit/" + ctx.ProjectName(),
+		Environments:        []string{"dev", "staging", "prod"},
+	}
+	if len(ctx.Project().VectorStores) > 0 {
+		d.VectorStore, _, _ = selectedVectorStore(ctx)
+	}
+	for _, flow := range ctx.Project().Flows {
+		if !slices.Contains(d.Flows, flow.Name) {
+			d.Flows = append(d.Flows, flow.Name)
+		}
+	}
+
+	if source := ctx.Project().Deployment; source != nil {
+		if cpu := parseCPU(source.CPU); cpu > 0 {
+			d.CPU = cpu
+		}
+		if memory := parseMiB(source.Memory); memory > 0 {
+			d.Memory = memory
+		}
+		if timeout := parseSeconds(source.Timeout); timeout > 0 {
+			d.Timeout = timeout
+		}
+		if source.MinInstances > 0 {
+			d.MinCount = source.MinInstances
+		}
+		if source.MaxInstances > 0 {
+			d.MaxCount = max(source.MaxInstances, d.MinCount)
+		}
+		if source.Concurrency > 0 {
+			d.Concurrency = source.Concurrency
+		}
+		if source.Port > 0 {
+			d.Port = source.Port
+		}
+	}
+
+	switch key {
+	case "lambda":
+		d.Memory = min(max(d.Memory, 128), 10240)
+		d.Timeout = min(d.Timeout, lambdaMaxTimeout)
+		if source := ctx.Project().Deployment; source != nil && source.MaxInstances > 0 {
+			d.ReservedConcurrency = source.MaxInstances
+		}
+	case "ecs-fargate":
+		d.CPU, d.Memory = taskSize(fargateSizes, d.CPU, d.Memory)
+	case "app-runner":
+		d.CPU, d.Memory = taskSize(appRunnerSizes, d.CPU, d.Memory)
+		d.Concurrency = min(d.Concurrency, 200)
+	case "eks":
+		// Pods request what the Cloud Run container was limited to.
+		d.Resources = map[string]map[string]string{
+			"requests": {"cpu": fmt.Sprintf("%dm", d.CPUMillis()), "memory": fmt.Sprintf("%dMi", d.Memory)},
+			"limits":   {"memory": fmt.Sprintf("%dMi", d.Memory)},
+		}
+		// Probes open a connection; see tcpHealthCheck.
+		d.Probes = map[string]map[string]interface{}{
+			"readinessProbe": {"tcpSocket": map[string]int{"port": d.Port}, "periodSeconds": 10},
+			"livenessProbe":  {"tcpSocket": map[string]int{"port": d.Port}, "initialDelaySeconds": 10, "periodSeconds": 20},
+		}
+		if source := ctx.Project().Deployment; source != nil && source.Container != nil {
+			if len(source.Container.Resources) > 0 {
+				d.Resources = source.Container.Resources
+			}
+			probes := map[string]map[string]interface{}{
+				"readinessProbe": source.Container.ReadinessProbe,
+				"livenessProbe":  source.Container.LivenessProbe,
+				"startupProbe":   source.Container.StartupProbe,
+			}
+			for name, probe := range probes {
+				if probe == nil {
+					delete(probes, name)
+				}
+			}
+			if len(probes) > 0 {
+				d.Probes = probes
+			}
+		}
+	}
+	return d
+}
+
+// resourceName turns a module path into a name ECR, ECS and load balancers
+// accept: lowercase letters, digits and hyphens.
+func resourceName(module string) string {
+	name := strings.ToLower(module[strings.LastIndex(module, "/")+1:])
+	name = strings.Trim(nonResourceName.ReplaceAllString(name, "-"), "-")
+	if name == "" {
+		return "genkit-app"
+	}
+	return name
+}
+
+var nonResourceName = regexp.MustCompile(`[^a-z0-9]+`)
+
+type taskSizes struct {
+	cpu    int
+	memory []int // allowed MiB, ascending
+}
+
+// fargateSizes lists the CPU and memory combinations Fargate tasks accept.
+var fargateSizes = []taskSizes{
+	{256, []int{512, 1024, 2048}},
+	{512, []int{1024, 2048, 3072, 4096}},
+	{1024, []int{2048, 3072, 4096, 5120, 6144, 7168, 8192}},
+	{2048, []int{4096, 5120, 6144, 7168, 8192, 9216, 10240, 11264, 12288, 13312, 14336, 15360, 16384}},
+	{4096, []int{8192, 12288, 16384, 20480, 24576, 28672, 30720}},
+}
+
+// appRunnerSizes lists the CPU and memory combinations App Runner accepts.
+var appRunnerSizes = []taskSizes{
+	{256, []int{512, 1024}},
+	{512, []int{1024}},
+	{1024, []int{2048, 3072, 4096}},
+	{2048, []int{4096, 6144}},
+	{4096, []int{8192, 10240, 12288}},
+}
+
+// taskSize rounds CPU and memory up to the closest accepted combination.
+func taskSize(sizes []taskSizes, cpu, memory int) (int, int) {
+	size := sizes[len(sizes)-1]
+	for _, candidate := range sizes {
+		if candidate.cpu >= cpu {
+			size = candidate
+			break
+		}
+	}
+	for _, allowed := range size.memory {
+		if allowed >= memory {
+			return size.cpu, allowed
+		}
+	}
+	return size.cpu, size.memory[len(size.memory)-1]
+}
+
+// parseCPU turns a Cloud Run CPU limit such as "1", "0.5" or "500m" into CPU
+// units.
+func parseCPU(value string) int {
+	if millis, found := strings.CutSuffix(value, "m"); found {
+		if n, err := strconv.Atoi(millis); err == nil {
+			return n * 1024 / 1000
+		}
+		return 0
+	}
+	cpus, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return 0
+	}
+	return int(math.Round(cpus * 1024))
+}
+
+// parseMiB turns a memory setting such as "512Mi", "1Gi" or "512MB" into MiB.
+func parseMiB(value string) int {
+	units := []struct {
+		suffix string
+		mib    float64
+	}{
+		{"Gi", 1024}, {"GiB", 1024}, {"G", 1e9 / (1 << 20)}, {"GB", 1e9 / (1 << 20)},
+		{"Mi", 1}, {"MiB", 1}, {"M", 1e6 / (1 << 20)}, {"MB", 1e6 / (1 << 20)},
+	}
+	for _, unit := range units {
+		if number, found := strings.CutSuffix(value, unit.suffix); found {
+			n, err := strconv.ParseFloat(number, 64)
+			if err != nil {
+				continue
+			}
+			return int(math.Ceil(n * unit.mib))
+		}
+	}
+	n, _ := strconv.Atoi(value)
+	return n
+}
+
+// parseSeconds turns a timeout such as "300s", "5m" or "300" into seconds.
+func parseSeconds(value string) int {
+	if value == "" {
+		return 0
+	}
+	if n, err := strconv.Atoi(value); err == nil {
+		return n
+	}
+	for suffix, seconds := range map[string]int{"s": 1, "m": 60, "h": 3600} {
+		if number, found := strings.CutSuffix(value, suffix); found {
+			if n, err := strconv.Atoi(number); err == nil {
+				return n * seconds
+			}
+		}
+	}
+	return 0
+}
+
+// generateDeployment writes the Terraform of the selected target, plus its
+// container build or Kubernetes manifests.
+func generateDeployment(ctx provider.Context) error {
+	iac, infra, err := selectedIaC(ctx)
+	if err != nil {
+		return err
+	}
+	key, reason, err := selectedDeployTarget(ctx)
+	if err != nil {
+		return err
+	}
+	target := deployTargets[key]
+	d := newDeployment(ctx, key, iac)
+	migration := ctx.Migration()
+
+	migration.Changes = append(migration.Changes, &models.Change{
+		Type:        "config",
+		Description: fmt.Sprintf("Deploying to %s with %s (%s)", target.name, infra.name, reason),
+		File:        infra.main,
+		NewValue:    key,
+	})
+	if key == "lambda" {
+		migration.Changes = append(migration.Changes, lambdaLimits(ctx.Project(), d)...)
+	}
+	if d.Policy.Empty() {
+		migration.Changes = append(migration.Changes, &models.Change{
+			Type:         "config",
+			Description:  "No Bedrock models were detected; grant bedrock:InvokeModel on the ARNs of the models the app invokes in " + infra.main,
+			File:         infra.main,
+			ManualReview: true,
+		})
+	}
+
+	var files map[string]string
+	switch iac {
+	case "cdk-go":
+		files = cdkFiles(migration, d)
+	case "sam":
+		files = samFiles(migration, d)
+	case "pulumi-go":
+		files = pulumiFiles(migration, d)
+	default:
+		files = map[string]string{
+			"terraform/main.tf":      terraformHeader + target.terraform + bedrockPolicyTerraform,
+			"terraform/variables.tf": commonVariables + target.variables,
+			"terraform/outputs.tf":   target.outputs,
+		}
+	}
+	// The guardrail and vector store stay Terraform, applied as a module of
+	// their own whose outputs the stack takes as inputs.
+	if iac != "terraform" && len(d.Environment) > 0 {
+		d.Inputs = stackInputs(migration, d)
+		files["terraform/main.tf"] = terraformHeader
+		files["terraform/variables.tf"] = commonVariables
+		if slices.ContainsFunc(d.Inputs, func(input stackInput) bool { return input.Value != "" }) {
+			files["terraform/outputs.tf"] = stackInputOutputs
+		}
+		migration.Changes = append(migration.Changes, stackInputChange(infra, d.Inputs))
+	}
+	if key == "lambda" {
+		handlers, changes := lambdaHandlerFiles(migration)
+		for path, content := range handlers {
+			files[path] = content
+		}
+		migration.Changes = append(migration.Changes, changes...)
+		if len(d.Flows) == 0 {
+			migration.Changes = append(migration.Changes, &models.Change{
+				Type:         "config",
+				Description:  "No flows were detected; add a POST /<flow> API Gateway route for each in " + infra.main,
+				File:         infra.main,
+				ManualReview: true,
+			})
+		}
+	}
+	chart := "charts/" + d.ProjectName + "/"
+	if key == "eks" {
+		files[chart+"Chart.yaml"] = helmChart
+		files[chart+"values.yaml"] = helmValues
+	}
+
+	data := map[string]interface{}{
+		"Deployment": d,
+		"Principal":  target.principal,
+		"Container":  target.container,
+		"IaCName":    infra.name,
+	}
+	if key == "eks" {
+		config, err := chartConfig(migration)
+		if err != nil {
+			return err
+		}
+		data["Config"] = config
+	}
+	if err := renderFiles(migration, files, data); err != nil {
+		return err
+	}
+	if key == "eks" {
+		for name, content := range helmTemplates {
+			migration.NewFiles[chart+"templates/"+name] = content
+		}
+		description := "Generated a Helm chart with a Deployment, Service, HPA, IRSA service account and a ConfigMap of config.yaml"
+		if source := ctx.Project().Deployment; source != nil && source.Container != nil {
+			description += fmt.Sprintf(", carrying over the resources and probes of %s", source.File)
+		}
+		migration.Changes = append(migration.Changes, &models.Change{
+			Type:        "config",
+			Description: description,
+			File:        chart + "values.yaml",
+		})
+	}
+	if iac == "terraform" || len(d.Inputs) > 0 {
+		if err := terraformEnvironments(migration, d); err != nil {
+			return err
+		}
+	}
+
+	if target.container {
+		provider.WriteDockerfile(migration)
+	}
+	return generateGitHubActions(migration, d)
+}
+
+// The generated health checks explain themselves with these, as
+// genkit.Handler only routes POST /<flow>.
+const (
+	httpHealthCheck = "genkit.Handler serves flows on POST; any HTTP response shows the server is up."
+	tcpHealthCheck  = "genkit.Handler has no GET route to probe, so check the port accepts connections."
+)
+
+var templateFuncs = template.FuncMap{
+	"logicalID":       logicalID,
+	"toYAML":          toYAML,
+	"httpHealthCheck": func() string { return httpHealthCheck },
+	"tcpHealthCheck":  func() string { return tcpHealthCheck },
+}
+
+func renderFiles(migration *models.Migration, files map[string]string, data interface{}) error {
+	for path, text := range files {
+		tmpl, err := template.New(path).Funcs(templateFuncs).Parse(text)
+		if err != nil {
+			return err
+		}
+		var content strings.Builder
+		if err := tmpl.Execute(&content, data); err != nil {
+			return fmt.Errorf("failed to render %s: %w", path, err)
+		}
+		if strings.HasSuffix(path, ".go") {
+			source, err := format.Source([]byte(content.String()))
+			if err != nil {
+				return fmt.Errorf("failed to format %s: %w", path, err)
+			}
+			migration.NewFiles[path] = string(source)
+			continue
+		}
+		migration.NewFiles[path] = content.String()
+	}
+	return nil
+}
+
+var (
+	terraformVariable = regexp.MustCompile(`(?m)^variable "([^"]+)"`)
+	terraformOutput   = regexp.MustCompile(`(?m)^output "([^"]+)"`)
+	// An output of a single-line value, as the generated .tf files write them.
+	terraformOutputValue = regexp.MustCompile(`(?m)^output "([^"]+)" \{\n\s+value\s+= (.+)\n\}`)
+)
+
+// terraformEnvironments writes a root configuration per environment under
+// terraform/envs, applying terraform/ as a module with its own S3 state and
+// tfvars, and forwarding the variables and outputs of every terraform/*.tf,
+// including the guardrail and vector store.
+func terraformEnvironments(migration *models.Migration, d *deployment) error {
+	var outputs []string
+	for path, content := range migration.NewFiles {
+		if dir, file := filepath.Split(path); dir != "terraform/" || filepath.Ext(file) != ".tf" {
+			continue
+		}
+		for _, match := range terraformOutput.FindAllStringSubmatch(content, -1) {
+			outputs = append(outputs, match[1])
+		}
+	}
+	sort.Strings(outputs)
+
+	variablesFile := migration.NewFiles["terraform/variables.tf"]
+	var variables []string
+	width := 0
+	for _, match := range terraformVariable.FindAllStringSubmatch(variablesFile, -1) {
+		variables = append(variables, match[1])
+		width = max(width, len(match[1]))
+	}
+
+	for _, environment := range d.Environments {
+		dir := "terraform/envs/" + environment + "/"
+		migration.NewFiles[dir+"variables.tf"] = variablesFile
+		err := renderFiles(migration, map[string]string{
+			dir + "main.tf":          environmentTerraform,
+			dir + "backend.hcl":      environmentBackend,
+			dir + "terraform.tfvars": environmentTfvars,
+			dir + "outputs.tf":       environmentOutputs,
+		}, map[string]interface{}{
+			"Deployment":  d,
+			"Environment": environment,
+			"Variables":   variables,
+			"Width":       width,
+			"Outputs":     outputs,
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	migration.Changes = append(migration.Changes, &models.Change{
+		Type: "config",
+		Description: fmt.Sprintf("Generated Terraform roots for %s under terraform/envs, each applying terraform/ as a module with its own tfvars and S3 state",
+			strings.Join(d.Environments, ", ")),
+		File: "terraform/envs/" + d.Environments[0] + "/main.tf",
+	}, &models.Change{
+		Type:         "config",
+		Description:  "Create the S3 state bucket and DynamoDB lock table named in terraform/envs/*/backend.hcl before the first terraform init",
+		File:         "terraform/envs/" + d.Environments[0] + "/backend.hcl",
+		ManualReview: true,
+	})
+	return nil
+}
+
+// cdkFiles returns the CDK app defining the stack.
+func cdkFiles(migration *models.Migration, d *deployment) map[string]string {
+	migration.Changes = append(migration.Changes, &models.Change{
+		Type:        "config",
+		Description: fmt.Sprintf("Generated a CDK app with a %s stack per environment and a snapshot test of its template", d.ProjectName),
+		File:        "cdk/app.go",
+	})
+	migration.Commands = append(migration.Commands, "(cd cdk && go mod tidy && go test ./...)")
+
+	return map[string]string{
+		"cdk/cdk.json":    cdkJSON,
+		"cdk/go.mod":      cdkGoMod,
+		"cdk/app.go":      cdkApp,
+		"cdk/app_test.go": cdkAppTest,
+	}
+}
+
+// samFiles returns the SAM application and its deploy configuration per
+// environment.
+func samFiles(migration *models.Migration, d *deployment) map[string]string {
+	migration.Changes = append(migration.Changes, &models.Change{
+		Type:        "config",
+		Description: fmt.Sprintf("Generated a SAM application with an HttpApi event per flow and samconfig.toml sections for %s", strings.Join(d.Environments, ", ")),
+		File:        "template.yaml",
+	})
+
+	return map[string]string{
+		"template.yaml":  samTemplate,
+		"samconfig.toml": samConfig,
+	}
+}
+
+// pulumiFiles returns the Pulumi program and the config of a stack per
+// environment.
+func pulumiFiles(migration *models.Migration, d *deployment) map[string]string {
+	migration.Changes = append(migration.Changes, &models.Change{
+		Type:        "config",
+		Description: fmt.Sprintf("Generated a Pulumi program with stacks for %s", strings.Join(d.Environments, ", ")),
+		File:        "pulumi/main.go",
+	})
+	migration.Commands = append(migration.Commands, "(cd pulumi && go mod tidy)")
+
+	files := map[string]string{
+		"pulumi/Pulumi.yaml": pulumiYAML,
+		"pulumi/go.mod":      pulumiGoMod,
+		"pulumi/main.go":     pulumiProgram,
+	}
+	for _, environment := range d.Environments {
+		files["pulumi/Pulumi."+environment+".yaml"] = pulumiStackConfig
+	}
+	return files
+}
+
+// stackInput is an environment variable of the app pointing at a Terraform
+// resource, read from the output of the resources module.
+type stackInput struct {
+	Name   string
+	Output string
+	// Value is the expression of an output to declare, empty when the
+	// resource's Terraform already declares Output.
+	Value string
+}
+
+// stackInputs returns the inputs of a stack in another format, outputs of the
+// guardrail and vector store Terraform. Outputs the .tf files declare with
+// the same value are reused.
+func stackInputs(migration *models.Migration, d *deployment) []stackInput {
+	declared := make(map[string]string)
+	for path, content := range migration.NewFiles {
+		if strings.HasPrefix(path, "terraform/") && strings.HasSuffix(path, ".tf") {
+			for _, match := range terraformOutputValue.FindAllStringSubmatch(content, -1) {
+				declared[match[2]] = match[1]
+			}
+		}
+	}
+
+	inputs := make([]stackInput, 0, len(d.Environment))
+	for name, value := range d.Environment {
+		input := stackInput{Name: name, Output: declared[value]}
+		if input.Output == "" {
+			input.Output = strings.ToLower(name)
+			input.Value = value
+		}
+		inputs = append(inputs, input)
+	}
+	sort.Slice(inputs, func(i, j int) bool { return inputs[i].Name < inputs[j].Name })
+	return inputs
+}
+
+// stackInputChange describes deploying the stack around the Terraform it
+// takes inputs from: the stack creates the role the Terraform grants access
+// to, and is redeployed with the Terraform outputs.
+func stackInputChange(infra *iacFormat, inputs []stackInput) *models.Change {
+	passed := make([]string, 0, len(inputs))
+	for _, input := range inputs {
+		passed = append(passed, fmt.Sprintf("%s (output %s)", input.Name, input.Output))
+	}
+	return &models.Change{
+		Type: "config",
+		Description: fmt.Sprintf("The guardrail and vector store are a Terraform module applied by terraform/envs/<environment>; deploy %s first, which creates the app role, apply the environment's root, then redeploy passing %s with %s",
+			infra.main, strings.Join(passed, ", "), infra.inputs),
+		File:         infra.main,
+		ManualReview: true,
+	}
+}
+
+// logicalID turns a flow or variable name into a CloudFormation logical ID,
+// as in BEDROCK_GUARDRAIL_ID to BedrockGuardrailId.
+func logicalID(name string) string {
+	var id strings.Builder
+	for _, part := range nonAlphanumeric.Split(name, -1) {
+		if part == "" {
+			continue
+		}
+		if strings.ToUpper(part) == part {
+			part = strings.ToLower(part)
+		}
+		id.WriteString(strings.ToUpper(part[:1]) + part[1:])
+	}
+	return id.String()
+}
+
+var nonAlphanumeric = regexp.MustCompile(`[^A-Za-z0-9]+`)
+
+// lambdaLimits reports what Lambda behind API Gateway cannot serve as the
+// source did.
+func lambdaLimits(project *models.Project, d *deployment) []*models.Change {
+	changes := make([]*models.Change, 0)
+	for _, flow := range project.Flows {
+		if flow.Streaming {
+			changes = append(changes, &models.Change{
+				Type:         "config",
+				Description:  fmt.Sprintf("Flow %s streams, but API Gateway buffers Lambda responses; deploy with --deploy-target=ecs-fargate to stream", flow.Name),
+				File:         flow.Position.Filename,
+				Line:         flow.Position.Line,
+				ManualReview: true,
+			})
+		}
+	}
+	if source := project.Deployment; source != nil && parseSeconds(source.Timeout) > apiGatewayTimeout {
+		changes = append(changes, &models.Change{
+			Type: "config",
+			Description: fmt.Sprintf("The source allows %s requests, but API Gateway times out after %d seconds; deploy with --deploy-target=ecs-fargate for longer requests",
+				source.Timeout, apiGatewayTimeout),
+			File:         source.File,
+			ManualReview: true,
+		})
+	}
+	return changes
+}
@@ -0,0 +1,855 @@
+package aws
+
+const terraformHeader = `# Terraform configuration for GenKit on AWS
+# This directory is a module; envs/<environment> apply it with their own
+# state and variables.
+terraform {
+  required_version = ">= 1.0"
+  required_providers {
+    aws = {
+      source  = "hashicorp/aws"
+      version = "~> 5.0"
+    }
+{{- if eq .Deployment.VectorStore "bedrock-kb" }}
+    opensearch = {
+      source  = "opensearch-project/opensearch"
+      version = "~> 2.3"
+    }
+{{- end }}
+  }
+}
+
+locals {
+  # Prefix of resource names, so environments can share an account
+  name = "${var.project_name}-${var.environment}"
+}
+
+data "aws_caller_identity" "current" {}
+{{- if ne .Deployment.IaC "terraform" }}
+
+# IAM role the app runs as, created by the {{ .IaCName }} stack
+data "aws_iam_role" "app_role" {
+  name = "${local.name}-app-role"
+}
+{{- else }}
+
+# IAM role the app runs as
+resource "aws_iam_role" "app_role" {
+  name = "${local.name}-app-role"
+{{- if .Principal }}
+
+  assume_role_policy = jsonencode({
+    Version = "2012-10-17"
+    Statement = [
+      {
+        Action = "sts:AssumeRole"
+        Effect = "Allow"
+        Principal = {
+          Service = "{{ .Principal }}"
+        }
+      }
+    ]
+  })
+{{- else }}
+  assume_role_policy = data.aws_iam_policy_document.irsa.json
+{{- end }}
+}
+{{- end }}
+`
+
+const lambdaTerraform = `
+# Lambda function for GenKit app
+# genkit-app.zip holds the bootstrap binary, the app built with -tags lambda,
+# which serves API Gateway requests in-process.
+resource "aws_lambda_function" "genkit_app" {
+  filename                       = "${path.module}/genkit-app.zip"
+  source_code_hash               = filebase64sha256("${path.module}/genkit-app.zip")
+  function_name                  = local.name
+  role                           = aws_iam_role.app_role.arn
+  handler                        = "bootstrap"
+  runtime                        = "provided.al2023"
+  memory_size                    = var.memory
+  timeout                        = var.timeout
+  reserved_concurrent_executions = var.reserved_concurrency
+
+  environment {
+    variables = {
+      GENKIT_ENV = "production"
+{{- range $key, $value := .Deployment.Environment }}
+      {{ $key }} = {{ $value }}
+{{- end }}
+    }
+  }
+
+  depends_on = [
+    aws_iam_role_policy_attachment.lambda_logs,
+    aws_cloudwatch_log_group.genkit_app,
+  ]
+}
+
+resource "aws_cloudwatch_log_group" "genkit_app" {
+  name              = "/aws/lambda/${local.name}"
+  retention_in_days = 14
+}
+
+resource "aws_iam_role_policy_attachment" "lambda_logs" {
+  role       = aws_iam_role.app_role.name
+  policy_arn = "arn:aws:iam::aws:policy/service-role/AWSLambdaBasicExecutionRole"
+}
+
+# HTTP API routing POST /<flow> to the function
+resource "aws_apigatewayv2_api" "genkit_api" {
+  name          = "${local.name}-api"
+  protocol_type = "HTTP"
+}
+
+resource "aws_apigatewayv2_integration" "lambda" {
+  api_id                 = aws_apigatewayv2_api.genkit_api.id
+  integration_type       = "AWS_PROXY"
+  integration_uri        = aws_lambda_function.genkit_app.invoke_arn
+  payload_format_version = "2.0"
+}
+
+resource "aws_apigatewayv2_route" "flows" {
+  for_each  = toset([{{ range $i, $flow := .Deployment.Flows }}{{ if $i }}, {{ end }}"{{ $flow }}"{{ end }}])
+  api_id    = aws_apigatewayv2_api.genkit_api.id
+  route_key = "POST /${each.value}"
+  target    = "integrations/${aws_apigatewayv2_integration.lambda.id}"
+}
+
+resource "aws_apigatewayv2_stage" "default" {
+  api_id      = aws_apigatewayv2_api.genkit_api.id
+  name        = "$default"
+  auto_deploy = true
+}
+
+resource "aws_lambda_permission" "api_gateway" {
+  statement_id  = "AllowExecutionFromAPIGateway"
+  action        = "lambda:InvokeFunction"
+  function_name = aws_lambda_function.genkit_app.function_name
+  principal     = "apigateway.amazonaws.com"
+  source_arn    = "${aws_apigatewayv2_api.genkit_api.execution_arn}/*/*"
+}
+`
+
+const ecrTerraform = `
+resource "aws_ecr_repository" "app" {
+  name = local.name
+
+  image_scanning_configuration {
+    scan_on_push = true
+  }
+}
+`
+
+const fargateTerraform = ecrTerraform + `
+data "aws_vpc" "default" {
+  default = true
+}
+
+data "aws_subnets" "default" {
+  filter {
+    name   = "vpc-id"
+    values = [data.aws_vpc.default.id]
+  }
+}
+
+resource "aws_ecs_cluster" "app" {
+  name = local.name
+}
+
+resource "aws_cloudwatch_log_group" "genkit_app" {
+  name              = "/ecs/${local.name}"
+  retention_in_days = 14
+}
+
+# Role ECS pulls the image and writes logs with
+resource "aws_iam_role" "execution_role" {
+  name = "${local.name}-execution-role"
+
+  assume_role_policy = jsonencode({
+    Version = "2012-10-17"
+    Statement = [
+      {
+        Action = "sts:AssumeRole"
+        Effect = "Allow"
+        Principal = {
+          Service = "ecs-tasks.amazonaws.com"
+        }
+      }
+    ]
+  })
+}
+
+resource "aws_iam_role_policy_attachment" "execution" {
+  role       = aws_iam_role.execution_role.name
+  policy_arn = "arn:aws:iam::aws:policy/service-role/AmazonECSTaskExecutionRolePolicy"
+}
+
+resource "aws_ecs_task_definition" "app" {
+  family                   = local.name
+  requires_compatibilities = ["FARGATE"]
+  network_mode             = "awsvpc"
+  cpu                      = var.cpu
+  memory                   = var.memory
+  execution_role_arn       = aws_iam_role.execution_role.arn
+  task_role_arn            = aws_iam_role.app_role.arn
+
+  container_definitions = jsonencode([
+    {
+      name         = "app"
+      image        = "${aws_ecr_repository.app.repository_url}:${var.image_tag}"
+      essential    = true
+      portMappings = [{ containerPort = var.port }]
+      environment = [
+        { name = "GENKIT_ENV", value = "production" },
+        { name = "AWS_REGION", value = var.aws_region },
+        { name = "PORT", value = tostring(var.port) },
+{{- range $key, $value := .Deployment.Environment }}
+        { name = "{{ $key }}", value = {{ $value }} },
+{{- end }}
+      ]
+      logConfiguration = {
+        logDriver = "awslogs"
+        options = {
+          awslogs-group         = aws_cloudwatch_log_group.genkit_app.name
+          awslogs-region        = var.aws_region
+          awslogs-stream-prefix = "app"
+        }
+      }
+    }
+  ])
+}
+
+resource "aws_security_group" "alb" {
+  name   = "${local.name}-alb"
+  vpc_id = data.aws_vpc.default.id
+
+  ingress {
+    from_port   = 80
+    to_port     = 80
+    protocol    = "tcp"
+    cidr_blocks = ["0.0.0.0/0"]
+  }
+
+  egress {
+    from_port   = 0
+    to_port     = 0
+    protocol    = "-1"
+    cidr_blocks = ["0.0.0.0/0"]
+  }
+}
+
+resource "aws_security_group" "app" {
+  name   = "${local.name}-app"
+  vpc_id = data.aws_vpc.default.id
+
+  ingress {
+    from_port       = var.port
+    to_port         = var.port
+    protocol        = "tcp"
+    security_groups = [aws_security_group.alb.id]
+  }
+
+  egress {
+    from_port   = 0
+    to_port     = 0
+    protocol    = "-1"
+    cidr_blocks = ["0.0.0.0/0"]
+  }
+}
+
+# The load balancer keeps connections open as long as the source allowed
+# requests to run, so long and streaming flows are not cut off.
+resource "aws_lb" "app" {
+  name               = local.name
+  load_balancer_type = "application"
+  subnets            = data.aws_subnets.default.ids
+  security_groups    = [aws_security_group.alb.id]
+  idle_timeout       = var.timeout
+}
+
+resource "aws_lb_target_group" "app" {
+  name        = local.name
+  port        = var.port
+  protocol    = "HTTP"
+  target_type = "ip"
+  vpc_id      = data.aws_vpc.default.id
+
+  # {{ httpHealthCheck }}
+  health_check {
+    path    = var.health_check_path
+    matcher = "200-499"
+  }
+}
+
+# Add an HTTPS listener with an ACM certificate before serving production
+# traffic.
+resource "aws_lb_listener" "http" {
+  load_balancer_arn = aws_lb.app.arn
+  port              = 80
+  protocol          = "HTTP"
+
+  default_action {
+    type             = "forward"
+    target_group_arn = aws_lb_target_group.app.arn
+  }
+}
+
+resource "aws_ecs_service" "app" {
+  name            = local.name
+  cluster         = aws_ecs_cluster.app.id
+  task_definition = aws_ecs_task_definition.app.arn
+  desired_count   = var.min_count
+  launch_type     = "FARGATE"
+
+  network_configuration {
+    subnets          = data.aws_subnets.default.ids
+    security_groups  = [aws_security_group.app.id]
+    assign_public_ip = true
+  }
+
+  load_balancer {
+    target_group_arn = aws_lb_target_group.app.arn
+    container_name   = "app"
+    container_port   = var.port
+  }
+
+  depends_on = [aws_lb_listener.http]
+}
+
+resource "aws_appautoscaling_target" "app" {
+  min_capacity       = var.min_count
+  max_capacity       = var.max_count
+  resource_id        = "service/${aws_ecs_cluster.app.name}/${aws_ecs_service.app.name}"
+  scalable_dimension = "ecs:service:DesiredCount"
+  service_namespace  = "ecs"
+}
+
+resource "aws_appautoscaling_policy" "cpu" {
+  name               = "${local.name}-cpu"
+  policy_type        = "TargetTrackingScaling"
+  resource_id        = aws_appautoscaling_target.app.resource_id
+  scalable_dimension = aws_appautoscaling_target.app.scalable_dimension
+  service_namespace  = aws_appautoscaling_target.app.service_namespace
+
+  target_tracking_scaling_policy_configuration {
+    target_value = 70
+
+    predefined_metric_specification {
+      predefined_metric_type = "ECSServiceAverageCPUUtilization"
+    }
+  }
+}
+`
+
+const appRunnerTerraform = ecrTerraform + `
+# Role App Runner pulls the image with
+resource "aws_iam_role" "access_role" {
+  name = "${local.name}-apprunner-access"
+
+  assume_role_policy = jsonencode({
+    Version = "2012-10-17"
+    Statement = [
+      {
+        Action = "sts:AssumeRole"
+        Effect = "Allow"
+        Principal = {
+          Service = "build.apprunner.amazonaws.com"
+        }
+      }
+    ]
+  })
+}
+
+resource "aws_iam_role_policy_attachment" "access" {
+  role       = aws_iam_role.access_role.name
+  policy_arn = "arn:aws:iam::aws:policy/service-role/AWSAppRunnerServicePolicyForECRAccess"
+}
+
+resource "aws_apprunner_auto_scaling_configuration_version" "app" {
+  auto_scaling_configuration_name = local.name
+  max_concurrency                 = var.concurrency
+  min_size                        = var.min_count
+  max_size                        = var.max_count
+}
+
+resource "aws_apprunner_service" "app" {
+  service_name                   = local.name
+  auto_scaling_configuration_arn = aws_apprunner_auto_scaling_configuration_version.app.arn
+
+  source_configuration {
+    auto_deployments_enabled = false
+
+    authentication_configuration {
+      access_role_arn = aws_iam_role.access_role.arn
+    }
+
+    image_repository {
+      image_identifier      = "${aws_ecr_repository.app.repository_url}:${var.image_tag}"
+      image_repository_type = "ECR"
+
+      image_configuration {
+        port = tostring(var.port)
+        runtime_environment_variables = {
+          GENKIT_ENV = "production"
+          AWS_REGION = var.aws_region
+{{- range $key, $value := .Deployment.Environment }}
+          {{ $key }} = {{ $value }}
+{{- end }}
+        }
+      }
+    }
+  }
+
+  instance_configuration {
+    cpu               = tostring(var.cpu)
+    memory            = tostring(var.memory)
+    instance_role_arn = aws_iam_role.app_role.arn
+  }
+
+  # {{ tcpHealthCheck }}
+  health_check_configuration {
+    protocol            = "TCP"
+    interval            = 10
+    healthy_threshold   = 1
+    unhealthy_threshold = 5
+  }
+
+  depends_on = [aws_iam_role_policy_attachment.access]
+}
+`
+
+const eksTerraform = ecrTerraform + `
+data "aws_eks_cluster" "cluster" {
+  name = var.eks_cluster_name
+}
+
+locals {
+  oidc_issuer = replace(data.aws_eks_cluster.cluster.identity[0].oidc[0].issuer, "https://", "")
+}
+
+# The app's service account assumes the app role through IRSA.
+data "aws_iam_policy_document" "irsa" {
+  statement {
+    actions = ["sts:AssumeRoleWithWebIdentity"]
+
+    principals {
+      type        = "Federated"
+      identifiers = ["arn:aws:iam::${data.aws_caller_identity.current.account_id}:oidc-provider/${local.oidc_issuer}"]
+    }
+
+    condition {
+      test     = "StringEquals"
+      variable = "${local.oidc_issuer}:sub"
+      values   = ["system:serviceaccount:${var.namespace}:${var.project_name}"]
+    }
+
+    condition {
+      test     = "StringEquals"
+      variable = "${local.oidc_issuer}:aud"
+      values   = ["sts.amazonaws.com"]
+    }
+  }
+}
+`
+
+const bedrockPolicyTerraform = `
+{{- with .Deployment.Policy }}
+{{- range .Profiles }}
+
+data "aws_bedrock_inference_profile" "{{ .Name }}" {
+  inference_profile_id = "{{ .ID }}"
+}
+{{- end }}
+{{- end }}
+
+# IAM policy for Bedrock access, limited to the models the app invokes, and
+# for publishing metrics to the app's CloudWatch namespace
+resource "aws_iam_role_policy" "bedrock_policy" {
+  name = "genkit-bedrock-policy"
+  role = aws_iam_role.app_role.id
+
+  policy = jsonencode({
+    Version = "2012-10-17"
+    Statement = [
+      {
+        Effect   = "Allow"
+        Action   = ["cloudwatch:PutMetricData"]
+        Resource = "*"
+        Condition = {
+          StringEquals = {
+            "cloudwatch:namespace" = "{{ .Deployment.MetricsNamespace }}"
+          }
+        }
+      },
+{{- with .Deployment.Policy }}{{ if not .Empty }}
+      {
+        Effect = "Allow"
+        # InvokeModel also authorizes Converse{{ if .Streaming }}, and InvokeModelWithResponseStream ConverseStream{{ end }}
+        Action = [
+          "bedrock:InvokeModel"{{ if .Streaming }},
+          "bedrock:InvokeModelWithResponseStream"{{ end }}
+        ]
+        Resource = {{ if .Profiles }}concat({{ end }}[
+{{- range .Models }}
+          "arn:aws:bedrock:${var.aws_region}::foundation-model/{{ . }}",
+{{- end }}
+{{- range .Profiles }}
+          data.aws_bedrock_inference_profile.{{ .Name }}.inference_profile_arn,
+{{- end }}
+        ]{{ range .Profiles }}, data.aws_bedrock_inference_profile.{{ .Name }}.models[*].model_arn{{ end }}{{ if .Profiles }}){{ end }}
+      },
+{{- end }}{{ end }}
+{{- if .Deployment.Guardrail }}
+      {
+        Effect   = "Allow"
+        Action   = ["bedrock:ApplyGuardrail"]
+        Resource = aws_bedrock_guardrail.genkit.guardrail_arn
+      },
+{{- end }}
+    ]
+  })
+}
+`
+
+const commonVariables = `variable "aws_region" {
+  description = "AWS region"
+  type        = string
+  default     = "{{ .Deployment.Region }}"
+}
+
+variable "project_name" {
+  description = "Project name used for resource naming"
+  type        = string
+  default     = "{{ .Deployment.ProjectName }}"
+}
+
+variable "environment" {
+  description = "Environment name (dev, staging, prod)"
+  type        = string
+
+  validation {
+    condition     = contains([{{ range $i, $env := .Deployment.Environments }}{{ if $i }}, {{ end }}"{{ $env }}"{{ end }}], var.environment)
+    error_message = "environment must be one of {{ range $i, $env := .Deployment.Environments }}{{ if $i }}, {{ end }}{{ $env }}{{ end }}."
+  }
+}
+`
+
+const lambdaVariables = `
+variable "memory" {
+  description = "Lambda memory in MB"
+  type        = number
+  default     = {{ .Deployment.Memory }}
+}
+
+variable "timeout" {
+  description = "Lambda timeout in seconds"
+  type        = number
+  default     = {{ .Deployment.Timeout }}
+}
+
+variable "reserved_concurrency" {
+  description = "Concurrent executions reserved for the function, -1 for none"
+  type        = number
+  default     = {{ .Deployment.ReservedConcurrency }}
+}
+`
+
+const imageVariables = `
+variable "image_tag" {
+  description = "Tag of the app image in ECR"
+  type        = string
+  default     = "latest"
+}
+
+variable "port" {
+  description = "Port the app listens on"
+  type        = number
+  default     = {{ .Deployment.Port }}
+}
+`
+
+const scalingVariables = `
+variable "cpu" {
+  description = "CPU units, 1024 per vCPU"
+  type        = number
+  default     = {{ .Deployment.CPU }}
+}
+
+variable "memory" {
+  description = "Memory in MiB"
+  type        = number
+  default     = {{ .Deployment.Memory }}
+}
+
+variable "min_count" {
+  description = "Minimum number of instances"
+  type        = number
+  default     = {{ .Deployment.MinCount }}
+}
+
+variable "max_count" {
+  description = "Maximum number of instances"
+  type        = number
+  default     = {{ .Deployment.MaxCount }}
+}
+`
+
+const fargateVariables = imageVariables + scalingVariables + `
+variable "timeout" {
+  description = "Seconds the load balancer keeps idle connections open"
+  type        = number
+  default     = {{ .Deployment.Timeout }}
+}
+
+variable "health_check_path" {
+  description = "Path the load balancer checks"
+  type        = string
+  default     = "/"
+}
+`
+
+const appRunnerVariables = imageVariables + scalingVariables + `
+variable "concurrency" {
+  description = "Concurrent requests per instance before scaling out"
+  type        = number
+  default     = {{ .Deployment.Concurrency }}
+}
+`
+
+const eksVariables = `
+variable "eks_cluster_name" {
+  description = "Name of the EKS cluster the app runs on"
+  type        = string
+}
+
+variable "namespace" {
+  description = "Kubernetes namespace of the app"
+  type        = string
+  default     = "default"
+}
+`
+
+const lambdaOutputs = `output "api_url" {
+  description = "URL of the API Gateway"
+  value       = aws_apigatewayv2_stage.default.invoke_url
+}
+
+output "function_name" {
+  description = "Name of the Lambda function"
+  value       = aws_lambda_function.genkit_app.function_name
+}
+
+output "function_arn" {
+  description = "ARN of the Lambda function"
+  value       = aws_lambda_function.genkit_app.arn
+}
+
+output "cloudwatch_log_group" {
+  description = "CloudWatch log group name"
+  value       = aws_cloudwatch_log_group.genkit_app.name
+}
+`
+
+const ecrOutputs = `output "ecr_repository_url" {
+  description = "ECR repository the app image is pushed to"
+  value       = aws_ecr_repository.app.repository_url
+}
+`
+
+const fargateOutputs = ecrOutputs + `
+output "api_url" {
+  description = "URL of the load balancer"
+  value       = "http://${aws_lb.app.dns_name}"
+}
+
+output "cluster_name" {
+  description = "Name of the ECS cluster"
+  value       = aws_ecs_cluster.app.name
+}
+
+output "service_name" {
+  description = "Name of the ECS service"
+  value       = aws_ecs_service.app.name
+}
+
+output "cloudwatch_log_group" {
+  description = "CloudWatch log group name"
+  value       = aws_cloudwatch_log_group.genkit_app.name
+}
+`
+
+const appRunnerOutputs = ecrOutputs + `
+output "api_url" {
+  description = "URL of the App Runner service"
+  value       = "https://${aws_apprunner_service.app.service_url}"
+}
+
+output "service_arn" {
+  description = "ARN of the App Runner service"
+  value       = aws_apprunner_service.app.arn
+}
+`
+
+const eksOutputs = ecrOutputs + `
+output "app_role_arn" {
+  description = "IAM role the app's service account assumes"
+  value       = aws_iam_role.app_role.arn
+}
+
+output "app_environment" {
+  description = "Environment of the app, applied as the app-env ConfigMap"
+  value = {
+    GENKIT_ENV = "production"
+    AWS_REGION = var.aws_region
+{{- range $key, $value := .Deployment.Environment }}
+    {{ $key }} = {{ $value }}
+{{- end }}
+  }
+}
+`
+
+// Root configurations of envs/<environment>, which apply the module in
+// terraform/ with the environment's state and variables.
+const environmentTerraform = `# {{ .Environment }} environment of {{ .Deployment.ProjectName }}
+terraform {
+  required_version = ">= 1.0"
+  required_providers {
+    aws = {
+      source  = "hashicorp/aws"
+      version = "~> 5.0"
+    }
+{{- if eq .Deployment.VectorStore "bedrock-kb" }}
+    opensearch = {
+      source  = "opensearch-project/opensearch"
+      version = "~> 2.3"
+    }
+{{- end }}
+  }
+
+  # Configured by backend.hcl: terraform init -backend-config=backend.hcl
+  backend "s3" {}
+}
+
+provider "aws" {
+  region = var.aws_region
+
+  default_tags {
+    tags = {
+      Project     = var.project_name
+      Environment = var.environment
+    }
+  }
+}
+{{- if eq .Deployment.VectorStore "bedrock-kb" }}
+
+# Creates the vector index of the knowledge base's OpenSearch collection
+provider "opensearch" {
+  url         = module.app.knowledge_base_collection_endpoint
+  aws_region  = var.aws_region
+  healthcheck = false
+}
+{{- end }}
+
+module "app" {
+  source = "../.."
+{{ range .Variables }}
+  {{ printf "%-*s" $.Width . }} = var.{{ . }}
+{{- end }}
+}
+`
+
+const environmentBackend = `# State of the {{ .Environment }} environment. The bucket and lock table are shared
+# by all environments; create them once per account, the table with a LockID
+# string partition key, and rename the bucket if the name is taken.
+bucket         = "{{ .Deployment.ProjectName }}-terraform-state"
+key            = "{{ .Environment }}/terraform.tfstate"
+region         = "{{ .Deployment.Region }}"
+dynamodb_table = "{{ .Deployment.ProjectName }}-terraform-locks"
+encrypt        = true
+`
+
+const environmentTfvars = `environment  = "{{ .Environment }}"
+aws_region   = "{{ .Deployment.Region }}"
+project_name = "{{ .Deployment.ProjectName }}"
+{{- if eq .Deployment.Target "eks" }}
+namespace    = "{{ .Environment }}"
+{{- end }}
+`
+
+const stackInputOutputs = `# Outputs the app's stack takes as inputs
+{{- range .Deployment.Inputs }}{{ if .Value }}
+
+output "{{ .Output }}" {
+  value = {{ .Value }}
+}
+{{- end }}{{ end }}
+`
+
+const environmentOutputs = `{{- range $i, $output := .Outputs }}{{ if $i }}
+{{ end }}output "{{ $output }}" {
+  value = module.app.{{ $output }}
+}
+{{ end }}`
+
+// serveFlowsHTTP and serveFlowsLambda implement serveFlows, which the
+// migrated main calls, for the HTTP server and the Lambda bootstrap builds.
+const serveFlowsHTTP = `// Code generated by genkit-migrate. Review before deploying.
+
+//go:build !lambda
+
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"os"
+)
+
+// serveFlows serves the flows on addr, or on :$PORT when PORT is set, until
+// ctx is done.
+func serveFlows(ctx context.Context, addr string, handler http.Handler) error {
+	if port := os.Getenv("PORT"); port != "" {
+		addr = ":" + port
+	}
+
+	server := &http.Server{Addr: addr, Handler: handler}
+	go func() {
+		<-ctx.Done()
+		server.Shutdown(context.Background())
+	}()
+	if err := server.ListenAndServe(); !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
+`
+
+const serveFlowsLambda = `// Code generated by genkit-migrate. Review before deploying.
+
+//go:build lambda
+
+// The lambda build is the function's bootstrap. API Gateway HTTP API requests
+// for POST /<flow> reach the flows' genkit.Handler routes in-process:
+//
+//	GOOS=linux GOARCH=amd64 CGO_ENABLED=0 go build -tags lambda,lambda.norpc -o build/bootstrap .
+
+package main
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/aws/aws-lambda-go/lambda"
+	"github.com/awslabs/aws-lambda-go-api-proxy/httpadapter"
+)
+
+// serveFlows hands the flows to the Lambda runtime, which serves each
+// invocation with handler; addr is unused. It returns only if ctx is done.
+func serveFlows(ctx context.Context, addr string, handler http.Handler) error {
+	lambda.StartWithOptions(httpadapter.NewV2(handler).ProxyWithContext, lambda.WithContext(ctx))
+	return ctx.Err()
+}
+`
@@ -0,0 +1,194 @@
+package aws
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+	"text/template"
+
+	"github.com/genkit-migrate/genkit-migrate/pkg/models"
+	"github.com/genkit-migrate/genkit-migrate/pkg/provider"
+)
+
+// bedrockGuardrail replaces Gemini safety settings at their call sites with
+// the guardrail generated from them, which Terraform passes to the app.
+var bedrockGuardrail = &provider.Guardrail{
+	Title:   "the Bedrock guardrail in terraform/guardrail.tf",
+	Field:   "Guardrail",
+	Expr:    `&bedrock.GuardrailConfig{Identifier: os.Getenv("BEDROCK_GUARDRAIL_ID"), Version: os.Getenv("BEDROCK_GUARDRAIL_VERSION")}`,
+	Imports: [][2]string{{"os", "os"}, {"bedrock", bedrockPackage}},
+}
+
+// Bedrock content filters enforcing each Gemini harm category. Dangerous
+// content spans both violence and criminal misconduct.
+var harmCategoryFilters = map[string][]string{
+	"HARM_CATEGORY_HARASSMENT":        {"INSULTS"},
+	"HARM_CATEGORY_HATE_SPEECH":       {"HATE"},
+	"HARM_CATEGORY_SEXUALLY_EXPLICIT": {"SEXUAL"},
+	"HARM_CATEGORY_DANGEROUS_CONTENT": {"VIOLENCE", "MISCONDUCT"},
+}
+
+var unsupportedHarmCategories = map[string]string{
+	"HARM_CATEGORY_CIVIC_INTEGRITY": "Bedrock has no civic integrity content filter; add a denied topic to the guardrail",
+}
+
+// Filter strengths for each Gemini block threshold: the less harmful the
+// content Gemini blocks, the stronger the Bedrock filter.
+var harmThresholdStrengths = map[string]string{
+	"BLOCK_LOW_AND_ABOVE":    "HIGH",
+	"BLOCK_MEDIUM_AND_ABOVE": "MEDIUM",
+	"BLOCK_ONLY_HIGH":        "LOW",
+	"BLOCK_NONE":             "NONE",
+	"OFF":                    "NONE",
+	// Gemini blocks medium and above when no threshold is set.
+	"HARM_BLOCK_THRESHOLD_UNSPECIFIED": "MEDIUM",
+	"":                                 "MEDIUM",
+}
+
+var filterStrengths = []string{"NONE", "LOW", "MEDIUM", "HIGH"}
+
+type guardrailFilter struct {
+	Type     string
+	Strength string
+}
+
+// generation returns the Bedrock generation config, enforcing safety settings
+// through the generated guardrail when the project has any.
+func generation(ctx provider.Context) *provider.Generation {
+	if project := ctx.Project(); project == nil || len(project.SafetySettings) == 0 {
+		return bedrockGeneration
+	}
+	withGuardrail := *bedrockGeneration
+	withGuardrail.Guardrail = bedrockGuardrail
+	return &withGuardrail
+}
+
+// guardrailEnvironment returns the variables the genkit-aws config reads the
+// guardrail from, as Terraform expressions.
+func guardrailEnvironment(ctx provider.Context) map[string]string {
+	if len(ctx.Project().SafetySettings) == 0 {
+		return map[string]string{}
+	}
+	return map[string]string{
+		"BEDROCK_GUARDRAIL_ID":      "aws_bedrock_guardrail.genkit.guardrail_id",
+		"BEDROCK_GUARDRAIL_VERSION": "aws_bedrock_guardrail_version.genkit.version",
+	}
+}
+
+// guardrailFilters merges the safety settings of the project into one content
+// filter per Bedrock filter type, keeping the strongest where call sites
+// disagree. Settings without an equivalent block the migration: dropping them
+// would silently weaken the app's content policy.
+func guardrailFilters(settings []*models.SafetySetting) ([]guardrailFilter, []*models.Change) {
+	strengths := make(map[string]string)
+	changes := make([]*models.Change, 0)
+
+	for _, setting := range settings {
+		change := &models.Change{
+			Type:     "config",
+			File:     setting.Position.Filename,
+			Line:     setting.Position.Line,
+			OldValue: setting.Category + " " + setting.Threshold,
+		}
+		changes = append(changes, change)
+
+		filterTypes, known := harmCategoryFilters[setting.Category]
+		strength, knownThreshold := harmThresholdStrengths[setting.Threshold]
+		switch {
+		case !known:
+			note, exists := unsupportedHarmCategories[setting.Category]
+			if !exists {
+				note = "not a known harm category; add the equivalent content filter to the guardrail"
+			}
+			change.Description = fmt.Sprintf("Safety setting %s has no Bedrock guardrail equivalent: %s", setting.Category, note)
+			change.ManualReview = true
+			change.Blocking = true
+			continue
+		case !knownThreshold:
+			change.Description = fmt.Sprintf("Safety setting %s uses threshold %s, which has no known Bedrock filter strength; set the guardrail filter strength by hand", setting.Category, setting.Threshold)
+			change.ManualReview = true
+			change.Blocking = true
+			continue
+		}
+
+		for _, filterType := range filterTypes {
+			if current, exists := strengths[filterType]; !exists || slices.Index(filterStrengths, strength) > slices.Index(filterStrengths, current) {
+				strengths[filterType] = strength
+			}
+		}
+		change.Description = fmt.Sprintf("Safety setting %s -> Bedrock guardrail %s filter at strength %s", setting.Category, strings.Join(filterTypes, " and "), strength)
+		change.NewValue = strings.Join(filterTypes, ", ") + " " + strength
+	}
+
+	filters := make([]guardrailFilter, 0, len(strengths))
+	for filterType, strength := range strengths {
+		filters = append(filters, guardrailFilter{Type: filterType, Strength: strength})
+	}
+	sort.Slice(filters, func(i, j int) bool {
+		return filters[i].Type < filters[j].Type
+	})
+	return filters, changes
+}
+
+const guardrailTerraform = `# Bedrock guardrail enforcing the Gemini safety settings of the source project.
+# Call sites pass it to Bedrock through BEDROCK_GUARDRAIL_ID and
+# BEDROCK_GUARDRAIL_VERSION.
+resource "aws_bedrock_guardrail" "genkit" {
+  name                      = "${local.name}-guardrail"
+  description               = "Content filters migrated from Gemini safety settings"
+  blocked_input_messaging   = "Sorry, I can't help with that request."
+  blocked_outputs_messaging = "Sorry, I can't provide that response."
+
+{{- if .Filters }}
+
+  content_policy_config {
+{{- range .Filters }}
+    filters_config {
+      type            = "{{ .Type }}"
+      input_strength  = "{{ .Strength }}"
+      output_strength = "{{ .Strength }}"
+    }
+{{- end }}
+  }
+{{- end }}
+}
+
+resource "aws_bedrock_guardrail_version" "genkit" {
+  guardrail_arn = aws_bedrock_guardrail.genkit.guardrail_arn
+  description   = "Migrated safety settings"
+}
+
+output "guardrail_id" {
+  value = aws_bedrock_guardrail.genkit.guardrail_id
+}
+`
+
+func transformSafetySettings(ctx provider.Context) error {
+	migration := ctx.Migration()
+	settings := migration.Project.SafetySettings
+	if len(settings) == 0 {
+		return nil
+	}
+
+	filters, changes := guardrailFilters(settings)
+	migration.Changes = append(migration.Changes, changes...)
+
+	tmpl, err := template.New("guardrail.tf").Parse(guardrailTerraform)
+	if err != nil {
+		return err
+	}
+
+	var content strings.Builder
+	if err := tmpl.Execute(&content, map[string]interface{}{"Filters": filters}); err != nil {
+		return err
+	}
+
+	migration.NewFiles["terraform/guardrail.tf"] = content.String()
+	migration.Changes = append(migration.Changes, &models.Change{
+		Type:        "config",
+		Description: fmt.Sprintf("Generated a Bedrock guardrail with %d content filters from %d safety settings", len(filters), len(settings)),
+		File:        "terraform/guardrail.tf",
+	})
+	return nil
+}
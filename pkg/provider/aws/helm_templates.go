@@ -0,0 +1,215 @@
+package aws
+
+// Chart.yaml and values.yaml are rendered from the deployment; the chart's
+// templates are Helm templates and are written as is.
+const helmChart = `apiVersion: v2
+name: {{ .Deployment.ProjectName }}
+description: GenKit app {{ .Deployment.ProjectName }} on Amazon EKS
+type: application
+version: 0.1.0
+appVersion: "1.0.0"
+`
+
+const helmValues = `# The workflow sets image.repository, image.tag, serviceAccount.roleArn and
+# env from the Terraform outputs of the environment it deploys.
+
+image:
+  repository: ""
+  tag: latest
+  pullPolicy: IfNotPresent
+
+# Pods to run when autoscaling is disabled
+replicaCount: {{ .Deployment.MinCount }}
+
+autoscaling:
+  enabled: true
+  minReplicas: {{ .Deployment.MinCount }}
+  maxReplicas: {{ .Deployment.MaxCount }}
+  targetCPUUtilizationPercentage: 70
+
+serviceAccount:
+  # The app role trusts this service account through IRSA; keep it in sync
+  # with var.project_name in terraform/.
+  name: {{ .Deployment.ProjectName }}
+  roleArn: ""
+
+service:
+  type: ClusterIP
+  port: 80
+
+containerPort: {{ .Deployment.Port }}
+
+resources:
+{{ toYAML 2 .Deployment.Resources }}
+{{- range $name, $probe := .Deployment.Probes }}
+
+{{ $name }}:
+{{ toYAML 2 $probe }}
+{{- end }}
+
+# Environment variables of the app, such as the guardrail ID
+env: {}
+
+# Bedrock models the app invokes, written to bedrock.models of config.yaml.
+# The app role is granted these models only; update terraform/ with them.
+{{- if or .Deployment.Policy.Models .Deployment.Policy.Profiles }}
+models:
+{{- range .Deployment.Policy.Models }}
+  - {{ . }}
+{{- end }}
+{{- range .Deployment.Policy.Profiles }}
+  - {{ .ID }}
+{{- end }}
+{{- else }}
+models: []
+{{- end }}
+
+# config.yaml, mounted at configPath, the working directory of the image
+configPath: /root/config.yaml
+{{- if .Config }}
+config:
+{{ toYAML 2 .Config }}
+{{- else }}
+config: {}
+{{- end }}
+`
+
+var helmTemplates = map[string]string{
+	"_helpers.tpl": `{{- define "app.fullname" -}}
+{{- .Release.Name | trunc 63 | trimSuffix "-" }}
+{{- end }}
+
+{{- define "app.selectorLabels" -}}
+app.kubernetes.io/name: {{ .Chart.Name }}
+app.kubernetes.io/instance: {{ .Release.Name }}
+{{- end }}
+
+{{- define "app.labels" -}}
+helm.sh/chart: {{ printf "%s-%s" .Chart.Name .Chart.Version }}
+{{ include "app.selectorLabels" . }}
+app.kubernetes.io/version: {{ .Chart.AppVersion | quote }}
+app.kubernetes.io/managed-by: {{ .Release.Service }}
+{{- end }}
+`,
+	"serviceaccount.yaml": `apiVersion: v1
+kind: ServiceAccount
+metadata:
+  name: {{ .Values.serviceAccount.name }}
+  labels:
+    {{- include "app.labels" . | nindent 4 }}
+  annotations:
+    eks.amazonaws.com/role-arn: {{ required "serviceAccount.roleArn is the app_role_arn Terraform output" .Values.serviceAccount.roleArn | quote }}
+`,
+	"configmap.yaml": `{{- $config := deepCopy (.Values.config | default dict) }}
+{{- $bedrock := deepCopy (get $config "bedrock" | default dict) }}
+{{- $_ := set $bedrock "models" .Values.models }}
+{{- $_ = set $config "bedrock" $bedrock -}}
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: {{ include "app.fullname" . }}
+  labels:
+    {{- include "app.labels" . | nindent 4 }}
+data:
+  config.yaml: |
+    {{- toYaml $config | nindent 4 }}
+`,
+	"deployment.yaml": `apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: {{ include "app.fullname" . }}
+  labels:
+    {{- include "app.labels" . | nindent 4 }}
+spec:
+  {{- if not .Values.autoscaling.enabled }}
+  replicas: {{ .Values.replicaCount }}
+  {{- end }}
+  selector:
+    matchLabels:
+      {{- include "app.selectorLabels" . | nindent 6 }}
+  template:
+    metadata:
+      annotations:
+        checksum/config: {{ include (print $.Template.BasePath "/configmap.yaml") . | sha256sum }}
+      labels:
+        {{- include "app.selectorLabels" . | nindent 8 }}
+    spec:
+      serviceAccountName: {{ .Values.serviceAccount.name }}
+      containers:
+        - name: app
+          image: "{{ required "image.repository is the ecr_repository_url Terraform output" .Values.image.repository }}:{{ .Values.image.tag }}"
+          imagePullPolicy: {{ .Values.image.pullPolicy }}
+          ports:
+            - name: http
+              containerPort: {{ .Values.containerPort }}
+          env:
+            - name: PORT
+              value: {{ .Values.containerPort | quote }}
+            {{- range $name, $value := .Values.env }}
+            - name: {{ $name }}
+              value: {{ $value | quote }}
+            {{- end }}
+          volumeMounts:
+            - name: config
+              mountPath: {{ .Values.configPath }}
+              subPath: config.yaml
+          {{- with .Values.resources }}
+          resources:
+            {{- toYaml . | nindent 12 }}
+          {{- end }}
+          {{- with .Values.readinessProbe }}
+          readinessProbe:
+            {{- toYaml . | nindent 12 }}
+          {{- end }}
+          {{- with .Values.livenessProbe }}
+          livenessProbe:
+            {{- toYaml . | nindent 12 }}
+          {{- end }}
+          {{- with .Values.startupProbe }}
+          startupProbe:
+            {{- toYaml . | nindent 12 }}
+          {{- end }}
+      volumes:
+        - name: config
+          configMap:
+            name: {{ include "app.fullname" . }}
+`,
+	"service.yaml": `apiVersion: v1
+kind: Service
+metadata:
+  name: {{ include "app.fullname" . }}
+  labels:
+    {{- include "app.labels" . | nindent 4 }}
+spec:
+  type: {{ .Values.service.type }}
+  selector:
+    {{- include "app.selectorLabels" . | nindent 4 }}
+  ports:
+    - name: http
+      port: {{ .Values.service.port }}
+      targetPort: http
+`,
+	"hpa.yaml": `{{- if .Values.autoscaling.enabled }}
+apiVersion: autoscaling/v2
+kind: HorizontalPodAutoscaler
+metadata:
+  name: {{ include "app.fullname" . }}
+  labels:
+    {{- include "app.labels" . | nindent 4 }}
+spec:
+  scaleTargetRef:
+    apiVersion: apps/v1
+    kind: Deployment
+    name: {{ include "app.fullname" . }}
+  minReplicas: {{ .Values.autoscaling.minReplicas }}
+  maxReplicas: {{ .Values.autoscaling.maxReplicas }}
+  metrics:
+    - type: Resource
+      resource:
+        name: cpu
+        target:
+          type: Utilization
+          averageUtilization: {{ .Values.autoscaling.targetCPUUtilizationPercentage }}
+{{- end }}
+`,
+}
@@ -0,0 +1,187 @@
+package aws
+
+import (
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/genkit-migrate/genkit-migrate/pkg/models"
+	"github.com/genkit-migrate/genkit-migrate/pkg/provider"
+)
+
+// Cross-region inference profile IDs carry the geography they route within,
+// as in us.anthropic.claude-3-5-sonnet-20241022-v2:0.
+var inferenceProfilePrefixes = []string{"us.", "eu.", "apac.", "us-gov.", "global."}
+
+// bedrockPolicy lists the Bedrock resources the migrated app invokes, so its
+// IAM policy can grant them instead of Resource = "*".
+type bedrockPolicy struct {
+	// Models are the foundation-model IDs.
+	Models []string
+	// Profiles are the inference profiles, looked up with the
+	// aws_bedrock_inference_profile data source, which lists the
+	// foundation models of every region the profile routes to.
+	Profiles  []inferenceProfile
+	Streaming bool
+}
+
+type inferenceProfile struct {
+	Name string // data source name
+	ID   string
+}
+
+// onDemandRegions lists the regions where models that are otherwise served
+// only through cross-region inference profiles can be invoked by their
+// foundation-model ID. Models missing here are served on demand everywhere.
+var onDemandRegions = map[string][]string{
+	"anthropic.claude-3-5-sonnet-20241022-v2:0": {"us-west-2"},
+	"anthropic.claude-3-5-haiku-20241022-v1:0":  {"us-west-2"},
+	"amazon.nova-lite-v1:0":                     {"us-east-1"},
+	"amazon.nova-micro-v1:0":                    {"us-east-1"},
+	"amazon.nova-pro-v1:0":                      {"us-east-1"},
+}
+
+// profileRegions lists the regions an inference profile of each geography
+// routes requests to.
+var profileRegions = map[string][]string{
+	"us":     {"us-east-1", "us-east-2", "us-west-2"},
+	"eu":     {"eu-central-1", "eu-north-1", "eu-west-1", "eu-west-3"},
+	"apac":   {"ap-northeast-1", "ap-northeast-2", "ap-south-1", "ap-southeast-1", "ap-southeast-2"},
+	"us-gov": {"us-gov-east-1", "us-gov-west-1"},
+	"global": {"*"},
+}
+
+// geography returns the inference profile geography of a region, or "" when
+// Bedrock has no profiles there.
+func geography(region string) string {
+	for _, prefix := range []string{"us-gov-", "us-", "eu-", "ap-"} {
+		if strings.HasPrefix(region, prefix) {
+			if prefix == "ap-" {
+				return "apac"
+			}
+			return strings.TrimSuffix(prefix, "-")
+		}
+	}
+	return ""
+}
+
+// foundationModel returns the foundation-model ID of a model or inference
+// profile ID.
+func foundationModel(id string) string {
+	for _, prefix := range inferenceProfilePrefixes {
+		if model, found := strings.CutPrefix(id, prefix); found {
+			return model
+		}
+	}
+	return id
+}
+
+// withInferenceProfiles adds the inference profile IDs of every geography
+// to model ID prefixes.
+func withInferenceProfiles(prefixes ...string) []string {
+	ids := slices.Clone(prefixes)
+	for _, geography := range inferenceProfilePrefixes {
+		for _, prefix := range prefixes {
+			ids = append(ids, geography+prefix)
+		}
+	}
+	return ids
+}
+
+// invocationID returns the ID the app invokes a foundation model with from
+// region: the model ID where it is served on demand, or else the inference
+// profile of the region's geography.
+func invocationID(model string, region string) string {
+	regions, exists := onDemandRegions[model]
+	if !exists || slices.Contains(regions, region) {
+		return model
+	}
+	if geography := geography(region); geography != "" {
+		return geography + "." + model
+	}
+	return model
+}
+
+var nonIdentifier = regexp.MustCompile(`[^A-Za-z0-9_]+`)
+
+// newBedrockPolicy collects the Bedrock model IDs the project's models,
+// direct SDK calls and embedders map to. InvokeModel also authorizes Converse, and the streaming
+// action, which authorizes ConverseStream, is only granted when a flow
+// streams.
+func newBedrockPolicy(ctx provider.Context) *bedrockPolicy {
+	project := ctx.Project()
+	catalog := ctx.Catalog()
+
+	target := func(name string, mapped string, exists bool) string {
+		switch {
+		case exists:
+			return mapped
+		case catalog.Prefix != "" && strings.HasPrefix(name, catalog.Prefix):
+			return strings.TrimPrefix(name, catalog.Prefix)
+		}
+		return ""
+	}
+
+	ids := make(map[string]bool)
+	for _, model := range project.Models {
+		mapped, exists := catalog.Models[model.Name]
+		ids[target(model.Name, mapped, exists)] = true
+	}
+	for _, output := range project.StructuredOutputs {
+		mapped, exists := catalog.Models[output.Model]
+		ids[target(output.Model, mapped, exists)] = true
+	}
+	for _, call := range project.DirectCalls {
+		mapped, exists := catalog.Models[call.Model]
+		ids[target(call.Model, mapped, exists)] = true
+	}
+	for _, embedder := range project.Embedders {
+		mapped, exists := catalog.Embedders[embedder.Name]
+		ids[target(embedder.Name, mapped.Target, exists)] = true
+	}
+	delete(ids, "")
+
+	sorted := make([]string, 0, len(ids))
+	for id := range ids {
+		sorted = append(sorted, id)
+	}
+	sort.Strings(sorted)
+
+	policy := &bedrockPolicy{
+		Models:   make([]string, 0),
+		Profiles: make([]inferenceProfile, 0),
+		Streaming: slices.ContainsFunc(project.Flows, func(flow *models.Flow) bool {
+			return flow.Streaming
+		}),
+	}
+	for _, id := range sorted {
+		if foundationModel(id) != id {
+			policy.Profiles = append(policy.Profiles, inferenceProfile{
+				Name: strings.Trim(nonIdentifier.ReplaceAllString(id, "_"), "_"),
+				ID:   id,
+			})
+			continue
+		}
+		policy.Models = append(policy.Models, id)
+	}
+	return policy
+}
+
+// Model returns the ID of the foundation model an inference profile
+// routes to.
+func (p inferenceProfile) Model() string {
+	return foundationModel(p.ID)
+}
+
+// Regions returns the regions the profile routes to, whose foundation models
+// the app must be granted along with the profile.
+func (p inferenceProfile) Regions() []string {
+	geography, _, _ := strings.Cut(p.ID, ".")
+	return profileRegions[geography]
+}
+
+// Empty reports whether no models were detected, leaving nothing to grant.
+func (p *bedrockPolicy) Empty() bool {
+	return len(p.Models) == 0 && len(p.Profiles) == 0
+}
@@ -0,0 +1,154 @@
+package aws
+
+import (
+	"fmt"
+	"go/ast"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/genkit-migrate/genkit-migrate/pkg/models"
+	"github.com/genkit-migrate/genkit-migrate/pkg/rewrite"
+)
+
+const genkitServerPackage = "github.com/firebase/genkit/go/plugins/server"
+
+// serveFlowsFunc replaces the app's listen call. Its lambda build hands the
+// flow handler to the Lambda runtime, which invokes it in-process.
+const serveFlowsFunc = "serveFlows"
+
+// lambdaHandlerRule routes the app's flow server through serveFlows, so the
+// same main package serves its flows over HTTP and from Lambda.
+type lambdaHandlerRule struct{}
+
+func (r *lambdaHandlerRule) Name() string {
+	return "lambda-handler"
+}
+
+func (r *lambdaHandlerRule) Apply(file *rewrite.File) error {
+	if file.AST.Name.Name != "main" {
+		return nil
+	}
+
+	usesContext := false
+	rewrite.Rewrite(file.AST, func(expr ast.Expr) ast.Expr {
+		call, ok := expr.(*ast.CallExpr)
+		if !ok {
+			return nil
+		}
+		pkg, name, ok := file.SelectorPackage(call.Fun)
+		if !ok {
+			return nil
+		}
+
+		var args []ast.Expr
+		switch {
+		case pkg == genkitServerPackage && name == "Start" && len(call.Args) == 3:
+			args = call.Args
+		case pkg == "net/http" && name == "ListenAndServe" && len(call.Args) == 2:
+			handler := call.Args[1]
+			if ident, ok := handler.(*ast.Ident); ok && ident.Name == "nil" {
+				httpName, _ := file.ImportName("net/http")
+				handler = selector(httpName, "DefaultServeMux")
+			}
+			contextName, imported := file.ImportName("context")
+			if !imported {
+				contextName = "context"
+				usesContext = true
+			}
+			background := &ast.CallExpr{Fun: selector(contextName, "Background")}
+			args = []ast.Expr{background, call.Args[0], handler}
+		default:
+			return nil
+		}
+
+		file.Report(call, &models.Change{
+			Type:        "config",
+			Description: fmt.Sprintf("Serve the flows with %s, which reads PORT, and in the lambda build hands them to the Lambda runtime", serveFlowsFunc),
+			OldValue:    rewrite.DefaultImportName(pkg) + "." + name,
+			NewValue:    serveFlowsFunc,
+		})
+		fun := ast.NewIdent(serveFlowsFunc)
+		fun.NamePos = call.Fun.Pos()
+		return &ast.CallExpr{Fun: fun, Lparen: call.Lparen, Args: args, Rparen: call.Rparen}
+	})
+
+	if usesContext {
+		file.AddImport("context", "context")
+	}
+	file.DeleteUnusedImport(genkitServerPackage)
+	file.DeleteUnusedImport("net/http")
+	return nil
+}
+
+// lambdaHandlerFiles returns the serveFlows implementations, generated in
+// the package of the migrated main files that call it, and the changes
+// describing them.
+func lambdaHandlerFiles(migration *models.Migration) (map[string]string, []*models.Change) {
+	dirs := make([]string, 0)
+	for filePath, sourceFile := range migration.Project.Files {
+		content, migrated := migration.NewFiles[filePath]
+		if migrated && sourceFile.PackageName == "main" && strings.Contains(content, serveFlowsFunc+"(") {
+			dirs = append(dirs, path.Dir(filePath))
+		}
+	}
+	sort.Strings(dirs)
+
+	changes := make([]*models.Change, 0)
+	dir := "."
+	if len(dirs) > 0 {
+		dir = dirs[0]
+	} else {
+		changes = append(changes, &models.Change{
+			Type:         "config",
+			Description:  fmt.Sprintf("No flow server was found; serve the flows' genkit.Handler routes with %s(ctx, addr, mux) in main", serveFlowsFunc),
+			File:         "serve_flows.go",
+			ManualReview: true,
+		})
+	}
+	if dir != "." {
+		changes = append(changes, &models.Change{
+			Type:         "config",
+			Description:  fmt.Sprintf("Build the Lambda bootstrap from ./%s rather than the module root", dir),
+			File:         path.Join(dir, "serve_flows_lambda.go"),
+			ManualReview: true,
+		})
+	}
+
+	files := map[string]string{
+		path.Join(dir, "serve_flows.go"):        serveFlowsHTTP,
+		path.Join(dir, "serve_flows_lambda.go"): serveFlowsLambda,
+	}
+	changes = append(changes, &models.Change{
+		Type:        "config",
+		Description: "Generated the Lambda handler, which API Gateway invokes in-process for POST /<flow>; build it with -tags lambda",
+		File:        path.Join(dir, "serve_flows_lambda.go"),
+	})
+	return files, changes
+}
+
+// lambdaGuide explains the Lambda entry point. It is the app's own main
+// package built with the lambda tag rather than a separate cmd/lambda, which
+// could not reach the flows main registers.
+func lambdaGuide(migration *models.Migration) string {
+	handlers := make([]string, 0)
+	for filePath := range migration.NewFiles {
+		if path.Base(filePath) == "serve_flows_lambda.go" {
+			handlers = append(handlers, filePath)
+		}
+	}
+	if len(handlers) == 0 {
+		return ""
+	}
+	sort.Strings(handlers)
+
+	return `
+### Lambda Entry Point
+
+The function runs the app's own ` + "`main`" + `, built with ` + "`-tags lambda`" + ` into ` + "`bootstrap`" + `. Flows are
+registered on the GenKit instance ` + "`main`" + ` creates, so a separate ` + "`cmd/lambda`" + ` package could not
+reach them; instead ` + "`" + handlers[0] + "`" + ` hands the mux serving the flows' ` + "`genkit.Handler`" + `
+routes to the Lambda runtime through the API Gateway HTTP API (v2) adapter, and POST /<flow>
+reaches each flow in-process. Builds without the tag serve the same mux over HTTP on ` + "`$PORT`" + `.
+`
+}
@@ -0,0 +1,514 @@
+package aws
+
+const pulumiYAML = `name: {{ .Deployment.ProjectName }}
+runtime: go
+description: AWS infrastructure of the {{ .Deployment.ProjectName }} GenKit app
+`
+
+// pulumiStackConfig is the Pulumi.<environment>.yaml of every stack.
+const pulumiStackConfig = `config:
+  aws:region: {{ .Deployment.Region }}
+  {{ .Deployment.ProjectName }}:projectName: {{ .Deployment.ProjectName }}
+`
+
+const pulumiGoMod = `module {{ .Deployment.ProjectName }}-pulumi
+
+go 1.23
+
+require (
+	github.com/pulumi/pulumi-aws/sdk/v6 v6.66.0
+	github.com/pulumi/pulumi/sdk/v3 v3.144.1
+)
+`
+
+const pulumiProgram = `// Code generated by genkit-migrate. Review before deploying.
+
+// Command pulumi provisions the AWS infrastructure of {{ .Deployment.ProjectName }}. Every
+// stack is an environment with its own resources:
+//
+//	pulumi up --stack dev
+//
+// The project name and region are read from the stack's config.
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/pulumi/pulumi-aws/sdk/v6/go/aws"
+{{- if eq .Deployment.Target "lambda" }}
+	"github.com/pulumi/pulumi-aws/sdk/v6/go/aws/apigatewayv2"
+{{- else }}
+	"github.com/pulumi/pulumi-aws/sdk/v6/go/aws/appautoscaling"
+{{- end }}
+	"github.com/pulumi/pulumi-aws/sdk/v6/go/aws/cloudwatch"
+{{- if ne .Deployment.Target "lambda" }}
+	"github.com/pulumi/pulumi-aws/sdk/v6/go/aws/ec2"
+	"github.com/pulumi/pulumi-aws/sdk/v6/go/aws/ecr"
+	"github.com/pulumi/pulumi-aws/sdk/v6/go/aws/ecs"
+{{- end }}
+	"github.com/pulumi/pulumi-aws/sdk/v6/go/aws/iam"
+{{- if eq .Deployment.Target "lambda" }}
+	"github.com/pulumi/pulumi-aws/sdk/v6/go/aws/lambda"
+{{- else }}
+	"github.com/pulumi/pulumi-aws/sdk/v6/go/aws/lb"
+{{- end }}
+	"github.com/pulumi/pulumi/sdk/v3/go/pulumi"
+	"github.com/pulumi/pulumi/sdk/v3/go/pulumi/config"
+)
+
+// Bedrock foundation models the app invokes.
+var foundationModels = []string{
+{{- range .Deployment.Policy.Models }}
+	"{{ . }}",
+{{- end }}
+}
+
+// Cross-region inference profiles the app invokes, and the foundation model
+// each routes to in the regions of its geography.
+var inferenceProfiles = []struct {
+	ID, Model string
+	Regions   []string
+}{
+{{- range .Deployment.Policy.Profiles }}
+	{"{{ .ID }}", "{{ .Model }}", []string{ {{- range $i, $region := .Regions }}{{ if $i }}, {{ end }}"{{ $region }}"{{ end }}}},
+{{- end }}
+}
+{{- if eq .Deployment.Target "lambda" }}
+
+// Flows API Gateway routes to the function as POST /<flow>.
+var flows = []string{
+{{- range .Deployment.Flows }}
+	"{{ . }}",
+{{- end }}
+}
+{{- end }}
+
+// Environment variables pointing at the guardrail and vector store, outputs
+// of terraform/envs/<environment> set with pulumi config set NAME value.
+var configEnvironment = []string{
+{{- range .Deployment.Inputs }}
+	"{{ .Name }}", // output {{ .Output }}
+{{- end }}
+}
+
+func main() {
+	pulumi.Run(func(ctx *pulumi.Context) error {
+		cfg := config.New(ctx, "")
+		projectName := cfg.Get("projectName")
+		if projectName == "" {
+			projectName = ctx.Project()
+		}
+		region := config.Require(ctx, "aws:region")
+		name := projectName + "-" + ctx.Stack()
+
+		partition, err := aws.GetPartition(ctx, nil)
+		if err != nil {
+			return err
+		}
+		identity, err := aws.GetCallerIdentity(ctx, nil)
+		if err != nil {
+			return err
+		}
+
+		environment := map[string]string{
+			"GENKIT_ENV": "production",
+		}
+		for _, key := range configEnvironment {
+			if value := cfg.Get(key); value != "" {
+				environment[key] = value
+			}
+		}
+
+		// IAM role the app runs as
+		assumeRolePolicy, err := json.Marshal(map[string]interface{}{
+			"Version": "2012-10-17",
+			"Statement": []map[string]interface{}{ {
+				"Action":    "sts:AssumeRole",
+				"Effect":    "Allow",
+				"Principal": map[string]interface{}{"Service": "{{ .Principal }}"},
+			}},
+		})
+		if err != nil {
+			return err
+		}
+		role, err := iam.NewRole(ctx, "app-role", &iam.RoleArgs{
+			Name:             pulumi.String(name + "-app-role"),
+			AssumeRolePolicy: pulumi.String(string(assumeRolePolicy)),
+		})
+		if err != nil {
+			return err
+		}
+
+		policy, err := json.Marshal(map[string]interface{}{
+			"Version":   "2012-10-17",
+			"Statement": appStatements(partition.Partition, region, identity.AccountId, environment),
+		})
+		if err != nil {
+			return err
+		}
+		_, err = iam.NewRolePolicy(ctx, "bedrock-policy", &iam.RolePolicyArgs{
+			Name:   pulumi.String("genkit-bedrock-policy"),
+			Role:   role.Name,
+			Policy: pulumi.String(string(policy)),
+		})
+		if err != nil {
+			return err
+		}
+
+		logGroup, err := cloudwatch.NewLogGroup(ctx, "genkit-app", &cloudwatch.LogGroupArgs{
+			Name:            pulumi.String("{{ if eq .Deployment.Target "lambda" }}/aws/lambda/{{ else }}/ecs/{{ end }}" + name),
+			RetentionInDays: pulumi.Int(14),
+		})
+		if err != nil {
+			return err
+		}
+{{- if eq .Deployment.Target "lambda" }}
+
+		// Lambda function for GenKit app, built with -tags lambda into
+		// build/bootstrap, which serves API Gateway requests in-process
+		_, err = iam.NewRolePolicyAttachment(ctx, "lambda-logs", &iam.RolePolicyAttachmentArgs{
+			Role:      role.Name,
+			PolicyArn: pulumi.String("arn:aws:iam::aws:policy/service-role/AWSLambdaBasicExecutionRole"),
+		})
+		if err != nil {
+			return err
+		}
+		function, err := lambda.NewFunction(ctx, "genkit-app", &lambda.FunctionArgs{
+			Name:                         pulumi.String(name),
+			Role:                         role.Arn,
+			Code:                         pulumi.NewFileArchive("../build"),
+			Handler:                      pulumi.String("bootstrap"),
+			Runtime:                      pulumi.String("provided.al2023"),
+			MemorySize:                   pulumi.Int({{ .Deployment.Memory }}),
+			Timeout:                      pulumi.Int({{ .Deployment.Timeout }}),
+{{- if ge .Deployment.ReservedConcurrency 0 }}
+			ReservedConcurrentExecutions: pulumi.Int({{ .Deployment.ReservedConcurrency }}),
+{{- end }}
+			Environment: &lambda.FunctionEnvironmentArgs{
+				Variables: pulumi.ToStringMap(environment),
+			},
+		}, pulumi.DependsOn([]pulumi.Resource{logGroup}))
+		if err != nil {
+			return err
+		}
+
+		// HTTP API routing POST /<flow> to the function
+		api, err := apigatewayv2.NewApi(ctx, "genkit-api", &apigatewayv2.ApiArgs{
+			Name:         pulumi.String(name + "-api"),
+			ProtocolType: pulumi.String("HTTP"),
+		})
+		if err != nil {
+			return err
+		}
+		integration, err := apigatewayv2.NewIntegration(ctx, "lambda", &apigatewayv2.IntegrationArgs{
+			ApiId:                api.ID(),
+			IntegrationType:      pulumi.String("AWS_PROXY"),
+			IntegrationUri:       function.InvokeArn,
+			PayloadFormatVersion: pulumi.String("2.0"),
+		})
+		if err != nil {
+			return err
+		}
+		for _, flow := range flows {
+			_, err := apigatewayv2.NewRoute(ctx, "flow-"+flow, &apigatewayv2.RouteArgs{
+				ApiId:    api.ID(),
+				RouteKey: pulumi.String("POST /" + flow),
+				Target:   pulumi.Sprintf("integrations/%s", integration.ID()),
+			})
+			if err != nil {
+				return err
+			}
+		}
+		stage, err := apigatewayv2.NewStage(ctx, "default", &apigatewayv2.StageArgs{
+			ApiId:      api.ID(),
+			Name:       pulumi.String("$default"),
+			AutoDeploy: pulumi.Bool(true),
+		})
+		if err != nil {
+			return err
+		}
+		_, err = lambda.NewPermission(ctx, "api-gateway", &lambda.PermissionArgs{
+			StatementId: pulumi.String("AllowExecutionFromAPIGateway"),
+			Action:      pulumi.String("lambda:InvokeFunction"),
+			Function:    function.Name,
+			Principal:   pulumi.String("apigateway.amazonaws.com"),
+			SourceArn:   pulumi.Sprintf("%s/*/*", api.ExecutionArn),
+		})
+		if err != nil {
+			return err
+		}
+
+		ctx.Export("apiUrl", stage.InvokeUrl)
+		ctx.Export("functionName", function.Name)
+		ctx.Export("functionArn", function.Arn)
+{{- else }}
+
+		imageTag := cfg.Get("imageTag")
+		if imageTag == "" {
+			imageTag = "latest"
+		}
+		repository, err := ecr.NewRepository(ctx, "app", &ecr.RepositoryArgs{
+			Name: pulumi.String(name),
+			ImageScanningConfiguration: &ecr.RepositoryImageScanningConfigurationArgs{
+				ScanOnPush: pulumi.Bool(true),
+			},
+		})
+		if err != nil {
+			return err
+		}
+
+		vpc, err := ec2.LookupVpc(ctx, &ec2.LookupVpcArgs{Default: pulumi.BoolRef(true)})
+		if err != nil {
+			return err
+		}
+		subnets, err := ec2.GetSubnets(ctx, &ec2.GetSubnetsArgs{
+			Filters: []ec2.GetSubnetsFilter{ {Name: "vpc-id", Values: []string{vpc.Id}}},
+		})
+		if err != nil {
+			return err
+		}
+
+		cluster, err := ecs.NewCluster(ctx, "app", &ecs.ClusterArgs{
+			Name: pulumi.String(name),
+		})
+		if err != nil {
+			return err
+		}
+
+		// Role ECS pulls the image and writes logs with
+		executionRolePolicy, err := json.Marshal(map[string]interface{}{
+			"Version": "2012-10-17",
+			"Statement": []map[string]interface{}{ {
+				"Action":    "sts:AssumeRole",
+				"Effect":    "Allow",
+				"Principal": map[string]interface{}{"Service": "ecs-tasks.amazonaws.com"},
+			}},
+		})
+		if err != nil {
+			return err
+		}
+		executionRole, err := iam.NewRole(ctx, "execution-role", &iam.RoleArgs{
+			Name:             pulumi.String(name + "-execution-role"),
+			AssumeRolePolicy: pulumi.String(string(executionRolePolicy)),
+		})
+		if err != nil {
+			return err
+		}
+		_, err = iam.NewRolePolicyAttachment(ctx, "execution", &iam.RolePolicyAttachmentArgs{
+			Role:      executionRole.Name,
+			PolicyArn: pulumi.String("arn:aws:iam::aws:policy/service-role/AmazonECSTaskExecutionRolePolicy"),
+		})
+		if err != nil {
+			return err
+		}
+
+		variables := []map[string]string{
+			{"name": "AWS_REGION", "value": region},
+			{"name": "PORT", "value": "{{ .Deployment.Port }}"},
+		}
+		for key, value := range environment {
+			variables = append(variables, map[string]string{"name": key, "value": value})
+		}
+		containers := pulumi.All(repository.RepositoryUrl, logGroup.Name).ApplyT(func(args []interface{}) (string, error) {
+			definitions, err := json.Marshal([]map[string]interface{}{ {
+				"name":         "app",
+				"image":        args[0].(string) + ":" + imageTag,
+				"essential":    true,
+				"portMappings": []map[string]int{ {"containerPort": {{ .Deployment.Port }}}},
+				"environment":  variables,
+				"logConfiguration": map[string]interface{}{
+					"logDriver": "awslogs",
+					"options": map[string]string{
+						"awslogs-group":         args[1].(string),
+						"awslogs-region":        region,
+						"awslogs-stream-prefix": "app",
+					},
+				},
+			}})
+			return string(definitions), err
+		}).(pulumi.StringOutput)
+		taskDefinition, err := ecs.NewTaskDefinition(ctx, "app", &ecs.TaskDefinitionArgs{
+			Family:                  pulumi.String(name),
+			RequiresCompatibilities: pulumi.StringArray{pulumi.String("FARGATE")},
+			NetworkMode:             pulumi.String("awsvpc"),
+			Cpu:                     pulumi.String("{{ .Deployment.CPU }}"),
+			Memory:                  pulumi.String("{{ .Deployment.Memory }}"),
+			ExecutionRoleArn:        executionRole.Arn,
+			TaskRoleArn:             role.Arn,
+			ContainerDefinitions:    containers,
+		})
+		if err != nil {
+			return err
+		}
+
+		anywhere := pulumi.StringArray{pulumi.String("0.0.0.0/0")}
+		albSecurityGroup, err := ec2.NewSecurityGroup(ctx, "alb", &ec2.SecurityGroupArgs{
+			Name:  pulumi.String(name + "-alb"),
+			VpcId: pulumi.String(vpc.Id),
+			Ingress: ec2.SecurityGroupIngressArray{&ec2.SecurityGroupIngressArgs{
+				Protocol: pulumi.String("tcp"), FromPort: pulumi.Int(80), ToPort: pulumi.Int(80), CidrBlocks: anywhere,
+			}},
+			Egress: ec2.SecurityGroupEgressArray{&ec2.SecurityGroupEgressArgs{
+				Protocol: pulumi.String("-1"), FromPort: pulumi.Int(0), ToPort: pulumi.Int(0), CidrBlocks: anywhere,
+			}},
+		})
+		if err != nil {
+			return err
+		}
+		appSecurityGroup, err := ec2.NewSecurityGroup(ctx, "app", &ec2.SecurityGroupArgs{
+			Name:  pulumi.String(name + "-app"),
+			VpcId: pulumi.String(vpc.Id),
+			Ingress: ec2.SecurityGroupIngressArray{&ec2.SecurityGroupIngressArgs{
+				Protocol:       pulumi.String("tcp"),
+				FromPort:       pulumi.Int({{ .Deployment.Port }}),
+				ToPort:         pulumi.Int({{ .Deployment.Port }}),
+				SecurityGroups: pulumi.StringArray{albSecurityGroup.ID().ToStringOutput()},
+			}},
+			Egress: ec2.SecurityGroupEgressArray{&ec2.SecurityGroupEgressArgs{
+				Protocol: pulumi.String("-1"), FromPort: pulumi.Int(0), ToPort: pulumi.Int(0), CidrBlocks: anywhere,
+			}},
+		})
+		if err != nil {
+			return err
+		}
+
+		// The load balancer keeps connections open as long as the source
+		// allowed requests to run, so long and streaming flows are not cut
+		// off.
+		loadBalancer, err := lb.NewLoadBalancer(ctx, "app", &lb.LoadBalancerArgs{
+			Name:             pulumi.String(name),
+			LoadBalancerType: pulumi.String("application"),
+			Subnets:          pulumi.ToStringArray(subnets.Ids),
+			SecurityGroups:   pulumi.StringArray{albSecurityGroup.ID().ToStringOutput()},
+			IdleTimeout:      pulumi.Int({{ .Deployment.Timeout }}),
+		})
+		if err != nil {
+			return err
+		}
+		targetGroup, err := lb.NewTargetGroup(ctx, "app", &lb.TargetGroupArgs{
+			Name:       pulumi.String(name),
+			Port:       pulumi.Int({{ .Deployment.Port }}),
+			Protocol:   pulumi.String("HTTP"),
+			TargetType: pulumi.String("ip"),
+			VpcId:      pulumi.String(vpc.Id),
+			// {{ httpHealthCheck }}
+			HealthCheck: &lb.TargetGroupHealthCheckArgs{
+				Path:    pulumi.String("/"),
+				Matcher: pulumi.String("200-499"),
+			},
+		})
+		if err != nil {
+			return err
+		}
+		// Add an HTTPS listener with an ACM certificate before serving
+		// production traffic.
+		listener, err := lb.NewListener(ctx, "http", &lb.ListenerArgs{
+			LoadBalancerArn: loadBalancer.Arn,
+			Port:            pulumi.Int(80),
+			Protocol:        pulumi.String("HTTP"),
+			DefaultActions: lb.ListenerDefaultActionArray{&lb.ListenerDefaultActionArgs{
+				Type:           pulumi.String("forward"),
+				TargetGroupArn: targetGroup.Arn,
+			}},
+		})
+		if err != nil {
+			return err
+		}
+
+		service, err := ecs.NewService(ctx, "app", &ecs.ServiceArgs{
+			Name:           pulumi.String(name),
+			Cluster:        cluster.Arn,
+			TaskDefinition: taskDefinition.Arn,
+			DesiredCount:   pulumi.Int({{ .Deployment.MinCount }}),
+			LaunchType:     pulumi.String("FARGATE"),
+			NetworkConfiguration: &ecs.ServiceNetworkConfigurationArgs{
+				Subnets:        pulumi.ToStringArray(subnets.Ids),
+				SecurityGroups: pulumi.StringArray{appSecurityGroup.ID().ToStringOutput()},
+				AssignPublicIp: pulumi.Bool(true),
+			},
+			LoadBalancers: ecs.ServiceLoadBalancerArray{&ecs.ServiceLoadBalancerArgs{
+				TargetGroupArn: targetGroup.Arn,
+				ContainerName:  pulumi.String("app"),
+				ContainerPort:  pulumi.Int({{ .Deployment.Port }}),
+			}},
+		}, pulumi.DependsOn([]pulumi.Resource{listener}))
+		if err != nil {
+			return err
+		}
+
+		scaling, err := appautoscaling.NewTarget(ctx, "app", &appautoscaling.TargetArgs{
+			MinCapacity:       pulumi.Int({{ .Deployment.MinCount }}),
+			MaxCapacity:       pulumi.Int({{ .Deployment.MaxCount }}),
+			ResourceId:        pulumi.Sprintf("service/%s/%s", cluster.Name, service.Name),
+			ScalableDimension: pulumi.String("ecs:service:DesiredCount"),
+			ServiceNamespace:  pulumi.String("ecs"),
+		})
+		if err != nil {
+			return err
+		}
+		_, err = appautoscaling.NewPolicy(ctx, "cpu", &appautoscaling.PolicyArgs{
+			Name:              pulumi.String(name + "-cpu"),
+			PolicyType:        pulumi.String("TargetTrackingScaling"),
+			ResourceId:        scaling.ResourceId,
+			ScalableDimension: scaling.ScalableDimension,
+			ServiceNamespace:  scaling.ServiceNamespace,
+			TargetTrackingScalingPolicyConfiguration: &appautoscaling.PolicyTargetTrackingScalingPolicyConfigurationArgs{
+				TargetValue: pulumi.Float64(70),
+				PredefinedMetricSpecification: &appautoscaling.PolicyTargetTrackingScalingPolicyConfigurationPredefinedMetricSpecificationArgs{
+					PredefinedMetricType: pulumi.String("ECSServiceAverageCPUUtilization"),
+				},
+			},
+		})
+		if err != nil {
+			return err
+		}
+
+		ctx.Export("ecrRepositoryUrl", repository.RepositoryUrl)
+		ctx.Export("apiUrl", pulumi.Sprintf("http://%s", loadBalancer.DnsName))
+		ctx.Export("clusterName", cluster.Name)
+		ctx.Export("serviceName", service.Name)
+{{- end }}
+		ctx.Export("cloudwatchLogGroup", logGroup.Name)
+		return nil
+	})
+}
+
+// appStatements grants the app the models it invokes, and publishing metrics
+// to its CloudWatch namespace. InvokeModel also authorizes Converse{{ if .Deployment.Policy.Streaming }}, and
+// InvokeModelWithResponseStream ConverseStream{{ end }}.
+func appStatements(partition, region, account string, environment map[string]string) []map[string]interface{} {
+	resources := make([]string, 0)
+	for _, model := range foundationModels {
+		resources = append(resources, fmt.Sprintf("arn:%s:bedrock:%s::foundation-model/%s", partition, region, model))
+	}
+	for _, profile := range inferenceProfiles {
+		resources = append(resources, fmt.Sprintf("arn:%s:bedrock:%s:%s:inference-profile/%s", partition, region, account, profile.ID))
+		for _, routed := range profile.Regions {
+			resources = append(resources, fmt.Sprintf("arn:%s:bedrock:%s::foundation-model/%s", partition, routed, profile.Model))
+		}
+	}
+
+	statements := []map[string]interface{}{ {
+		"Effect":    "Allow",
+		"Action":    "cloudwatch:PutMetricData",
+		"Resource":  "*",
+		"Condition": map[string]interface{}{"StringEquals": map[string]string{"cloudwatch:namespace": "{{ .Deployment.MetricsNamespace }}"}},
+	}}
+	if len(resources) > 0 {
+		statements = append(statements, map[string]interface{}{
+			"Effect":   "Allow",
+			"Action":   []string{"bedrock:InvokeModel"{{ if .Deployment.Policy.Streaming }}, "bedrock:InvokeModelWithResponseStream"{{ end }}},
+			"Resource": resources,
+		})
+	}
+	if guardrail, ok := environment["BEDROCK_GUARDRAIL_ID"]; ok {
+		statements = append(statements, map[string]interface{}{
+			"Effect":   "Allow",
+			"Action":   "bedrock:ApplyGuardrail",
+			"Resource": fmt.Sprintf("arn:%s:bedrock:%s:%s:guardrail/%s", partition, region, account, guardrail),
+		})
+	}
+	return statements
+}
+`
@@ -0,0 +1,152 @@
+package aws
+
+const samTemplate = `AWSTemplateFormatVersion: "2010-09-09"
+Transform: AWS::Serverless-2016-10-31
+Description: {{ .Deployment.ProjectName }} GenKit app on AWS Lambda
+
+Parameters:
+  Environment:
+    Type: String
+    Default: {{ index .Deployment.Environments 0 }}
+    AllowedValues:
+{{- range .Deployment.Environments }}
+      - {{ . }}
+{{- end }}
+{{- range .Deployment.Inputs }}
+  {{ logicalID .Name }}:
+    Type: String
+    Description: {{ .Name }}, output {{ .Output }} of terraform/envs/<environment>
+    Default: ""
+{{- end }}
+
+Resources:
+  # The app built with -tags lambda into build/bootstrap, which serves
+  # API Gateway requests in-process.
+  GenKitFunction:
+    Type: AWS::Serverless::Function
+    Properties:
+      FunctionName: !Sub "{{ .Deployment.ProjectName }}-${Environment}"
+      CodeUri: build/
+      Handler: bootstrap
+      Runtime: provided.al2023
+      Role: !GetAtt GenKitAppRole.Arn
+      MemorySize: {{ .Deployment.Memory }}
+      Timeout: {{ .Deployment.Timeout }}
+{{- if ge .Deployment.ReservedConcurrency 0 }}
+      ReservedConcurrentExecutions: {{ .Deployment.ReservedConcurrency }}
+{{- end }}
+      LoggingConfig:
+        LogGroup: !Ref GenKitLogGroup
+      Environment:
+        Variables:
+          GENKIT_ENV: production
+{{- range $name, $value := .Deployment.Environment }}
+          {{ $name }}: !Ref {{ logicalID $name }}
+{{- end }}
+      Events:
+{{- range .Deployment.Flows }}
+        {{ logicalID . }}:
+          Type: HttpApi
+          Properties:
+            Path: /{{ . }}
+            Method: POST
+{{- else }}
+        # TODO: add a POST /<flow> event for every flow the app defines.
+        Default:
+          Type: HttpApi
+{{- end }}
+
+  # Named so the guardrail and vector store Terraform can grant it access.
+  GenKitAppRole:
+    Type: AWS::IAM::Role
+    Properties:
+      RoleName: !Sub "{{ .Deployment.ProjectName }}-${Environment}-app-role"
+      AssumeRolePolicyDocument:
+        Version: "2012-10-17"
+        Statement:
+          - Effect: Allow
+            Principal:
+              Service: lambda.amazonaws.com
+            Action: sts:AssumeRole
+      ManagedPolicyArns:
+        - !Sub "arn:${AWS::Partition}:iam::aws:policy/service-role/AWSLambdaBasicExecutionRole"
+      Policies:
+        - PolicyName: genkit-app
+          PolicyDocument:
+            Version: "2012-10-17"
+            Statement:
+{{- with .Deployment.Policy }}{{ if not .Empty }}
+              # InvokeModel also authorizes Converse{{ if .Streaming }}, and InvokeModelWithResponseStream ConverseStream{{ end }}
+              - Effect: Allow
+                Action:
+                  - bedrock:InvokeModel
+{{- if .Streaming }}
+                  - bedrock:InvokeModelWithResponseStream
+{{- end }}
+                Resource:
+{{- range .Models }}
+                  - !Sub "arn:${AWS::Partition}:bedrock:${AWS::Region}::foundation-model/{{ . }}"
+{{- end }}
+{{- range .Profiles }}
+                  - !Sub "arn:${AWS::Partition}:bedrock:${AWS::Region}:${AWS::AccountId}:inference-profile/{{ .ID }}"
+{{- $model := .Model }}
+{{- range .Regions }}
+                  - !Sub "arn:${AWS::Partition}:bedrock:{{ . }}::foundation-model/{{ $model }}"
+{{- end }}
+{{- end }}
+{{- end }}{{ end }}
+{{- if .Deployment.Guardrail }}
+              - Effect: Allow
+                Action: bedrock:ApplyGuardrail
+                Resource: !Sub "arn:${AWS::Partition}:bedrock:${AWS::Region}:${AWS::AccountId}:guardrail/${BedrockGuardrailId}"
+{{- end }}
+              - Effect: Allow
+                Action: cloudwatch:PutMetricData
+                Resource: "*"
+                Condition:
+                  StringEquals:
+                    cloudwatch:namespace: {{ .Deployment.MetricsNamespace }}
+
+  GenKitLogGroup:
+    Type: AWS::Logs::LogGroup
+    Properties:
+      LogGroupName: !Sub "/aws/lambda/{{ .Deployment.ProjectName }}-${Environment}"
+      RetentionInDays: 14
+
+Outputs:
+  ApiUrl:
+    Description: URL of the API Gateway
+    Value: !Sub "https://${ServerlessHttpApi}.execute-api.${AWS::Region}.${AWS::URLSuffix}"
+  FunctionName:
+    Description: Name of the Lambda function
+    Value: !Ref GenKitFunction
+  FunctionArn:
+    Description: ARN of the Lambda function
+    Value: !GetAtt GenKitFunction.Arn
+  LogGroupName:
+    Description: CloudWatch log group name
+    Value: !Ref GenKitLogGroup
+`
+
+// samConfig deploys each environment as its own stack with
+// sam deploy --config-env <name>; the default section serves sam local.
+// Changesets apply without a prompt, as the deploy workflow gates staging
+// and prod with GitHub environment reviewers.
+const samConfig = `version = 0.1
+
+[default.global.parameters]
+stack_name = "{{ .Deployment.ProjectName }}-{{ index .Deployment.Environments 0 }}"
+
+[default.local_start_api.parameters]
+parameter_overrides = "Environment={{ index .Deployment.Environments 0 }}"
+{{- range .Deployment.Environments }}
+
+[{{ . }}.deploy.parameters]
+stack_name = "{{ $.Deployment.ProjectName }}-{{ . }}"
+region = "{{ $.Deployment.Region }}"
+capabilities = "CAPABILITY_NAMED_IAM"
+resolve_s3 = true
+confirm_changeset = false
+parameter_overrides = "Environment={{ . }}"
+{{- end }}
+`
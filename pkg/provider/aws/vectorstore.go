@@ -0,0 +1,623 @@
+package aws
+
+import (
+	"fmt"
+	"go/ast"
+	"go/format"
+	"go/token"
+	"sort"
+	"strings"
+	"text/template"
+
+	"github.com/genkit-migrate/genkit-migrate/pkg/models"
+	"github.com/genkit-migrate/genkit-migrate/pkg/provider"
+	"github.com/genkit-migrate/genkit-migrate/pkg/rewrite"
+)
+
+const (
+	firebasePluginPackage = "github.com/firebase/genkit/go/plugins/firebase"
+	firestorePackage      = "cloud.google.com/go/firestore"
+)
+
+// VectorStoreOption selects the backend replacing Firestore vector search and
+// Vertex AI Vector Search; it is one of VectorStores, the first by default.
+const VectorStoreOption = "vector-store"
+
+var VectorStores = []string{"opensearch-serverless", "aurora-pgvector", "bedrock-kb"}
+
+type vectorStoreBackend struct {
+	name            string
+	pkg             string
+	pkgName         string
+	connectionField string // option set from connectionEnv in place of the Firestore client
+	connectionEnv   string
+	collectionField string
+	fields          map[string]string // firebase.RetrieverOptions field -> target field
+	notes           map[string]string // why a field has no target
+	terraform       string
+}
+
+var vectorStoreBackends = map[string]*vectorStoreBackend{
+	"opensearch-serverless": {
+		name:            "Amazon OpenSearch Serverless",
+		pkg:             "github.com/scttfrdmn/genkit-aws/pkg/opensearch",
+		pkgName:         "opensearch",
+		connectionField: "Endpoint",
+		connectionEnv:   "OPENSEARCH_ENDPOINT",
+		collectionField: "Index",
+		fields: map[string]string{
+			"Name":            "Name",
+			"Collection":      "Index",
+			"Embedder":        "Embedder",
+			"EmbedderOptions": "EmbedderOptions",
+			"VectorField":     "VectorField",
+			"ContentField":    "TextField",
+			"MetadataFields":  "MetadataFields",
+			"Limit":           "K",
+		},
+		notes: map[string]string{
+			"DistanceMeasure": "the space type is part of the index mapping",
+			"VectorType":      "OpenSearch stores float32 vectors",
+		},
+		terraform: openSearchTerraform,
+	},
+	"aurora-pgvector": {
+		name:            "Amazon Aurora PostgreSQL with pgvector",
+		pkg:             "github.com/scttfrdmn/genkit-aws/pkg/pgvector",
+		pkgName:         "pgvector",
+		connectionField: "ConnectionString",
+		connectionEnv:   "DATABASE_URL",
+		collectionField: "Table",
+		fields: map[string]string{
+			"Name":            "Name",
+			"Collection":      "Table",
+			"Embedder":        "Embedder",
+			"EmbedderOptions": "EmbedderOptions",
+			"VectorField":     "EmbeddingColumn",
+			"ContentField":    "ContentColumn",
+			"MetadataFields":  "MetadataColumns",
+			"Limit":           "Limit",
+		},
+		notes: map[string]string{
+			"DistanceMeasure": "the distance operator follows the HNSW index operator class in schema.sql",
+			"VectorType":      "pgvector stores float32 vectors",
+		},
+		terraform: auroraTerraform,
+	},
+	"bedrock-kb": {
+		name:            "Amazon Bedrock Knowledge Base",
+		pkg:             "github.com/scttfrdmn/genkit-aws/pkg/knowledgebase",
+		pkgName:         "knowledgebase",
+		connectionField: "KnowledgeBaseID",
+		connectionEnv:   "BEDROCK_KNOWLEDGE_BASE_ID",
+		fields: map[string]string{
+			"Name":  "Name",
+			"Limit": "NumberOfResults",
+		},
+		notes: map[string]string{
+			"Collection":      "documents are stored in the knowledge base data source",
+			"Embedder":        "the knowledge base embeds documents and queries with its own model",
+			"EmbedderOptions": "the knowledge base embeds documents and queries with its own model",
+			"VectorField":     "the vector index is managed by the knowledge base",
+			"ContentField":    "the vector index is managed by the knowledge base",
+			"MetadataFields":  "metadata comes from the .metadata.json files next to each document",
+			"DistanceMeasure": "the vector index is managed by the knowledge base",
+			"VectorType":      "the vector index is managed by the knowledge base",
+		},
+		terraform: knowledgeBaseTerraform,
+	},
+}
+
+func selectedVectorStore(ctx provider.Context) (string, *vectorStoreBackend, error) {
+	key := ctx.Option(VectorStoreOption)
+	if key == "" {
+		key = VectorStores[0]
+	}
+
+	backend, exists := vectorStoreBackends[key]
+	if !exists {
+		return "", nil, fmt.Errorf("unsupported vector store %q (supported: %s)", key, strings.Join(VectorStores, ", "))
+	}
+	return key, backend, nil
+}
+
+// vectorStoreEnvironment returns the variables the deployed app needs to reach
+// the vector store, as Terraform expressions.
+func vectorStoreEnvironment(ctx provider.Context) map[string]string {
+	environment := make(map[string]string)
+	if len(ctx.Project().VectorStores) == 0 {
+		return environment
+	}
+
+	key, _, err := selectedVectorStore(ctx)
+	if err != nil {
+		return environment
+	}
+
+	switch key {
+	case "opensearch-serverless":
+		environment["OPENSEARCH_ENDPOINT"] = "aws_opensearchserverless_collection.vectors.collection_endpoint"
+	case "aurora-pgvector":
+		environment["DATABASE_URL"] = `"postgres://genkit@${aws_rds_cluster.vectors.endpoint}:5432/vectors"`
+	case "bedrock-kb":
+		environment["BEDROCK_KNOWLEDGE_BASE_ID"] = "aws_bedrockagent_knowledge_base.vectors.id"
+	}
+	return environment
+}
+
+func transformVectorStores(ctx provider.Context) error {
+	migration := ctx.Migration()
+	project := migration.Project
+	if len(project.VectorStores) == 0 {
+		return nil
+	}
+
+	key, backend, err := selectedVectorStore(ctx)
+	if err != nil {
+		return err
+	}
+	iac, _, err := selectedIaC(ctx)
+	if err != nil {
+		return err
+	}
+	// Other formats create the app role; Terraform looks it up.
+	appRole := "aws_iam_role.app_role"
+	if iac != "terraform" {
+		appRole = "data.aws_iam_role.app_role"
+	}
+
+	hasFirestore := false
+	collections := make([]string, 0)
+	seen := make(map[string]bool)
+	contentField, vectorField := "content", "embedding"
+
+	for _, store := range project.VectorStores {
+		change := &models.Change{
+			Type:     "vectorstore",
+			File:     store.Position.Filename,
+			Line:     store.Position.Line,
+			NewValue: backend.name,
+		}
+
+		switch {
+		case store.Kind == "firestore" && store.Package == firebasePluginPackage:
+			hasFirestore = true
+			change.OldValue = "Firestore vector search"
+			change.Description = fmt.Sprintf("Migrate Firestore vector retriever %s -> %s", describeVectorStore(store), backend.name)
+			if store.ContentField != "" {
+				contentField = store.ContentField
+			}
+			if store.VectorField != "" {
+				vectorField = store.VectorField
+			}
+		case store.Kind == "firestore":
+			hasFirestore = true
+			change.OldValue = "Firestore FindNearest"
+			change.Description = fmt.Sprintf("Replace the Firestore FindNearest query with a %s retriever (%s.DefineRetriever)", backend.name, backend.pkgName)
+			change.ManualReview = true
+		default:
+			change.OldValue = "Vertex AI Vector Search"
+			change.Description = fmt.Sprintf("Replace the Vertex AI Vector Search client with a %s retriever (%s.DefineRetriever)", backend.name, backend.pkgName)
+			change.ManualReview = true
+		}
+		migration.Changes = append(migration.Changes, change)
+
+		if store.Collection != "" && !seen[store.Collection] {
+			seen[store.Collection] = true
+			collections = append(collections, store.Collection)
+		}
+	}
+	if len(collections) == 0 {
+		collections = append(collections, "documents")
+	}
+	sort.Strings(collections)
+
+	embedder, dimensions := "amazon.titan-embed-text-v2:0", 1024
+	embedderMappings := ctx.Catalog().Embedders
+	for _, source := range project.Embedders {
+		if mapping, exists := embedderMappings[source.Name]; exists {
+			embedder, dimensions = mapping.Target, mapping.Dimensions
+			break
+		}
+	}
+
+	projectID, _ := ctx.Setting("PROJECT_NAME")
+	if projectID == "" {
+		projectID = "<gcp-project-id>"
+	}
+
+	data := map[string]interface{}{
+		"Backend":         key,
+		"BackendName":     backend.name,
+		"Package":         backend.pkg,
+		"PackageName":     backend.pkgName,
+		"ConnectionField": backend.connectionField,
+		"ConnectionEnv":   backend.connectionEnv,
+		"CollectionField": backend.collectionField,
+		"Collections":     collections,
+		"Collection":      collections[0],
+		"ContentField":    contentField,
+		"VectorField":     vectorField,
+		"Firestore":       hasFirestore,
+		"ProjectID":       projectID,
+		"Region":          region(ctx),
+		"Embedder":        embedder,
+		"Dimensions":      dimensions,
+		"AppRole":         appRole,
+	}
+
+	files := map[string]string{
+		"terraform/vectorstore.tf":          backend.terraform,
+		"scripts/migrate-vectors/main.go":   migrateVectorsScript,
+		"scripts/migrate-vectors/go.mod":    migrateVectorsGoMod,
+		"scripts/migrate-vectors/README.md": migrateVectorsReadme,
+	}
+	if key == "aurora-pgvector" {
+		files["scripts/migrate-vectors/schema.sql"] = pgvectorSchema
+	}
+
+	for filePath, text := range files {
+		tmpl, err := template.New(filePath).Parse(text)
+		if err != nil {
+			return err
+		}
+
+		var content strings.Builder
+		if err := tmpl.Execute(&content, data); err != nil {
+			return err
+		}
+
+		output := content.String()
+		if strings.HasSuffix(filePath, ".go") {
+			formatted, err := format.Source([]byte(output))
+			if err != nil {
+				return fmt.Errorf("failed to format %s: %w", filePath, err)
+			}
+			output = string(formatted)
+		}
+		migration.NewFiles[filePath] = output
+	}
+
+	migration.Changes = append(migration.Changes, &models.Change{
+		Type:        "vectorstore",
+		Description: fmt.Sprintf("Generated %s Terraform and a data migration script for %s", backend.name, strings.Join(collections, ", ")),
+		File:        "terraform/vectorstore.tf",
+	})
+
+	if key == "aurora-pgvector" {
+		migration.Changes = append(migration.Changes, &models.Change{
+			Type:         "vectorstore",
+			Description:  "Run the Lambda function inside the Aurora cluster's VPC and add the database password from the master user secret to DATABASE_URL",
+			File:         "terraform/vectorstore.tf",
+			ManualReview: true,
+		})
+	}
+
+	migration.Commands = append(migration.Commands,
+		"(cd scripts/migrate-vectors && go mod tidy)")
+	if key == "aurora-pgvector" {
+		migration.Commands = append(migration.Commands,
+			`psql "$DATABASE_URL" -f scripts/migrate-vectors/schema.sql`)
+	}
+	if hasFirestore {
+		migration.Commands = append(migration.Commands,
+			fmt.Sprintf("(cd scripts/migrate-vectors && go run . export -project %s -collection %s -out documents.jsonl)", projectID, collections[0]))
+	}
+	// Terraform applies the store per environment; the documents are
+	// imported into prod's.
+	root := "../../terraform/envs/prod"
+	importCommand := "(cd scripts/migrate-vectors && go run . import -in documents.jsonl"
+	switch key {
+	case "opensearch-serverless":
+		importCommand += fmt.Sprintf(` -connection "$(terraform -chdir=%s output -raw opensearch_endpoint)"`, root)
+	case "bedrock-kb":
+		importCommand += fmt.Sprintf(` -bucket "$(terraform -chdir=%s output -raw documents_bucket)"`, root) +
+			fmt.Sprintf(` -knowledge-base-id "$(terraform -chdir=%s output -raw knowledge_base_id)"`, root) +
+			fmt.Sprintf(` -data-source-id "$(terraform -chdir=%s output -raw data_source_id)"`, root)
+	}
+	migration.Commands = append(migration.Commands, importCommand+")")
+
+	return nil
+}
+
+func describeVectorStore(store *models.VectorStore) string {
+	switch {
+	case store.Name != "" && store.Collection != "":
+		return fmt.Sprintf("%s (collection %s)", store.Name, store.Collection)
+	case store.Name != "":
+		return store.Name
+	case store.Collection != "":
+		return "for collection " + store.Collection
+	default:
+		return "definition"
+	}
+}
+
+type vectorStoreRule struct {
+	backend *vectorStoreBackend
+}
+
+func (r *vectorStoreRule) Name() string {
+	return "vector-store"
+}
+
+func (r *vectorStoreRule) Apply(file *rewrite.File) error {
+	pluginName, imported := file.ImportName(firebasePluginPackage)
+	if !imported {
+		return nil
+	}
+
+	var applyErr error
+	touched := false
+	clients := make(map[string]bool)
+	rewrite.Rewrite(file.AST, func(expr ast.Expr) ast.Expr {
+		switch node := expr.(type) {
+		case *ast.CompositeLit:
+			pkg, name, ok := file.SelectorPackage(node.Type)
+			if !ok || pkg != firebasePluginPackage || name != "RetrieverOptions" {
+				return nil
+			}
+			for _, elt := range node.Elts {
+				if kv, ok := elt.(*ast.KeyValueExpr); ok {
+					if key, ok := kv.Key.(*ast.Ident); ok && key.Name == "Client" {
+						if client, ok := kv.Value.(*ast.Ident); ok {
+							clients[client.Name] = true
+						}
+					}
+				}
+			}
+			translated, err := r.translateOptions(file, pluginName, node)
+			if err != nil {
+				applyErr = err
+				return nil
+			}
+			touched = true
+			return translated
+		case *ast.CallExpr:
+			pkg, name, ok := file.SelectorPackage(node.Fun)
+			if !ok || pkg != firebasePluginPackage || name != "DefineRetriever" {
+				return nil
+			}
+			touched = true
+			file.Report(node, &models.Change{
+				Type:        "vectorstore",
+				Description: fmt.Sprintf("Replaced %s.DefineRetriever with %s.DefineRetriever", pluginName, r.backend.pkgName),
+			})
+			fun := selector(r.backend.pkgName, "DefineRetriever")
+			fun.X.(*ast.Ident).NamePos = node.Fun.Pos()
+			return &ast.CallExpr{Fun: fun, Lparen: node.Lparen, Args: node.Args, Ellipsis: node.Ellipsis, Rparen: node.Rparen}
+		}
+		return nil
+	})
+	if applyErr != nil {
+		return applyErr
+	}
+	if !touched {
+		return nil
+	}
+
+	file.AddImport(r.backend.pkgName, r.backend.pkg)
+	for client := range clients {
+		keepUnusedClient(file, client)
+	}
+	if file.DeleteUnusedImport(firebasePluginPackage) {
+		file.Report(nil, &models.Change{
+			Type:        "import",
+			Description: fmt.Sprintf("Replaced %s plugin with %s", pluginName, r.backend.pkgName),
+			OldValue:    firebasePluginPackage,
+			NewValue:    r.backend.pkg,
+		})
+		return nil
+	}
+
+	ast.Inspect(file.AST, func(n ast.Node) bool {
+		if sel, ok := n.(*ast.SelectorExpr); ok {
+			if ident, ok := sel.X.(*ast.Ident); ok && ident.Name == pluginName {
+				file.Report(sel, &models.Change{
+					Type:         "import",
+					Description:  fmt.Sprintf("No automatic translation for %s.%s", pluginName, sel.Sel.Name),
+					ManualReview: true,
+				})
+			}
+		}
+		return true
+	})
+
+	return nil
+}
+
+// keepUnusedClient assigns a Firestore client that only the replaced
+// retriever used to _, so the function still compiles, and leaves a TODO to
+// remove it.
+func keepUnusedClient(file *rewrite.File, client string) {
+	for _, decl := range file.AST.Decls {
+		fn, ok := decl.(*ast.FuncDecl)
+		if !ok || fn.Body == nil {
+			continue
+		}
+
+		var block *ast.BlockStmt
+		var definition *ast.AssignStmt
+		index := -1
+		ast.Inspect(fn.Body, func(n ast.Node) bool {
+			b, ok := n.(*ast.BlockStmt)
+			if !ok || block != nil {
+				return block == nil
+			}
+			for i, stmt := range b.List {
+				if assign, ok := stmt.(*ast.AssignStmt); ok && isClientDefinition(file, assign, client) {
+					block, definition, index = b, assign, i
+					return false
+				}
+			}
+			return true
+		})
+		if block == nil {
+			continue
+		}
+
+		used := false
+		ast.Inspect(fn.Body, func(n ast.Node) bool {
+			if ident, ok := n.(*ast.Ident); ok && ident.Name == client && ident != definition.Lhs[0] {
+				used = true
+			}
+			return !used
+		})
+		if used {
+			continue
+		}
+
+		// Keep the error check of the constructor in front of the assignment.
+		if index+1 < len(block.List) {
+			if check, ok := block.List[index+1].(*ast.IfStmt); ok && check.Init == nil && mentionsError(check.Cond, definition) {
+				index++
+			}
+		}
+
+		pos := block.List[index].End()
+		assign := &ast.AssignStmt{
+			Lhs:    []ast.Expr{&ast.Ident{Name: "_", NamePos: pos}},
+			TokPos: pos,
+			Tok:    token.ASSIGN,
+			Rhs:    []ast.Expr{&ast.Ident{Name: client, NamePos: pos}},
+		}
+		block.List = append(block.List[:index+1], append([]ast.Stmt{assign}, block.List[index+1:]...)...)
+		file.AST.Comments = append(file.AST.Comments, &ast.CommentGroup{List: []*ast.Comment{
+			{Slash: pos, Text: "// TODO: remove the Firestore client if nothing else uses it."},
+		}})
+		sort.Slice(file.AST.Comments, func(i, j int) bool {
+			return file.AST.Comments[i].Pos() < file.AST.Comments[j].Pos()
+		})
+
+		file.Report(definition, &models.Change{
+			Type:         "vectorstore",
+			Description:  fmt.Sprintf("The Firestore client %s is no longer used by the retriever; remove it if nothing else needs it", client),
+			OldValue:     client,
+			ManualReview: true,
+		})
+	}
+}
+
+// isClientDefinition reports whether assign creates client with
+// firestore.NewClient or a variant of it.
+func isClientDefinition(file *rewrite.File, assign *ast.AssignStmt, client string) bool {
+	if assign.Tok != token.DEFINE || len(assign.Lhs) == 0 || len(assign.Rhs) != 1 {
+		return false
+	}
+	if ident, ok := assign.Lhs[0].(*ast.Ident); !ok || ident.Name != client {
+		return false
+	}
+	call, ok := assign.Rhs[0].(*ast.CallExpr)
+	if !ok {
+		return false
+	}
+	pkg, name, ok := file.SelectorPackage(call.Fun)
+	return ok && pkg == firestorePackage && strings.HasPrefix(name, "NewClient")
+}
+
+// mentionsError reports whether cond uses the error returned next to the
+// client by assign.
+func mentionsError(cond ast.Expr, assign *ast.AssignStmt) bool {
+	if len(assign.Lhs) < 2 {
+		return false
+	}
+	errIdent, ok := assign.Lhs[len(assign.Lhs)-1].(*ast.Ident)
+	if !ok || errIdent.Name == "_" {
+		return false
+	}
+
+	found := false
+	ast.Inspect(cond, func(n ast.Node) bool {
+		if ident, ok := n.(*ast.Ident); ok && ident.Name == errIdent.Name {
+			found = true
+		}
+		return !found
+	})
+	return found
+}
+
+func (r *vectorStoreRule) translateOptions(file *rewrite.File, pluginName string, lit *ast.CompositeLit) (ast.Expr, error) {
+	connection, err := rewrite.ParseExpr(fmt.Sprintf("os.Getenv(%q)", r.backend.connectionEnv))
+	if err != nil {
+		return nil, err
+	}
+	file.AddImport("os", "os")
+	connectionField := &ast.KeyValueExpr{Key: ast.NewIdent(r.backend.connectionField), Value: connection}
+
+	translated := &ast.CompositeLit{
+		Type:   selector(r.backend.pkgName, "RetrieverOptions"),
+		Lbrace: lit.Lbrace,
+		Rbrace: lit.Rbrace,
+	}
+
+	connected := false
+	for _, elt := range lit.Elts {
+		kv, ok := elt.(*ast.KeyValueExpr)
+		var key *ast.Ident
+		if ok {
+			key, _ = kv.Key.(*ast.Ident)
+		}
+		if key == nil {
+			file.Report(elt, &models.Change{
+				Type:         "vectorstore",
+				Description:  fmt.Sprintf("Unkeyed %s.RetrieverOptions field cannot be translated", pluginName),
+				ManualReview: true,
+			})
+			continue
+		}
+
+		if key.Name == "Client" {
+			connectionField.Key = &ast.Ident{Name: r.backend.connectionField, NamePos: key.NamePos}
+			connectionField.Colon = kv.Colon
+			translated.Elts = append(translated.Elts, connectionField)
+			connected = true
+			file.Report(kv, &models.Change{
+				Type: "vectorstore",
+				Description: fmt.Sprintf("The Firestore client is replaced by %s from $%s; remove the client if nothing else uses it",
+					r.backend.connectionField, r.backend.connectionEnv),
+				OldValue:     "Client",
+				NewValue:     r.backend.connectionField,
+				ManualReview: true,
+			})
+			continue
+		}
+
+		target, exists := r.backend.fields[key.Name]
+		if !exists {
+			note, hasNote := r.backend.notes[key.Name]
+			if !hasNote {
+				note = "not supported by " + r.backend.name
+			}
+			file.Report(kv, &models.Change{
+				Type:         "vectorstore",
+				Description:  fmt.Sprintf("Dropped retriever option %s: %s", key.Name, note),
+				OldValue:     key.Name,
+				ManualReview: true,
+			})
+			continue
+		}
+
+		translated.Elts = append(translated.Elts, &ast.KeyValueExpr{
+			Key:   &ast.Ident{Name: target, NamePos: key.NamePos},
+			Colon: kv.Colon,
+			Value: kv.Value,
+		})
+	}
+
+	if !connected {
+		translated.Elts = append(translated.Elts, connectionField)
+	}
+
+	file.Report(lit, &models.Change{
+		Type:        "vectorstore",
+		Description: fmt.Sprintf("Translated %s.RetrieverOptions to %s.RetrieverOptions", pluginName, r.backend.pkgName),
+		OldValue:    pluginName + ".RetrieverOptions",
+		NewValue:    r.backend.pkgName + ".RetrieverOptions",
+	})
+
+	return translated, nil
+}
+
+func selector(pkg, name string) *ast.SelectorExpr {
+	return &ast.SelectorExpr{X: ast.NewIdent(pkg), Sel: ast.NewIdent(name)}
+}
@@ -0,0 +1,728 @@
+package aws
+
+const openSearchTerraform = `# Vector store: Amazon OpenSearch Serverless
+#
+# Indexes ({{ .Dimensions }}-dimensional knn_vector field "{{ .VectorField }}"):
+{{- range .Collections }}
+#   - {{ . }}
+{{- end }}
+# The indexes are created by the opensearch retriever/indexer on first use.
+
+resource "aws_opensearchserverless_security_policy" "vectors_encryption" {
+  name = "${local.name}-vectors"
+  type = "encryption"
+  policy = jsonencode({
+    Rules = [
+      {
+        ResourceType = "collection"
+        Resource     = ["collection/${local.name}-vectors"]
+      }
+    ]
+    AWSOwnedKey = true
+  })
+}
+
+resource "aws_opensearchserverless_security_policy" "vectors_network" {
+  name = "${local.name}-vectors"
+  type = "network"
+  policy = jsonencode([
+    {
+      Rules = [
+        {
+          ResourceType = "collection"
+          Resource     = ["collection/${local.name}-vectors"]
+        }
+      ]
+      AllowFromPublic = true
+    }
+  ])
+}
+
+resource "aws_opensearchserverless_access_policy" "vectors" {
+  name = "${local.name}-vectors"
+  type = "data"
+  policy = jsonencode([
+    {
+      Rules = [
+        {
+          ResourceType = "collection"
+          Resource     = ["collection/${local.name}-vectors"]
+          Permission   = ["aoss:DescribeCollectionItems", "aoss:CreateCollectionItems", "aoss:UpdateCollectionItems"]
+        },
+        {
+          ResourceType = "index"
+          Resource     = ["index/${local.name}-vectors/*"]
+          Permission   = ["aoss:DescribeIndex", "aoss:CreateIndex", "aoss:UpdateIndex", "aoss:ReadDocument", "aoss:WriteDocument"]
+        }
+      ]
+      Principal = [{{ .AppRole }}.arn]
+    }
+  ])
+}
+
+resource "aws_opensearchserverless_collection" "vectors" {
+  name = "${local.name}-vectors"
+  type = "VECTORSEARCH"
+
+  depends_on = [
+    aws_opensearchserverless_security_policy.vectors_encryption,
+    aws_opensearchserverless_security_policy.vectors_network,
+  ]
+}
+
+resource "aws_iam_role_policy" "vectors_policy" {
+  name = "genkit-vectors-policy"
+  role = {{ .AppRole }}.id
+
+  policy = jsonencode({
+    Version = "2012-10-17"
+    Statement = [
+      {
+        Effect   = "Allow"
+        Action   = ["aoss:APIAccessAll"]
+        Resource = aws_opensearchserverless_collection.vectors.arn
+      }
+    ]
+  })
+}
+
+output "opensearch_endpoint" {
+  value = aws_opensearchserverless_collection.vectors.collection_endpoint
+}
+`
+
+const auroraTerraform = `# Vector store: Amazon Aurora PostgreSQL with pgvector
+#
+# Tables ({{ .Dimensions }}-dimensional "{{ .VectorField }}" column) are created by
+# scripts/migrate-vectors/schema.sql:
+{{- range .Collections }}
+#   - {{ . }}
+{{- end }}
+
+resource "aws_rds_cluster" "vectors" {
+  cluster_identifier          = "${local.name}-vectors"
+  engine                      = "aurora-postgresql"
+  engine_mode                 = "provisioned"
+  engine_version              = "16.4"
+  database_name               = "vectors"
+  master_username             = "genkit"
+  manage_master_user_password = true
+  storage_encrypted           = true
+  final_snapshot_identifier   = "${local.name}-vectors-final"
+
+  serverlessv2_scaling_configuration {
+    min_capacity = 0.5
+    max_capacity = 4
+  }
+}
+
+resource "aws_rds_cluster_instance" "vectors" {
+  identifier         = "${local.name}-vectors-1"
+  cluster_identifier = aws_rds_cluster.vectors.id
+  instance_class     = "db.serverless"
+  engine             = aws_rds_cluster.vectors.engine
+  engine_version     = aws_rds_cluster.vectors.engine_version
+}
+
+resource "aws_iam_role_policy" "vectors_policy" {
+  name = "genkit-vectors-policy"
+  role = {{ .AppRole }}.id
+
+  policy = jsonencode({
+    Version = "2012-10-17"
+    Statement = [
+      {
+        Effect   = "Allow"
+        Action   = ["secretsmanager:GetSecretValue"]
+        Resource = aws_rds_cluster.vectors.master_user_secret[0].secret_arn
+      }
+    ]
+  })
+}
+
+output "database_endpoint" {
+  value = aws_rds_cluster.vectors.endpoint
+}
+
+output "database_secret_arn" {
+  value = aws_rds_cluster.vectors.master_user_secret[0].secret_arn
+}
+`
+
+const knowledgeBaseTerraform = `# Vector store: Amazon Bedrock Knowledge Base
+#
+# Documents are uploaded to the S3 data source by scripts/migrate-vectors and
+# embedded by the knowledge base with {{ .Embedder }}.
+# The OpenSearch Serverless index backing the knowledge base is created with
+# the opensearch provider, which the environment roots configure with
+# knowledge_base_collection_endpoint.
+
+locals {
+  kb_embedding_model_arn = "arn:aws:bedrock:${var.aws_region}::foundation-model/{{ .Embedder }}"
+}
+
+resource "aws_s3_bucket" "documents" {
+  bucket_prefix = "${local.name}-documents-"
+}
+
+resource "aws_iam_role" "knowledge_base" {
+  name = "${local.name}-knowledge-base"
+
+  assume_role_policy = jsonencode({
+    Version = "2012-10-17"
+    Statement = [
+      {
+        Action = "sts:AssumeRole"
+        Effect = "Allow"
+        Principal = {
+          Service = "bedrock.amazonaws.com"
+        }
+        Condition = {
+          StringEquals = {
+            "aws:SourceAccount" = data.aws_caller_identity.current.account_id
+          }
+        }
+      }
+    ]
+  })
+}
+
+resource "aws_iam_role_policy" "knowledge_base" {
+  name = "${local.name}-knowledge-base"
+  role = aws_iam_role.knowledge_base.id
+
+  policy = jsonencode({
+    Version = "2012-10-17"
+    Statement = [
+      {
+        Effect   = "Allow"
+        Action   = ["bedrock:InvokeModel"]
+        Resource = local.kb_embedding_model_arn
+      },
+      {
+        Effect   = "Allow"
+        Action   = ["aoss:APIAccessAll"]
+        Resource = aws_opensearchserverless_collection.vectors.arn
+      },
+      {
+        Effect   = "Allow"
+        Action   = ["s3:GetObject", "s3:ListBucket"]
+        Resource = [aws_s3_bucket.documents.arn, "${aws_s3_bucket.documents.arn}/*"]
+      }
+    ]
+  })
+}
+
+resource "aws_opensearchserverless_security_policy" "vectors_encryption" {
+  name = "${local.name}-kb"
+  type = "encryption"
+  policy = jsonencode({
+    Rules = [
+      {
+        ResourceType = "collection"
+        Resource     = ["collection/${local.name}-kb"]
+      }
+    ]
+    AWSOwnedKey = true
+  })
+}
+
+resource "aws_opensearchserverless_security_policy" "vectors_network" {
+  name = "${local.name}-kb"
+  type = "network"
+  policy = jsonencode([
+    {
+      Rules = [
+        {
+          ResourceType = "collection"
+          Resource     = ["collection/${local.name}-kb"]
+        }
+      ]
+      AllowFromPublic = true
+    }
+  ])
+}
+
+resource "aws_opensearchserverless_access_policy" "vectors" {
+  name = "${local.name}-kb"
+  type = "data"
+  policy = jsonencode([
+    {
+      Rules = [
+        {
+          ResourceType = "collection"
+          Resource     = ["collection/${local.name}-kb"]
+          Permission   = ["aoss:*"]
+        },
+        {
+          ResourceType = "index"
+          Resource     = ["index/${local.name}-kb/*"]
+          Permission   = ["aoss:*"]
+        }
+      ]
+      Principal = [aws_iam_role.knowledge_base.arn, data.aws_caller_identity.current.arn]
+    }
+  ])
+}
+
+resource "aws_opensearchserverless_collection" "vectors" {
+  name = "${local.name}-kb"
+  type = "VECTORSEARCH"
+
+  depends_on = [
+    aws_opensearchserverless_security_policy.vectors_encryption,
+    aws_opensearchserverless_security_policy.vectors_network,
+  ]
+}
+
+resource "opensearch_index" "vectors" {
+  name                           = "bedrock-knowledge-base-index"
+  number_of_shards               = "2"
+  number_of_replicas             = "0"
+  index_knn                      = true
+  index_knn_algo_param_ef_search = "512"
+  force_destroy                  = true
+  mappings = jsonencode({
+    properties = {
+      "bedrock-knowledge-base-vector" = {
+        type      = "knn_vector"
+        dimension = {{ .Dimensions }}
+        method = {
+          name       = "hnsw"
+          engine     = "faiss"
+          space_type = "l2"
+        }
+      }
+      "AMAZON_BEDROCK_TEXT_CHUNK" = {
+        type = "text"
+      }
+      "AMAZON_BEDROCK_METADATA" = {
+        type  = "text"
+        index = false
+      }
+    }
+  })
+
+  depends_on = [aws_opensearchserverless_access_policy.vectors]
+}
+
+resource "aws_bedrockagent_knowledge_base" "vectors" {
+  name     = "${local.name}-kb"
+  role_arn = aws_iam_role.knowledge_base.arn
+
+  knowledge_base_configuration {
+    type = "VECTOR"
+    vector_knowledge_base_configuration {
+      embedding_model_arn = local.kb_embedding_model_arn
+    }
+  }
+
+  storage_configuration {
+    type = "OPENSEARCH_SERVERLESS"
+    opensearch_serverless_configuration {
+      collection_arn    = aws_opensearchserverless_collection.vectors.arn
+      vector_index_name = opensearch_index.vectors.name
+      field_mapping {
+        vector_field   = "bedrock-knowledge-base-vector"
+        text_field     = "AMAZON_BEDROCK_TEXT_CHUNK"
+        metadata_field = "AMAZON_BEDROCK_METADATA"
+      }
+    }
+  }
+}
+
+resource "aws_bedrockagent_data_source" "documents" {
+  name              = "${local.name}-documents"
+  knowledge_base_id = aws_bedrockagent_knowledge_base.vectors.id
+
+  data_source_configuration {
+    type = "S3"
+    s3_configuration {
+      bucket_arn = aws_s3_bucket.documents.arn
+    }
+  }
+}
+
+resource "aws_iam_role_policy" "vectors_policy" {
+  name = "genkit-vectors-policy"
+  role = {{ .AppRole }}.id
+
+  policy = jsonencode({
+    Version = "2012-10-17"
+    Statement = [
+      {
+        Effect   = "Allow"
+        Action   = ["bedrock:Retrieve"]
+        Resource = aws_bedrockagent_knowledge_base.vectors.arn
+      }
+    ]
+  })
+}
+
+output "knowledge_base_id" {
+  value = aws_bedrockagent_knowledge_base.vectors.id
+}
+
+output "data_source_id" {
+  value = aws_bedrockagent_data_source.documents.data_source_id
+}
+
+output "documents_bucket" {
+  value = aws_s3_bucket.documents.bucket
+}
+
+output "knowledge_base_collection_endpoint" {
+  value = aws_opensearchserverless_collection.vectors.collection_endpoint
+}
+`
+
+const pgvectorSchema = `-- Schema for the pgvector tables that replace the Firestore collections.
+CREATE EXTENSION IF NOT EXISTS vector;
+{{ range .Collections }}
+CREATE TABLE IF NOT EXISTS {{ . }} (
+    id TEXT PRIMARY KEY,
+    {{ $.ContentField }} TEXT NOT NULL,
+    metadata JSONB,
+    {{ $.VectorField }} vector({{ $.Dimensions }}) NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS {{ . }}_{{ $.VectorField }}_idx
+    ON {{ . }} USING hnsw ({{ $.VectorField }} vector_cosine_ops);
+{{ end -}}
+`
+
+const migrateVectorsGoMod = `module migrate-vectors
+
+go 1.23
+
+require (
+{{- if .Firestore }}
+	cloud.google.com/go/firestore v1.18.0
+	google.golang.org/api v0.214.0
+{{- end }}
+{{- if eq .Backend "bedrock-kb" }}
+	github.com/aws/aws-sdk-go-v2 v1.36.3
+	github.com/aws/aws-sdk-go-v2/config v1.29.14
+	github.com/aws/aws-sdk-go-v2/service/bedrockagent v1.42.1
+	github.com/aws/aws-sdk-go-v2/service/s3 v1.79.3
+{{- else }}
+	github.com/firebase/genkit/go v1.0.2
+	github.com/scttfrdmn/genkit-aws v0.1.0
+{{- end }}
+)
+`
+
+const migrateVectorsReadme = `# migrate-vectors
+
+Moves the documents behind the Google Cloud vector store to {{ .BackendName }}.
+
+Only document content and metadata are copied. The stored vectors were
+produced by the old embedding model and are recomputed during the import.
+
+` + "```bash" + `
+go mod tidy
+{{- if eq .Backend "aurora-pgvector" }}
+psql "$DATABASE_URL" -f schema.sql
+{{- end }}
+{{- if .Firestore }}
+go run . export -project {{ .ProjectID }} -collection {{ .Collection }} -out documents.jsonl
+{{- end }}
+go run . import -in documents.jsonl
+` + "```" + `
+
+The export file contains one JSON object per line:
+` + "`" + `{"id": "...", "content": "...", "metadata": {...}}` + "`" + `. The same format
+is accepted by the generated reindex job, so it can also be used to re-embed
+documents through the app's own indexer.
+{{- if not .Firestore }}
+
+Vertex AI Vector Search only stores vectors, so the documents have to be
+exported from their system of record into this format before the import.
+{{- end }}
+`
+
+const migrateVectorsScript = `// Command migrate-vectors moves the documents behind the Google Cloud vector
+// store to {{ .BackendName }}. See README.md.
+package main
+
+import (
+	"bufio"
+{{- if eq .Backend "bedrock-kb" }}
+	"bytes"
+{{- end }}
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+{{ if .Firestore }}
+	"cloud.google.com/go/firestore"
+	"google.golang.org/api/iterator"
+{{- end }}
+{{- if eq .Backend "bedrock-kb" }}
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/bedrockagent"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+{{- else }}
+	"github.com/firebase/genkit/go/ai"
+	"github.com/firebase/genkit/go/genkit"
+	"github.com/scttfrdmn/genkit-aws/pkg/bedrock"
+	genkitaws "github.com/scttfrdmn/genkit-aws/pkg/genkit-aws"
+	"{{ .Package }}"
+{{- end }}
+)
+
+const batchSize = 100
+
+type record struct {
+	ID       string         ` + "`" + `json:"id"` + "`" + `
+	Content  string         ` + "`" + `json:"content"` + "`" + `
+	Metadata map[string]any ` + "`" + `json:"metadata,omitempty"` + "`" + `
+}
+
+func main() {
+	if len(os.Args) < 2 {
+		fmt.Fprintln(os.Stderr, "usage: migrate-vectors export|import [flags]")
+		os.Exit(2)
+	}
+
+	ctx := context.Background()
+
+	var err error
+	switch os.Args[1] {
+	case "export":
+		err = exportDocuments(ctx, os.Args[2:])
+	case "import":
+		err = importDocuments(ctx, os.Args[2:])
+	default:
+		fmt.Fprintf(os.Stderr, "unknown command %q\n", os.Args[1])
+		os.Exit(2)
+	}
+	if err != nil {
+		log.Fatal(err)
+	}
+}
+{{ if .Firestore }}
+func exportDocuments(ctx context.Context, args []string) error {
+	flags := flag.NewFlagSet("export", flag.ExitOnError)
+	projectID := flags.String("project", "{{ .ProjectID }}", "Google Cloud project ID")
+	collection := flags.String("collection", "{{ .Collection }}", "Firestore collection")
+	contentField := flags.String("content-field", "{{ .ContentField }}", "field holding the document text")
+	vectorField := flags.String("vector-field", "{{ .VectorField }}", "field holding the embedding")
+	out := flags.String("out", "documents.jsonl", "output file")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	client, err := firestore.NewClient(ctx, *projectID)
+	if err != nil {
+		return fmt.Errorf("failed to create Firestore client: %w", err)
+	}
+	defer client.Close()
+
+	file, err := os.Create(*out)
+	if err != nil {
+		return fmt.Errorf("failed to create %s: %w", *out, err)
+	}
+	defer file.Close()
+
+	writer := bufio.NewWriter(file)
+	encoder := json.NewEncoder(writer)
+
+	docs := client.Collection(*collection).Documents(ctx)
+	defer docs.Stop()
+
+	count := 0
+	for {
+		doc, err := docs.Next()
+		if err == iterator.Done {
+			break
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read %s: %w", *collection, err)
+		}
+
+		data := doc.Data()
+		content, _ := data[*contentField].(string)
+		delete(data, *contentField)
+		delete(data, *vectorField)
+
+		if err := encoder.Encode(record{ID: doc.Ref.ID, Content: content, Metadata: data}); err != nil {
+			return fmt.Errorf("failed to write %s: %w", doc.Ref.ID, err)
+		}
+		count++
+	}
+
+	if err := writer.Flush(); err != nil {
+		return fmt.Errorf("failed to write %s: %w", *out, err)
+	}
+
+	log.Printf("exported %d documents to %s", count, *out)
+	return nil
+}
+{{ else }}
+func exportDocuments(ctx context.Context, args []string) error {
+	return fmt.Errorf("Vertex AI Vector Search only stores vectors; export the documents from their system of record as JSON lines")
+}
+{{ end }}
+{{- if eq .Backend "bedrock-kb" }}
+func importDocuments(ctx context.Context, args []string) error {
+	flags := flag.NewFlagSet("import", flag.ExitOnError)
+	in := flags.String("in", "documents.jsonl", "export file")
+	region := flags.String("region", "{{ .Region }}", "AWS region")
+	bucket := flags.String("bucket", os.Getenv("DOCUMENTS_BUCKET"), "knowledge base data source bucket (terraform output documents_bucket)")
+	knowledgeBaseID := flags.String("knowledge-base-id", os.Getenv("BEDROCK_KNOWLEDGE_BASE_ID"), "terraform output knowledge_base_id")
+	dataSourceID := flags.String("data-source-id", os.Getenv("BEDROCK_DATA_SOURCE_ID"), "terraform output data_source_id")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	cfg, err := config.LoadDefaultConfig(ctx, config.WithRegion(*region))
+	if err != nil {
+		return fmt.Errorf("failed to load AWS config: %w", err)
+	}
+	storage := s3.NewFromConfig(cfg)
+
+	count := 0
+	err = readRecords(*in, func(batch []record) error {
+		for _, rec := range batch {
+			key := rec.ID + ".txt"
+			_, err := storage.PutObject(ctx, &s3.PutObjectInput{
+				Bucket: bucket,
+				Key:    aws.String(key),
+				Body:   bytes.NewReader([]byte(rec.Content)),
+			})
+			if err != nil {
+				return fmt.Errorf("failed to upload %s: %w", key, err)
+			}
+
+			if len(rec.Metadata) > 0 {
+				metadata, err := json.Marshal(map[string]any{"metadataAttributes": rec.Metadata})
+				if err != nil {
+					return err
+				}
+				_, err = storage.PutObject(ctx, &s3.PutObjectInput{
+					Bucket: bucket,
+					Key:    aws.String(key + ".metadata.json"),
+					Body:   bytes.NewReader(metadata),
+				})
+				if err != nil {
+					return fmt.Errorf("failed to upload metadata for %s: %w", key, err)
+				}
+			}
+			count++
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	job, err := bedrockagent.NewFromConfig(cfg).StartIngestionJob(ctx, &bedrockagent.StartIngestionJobInput{
+		KnowledgeBaseId: knowledgeBaseID,
+		DataSourceId:    dataSourceID,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to start ingestion job: %w", err)
+	}
+
+	log.Printf("uploaded %d documents, ingestion job %s started", count, aws.ToString(job.IngestionJob.IngestionJobId))
+	return nil
+}
+{{- else }}
+func importDocuments(ctx context.Context, args []string) error {
+	flags := flag.NewFlagSet("import", flag.ExitOnError)
+	in := flags.String("in", "documents.jsonl", "export file")
+	region := flags.String("region", "{{ .Region }}", "AWS region")
+	connection := flags.String("connection", os.Getenv("{{ .ConnectionEnv }}"), "{{ .ConnectionField }} of the vector store")
+	collection := flags.String("{{ if eq .Backend "aurora-pgvector" }}table{{ else }}index{{ end }}", "{{ .Collection }}", "target {{ .CollectionField }}")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	g, err := genkit.Init(ctx, genkit.WithPlugins(genkitaws.New(&genkitaws.Config{Region: *region})))
+	if err != nil {
+		return fmt.Errorf("failed to initialize genkit: %w", err)
+	}
+
+	indexer, err := {{ .PackageName }}.DefineIndexer(ctx, g, {{ .PackageName }}.IndexerOptions{
+		Name:                 "migrate-vectors",
+		{{ .ConnectionField }}: *connection,
+		{{ .CollectionField }}: *collection,
+		Embedder:             bedrock.Embedder(g, "{{ .Embedder }}"),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to define indexer: %w", err)
+	}
+
+	count := 0
+	err = readRecords(*in, func(batch []record) error {
+		docs := make([]*ai.Document, 0, len(batch))
+		for _, rec := range batch {
+			metadata := rec.Metadata
+			if metadata == nil {
+				metadata = make(map[string]any)
+			}
+			metadata["id"] = rec.ID
+			docs = append(docs, ai.DocumentFromText(rec.Content, metadata))
+		}
+		if err := indexer.Index(ctx, &ai.IndexerRequest{Documents: docs}); err != nil {
+			return fmt.Errorf("failed to index documents: %w", err)
+		}
+		count += len(docs)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	log.Printf("imported %d documents", count)
+	return nil
+}
+{{- end }}
+
+func readRecords(path string, fn func([]record) error) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("failed to open %s: %w", path, err)
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+
+	batch := make([]record, 0, batchSize)
+	line := 0
+	for scanner.Scan() {
+		line++
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+
+		var rec record
+		if err := json.Unmarshal(scanner.Bytes(), &rec); err != nil {
+			return fmt.Errorf("invalid record on line %d: %w", line, err)
+		}
+		if rec.ID == "" {
+			rec.ID = fmt.Sprintf("doc-%d", line)
+		}
+
+		batch = append(batch, rec)
+		if len(batch) == batchSize {
+			if err := fn(batch); err != nil {
+				return err
+			}
+			batch = batch[:0]
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	if len(batch) > 0 {
+		return fn(batch)
+	}
+	return nil
+}
+`
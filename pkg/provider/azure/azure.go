@@ -0,0 +1,562 @@
+// Package azure migrates GenKit projects to Azure OpenAI, deployed to Azure
+// Container Apps with Terraform.
+package azure
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"text/template"
+
+	"github.com/genkit-migrate/genkit-migrate/pkg/models"
+	"github.com/genkit-migrate/genkit-migrate/pkg/provider"
+)
+
+const (
+	openAIPluginPackage = "github.com/firebase/genkit/go/plugins/compat_oai/openai"
+	azureOpenAIVersion  = "2024-10-21"
+)
+
+// Options read from the providers.azure section of the config file.
+const (
+	SubscriptionOption  = "azure-subscription-id"
+	ResourceGroupOption = "azure-resource-group"
+	LocationOption      = "azure-location"
+)
+
+func init() {
+	provider.Register(azureProvider{})
+}
+
+type azureProvider struct{}
+
+func (azureProvider) Name() string {
+	return "azure"
+}
+
+func (azureProvider) Title() string {
+	return "Azure"
+}
+
+// DetectModel leaves openai/ references to the OpenAI API provider; Azure
+// deployments are registered under the same names.
+func (azureProvider) DetectModel(ref string) bool {
+	return false
+}
+
+func (azureProvider) Source() *provider.Source {
+	return nil
+}
+
+// Azure OpenAI deployments the migrated models are served from. The openai
+// plugin registers models under their OpenAI names and sends that name as the
+// deployment, so each deployment is named after its model.
+type azureDeployment struct {
+	Name     string
+	Model    string
+	Version  string
+	Capacity int
+}
+
+var azureDeployments = map[string]azureDeployment{
+	"gpt-4o":                 {Name: "gpt-4o", Model: "gpt-4o", Version: "2024-08-06", Capacity: 10},
+	"gpt-4o-mini":            {Name: "gpt-4o-mini", Model: "gpt-4o-mini", Version: "2024-07-18", Capacity: 10},
+	"text-embedding-3-small": {Name: "text-embedding-3-small", Model: "text-embedding-3-small", Version: "1", Capacity: 10},
+}
+
+const openAIDimensionsNote = "text-embedding-3-small can shorten vectors through the dimensions request option"
+
+// Catalog maps to deployment names. Deployments are named after their OpenAI
+// model, so the OpenAI API provider uses the same catalog.
+func (azureProvider) Catalog(ctx provider.Context) *provider.Catalog {
+	return OpenAICatalog(ctx.Source())
+}
+
+func OpenAICatalog(source string) *provider.Catalog {
+	textEmbedding := provider.Embedder{Target: "text-embedding-3-small", Dimensions: 1536, Note: openAIDimensionsNote}
+	catalog := &provider.Catalog{
+		Embedders: map[string]provider.Embedder{
+			"googleai/text-embedding-004":              textEmbedding,
+			"googleai/embedding-001":                   textEmbedding,
+			"googleai/gemini-embedding-001":            textEmbedding,
+			"vertexai/text-embedding-004":              textEmbedding,
+			"vertexai/text-embedding-005":              textEmbedding,
+			"vertexai/textembedding-gecko@003":         textEmbedding,
+			"vertexai/gemini-embedding-001":            textEmbedding,
+			"vertexai/text-multilingual-embedding-002": textEmbedding,
+			"bedrock/amazon.titan-embed-text-v2:0":     textEmbedding,
+			"bedrock/amazon.titan-embed-text-v1":       textEmbedding,
+			"bedrock/cohere.embed-english-v3":          textEmbedding,
+			"bedrock/cohere.embed-multilingual-v3":     textEmbedding,
+		},
+		Prefix: "openai/",
+	}
+
+	switch source {
+	case "gcp":
+		catalog.Models = map[string]string{
+			"googleai/gemini-1.5-flash":    "gpt-4o-mini",
+			"googleai/gemini-1.5-flash-8b": "gpt-4o-mini",
+			"googleai/gemini-1.5-pro":      "gpt-4o",
+			"googleai/gemini-2.0-flash":    "gpt-4o",
+			"vertexai/gemini-pro":          "gpt-4o",
+			"vertexai/gemini-1.5-pro":      "gpt-4o",
+			"vertexai/gemini-1.5-flash":    "gpt-4o-mini",
+		}
+	case "aws":
+		catalog.Models = map[string]string{
+			"bedrock/anthropic.claude-3-haiku-20240307-v1:0":    "gpt-4o-mini",
+			"bedrock/anthropic.claude-3-sonnet-20240229-v1:0":   "gpt-4o",
+			"bedrock/anthropic.claude-3-opus-20240229-v1:0":     "gpt-4o",
+			"bedrock/anthropic.claude-3-5-sonnet-20240620-v1:0": "gpt-4o",
+			"bedrock/anthropic.claude-3-5-sonnet-20241022-v2:0": "gpt-4o",
+			"bedrock/amazon.nova-micro-v1:0":                    "gpt-4o-mini",
+			"bedrock/amazon.nova-lite-v1:0":                     "gpt-4o-mini",
+			"bedrock/amazon.nova-pro-v1:0":                      "gpt-4o",
+		}
+	}
+	return catalog
+}
+
+// Rewrite registers GenKit's OpenAI-compatible plugin against the Azure
+// OpenAI endpoint of the generated account.
+func (azureProvider) Rewrite(ctx provider.Context) *provider.Rewrite {
+	return &provider.Rewrite{
+		Plugin: &provider.Plugin{
+			Name: "openai",
+			Path: openAIPluginPackage,
+			Imports: [][2]string{
+				{"os", "os"},
+				{"openai", openAIPluginPackage},
+				{"option", "github.com/openai/openai-go/option"},
+				{"azure", "github.com/openai/openai-go/azure"},
+			},
+			Expr: fmt.Sprintf(`&openai.OpenAI{APIKey: os.Getenv("AZURE_OPENAI_API_KEY"), Opts: []option.RequestOption{`+
+				`azure.WithEndpoint(os.Getenv("AZURE_OPENAI_ENDPOINT"), %q), azure.WithAPIKey(os.Getenv("AZURE_OPENAI_API_KEY"))}}`, azureOpenAIVersion),
+			Lookup:         "genkit",
+			LookupPath:     "github.com/firebase/genkit/go/genkit",
+			LookupProvider: "openai",
+		},
+		Requires: [][2]string{{"github.com/openai/openai-go", "v1.8.2"}},
+	}
+}
+
+var gcpAzureRegions = map[string]string{
+	"us-central1":          "centralus",
+	"us-east1":             "eastus",
+	"us-east4":             "eastus2",
+	"us-west1":             "westus2",
+	"us-west2":             "westus",
+	"europe-west1":         "westeurope",
+	"europe-west2":         "uksouth",
+	"europe-west3":         "germanywestcentral",
+	"europe-west4":         "westeurope",
+	"asia-northeast1":      "japaneast",
+	"asia-southeast1":      "southeastasia",
+	"australia-southeast1": "australiaeast",
+}
+
+var awsAzureRegions = map[string]string{
+	"us-east-1":      "eastus",
+	"us-east-2":      "eastus2",
+	"us-west-1":      "westus",
+	"us-west-2":      "westus2",
+	"eu-west-1":      "northeurope",
+	"eu-west-2":      "uksouth",
+	"eu-central-1":   "germanywestcentral",
+	"ap-northeast-1": "japaneast",
+	"ap-southeast-1": "southeastasia",
+	"ap-southeast-2": "australiaeast",
+}
+
+func translateGCPRegion(region string) string {
+	if azureRegion, exists := gcpAzureRegions[region]; exists {
+		return azureRegion
+	}
+	return "eastus"
+}
+
+func translateAWSRegion(region string) string {
+	if azureRegion, exists := awsAzureRegions[region]; exists {
+		return azureRegion
+	}
+	return "eastus"
+}
+
+const managedIdentityNote = "Azure Container Apps authenticate with a managed identity"
+
+var gcpSettingKeys = map[string]provider.SettingKey{
+	"GOOGLE_CLOUD_PROJECT":           {Note: "Azure resources are scoped by subscription and resource group"},
+	"GCLOUD_PROJECT":                 {Note: "Azure resources are scoped by subscription and resource group"},
+	"GCP_PROJECT":                    {Note: "Azure resources are scoped by subscription and resource group"},
+	"PROJECT_ID":                     {Note: "Azure resources are scoped by subscription and resource group"},
+	"GCLOUD_LOCATION":                {Key: "AZURE_LOCATION", Value: translateGCPRegion},
+	"GOOGLE_CLOUD_LOCATION":          {Key: "AZURE_LOCATION", Value: translateGCPRegion},
+	"GOOGLE_CLOUD_REGION":            {Key: "AZURE_LOCATION", Value: translateGCPRegion},
+	"LOCATION":                       {Key: "AZURE_LOCATION", Value: translateGCPRegion},
+	"REGION":                         {Key: "AZURE_LOCATION", Value: translateGCPRegion},
+	"GOOGLE_APPLICATION_CREDENTIALS": {Note: managedIdentityNote},
+	"GOOGLE_API_KEY":                 {Note: "Azure OpenAI reads AZURE_OPENAI_API_KEY from the Container App secret"},
+	"GOOGLE_GENAI_API_KEY":           {Note: "Azure OpenAI reads AZURE_OPENAI_API_KEY from the Container App secret"},
+	"GEMINI_API_KEY":                 {Note: "Azure OpenAI reads AZURE_OPENAI_API_KEY from the Container App secret"},
+}
+
+var awsSettingKeys = map[string]provider.SettingKey{
+	"AWS_REGION":            {Key: "AZURE_LOCATION", Value: translateAWSRegion},
+	"AWS_DEFAULT_REGION":    {Key: "AZURE_LOCATION", Value: translateAWSRegion},
+	"AWS_PROFILE":           {Note: managedIdentityNote},
+	"AWS_ACCESS_KEY_ID":     {Note: managedIdentityNote},
+	"AWS_SECRET_ACCESS_KEY": {Note: managedIdentityNote},
+	"AWS_SESSION_TOKEN":     {Note: managedIdentityNote},
+}
+
+// Container App secrets belong to the app and are lowercase.
+func (azureProvider) Settings(ctx provider.Context) *provider.Settings {
+	settings := &provider.Settings{
+		Keys:     gcpSettingKeys,
+		Reserved: []string{"subscription_id", "resource_group", "location", "openai", "environment"},
+		Secret: func(key, value string) *provider.Secret {
+			name := strings.ReplaceAll(strings.ToLower(key), "_", "-")
+			return &provider.Secret{
+				Name:      name,
+				Reference: "secretref:" + name,
+				Store:     "Container App secret",
+				Command: fmt.Sprintf("az containerapp secret set --name %s --resource-group %s --secrets %s=\"%s\"",
+					ctx.ModuleName(), resourceGroup(ctx), name, value),
+			}
+		},
+	}
+	if ctx.Source() == "aws" {
+		settings.Keys = awsSettingKeys
+	}
+	return settings
+}
+
+func resourceGroup(ctx provider.Context) string {
+	if group := ctx.Option(ResourceGroupOption); group != "" {
+		return group
+	}
+	return "genkit-app-rg"
+}
+
+func location(ctx provider.Context) string {
+	if location := ctx.Option(LocationOption); location != "" {
+		return location
+	}
+	if location, exists := ctx.Setting("AZURE_LOCATION"); exists {
+		return location
+	}
+	return "eastus"
+}
+
+// usedDeployments returns the deployments the migrated models and embedders
+// are served from, including models only referenced by name in the
+// rewritten sources.
+func usedDeployments(ctx provider.Context) []azureDeployment {
+	migration := ctx.Migration()
+	catalog := ctx.Catalog()
+
+	names := make(map[string]bool)
+	for _, model := range migration.Project.Models {
+		if deployment, exists := catalog.Models[model.Name]; exists {
+			names[deployment] = true
+		}
+	}
+	for _, embedder := range migration.Project.Embedders {
+		if deployment, exists := catalog.Embedders[embedder.Name]; exists {
+			names[deployment.Target] = true
+		}
+	}
+	for _, change := range migration.Changes {
+		deployment := strings.TrimPrefix(change.NewValue, "openai/")
+		if _, exists := azureDeployments[deployment]; exists && (change.Type == "model" || change.Type == "embedder") {
+			names[deployment] = true
+		}
+	}
+	if len(names) == 0 {
+		names["gpt-4o"] = true
+	}
+
+	deployments := make([]azureDeployment, 0, len(names))
+	for name := range names {
+		deployments = append(deployments, azureDeployments[name])
+	}
+	sort.Slice(deployments, func(i, j int) bool { return deployments[i].Name < deployments[j].Name })
+
+	return deployments
+}
+
+func (azureProvider) Configure(ctx provider.Context) error {
+	configTemplate := `# Azure Configuration for GenKit
+subscription_id: "{{ .SubscriptionID }}"
+resource_group: {{ .ResourceGroup }}
+location: {{ .Location }}
+
+openai:
+  endpoint: https://{{ .AccountName }}.openai.azure.com/
+  api_version: "{{ .APIVersion }}"
+  # Models are referenced by deployment name
+  deployments:
+{{- range .Deployments }}
+    - {{ .Name }}
+{{- end }}
+
+# Environment variables
+environment:
+  - GENKIT_ENV=production
+  - AZURE_OPENAI_ENDPOINT=https://{{ .AccountName }}.openai.azure.com/
+`
+
+	tmpl, err := template.New("config").Parse(configTemplate)
+	if err != nil {
+		return err
+	}
+
+	var content strings.Builder
+	err = tmpl.Execute(&content, map[string]interface{}{
+		"SubscriptionID": ctx.Option(SubscriptionOption),
+		"ResourceGroup":  resourceGroup(ctx),
+		"Location":       location(ctx),
+		"AccountName":    ctx.ModuleName() + "-openai",
+		"APIVersion":     azureOpenAIVersion,
+		"Deployments":    usedDeployments(ctx),
+	})
+	if err != nil {
+		return err
+	}
+
+	return ctx.WriteConfig(content.String())
+}
+
+func (azureProvider) Deploy(ctx provider.Context) error {
+	if err := generateTerraform(ctx); err != nil {
+		return err
+	}
+
+	provider.WriteDockerfile(ctx.Migration())
+	return nil
+}
+
+func generateTerraform(ctx provider.Context) error {
+	terraformMain := `# Terraform configuration for GenKit on Azure
+terraform {
+  required_version = ">= 1.0"
+  required_providers {
+    azurerm = {
+      source  = "hashicorp/azurerm"
+      version = "~> 4.0"
+    }
+  }
+}
+
+provider "azurerm" {
+  features {}
+  subscription_id = var.subscription_id
+}
+
+resource "azurerm_resource_group" "genkit" {
+  name     = var.resource_group_name
+  location = var.location
+}
+
+# Azure OpenAI account serving the migrated models
+resource "azurerm_cognitive_account" "openai" {
+  name                  = "${var.project_name}-openai"
+  custom_subdomain_name = "${var.project_name}-openai"
+  location              = azurerm_resource_group.genkit.location
+  resource_group_name   = azurerm_resource_group.genkit.name
+  kind                  = "OpenAI"
+  sku_name              = "S0"
+}
+{{ range .Deployments }}
+resource "azurerm_cognitive_deployment" "{{ resource .Name }}" {
+  name                 = "{{ .Name }}"
+  cognitive_account_id = azurerm_cognitive_account.openai.id
+
+  model {
+    format  = "OpenAI"
+    name    = "{{ .Model }}"
+    version = "{{ .Version }}"
+  }
+
+  sku {
+    name     = "Standard"
+    capacity = {{ .Capacity }}
+  }
+}
+{{ end }}
+resource "azurerm_log_analytics_workspace" "genkit" {
+  name                = "${var.project_name}-logs"
+  location            = azurerm_resource_group.genkit.location
+  resource_group_name = azurerm_resource_group.genkit.name
+  sku                 = "PerGB2018"
+  retention_in_days   = 30
+}
+
+resource "azurerm_container_app_environment" "genkit" {
+  name                       = "${var.project_name}-env"
+  location                   = azurerm_resource_group.genkit.location
+  resource_group_name        = azurerm_resource_group.genkit.name
+  log_analytics_workspace_id = azurerm_log_analytics_workspace.genkit.id
+}
+
+# Container App for GenKit app
+resource "azurerm_container_app" "genkit_app" {
+  name                         = var.project_name
+  container_app_environment_id = azurerm_container_app_environment.genkit.id
+  resource_group_name          = azurerm_resource_group.genkit.name
+  revision_mode                = "Single"
+
+  identity {
+    type = "SystemAssigned"
+  }
+
+  secret {
+    name  = "azure-openai-api-key"
+    value = azurerm_cognitive_account.openai.primary_access_key
+  }
+
+  template {
+    container {
+      name   = "genkit-app"
+      image  = var.image
+      cpu    = 0.5
+      memory = "1Gi"
+
+      env {
+        name  = "GENKIT_ENV"
+        value = "production"
+      }
+      env {
+        name  = "AZURE_OPENAI_ENDPOINT"
+        value = azurerm_cognitive_account.openai.endpoint
+      }
+      env {
+        name        = "AZURE_OPENAI_API_KEY"
+        secret_name = "azure-openai-api-key"
+      }
+    }
+  }
+
+  ingress {
+    external_enabled = true
+    target_port      = 8080
+
+    traffic_weight {
+      percentage      = 100
+      latest_revision = true
+    }
+  }
+}
+
+output "app_url" {
+  value = "https://${azurerm_container_app.genkit_app.latest_revision_fqdn}"
+}
+
+output "openai_endpoint" {
+  value = azurerm_cognitive_account.openai.endpoint
+}
+`
+
+	tmpl, err := template.New("main.tf").Funcs(template.FuncMap{
+		"resource": func(name string) string {
+			return strings.NewReplacer("-", "_", ".", "_").Replace(name)
+		},
+	}).Parse(terraformMain)
+	if err != nil {
+		return err
+	}
+
+	var content strings.Builder
+	err = tmpl.Execute(&content, map[string]interface{}{
+		"Deployments": usedDeployments(ctx),
+	})
+	if err != nil {
+		return err
+	}
+
+	migration := ctx.Migration()
+	migration.NewFiles["terraform/main.tf"] = content.String()
+
+	terraformVars := `variable "subscription_id" {
+  description = "Azure subscription ID"
+  type        = string
+  default     = "{{ .SubscriptionID }}"
+}
+
+variable "resource_group_name" {
+  description = "Resource group for the app"
+  type        = string
+  default     = "{{ .ResourceGroup }}"
+}
+
+variable "location" {
+  description = "Azure region"
+  type        = string
+  default     = "{{ .Location }}"
+}
+
+variable "project_name" {
+  description = "Project name"
+  type        = string
+  default     = "genkit-app"
+}
+
+variable "image" {
+  description = "Container image built from the Dockerfile"
+  type        = string
+}
+`
+
+	tmpl, err = template.New("variables.tf").Parse(terraformVars)
+	if err != nil {
+		return err
+	}
+
+	content.Reset()
+	err = tmpl.Execute(&content, map[string]interface{}{
+		"SubscriptionID": ctx.Option(SubscriptionOption),
+		"ResourceGroup":  resourceGroup(ctx),
+		"Location":       location(ctx),
+	})
+	if err != nil {
+		return err
+	}
+
+	migration.NewFiles["terraform/variables.tf"] = content.String()
+
+	return nil
+}
+
+func (azureProvider) Guide(migration *models.Migration) string {
+	content := `
+
+## Azure Deployment
+
+### Prerequisites
+
+1. Azure CLI logged in to the target subscription (` + "`az login`" + `)
+2. Terraform installed (>= 1.0)
+3. Azure OpenAI access approved for the subscription
+
+### Build the Container Image
+
+` + "```bash" + `
+az acr build --registry <your-registry> --image genkit-app:latest .
+` + "```" + `
+
+### Deploy with Terraform
+
+` + "```bash" + `
+cd terraform
+terraform init
+terraform apply -var image=<your-registry>.azurecr.io/genkit-app:latest
+` + "```" + `
+
+Terraform creates the Azure OpenAI account, one deployment per migrated model
+and a Container App that receives the endpoint and API key as environment
+variables. Models are referenced by deployment name (` + "`openai/<deployment>`" + `).
+`
+
+	return content + provider.ModelMappingsGuide(migration)
+}
@@ -0,0 +1,55 @@
+package provider
+
+import (
+	"fmt"
+
+	"github.com/genkit-migrate/genkit-migrate/pkg/models"
+)
+
+// Dockerfile builds the migrated app into a small image; every container
+// based target ships it.
+const Dockerfile = `# Multi-stage build for GenKit Go app
+FROM golang:1.23-alpine AS builder
+
+WORKDIR /app
+COPY go.mod go.sum ./
+RUN go mod download
+
+COPY . .
+RUN CGO_ENABLED=0 GOOS=linux go build -o main .
+
+FROM alpine:latest
+RUN apk --no-cache add ca-certificates
+WORKDIR /root/
+
+COPY --from=builder /app/main .
+
+EXPOSE 8080
+CMD ["./main"]
+`
+
+func WriteDockerfile(migration *models.Migration) {
+	migration.NewFiles["Dockerfile"] = Dockerfile
+}
+
+// ModelMappingsGuide lists the model mappings applied by the migration for
+// the deployment sections of MIGRATION.md.
+func ModelMappingsGuide(migration *models.Migration) string {
+	seen := make(map[string]bool)
+	mappings := ""
+	for _, change := range migration.Changes {
+		if change.Type != "model" || change.OldValue == "" || change.NewValue == "" {
+			continue
+		}
+		mapping := fmt.Sprintf("- `%s` → `%s`\n", change.OldValue, change.NewValue)
+		if !seen[mapping] {
+			seen[mapping] = true
+			mappings += mapping
+		}
+	}
+	if mappings == "" {
+		return ""
+	}
+
+	return "\n## Model Mappings Applied\n\n" + mappings
+}
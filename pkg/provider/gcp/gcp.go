@@ -0,0 +1,527 @@
+// Package gcp migrates GenKit projects to and from Google Cloud: Gemini
+// through the googleai and Vertex AI plugins, deployed to Cloud Run.
+package gcp
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"text/template"
+
+	"github.com/genkit-migrate/genkit-migrate/pkg/models"
+	"github.com/genkit-migrate/genkit-migrate/pkg/provider"
+)
+
+const vertexAIPackage = "github.com/firebase/genkit/go/plugins/vertexai"
+
+func init() {
+	provider.Register(gcpProvider{})
+}
+
+type gcpProvider struct{}
+
+func (gcpProvider) Name() string {
+	return "gcp"
+}
+
+func (gcpProvider) Title() string {
+	return "Google Cloud"
+}
+
+func (gcpProvider) DetectModel(ref string) bool {
+	return strings.Contains(ref, "googleai/") || strings.Contains(ref, "vertexai/")
+}
+
+var source = &provider.Source{
+	Plugins: []provider.PluginSource{
+		{Path: "github.com/firebase/genkit/go/plugins/googleai", Prefixes: []string{"googleai/"}, Literals: []string{"GoogleAI", "VertexAI"}, Init: true},
+		{Path: vertexAIPackage, Prefixes: []string{"vertexai/"}, Literals: []string{"GoogleAI", "VertexAI"}, Init: true},
+		{Path: "github.com/firebase/genkit/go/plugins/googlegenai", Prefixes: []string{"googleai/", "vertexai/"}, Literals: []string{"GoogleAI", "VertexAI"}, Init: true},
+	},
+	Clients: []provider.ClientSource{
+		{Path: "google.golang.org/genai", Prefix: "googleai/", Methods: clientMethods},
+		{Path: "github.com/google/generative-ai-go/genai", Prefix: "googleai/", Methods: clientMethods, Handles: true},
+		{Path: "cloud.google.com/go/vertexai/genai", Prefix: "vertexai/", Methods: clientMethods, Handles: true},
+	},
+	SDKs:     []string{"firebase", "google"},
+	Sections: []string{"GCP", "GOOGLE", "GCLOUD"},
+	// The analyzer flags Google Cloud settings by key prefix and section.
+	Owns: func(name string, known bool, setting *models.ConfigSetting) bool {
+		return setting.GCP
+	},
+	Generation: geminiGeneration,
+	Features:   features,
+}
+
+var genaiPackages = []string{
+	"google.golang.org/genai",
+	"github.com/google/generative-ai-go/genai",
+	"cloud.google.com/go/vertexai/genai",
+	"github.com/firebase/genkit/go/plugins/googlegenai",
+}
+
+var features = []provider.Feature{
+	{
+		Name:        "google-search-grounding",
+		Title:       "Grounding with Google Search",
+		Packages:    genaiPackages,
+		Identifiers: []string{"GoogleSearch", "GoogleSearchRetrieval"},
+		Settings:    []string{"GOOGLESEARCH", "GOOGLESEARCHRETRIEVAL", "GOOGLESEARCHGROUNDING"},
+	},
+	{
+		Name:        "context-caching",
+		Title:       "Gemini context caching",
+		Packages:    genaiPackages,
+		Identifiers: []string{"CachedContent", "Caches", "CreateCachedContent", "GenerativeModelFromCachedContent"},
+		Settings:    []string{"CACHEDCONTENT", "CONTEXTCACHE", "CONTEXTCACHING"},
+	},
+	{
+		Name:        "code-execution",
+		Title:       "Gemini code execution",
+		Packages:    genaiPackages,
+		Identifiers: []string{"CodeExecution", "ToolCodeExecution"},
+		Settings:    []string{"CODEEXECUTION"},
+	},
+	{
+		Name:  "vertex-rag-engine",
+		Title: "Vertex AI RAG Engine",
+		Packages: append([]string{
+			"cloud.google.com/go/aiplatform/apiv1beta1",
+			"cloud.google.com/go/aiplatform/apiv1beta1/aiplatformpb",
+			"cloud.google.com/go/aiplatform/apiv1",
+			"cloud.google.com/go/aiplatform/apiv1/aiplatformpb",
+		}, genaiPackages...),
+		Identifiers: []string{"VertexRAGStore", "VertexRagStore", "NewVertexRagDataClient", "NewVertexRagClient", "RagCorpus"},
+		Settings:    []string{"RAGCORPUS", "RAGCORPORA", "VERTEXRAGSTORE"},
+	},
+}
+
+var clientMethods = []string{
+	"GenerativeModel", "EmbeddingModel", "GenerateContent", "GenerateContentStream",
+	"StartChat", "SendMessage", "SendMessageStream", "EmbedContent", "CountTokens",
+}
+
+func (gcpProvider) Source() *provider.Source {
+	return source
+}
+
+// Gemini option names are also GenKit's common option names.
+var geminiGeneration = &provider.Generation{
+	Title: "Gemini",
+	Packages: []string{
+		"google.golang.org/genai",
+		"github.com/google/generative-ai-go/genai",
+		"cloud.google.com/go/vertexai/genai",
+		"github.com/firebase/genkit/go/plugins/googleai",
+		"github.com/firebase/genkit/go/plugins/vertexai",
+		"github.com/firebase/genkit/go/plugins/googlegenai",
+	},
+	Types: []string{"GenerationConfig", "GenerateContentConfig", "GeminiConfig"},
+	Options: []provider.GenerationOption{
+		{Field: "Temperature", Prompt: "temperature", Common: "Temperature"},
+		{Field: "MaxOutputTokens", Prompt: "maxOutputTokens", Common: "MaxOutputTokens"},
+		{Field: "TopP", Prompt: "topP", Common: "TopP"},
+		{Field: "TopK", Prompt: "topK", Common: "TopK"},
+		{Field: "StopSequences", Prompt: "stopSequences", Common: "StopSequences"},
+		{Field: "CandidateCount", Prompt: "candidateCount"},
+		{Field: "ResponseMIMEType", Prompt: "responseMimeType"},
+		{Field: "ResponseSchema", Prompt: "responseSchema", Schema: true},
+		{Field: "SafetySettings", Prompt: "safetySettings", Safety: true},
+		{Field: "PresencePenalty", Prompt: "presencePenalty"},
+		{Field: "FrequencyPenalty", Prompt: "frequencyPenalty"},
+		{Field: "Seed", Prompt: "seed"},
+	},
+}
+
+const vertexDimensionsNote = "Vertex AI text embeddings produce at most 768 dimensions"
+
+func (gcpProvider) Catalog(ctx provider.Context) *provider.Catalog {
+	if ctx.Source() != "aws" {
+		return &provider.Catalog{}
+	}
+
+	textEmbedding := provider.Embedder{Target: "vertexai/text-embedding-004", Dimensions: 768, Note: vertexDimensionsNote}
+	return &provider.Catalog{
+		Models: map[string]string{
+			"bedrock/anthropic.claude-3-haiku-20240307-v1:0":    "vertexai/gemini-1.5-flash",
+			"bedrock/anthropic.claude-3-sonnet-20240229-v1:0":   "vertexai/gemini-1.5-pro",
+			"bedrock/anthropic.claude-3-opus-20240229-v1:0":     "vertexai/gemini-1.5-pro",
+			"bedrock/anthropic.claude-3-5-sonnet-20240620-v1:0": "vertexai/gemini-2.0-flash",
+			"bedrock/anthropic.claude-3-5-sonnet-20241022-v2:0": "vertexai/gemini-2.0-flash",
+			"bedrock/amazon.nova-micro-v1:0":                    "vertexai/gemini-1.5-flash-8b",
+			"bedrock/amazon.nova-lite-v1:0":                     "vertexai/gemini-1.5-flash-8b",
+			"bedrock/amazon.nova-pro-v1:0":                      "vertexai/gemini-1.5-pro",
+		},
+		Embedders: map[string]provider.Embedder{
+			"bedrock/amazon.titan-embed-text-v2:0": textEmbedding,
+			"bedrock/amazon.titan-embed-text-v1":   textEmbedding,
+			"bedrock/cohere.embed-english-v3":      textEmbedding,
+			"bedrock/cohere.embed-multilingual-v3": {Target: "vertexai/text-multilingual-embedding-002", Dimensions: 768, Note: vertexDimensionsNote},
+		},
+	}
+}
+
+// Rewrite registers the Vertex AI plugin. The project is read from
+// GOOGLE_CLOUD_PROJECT, which the generated Cloud Run service sets, and Cloud
+// Run authenticates with its service account the way Lambda does with IAM.
+func (gcpProvider) Rewrite(ctx provider.Context) *provider.Rewrite {
+	region := region(ctx)
+	return &provider.Rewrite{
+		Plugin: &provider.Plugin{
+			Name:         "vertexai",
+			Path:         vertexAIPackage,
+			Imports:      [][2]string{{"vertexai", vertexAIPackage}},
+			Init:         "vertexai.Init",
+			InitConfig:   fmt.Sprintf(`&vertexai.Config{Location: %q}`, region),
+			Expr:         fmt.Sprintf(`&vertexai.VertexAI{Location: %q}`, region),
+			Lookup:       "vertexai",
+			LookupPath:   vertexAIPackage,
+			LookupPrefix: "vertexai/",
+		},
+	}
+}
+
+func region(ctx provider.Context) string {
+	if region, exists := ctx.Setting("GOOGLE_CLOUD_LOCATION"); exists {
+		return region
+	}
+	return "us-central1"
+}
+
+func projectID(ctx provider.Context) string {
+	if projectID, exists := ctx.Setting("GOOGLE_CLOUD_PROJECT"); exists {
+		return projectID
+	}
+	return "my-project"
+}
+
+var awsRegions = map[string]string{
+	"us-east-1":      "us-east4",
+	"us-east-2":      "us-east5",
+	"us-west-1":      "us-west2",
+	"us-west-2":      "us-west1",
+	"eu-west-1":      "europe-west1",
+	"eu-west-2":      "europe-west2",
+	"eu-central-1":   "europe-west3",
+	"ap-northeast-1": "asia-northeast1",
+	"ap-southeast-1": "asia-southeast1",
+	"ap-southeast-2": "australia-southeast1",
+}
+
+func translateRegion(region string) string {
+	if gcpRegion, exists := awsRegions[region]; exists {
+		return gcpRegion
+	}
+	return "us-central1"
+}
+
+var awsSettingKeys = map[string]provider.SettingKey{
+	"PROJECT_NAME":          {Key: "GOOGLE_CLOUD_PROJECT"},
+	"AWS_REGION":            {Key: "GOOGLE_CLOUD_LOCATION", Value: translateRegion},
+	"AWS_DEFAULT_REGION":    {Key: "GOOGLE_CLOUD_LOCATION", Value: translateRegion},
+	"AWS_PROFILE":           {Note: "Cloud Run authenticates with its service account"},
+	"AWS_ACCESS_KEY_ID":     {Note: "Cloud Run authenticates with its service account"},
+	"AWS_SECRET_ACCESS_KEY": {Note: "Cloud Run authenticates with its service account"},
+	"AWS_SESSION_TOKEN":     {Note: "Cloud Run authenticates with its service account"},
+}
+
+// Secret Manager IDs may not contain slashes, so secrets use an underscore
+// separator.
+func (gcpProvider) Settings(ctx provider.Context) *provider.Settings {
+	settings := &provider.Settings{
+		Reserved: []string{"project", "location", "vertexai", "environment"},
+		Secret: func(key, value string) *provider.Secret {
+			name := ctx.ModuleName() + "_" + key
+			return &provider.Secret{
+				Name:      name,
+				Reference: fmt.Sprintf("sm://%s/%s", projectID(ctx), name),
+				Store:     "Secret Manager secret",
+				Command:   fmt.Sprintf("printf '%%s' \"%s\" | gcloud secrets create %s --data-file=-", value, name),
+			}
+		},
+	}
+	if ctx.Source() == "aws" {
+		settings.Keys = awsSettingKeys
+	}
+	return settings
+}
+
+func (gcpProvider) Configure(ctx provider.Context) error {
+	configTemplate := `# Google Cloud Configuration for GenKit
+project: {{ .ProjectID }}
+location: {{ .Region }}
+
+vertexai:
+  models:
+{{- range .Models }}
+    - {{ . }}
+{{- end }}
+
+# Environment variables
+environment:
+  - GENKIT_ENV=production
+  - GOOGLE_CLOUD_PROJECT={{ .ProjectID }}
+  - GOOGLE_CLOUD_LOCATION={{ .Region }}
+`
+
+	tmpl, err := template.New("config").Parse(configTemplate)
+	if err != nil {
+		return err
+	}
+
+	var content strings.Builder
+	err = tmpl.Execute(&content, map[string]interface{}{
+		"ProjectID": projectID(ctx),
+		"Region":    region(ctx),
+		"Models":    geminiModels(ctx),
+	})
+	if err != nil {
+		return err
+	}
+
+	return ctx.WriteConfig(content.String())
+}
+
+// geminiModels lists the Gemini models the migrated app uses, without the
+// plugin prefix.
+func geminiModels(ctx provider.Context) []string {
+	mappings := ctx.Catalog().Models
+
+	seen := make(map[string]bool)
+	geminiModels := make([]string, 0)
+	for _, model := range ctx.Project().Models {
+		if newModel, exists := mappings[model.Name]; exists && !seen[newModel] {
+			seen[newModel] = true
+			geminiModels = append(geminiModels, strings.TrimPrefix(newModel, "vertexai/"))
+		}
+	}
+	if len(geminiModels) == 0 {
+		geminiModels = append(geminiModels, "gemini-1.5-pro")
+	}
+	sort.Strings(geminiModels)
+
+	return geminiModels
+}
+
+func (gcpProvider) Deploy(ctx provider.Context) error {
+	if err := generateCloudRunTerraform(ctx); err != nil {
+		return err
+	}
+
+	provider.WriteDockerfile(ctx.Migration())
+	return generateCloudBuild(ctx)
+}
+
+func generateCloudRunTerraform(ctx provider.Context) error {
+	terraformMain := `# Terraform configuration for GenKit on Google Cloud
+terraform {
+  required_version = ">= 1.0"
+  required_providers {
+    google = {
+      source  = "hashicorp/google"
+      version = "~> 5.0"
+    }
+  }
+}
+
+provider "google" {
+  project = var.project_id
+  region  = var.region
+}
+
+resource "google_project_service" "services" {
+  for_each = toset([
+    "aiplatform.googleapis.com",
+    "artifactregistry.googleapis.com",
+    "cloudbuild.googleapis.com",
+    "run.googleapis.com",
+  ])
+
+  service            = each.value
+  disable_on_destroy = false
+}
+
+# Container registry for the app image built by Cloud Build
+resource "google_artifact_registry_repository" "genkit_app" {
+  location      = var.region
+  repository_id = var.project_name
+  format        = "DOCKER"
+
+  depends_on = [google_project_service.services]
+}
+
+# Service account the Cloud Run service runs as
+resource "google_service_account" "genkit_app" {
+  account_id   = var.project_name
+  display_name = "GenKit app"
+}
+
+# Vertex AI access for Gemini models
+resource "google_project_iam_member" "vertex_ai_user" {
+  project = var.project_id
+  role    = "roles/aiplatform.user"
+  member  = "serviceAccount:${google_service_account.genkit_app.email}"
+}
+
+# Cloud Run service for GenKit app
+resource "google_cloud_run_v2_service" "genkit_app" {
+  name     = var.project_name
+  location = var.region
+
+  template {
+    service_account = google_service_account.genkit_app.email
+
+    containers {
+      image = "${var.region}-docker.pkg.dev/${var.project_id}/${google_artifact_registry_repository.genkit_app.repository_id}/${var.project_name}:latest"
+
+      ports {
+        container_port = 8080
+      }
+
+      env {
+        name  = "GENKIT_ENV"
+        value = "production"
+      }
+      env {
+        name  = "GOOGLE_CLOUD_PROJECT"
+        value = var.project_id
+      }
+      env {
+        name  = "GOOGLE_CLOUD_LOCATION"
+        value = var.region
+      }
+    }
+  }
+
+  # Cloud Build deploys new revisions; Terraform only creates the service.
+  lifecycle {
+    ignore_changes = [template[0].containers[0].image]
+  }
+
+  depends_on = [google_project_service.services]
+}
+
+output "service_url" {
+  value = google_cloud_run_v2_service.genkit_app.uri
+}
+`
+
+	migration := ctx.Migration()
+	migration.NewFiles["terraform/main.tf"] = terraformMain
+
+	terraformVars := `variable "project_id" {
+  description = "Google Cloud project ID"
+  type        = string
+  default     = "{{ .ProjectID }}"
+}
+
+variable "region" {
+  description = "Google Cloud region"
+  type        = string
+  default     = "{{ .Region }}"
+}
+
+variable "project_name" {
+  description = "Project name"
+  type        = string
+  default     = "genkit-app"
+}
+`
+
+	tmpl, err := template.New("variables.tf").Parse(terraformVars)
+	if err != nil {
+		return err
+	}
+
+	var content strings.Builder
+	err = tmpl.Execute(&content, map[string]interface{}{
+		"ProjectID": projectID(ctx),
+		"Region":    region(ctx),
+	})
+	if err != nil {
+		return err
+	}
+
+	migration.NewFiles["terraform/variables.tf"] = content.String()
+
+	return nil
+}
+
+func generateCloudBuild(ctx provider.Context) error {
+	cloudBuild := `# Build, push and deploy the GenKit app to Cloud Run
+steps:
+  - id: test
+    name: golang:1.23
+    entrypoint: go
+    args: ["test", "./..."]
+
+  - id: build
+    name: gcr.io/cloud-builders/docker
+    args: ["build", "-t", "${_IMAGE}:${BUILD_ID}", "-t", "${_IMAGE}:latest", "."]
+
+  - id: push
+    name: gcr.io/cloud-builders/docker
+    args: ["push", "--all-tags", "${_IMAGE}"]
+
+  - id: deploy
+    name: gcr.io/google.com/cloudsdktool/cloud-sdk
+    entrypoint: gcloud
+    args: ["run", "deploy", "${_SERVICE}", "--image", "${_IMAGE}:${BUILD_ID}", "--region", "${_REGION}"]
+
+substitutions:
+  _REGION: {{ .Region }}
+  _SERVICE: genkit-app
+  _IMAGE: ${_REGION}-docker.pkg.dev/${PROJECT_ID}/genkit-app/genkit-app
+
+options:
+  dynamicSubstitutions: true
+`
+
+	tmpl, err := template.New("cloudbuild.yaml").Parse(cloudBuild)
+	if err != nil {
+		return err
+	}
+
+	var content strings.Builder
+	err = tmpl.Execute(&content, map[string]interface{}{
+		"Region": region(ctx),
+	})
+	if err != nil {
+		return err
+	}
+
+	ctx.Migration().NewFiles["cloudbuild.yaml"] = content.String()
+	return nil
+}
+
+func (gcpProvider) Guide(migration *models.Migration) string {
+	content := `
+
+## Google Cloud Deployment
+
+### Prerequisites
+
+1. gcloud CLI authenticated against the target project
+2. Terraform installed (>= 1.0)
+3. Vertex AI, Cloud Run, Cloud Build and Artifact Registry APIs available
+
+### Deploy with Terraform
+
+` + "```bash" + `
+cd terraform
+terraform init
+terraform apply
+` + "```" + `
+
+### Build and Deploy with Cloud Build
+
+` + "```bash" + `
+gcloud builds submit --config cloudbuild.yaml
+` + "```" + `
+
+The Cloud Run service runs as a dedicated service account with the
+` + "`roles/aiplatform.user`" + ` role, so no API keys are needed to call Gemini.
+`
+
+	return content + provider.ModelMappingsGuide(migration)
+}
@@ -0,0 +1,419 @@
+// Package ollama migrates GenKit projects to models served by a local Ollama
+// server, for offline development and CI.
+package ollama
+
+import (
+	"fmt"
+	"go/format"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/template"
+
+	"github.com/genkit-migrate/genkit-migrate/pkg/models"
+	"github.com/genkit-migrate/genkit-migrate/pkg/provider"
+)
+
+const (
+	ollamaPluginPackage = "github.com/firebase/genkit/go/plugins/ollama"
+	ollamaEmbedder      = "nomic-embed-text"
+	ollamaServerAddress = "http://localhost:11434"
+	ollamaImage         = "ollama/ollama:0.5.7"
+)
+
+// ModelOption selects the local model, one of Models.
+const ModelOption = "ollama-model"
+
+// Models are the local models Gemini and Bedrock models can be mapped to; the
+// first one is the default.
+var Models = []string{"llama3", "mistral", "qwen2.5"}
+
+func init() {
+	provider.Register(ollamaProvider{})
+}
+
+type ollamaProvider struct{}
+
+func (ollamaProvider) Name() string {
+	return "ollama"
+}
+
+func (ollamaProvider) Title() string {
+	return "Ollama"
+}
+
+func (ollamaProvider) DetectModel(ref string) bool {
+	return strings.HasPrefix(ref, "ollama/")
+}
+
+func (ollamaProvider) Source() *provider.Source {
+	return nil
+}
+
+func model(ctx provider.Context) string {
+	if model := ctx.Option(ModelOption); model != "" {
+		return model
+	}
+	return Models[0]
+}
+
+var geminiModelRefs = []string{
+	"googleai/gemini-1.5-flash",
+	"googleai/gemini-1.5-flash-8b",
+	"googleai/gemini-1.5-pro",
+	"googleai/gemini-2.0-flash",
+	"vertexai/gemini-pro",
+	"vertexai/gemini-1.5-pro",
+	"vertexai/gemini-1.5-flash",
+}
+
+var bedrockModelRefs = []string{
+	"bedrock/anthropic.claude-3-haiku-20240307-v1:0",
+	"bedrock/anthropic.claude-3-sonnet-20240229-v1:0",
+	"bedrock/anthropic.claude-3-opus-20240229-v1:0",
+	"bedrock/anthropic.claude-3-5-sonnet-20240620-v1:0",
+	"bedrock/anthropic.claude-3-5-sonnet-20241022-v2:0",
+	"bedrock/amazon.nova-micro-v1:0",
+	"bedrock/amazon.nova-lite-v1:0",
+	"bedrock/amazon.nova-pro-v1:0",
+}
+
+const nomicDimensionsNote = "nomic-embed-text always produces 768 dimensions"
+
+// Catalog sends every chat model to the configured local model.
+func (ollamaProvider) Catalog(ctx provider.Context) *provider.Catalog {
+	sourceModels := geminiModelRefs
+	if ctx.Source() == "aws" {
+		sourceModels = bedrockModelRefs
+	}
+
+	mappings := make(map[string]string, len(sourceModels))
+	for _, ref := range sourceModels {
+		mappings[ref] = model(ctx)
+	}
+
+	nomic := provider.Embedder{Target: ollamaEmbedder, Dimensions: 768, Note: nomicDimensionsNote}
+	return &provider.Catalog{
+		Models: mappings,
+		Embedders: map[string]provider.Embedder{
+			"googleai/text-embedding-004":              nomic,
+			"googleai/embedding-001":                   nomic,
+			"googleai/gemini-embedding-001":            nomic,
+			"vertexai/text-embedding-004":              nomic,
+			"vertexai/text-embedding-005":              nomic,
+			"vertexai/textembedding-gecko@003":         nomic,
+			"vertexai/gemini-embedding-001":            nomic,
+			"vertexai/text-multilingual-embedding-002": nomic,
+			"bedrock/amazon.titan-embed-text-v2:0":     nomic,
+			"bedrock/amazon.titan-embed-text-v1":       nomic,
+			"bedrock/cohere.embed-english-v3":          nomic,
+			"bedrock/cohere.embed-multilingual-v3":     nomic,
+		},
+		Prefix: "ollama/",
+	}
+}
+
+// Rewrite registers the wrapper plugin generated by generatePlugin, which
+// defines the local models on Init; the Ollama plugin does not discover
+// models by itself.
+func (ollamaProvider) Rewrite(ctx provider.Context) *provider.Rewrite {
+	return &provider.Rewrite{
+		Plugin: &provider.Plugin{
+			Name:           "ollama",
+			Path:           ollamaPluginPackage,
+			Expr:           "newOllamaPlugin()",
+			Lookup:         "genkit",
+			LookupPath:     "github.com/firebase/genkit/go/genkit",
+			LookupProvider: "ollama",
+		},
+	}
+}
+
+const localCredentialsNote = "the local Ollama server needs no cloud credentials"
+
+var gcpSettingKeys = map[string]provider.SettingKey{
+	"GOOGLE_CLOUD_PROJECT":           {Note: "the app runs locally against Ollama"},
+	"GCLOUD_PROJECT":                 {Note: "the app runs locally against Ollama"},
+	"GCP_PROJECT":                    {Note: "the app runs locally against Ollama"},
+	"PROJECT_ID":                     {Note: "the app runs locally against Ollama"},
+	"GCLOUD_LOCATION":                {Note: "the app runs locally against Ollama"},
+	"GOOGLE_CLOUD_LOCATION":          {Note: "the app runs locally against Ollama"},
+	"GOOGLE_CLOUD_REGION":            {Note: "the app runs locally against Ollama"},
+	"LOCATION":                       {Note: "the app runs locally against Ollama"},
+	"REGION":                         {Note: "the app runs locally against Ollama"},
+	"GOOGLE_APPLICATION_CREDENTIALS": {Note: localCredentialsNote},
+	"GOOGLE_API_KEY":                 {Note: localCredentialsNote},
+	"GOOGLE_GENAI_API_KEY":           {Note: localCredentialsNote},
+	"GEMINI_API_KEY":                 {Note: localCredentialsNote},
+}
+
+var awsSettingKeys = map[string]provider.SettingKey{
+	"AWS_REGION":            {Note: "the app runs locally against Ollama"},
+	"AWS_DEFAULT_REGION":    {Note: "the app runs locally against Ollama"},
+	"AWS_PROFILE":           {Note: localCredentialsNote},
+	"AWS_ACCESS_KEY_ID":     {Note: localCredentialsNote},
+	"AWS_SECRET_ACCESS_KEY": {Note: localCredentialsNote},
+	"AWS_SESSION_TOKEN":     {Note: localCredentialsNote},
+}
+
+// Settings has no secret store; the app reads its secrets from the
+// environment.
+func (ollamaProvider) Settings(ctx provider.Context) *provider.Settings {
+	settings := &provider.Settings{
+		Keys:     gcpSettingKeys,
+		Reserved: []string{"server_address", "ollama", "environment"},
+	}
+	if ctx.Source() == "aws" {
+		settings.Keys = awsSettingKeys
+	}
+	return settings
+}
+
+// usedEmbedders returns the local embedders the migrated app needs.
+func usedEmbedders(migration *models.Migration) []string {
+	for _, change := range migration.Changes {
+		if change.Type == "embedder" && strings.TrimPrefix(change.NewValue, "ollama/") == ollamaEmbedder {
+			return []string{ollamaEmbedder}
+		}
+	}
+	if len(migration.Project.Embedders) > 0 {
+		return []string{ollamaEmbedder}
+	}
+	return nil
+}
+
+func (ollamaProvider) Configure(ctx provider.Context) error {
+	configTemplate := `# Ollama Configuration for GenKit
+server_address: {{ .ServerAddress }}
+
+ollama:
+  models:
+    - {{ .Model }}
+{{- if .Embedders }}
+  embedders:
+{{- range .Embedders }}
+    - {{ . }}
+{{- end }}
+{{- end }}
+
+# Environment variables
+environment:
+  - GENKIT_ENV=dev
+  - OLLAMA_HOST={{ .ServerAddress }}
+`
+
+	tmpl, err := template.New("config").Parse(configTemplate)
+	if err != nil {
+		return err
+	}
+
+	var content strings.Builder
+	err = tmpl.Execute(&content, map[string]interface{}{
+		"ServerAddress": ollamaServerAddress,
+		"Model":         model(ctx),
+		"Embedders":     usedEmbedders(ctx.Migration()),
+	})
+	if err != nil {
+		return err
+	}
+
+	return ctx.WriteConfig(content.String())
+}
+
+func (ollamaProvider) Deploy(ctx provider.Context) error {
+	if err := generatePlugin(ctx); err != nil {
+		return err
+	}
+
+	provider.WriteDockerfile(ctx.Migration())
+	return generateDockerCompose(ctx)
+}
+
+// generatePlugin writes newOllamaPlugin next to every rewritten file that
+// registers it.
+func generatePlugin(ctx provider.Context) error {
+	pluginTemplate := `// Generated by genkit-migrate: registers the local Ollama models the
+// migrated code uses.
+
+package {{ .Package }}
+
+import (
+	"context"
+	"os"
+
+	"github.com/firebase/genkit/go/genkit"
+	"github.com/firebase/genkit/go/plugins/ollama"
+)
+
+// ollamaModels defines the models on Init so they can be looked up by name.
+type ollamaModels struct {
+	*ollama.Ollama
+}
+
+func newOllamaPlugin() *ollamaModels {
+	serverAddress := os.Getenv("OLLAMA_HOST")
+	if serverAddress == "" {
+		serverAddress = "{{ .ServerAddress }}"
+	}
+	return &ollamaModels{Ollama: &ollama.Ollama{ServerAddress: serverAddress}}
+}
+
+func (p *ollamaModels) Init(ctx context.Context, g *genkit.Genkit) error {
+	if err := p.Ollama.Init(ctx, g); err != nil {
+		return err
+	}
+	p.DefineModel(g, ollama.ModelDefinition{Name: "{{ .Model }}", Type: "chat"}, nil)
+{{- range .Embedders }}
+	p.DefineEmbedder(g, p.ServerAddress, "{{ . }}", nil)
+{{- end }}
+	return nil
+}
+`
+
+	tmpl, err := template.New("ollama.go").Parse(pluginTemplate)
+	if err != nil {
+		return err
+	}
+
+	migration := ctx.Migration()
+	paths := make([]string, 0)
+	for filePath := range migration.Project.Files {
+		if strings.Contains(migration.NewFiles[filePath], "newOllamaPlugin()") {
+			paths = append(paths, filePath)
+		}
+	}
+	sort.Strings(paths)
+
+	generated := make(map[string]bool)
+	for _, filePath := range paths {
+		pluginPath := filepath.Join(filepath.Dir(filePath), "genkit_ollama.go")
+		if generated[pluginPath] {
+			continue
+		}
+		generated[pluginPath] = true
+
+		var content strings.Builder
+		err = tmpl.Execute(&content, map[string]interface{}{
+			"Package":       migration.Project.Files[filePath].PackageName,
+			"ServerAddress": ollamaServerAddress,
+			"Model":         model(ctx),
+			"Embedders":     usedEmbedders(migration),
+		})
+		if err != nil {
+			return err
+		}
+
+		source, err := format.Source([]byte(content.String()))
+		if err != nil {
+			return fmt.Errorf("failed to format %s: %w", pluginPath, err)
+		}
+		migration.NewFiles[pluginPath] = string(source)
+	}
+
+	return nil
+}
+
+func generateDockerCompose(ctx provider.Context) error {
+	compose := `# Runs the GenKit app against a local Ollama server
+services:
+  ollama:
+    image: {{ .Image }}
+    ports:
+      - "11434:11434"
+    volumes:
+      - ollama:/root/.ollama
+    healthcheck:
+      test: ["CMD", "ollama", "list"]
+      interval: 5s
+      timeout: 5s
+      retries: 12
+
+  # Pulls the models once; they are kept in the ollama volume.
+  ollama-pull:
+    image: {{ .Image }}
+    environment:
+      OLLAMA_HOST: http://ollama:11434
+    entrypoint: ["/bin/sh", "-c", "{{ .Pull }}"]
+    depends_on:
+      ollama:
+        condition: service_healthy
+
+  app:
+    build: .
+    ports:
+      - "8080:8080"
+{{- if .EnvFile }}
+    env_file: .env
+{{- end }}
+    environment:
+      GENKIT_ENV: dev
+      OLLAMA_HOST: http://ollama:11434
+    depends_on:
+      ollama-pull:
+        condition: service_completed_successfully
+
+volumes:
+  ollama:
+`
+
+	tmpl, err := template.New("docker-compose.yml").Parse(compose)
+	if err != nil {
+		return err
+	}
+
+	migration := ctx.Migration()
+	pulls := []string{"ollama pull " + model(ctx)}
+	for _, embedder := range usedEmbedders(migration) {
+		pulls = append(pulls, "ollama pull "+embedder)
+	}
+
+	_, envFile := migration.NewFiles[".env"]
+
+	var content strings.Builder
+	err = tmpl.Execute(&content, map[string]interface{}{
+		"Image":   ollamaImage,
+		"Pull":    strings.Join(pulls, " && "),
+		"EnvFile": envFile,
+	})
+	if err != nil {
+		return err
+	}
+
+	migration.NewFiles["docker-compose.yml"] = content.String()
+	return nil
+}
+
+func (ollamaProvider) Guide(migration *models.Migration) string {
+	content := `
+
+## Local Development with Ollama
+
+### Prerequisites
+
+1. Docker with the Compose plugin
+2. Enough memory for the local model (8 GB for the 7-8B models)
+
+### Run with Docker Compose
+
+` + "```bash" + `
+docker compose up --build
+` + "```" + `
+
+Compose starts Ollama, pulls the models into the ` + "`ollama`" + ` volume once and
+then starts the app on port 8080. After the first pull no network access or
+cloud credentials are needed.
+
+### Run against a Host Ollama
+
+` + "```bash" + `
+ollama serve &
+ollama pull <model>
+OLLAMA_HOST=http://localhost:11434 go run .
+` + "```" + `
+
+` + "`genkit_ollama.go`" + ` defines the local models when the plugin initializes;
+add a ` + "`DefineModel`" + ` call there to use another model.
+`
+
+	return content + provider.ModelMappingsGuide(migration)
+}
@@ -0,0 +1,310 @@
+// Package provider defines what genkit-migrate needs to know about a cloud or
+// model API to migrate GenKit projects to or from it, and the registry the
+// analyzer, transformer and generator look providers up in.
+//
+// The built-in providers live in the packages below this one and register
+// themselves when imported; import pkg/provider/all to enable all of them.
+// Out-of-tree providers implement Provider and call Register from init.
+package provider
+
+import (
+	"strings"
+
+	"github.com/genkit-migrate/genkit-migrate/pkg/models"
+	"github.com/genkit-migrate/genkit-migrate/pkg/rewrite"
+)
+
+type Provider interface {
+	// Name is the identifier used by --from and --to.
+	Name() string
+	// Title is the display name used in descriptions and generated files.
+	Title() string
+	// DetectModel reports whether a model or embedder reference belongs to
+	// the provider.
+	DetectModel(ref string) bool
+	// Source describes the provider in projects migrated away from it, or
+	// returns nil when it is not supported as a source.
+	Source() *Source
+	// Catalog maps the models and embedders of ctx.Source() to the
+	// provider's own.
+	Catalog(ctx Context) *Catalog
+	// Rewrite describes how migrated code registers and uses the provider.
+	Rewrite(ctx Context) *Rewrite
+	// Settings describes how the settings of ctx.Source() translate.
+	Settings(ctx Context) *Settings
+	// Configure writes the migrated configuration files.
+	Configure(ctx Context) error
+	// Deploy writes the deployment artifacts.
+	Deploy(ctx Context) error
+	// Guide returns the deployment section of MIGRATION.md.
+	Guide(migration *models.Migration) string
+}
+
+// Context gives providers access to the migration in progress and to the
+// transformer's configuration helpers.
+type Context interface {
+	Source() string
+	Project() *models.Project
+	// Migration is nil while single files are rewritten outside of a
+	// project migration.
+	Migration() *models.Migration
+	// Option returns a provider option such as "vector-store", or "".
+	Option(name string) string
+	Catalog() *Catalog
+	ModuleName() string
+	ProjectName() string
+	// Setting returns the translated value of the first source setting
+	// that maps to the given target key.
+	Setting(key string) (string, bool)
+	// WriteConfig writes config.yaml, made of header followed by the
+	// application settings migrated from the source config.yaml, and
+	// migrates config.json and .env.
+	WriteConfig(header string) error
+}
+
+// Source describes the plugins, modules and settings of a source provider.
+type Source struct {
+	Plugins []PluginSource
+	// Clients are model SDKs that apps call directly, outside of GenKit.
+	Clients []ClientSource
+	// SDKs are fragments of the module paths dropped from go.mod.
+	SDKs []string
+	// Sections are the upper-case names of config sections holding the
+	// provider's settings.
+	Sections []string
+	// Owns reports whether a setting belongs to the provider; name is the
+	// upper-case last segment of its key and known reports whether the
+	// target translates it.
+	Owns       func(name string, known bool, setting *models.ConfigSetting) bool
+	Generation *Generation
+	// Features are capabilities specific to the provider, which targets
+	// without an equivalent report as blockers.
+	Features []Feature
+}
+
+// Feature describes how apps use a provider-specific capability.
+type Feature struct {
+	Name  string // key of the target's Catalog.Alternatives
+	Title string // e.g. "Grounding with Google Search"
+	// Packages are the import paths the feature is used through.
+	Packages []string
+	// Identifiers are the functions, types, fields and methods of those
+	// packages that use the feature.
+	Identifiers []string
+	// Settings are the prompt config keys and config file settings that
+	// enable it, compared in upper case without separators.
+	Settings []string
+}
+
+// PluginSource describes a GenKit plugin package the migration replaces.
+type PluginSource struct {
+	Path         string
+	Prefixes     []string // prefixes its models are registered under
+	Literals     []string // plugin struct types, e.g. &googleai.GoogleAI{}
+	Constructors []string // functions returning a plugin, e.g. genkitaws.New(cfg)
+	Init         bool
+}
+
+// ClientSource describes a model SDK whose generation calls are rewritten to
+// genkit.Generate.
+type ClientSource struct {
+	Path    string
+	Prefix  string   // prefix of the models it calls, e.g. "googleai/"
+	Methods []string // client methods that need migrating
+	// Handles reports whether models are bound to a handle, as in
+	// client.GenerativeModel(name).GenerateContent(ctx, parts...), rather
+	// than named per call, as in client.Models.GenerateContent(ctx, name,
+	// contents, config).
+	Handles bool
+}
+
+// Catalog maps source model and embedder references to target names.
+type Catalog struct {
+	Models    map[string]string
+	Embedders map[string]Embedder
+	// Prefix turns a mapped name into the reference GenKit resolves it by,
+	// e.g. "bedrock/"; empty when the mapped names carry their prefix.
+	Prefix string
+	// Alternatives suggest replacements for the source features the
+	// provider has no equivalent of, keyed by feature name.
+	Alternatives map[string]string
+}
+
+type Embedder struct {
+	Target     string
+	Dimensions int
+	Note       string
+}
+
+// Ref returns the registered name of a mapped model or embedder.
+func (c *Catalog) Ref(name string) string {
+	return c.Prefix + name
+}
+
+// EmbedderTargets returns the embedder mappings without their dimensions.
+func (c *Catalog) EmbedderTargets() map[string]string {
+	targets := make(map[string]string, len(c.Embedders))
+	for name, embedder := range c.Embedders {
+		targets[name] = embedder.Target
+	}
+	return targets
+}
+
+// Rewrite describes the plugin replacing the source plugins. The expressions
+// are Go source parsed with rewrite.ParseExpr.
+type Rewrite struct {
+	Plugin *Plugin
+	// Requires are the modules added to go.mod next to GenKit.
+	Requires [][2]string
+	// Generation is the provider's generation config; nil when the app
+	// uses ai.GenerationCommonConfig.
+	Generation *Generation
+	// Client receives the direct SDK generations that have no
+	// *genkit.Genkit in scope to go through; nil when they are flagged.
+	Client *Client
+	// Rules run after the built-in plugin and model rules.
+	Rules []rewrite.Rule
+}
+
+// Client is a generated helper calling the provider's own model SDK, as
+// Func(ctx, model, prompt). Its result has a Text method, like the
+// google.golang.org/genai response it replaces.
+type Client struct {
+	Title string // e.g. "Bedrock Converse"
+	Func  string
+}
+
+type Plugin struct {
+	Name         string
+	Path         string
+	Imports      [][2]string // package name and import path used by the expressions
+	Init         string      // function replacing the source Init, empty when there is none
+	InitConfig   string      // config passed to Init
+	Expr         string      // expression registering the plugin
+	Lookup       string      // package name providing Model and Embedder
+	LookupPath   string
+	LookupPrefix string // stripped from mapped model names
+	// When set, lookups become genkit.LookupModel(g, LookupProvider, name).
+	LookupProvider string
+}
+
+// Generation describes a provider-specific generation config type in terms
+// of GenKit's ai.GenerationCommonConfig.
+type Generation struct {
+	Title    string // used in range descriptions, e.g. "Bedrock"
+	Packages []string
+	Types    []string
+	// Package, Name and Type identify the config type migrated code uses.
+	Package string
+	Name    string
+	Type    string
+	Options []GenerationOption
+	// Unsupported explains why a field of another provider has no
+	// equivalent, keyed by field name.
+	Unsupported map[string]string
+	// Guardrail replaces per-request safety settings; nil when they are
+	// dropped.
+	Guardrail *Guardrail
+	// StructuredOutput enables schema-constrained output; nil when the
+	// provider's models take the schema as it is.
+	StructuredOutput *StructuredOutput
+}
+
+type GenerationOption struct {
+	Field    string // config struct field
+	Prompt   string // Dotprompt config key
+	Common   string // ai.GenerationCommonConfig field, empty when there is none
+	Min, Max float64
+	Limited  bool
+	Safety   bool // harm category thresholds, enforced by the target's Guardrail
+	Schema   bool // response schema, enforced by the target's StructuredOutput
+}
+
+// Guardrail describes the config field that applies content filters the
+// provider manages outside the request, such as a Bedrock guardrail.
+type Guardrail struct {
+	Title   string      // used in descriptions, e.g. "the Bedrock guardrail"
+	Field   string      // config field replacing the safety settings
+	Expr    string      // expression the field is set to
+	Imports [][2]string // package name and import path used by Expr
+}
+
+// StructuredOutput describes the config field that makes the provider's
+// models answer with JSON matching the requested output type, such as
+// Bedrock's forced tool use.
+type StructuredOutput struct {
+	Title   string      // used in descriptions, e.g. "Bedrock tool-use JSON mode"
+	Field   string      // config field enabling it
+	Expr    string      // expression the field is set to
+	Imports [][2]string // package name and import path used by Expr
+	// Models are prefixes of the models that support it; all do when
+	// empty.
+	Models []string
+}
+
+// Supports reports whether a model supports schema-constrained output.
+func (s *StructuredOutput) Supports(model string) bool {
+	if len(s.Models) == 0 {
+		return true
+	}
+	for _, prefix := range s.Models {
+		if strings.HasPrefix(model, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+func (g *Generation) ByField(field string) (GenerationOption, bool) {
+	for _, option := range g.Options {
+		if option.Field == field {
+			return option, true
+		}
+	}
+	return GenerationOption{}, false
+}
+
+func (g *Generation) ByPrompt(key string) (GenerationOption, bool) {
+	for _, option := range g.Options {
+		if option.Prompt == key {
+			return option, true
+		}
+	}
+	return GenerationOption{}, false
+}
+
+func (g *Generation) ByCommon(field string) (GenerationOption, bool) {
+	for _, option := range g.Options {
+		if field != "" && option.Common == field {
+			return option, true
+		}
+	}
+	return GenerationOption{}, false
+}
+
+// Settings describes how the source provider's settings translate.
+type Settings struct {
+	// Keys are keyed by the upper-case setting name.
+	Keys map[string]SettingKey
+	// Reserved are the top-level keys of the generated config.yaml.
+	Reserved []string
+	// Secret returns where a secret setting is stored, with a command
+	// creating it from value; nil when the app reads secrets from its
+	// environment.
+	Secret func(key, value string) *Secret
+}
+
+type SettingKey struct {
+	Key   string
+	Value func(string) string
+	Note  string
+}
+
+type Secret struct {
+	Name string
+	// Reference replaces the value, resolved by the target's runtime; empty
+	// when nothing resolves it and the app must read the secret itself.
+	Reference string
+	Store     string // e.g. "AWS Secrets Manager secret"
+	Command   string
+}
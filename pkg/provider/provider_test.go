@@ -0,0 +1,44 @@
+package provider_test
+
+import (
+	"testing"
+
+	"github.com/genkit-migrate/genkit-migrate/pkg/provider"
+	_ "github.com/genkit-migrate/genkit-migrate/pkg/provider/all"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRegistry(t *testing.T) {
+	assert.Equal(t, []string{"anthropic", "aws", "azure", "gcp", "ollama", "openai"}, provider.Names())
+	assert.Equal(t, []string{"aws", "gcp"}, provider.Sources())
+
+	aws, err := provider.Get("aws")
+	require.NoError(t, err)
+	assert.Equal(t, "AWS", aws.Title())
+
+	_, err = provider.Get("oracle")
+	assert.ErrorContains(t, err, `unknown provider "oracle"`)
+
+	assert.Panics(t, func() { provider.Register(aws) })
+}
+
+func TestDetectModel(t *testing.T) {
+	tests := []struct {
+		ref      string
+		expected string
+	}{
+		{"googleai/gemini-1.5-pro", "gcp"},
+		{"vertexai/text-embedding-004", "gcp"},
+		{"bedrock/anthropic.claude-3-haiku-20240307-v1:0", "aws"},
+		{"amazon.titan-embed-text-v2:0", "aws"},
+		{"openai/gpt-4o", "openai"},
+		{"claude-3-5-sonnet-20241022", "anthropic"},
+		{"ollama/llama3", "ollama"},
+		{"mystery-model", "unknown"},
+	}
+
+	for _, test := range tests {
+		assert.Equal(t, test.expected, provider.DetectModel(test.ref), "Model: %s", test.ref)
+	}
+}
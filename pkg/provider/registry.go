@@ -0,0 +1,74 @@
+package provider
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+)
+
+var (
+	mu        sync.RWMutex
+	providers = make(map[string]Provider)
+)
+
+// Register makes a provider available by name. It panics if the name is
+// already registered, like database/sql drivers.
+func Register(p Provider) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	if _, exists := providers[p.Name()]; exists {
+		panic(fmt.Sprintf("provider: Register called twice for %s", p.Name()))
+	}
+	providers[p.Name()] = p
+}
+
+func Get(name string) (Provider, error) {
+	mu.RLock()
+	defer mu.RUnlock()
+
+	p, exists := providers[name]
+	if !exists {
+		return nil, fmt.Errorf("unknown provider %q (registered: %v)", name, namesLocked())
+	}
+	return p, nil
+}
+
+// Names returns the registered providers in sorted order.
+func Names() []string {
+	mu.RLock()
+	defer mu.RUnlock()
+
+	return namesLocked()
+}
+
+// Sources returns the registered providers that can be migrated from.
+func Sources() []string {
+	names := make([]string, 0)
+	for _, name := range Names() {
+		if p, _ := Get(name); p.Source() != nil {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+func namesLocked() []string {
+	names := make([]string, 0, len(providers))
+	for name := range providers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// DetectModel returns the provider a model or embedder reference belongs to,
+// or "unknown".
+func DetectModel(ref string) string {
+	for _, name := range Names() {
+		if p, _ := Get(name); p.DetectModel(ref) {
+			return name
+		}
+	}
+	return "unknown"
+}
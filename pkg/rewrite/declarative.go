@@ -0,0 +1,473 @@
+package rewrite
+
+import (
+	"bytes"
+	"fmt"
+	"go/ast"
+	"go/format"
+	"go/token"
+	"go/types"
+	"os"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/genkit-migrate/genkit-migrate/pkg/models"
+	"gopkg.in/yaml.v3"
+)
+
+// RuleSpec is a user-supplied rewrite rule. Match is a Go expression pattern
+// in which $name matches any expression and a trailing $name... argument
+// matches the remaining call arguments; Replace is the template the match is
+// rewritten to, using the same metavariables.
+type RuleSpec struct {
+	Name        string      `yaml:"name"`
+	Description string      `yaml:"description"`
+	Match       string      `yaml:"match"`
+	Replace     string      `yaml:"replace"`
+	Imports     RuleImports `yaml:"imports"`
+	Review      bool        `yaml:"review"`
+}
+
+// RuleImports maps package names, as used in the match and replace
+// expressions, to import paths.
+type RuleImports struct {
+	Add    map[string]string `yaml:"add"`
+	Remove map[string]string `yaml:"remove"`
+}
+
+type ruleFile struct {
+	Rules []RuleSpec `yaml:"rules"`
+}
+
+type declarativeRule struct {
+	spec     RuleSpec
+	pattern  ast.Expr
+	replace  string
+	packages map[string]string
+}
+
+// restBinding holds the call arguments a $name... metavariable matched.
+type restBinding struct {
+	args     []ast.Expr
+	ellipsis token.Pos
+}
+
+// Metavariables are rewritten to reserved identifiers so patterns parse as
+// ordinary Go expressions.
+const (
+	metaPrefix = "__meta_"
+	restPrefix = "__rest_"
+)
+
+var (
+	restVariable = regexp.MustCompile(`\$([A-Za-z_][A-Za-z0-9_]*)\.\.\.`)
+	metavariable = regexp.MustCompile(`\$([A-Za-z_][A-Za-z0-9_]*)`)
+)
+
+func LoadRules(path string) ([]Rule, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read rules: %w", err)
+	}
+
+	rules, err := ParseRules(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load %s: %w", path, err)
+	}
+	return rules, nil
+}
+
+func ParseRules(data []byte) ([]Rule, error) {
+	var file ruleFile
+	if err := yaml.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("failed to parse rules: %w", err)
+	}
+
+	rules := make([]Rule, 0, len(file.Rules))
+	names := make(map[string]bool)
+	for i, spec := range file.Rules {
+		rule, err := NewRule(spec)
+		if err != nil {
+			return nil, fmt.Errorf("rule %d: %w", i+1, err)
+		}
+		if names[spec.Name] {
+			return nil, fmt.Errorf("rule %d: duplicate rule name %q", i+1, spec.Name)
+		}
+		names[spec.Name] = true
+		rules = append(rules, rule)
+	}
+	return rules, nil
+}
+
+// NewRule compiles a declarative rule so it can run alongside the built-in
+// rules.
+func NewRule(spec RuleSpec) (Rule, error) {
+	switch {
+	case spec.Name == "":
+		return nil, fmt.Errorf("missing rule name")
+	case spec.Match == "":
+		return nil, fmt.Errorf("rule %s: missing match pattern", spec.Name)
+	case spec.Replace == "":
+		return nil, fmt.Errorf("rule %s: missing replace template", spec.Name)
+	}
+
+	pattern, err := ParseExpr(expandMetavariables(spec.Match))
+	if err != nil {
+		return nil, fmt.Errorf("rule %s: %w", spec.Name, err)
+	}
+
+	replace := expandMetavariables(spec.Replace)
+	template, err := ParseExpr(replace)
+	if err != nil {
+		return nil, fmt.Errorf("rule %s: %w", spec.Name, err)
+	}
+
+	for _, expr := range []ast.Expr{pattern, template} {
+		if name, ok := misplacedRest(expr); ok {
+			return nil, fmt.Errorf("rule %s: $%s... can only be the last call argument", spec.Name, name)
+		}
+	}
+
+	bound := metavariables(pattern)
+	for name := range metavariables(template) {
+		if !bound[name] {
+			return nil, fmt.Errorf("rule %s: metavariable $%s is not bound by the match pattern", spec.Name, name)
+		}
+	}
+
+	packages := make(map[string]string)
+	for name, importPath := range spec.Imports.Remove {
+		packages[name] = importPath
+	}
+	for name, importPath := range spec.Imports.Add {
+		packages[name] = importPath
+	}
+
+	return &declarativeRule{spec: spec, pattern: pattern, replace: replace, packages: packages}, nil
+}
+
+// RulePackages returns the import paths the match patterns of rules refer to,
+// so files that only use those packages are migrated too.
+func RulePackages(rules []Rule) []string {
+	seen := make(map[string]bool)
+	for _, rule := range rules {
+		r, ok := rule.(*declarativeRule)
+		if !ok {
+			continue
+		}
+		ast.Inspect(r.pattern, func(n ast.Node) bool {
+			if sel, ok := n.(*ast.SelectorExpr); ok {
+				if ident, ok := sel.X.(*ast.Ident); ok {
+					if importPath, exists := r.packages[ident.Name]; exists {
+						seen[importPath] = true
+					}
+				}
+			}
+			return true
+		})
+	}
+
+	packages := make([]string, 0, len(seen))
+	for importPath := range seen {
+		packages = append(packages, importPath)
+	}
+	sort.Strings(packages)
+	return packages
+}
+
+func expandMetavariables(src string) string {
+	src = restVariable.ReplaceAllString(src, restPrefix+"$1")
+	return metavariable.ReplaceAllString(src, metaPrefix+"$1")
+}
+
+// metavariables returns the metavariables in node as they are written in the
+// rule, e.g. "model" or "opts...".
+func metavariables(node ast.Node) map[string]bool {
+	names := make(map[string]bool)
+	ast.Inspect(node, func(n ast.Node) bool {
+		if name, ok := metaName(n); ok {
+			names[name] = true
+		}
+		if name, ok := restName(n); ok {
+			names[name+"..."] = true
+		}
+		return true
+	})
+	return names
+}
+
+func misplacedRest(node ast.Node) (string, bool) {
+	allowed := make(map[ast.Node]bool)
+	ast.Inspect(node, func(n ast.Node) bool {
+		if call, ok := n.(*ast.CallExpr); ok && len(call.Args) > 0 {
+			allowed[call.Args[len(call.Args)-1]] = true
+		}
+		return true
+	})
+
+	misplaced := ""
+	ast.Inspect(node, func(n ast.Node) bool {
+		if name, ok := restName(n); ok && !allowed[n] && misplaced == "" {
+			misplaced = name
+		}
+		return true
+	})
+	return misplaced, misplaced != ""
+}
+
+func metaName(node ast.Node) (string, bool) {
+	return prefixedName(node, metaPrefix)
+}
+
+func restName(node ast.Node) (string, bool) {
+	return prefixedName(node, restPrefix)
+}
+
+func prefixedName(node ast.Node, prefix string) (string, bool) {
+	ident, ok := node.(*ast.Ident)
+	if !ok || !strings.HasPrefix(ident.Name, prefix) {
+		return "", false
+	}
+	return strings.TrimPrefix(ident.Name, prefix), true
+}
+
+func (r *declarativeRule) Name() string {
+	return r.spec.Name
+}
+
+func (r *declarativeRule) Apply(file *File) error {
+	var applyErr error
+	applied := false
+
+	Rewrite(file.AST, func(expr ast.Expr) ast.Expr {
+		if applyErr != nil {
+			return nil
+		}
+
+		m := &matcher{file: file, packages: r.packages, exprs: make(map[string]ast.Expr), rests: make(map[string]restBinding)}
+		if !m.match(r.pattern, expr) {
+			return nil
+		}
+
+		replacement, err := r.instantiate(file, m)
+		if err != nil {
+			applyErr = err
+			return nil
+		}
+
+		anchor(replacement, expr.Pos())
+
+		oldValue, newValue := file.ExprString(expr), file.ExprString(replacement)
+		description := r.spec.Description
+		if description == "" {
+			description = fmt.Sprintf("%s -> %s", oldValue, newValue)
+		}
+		file.Report(expr, &models.Change{
+			Type:         "rule",
+			Description:  fmt.Sprintf("Applied rule %s: %s", r.spec.Name, description),
+			OldValue:     oldValue,
+			NewValue:     newValue,
+			ManualReview: r.spec.Review,
+		})
+		applied = true
+		return replacement
+	})
+	if applyErr != nil || !applied {
+		return applyErr
+	}
+
+	for _, importPath := range r.spec.Imports.Remove {
+		file.DeleteUnusedImport(importPath)
+	}
+	return nil
+}
+
+// instantiate builds the replacement for a match. Packages the template adds
+// are referred to by their existing local name when the file already imports
+// them under another one.
+func (r *declarativeRule) instantiate(file *File, m *matcher) (ast.Expr, error) {
+	template, err := ParseExpr(r.replace)
+	if err != nil {
+		return nil, err
+	}
+
+	names := make(map[string]string)
+	for name, importPath := range r.spec.Imports.Add {
+		local, exists := file.ImportName(importPath)
+		if !exists {
+			file.AddImport(name, importPath)
+			local = name
+		}
+		names[name] = local
+	}
+
+	holder := &ast.ParenExpr{X: template}
+	Rewrite(holder, func(expr ast.Expr) ast.Expr {
+		switch node := expr.(type) {
+		case *ast.Ident:
+			if name, ok := metaName(node); ok {
+				if bound, exists := m.exprs[name]; exists {
+					return bound
+				}
+			}
+		case *ast.SelectorExpr:
+			if ident, ok := node.X.(*ast.Ident); ok && names[ident.Name] != "" && names[ident.Name] != ident.Name {
+				return &ast.SelectorExpr{X: ast.NewIdent(names[ident.Name]), Sel: node.Sel}
+			}
+		case *ast.CallExpr:
+			if len(node.Args) == 0 {
+				break
+			}
+			if name, ok := restName(node.Args[len(node.Args)-1]); ok {
+				rest := m.rests[name]
+				node.Args = append(node.Args[:len(node.Args)-1], rest.args...)
+				node.Ellipsis = rest.ellipsis
+			}
+		}
+		return nil
+	})
+
+	return holder.X, nil
+}
+
+// anchor gives the leftmost token of a replacement the position of the
+// expression it replaces, so that comments preceding it stay in front of it.
+func anchor(expr ast.Expr, pos token.Pos) {
+	for {
+		switch node := expr.(type) {
+		case *ast.CallExpr:
+			expr = node.Fun
+		case *ast.SelectorExpr:
+			expr = node.X
+		case *ast.IndexExpr:
+			expr = node.X
+		case *ast.Ident:
+			if !node.NamePos.IsValid() {
+				node.NamePos = pos
+			}
+			return
+		default:
+			return
+		}
+	}
+}
+
+func (f *File) ExprString(expr ast.Expr) string {
+	var buf bytes.Buffer
+	if err := format.Node(&buf, f.Fset, expr); err != nil {
+		return types.ExprString(expr)
+	}
+	return buf.String()
+}
+
+// matcher compares a pattern with an expression structurally, ignoring
+// positions, and records what the metavariables bind to.
+type matcher struct {
+	file     *File
+	packages map[string]string
+	exprs    map[string]ast.Expr
+	rests    map[string]restBinding
+}
+
+func (m *matcher) match(pattern, node ast.Node) bool {
+	if name, ok := metaName(pattern); ok {
+		expr, ok := node.(ast.Expr)
+		if !ok {
+			return false
+		}
+		if bound, exists := m.exprs[name]; exists {
+			return types.ExprString(bound) == types.ExprString(expr)
+		}
+		m.exprs[name] = expr
+		return true
+	}
+
+	switch p := pattern.(type) {
+	case *ast.SelectorExpr:
+		if ident, ok := p.X.(*ast.Ident); ok {
+			if importPath, exists := m.packages[ident.Name]; exists {
+				expr, _ := node.(ast.Expr)
+				found, name, ok := m.file.SelectorPackage(expr)
+				return ok && found == importPath && name == p.Sel.Name
+			}
+		}
+	case *ast.CallExpr:
+		n, ok := node.(*ast.CallExpr)
+		if !ok {
+			return false
+		}
+		if len(p.Args) > 0 {
+			if name, ok := restName(p.Args[len(p.Args)-1]); ok {
+				return m.matchRest(name, p, n)
+			}
+		}
+	}
+
+	pv, nv := reflect.ValueOf(pattern), reflect.ValueOf(node)
+	if pv.Type() != nv.Type() {
+		return false
+	}
+	if pv.IsNil() || nv.IsNil() {
+		return pv.IsNil() && nv.IsNil()
+	}
+	return m.value(pv.Elem(), nv.Elem())
+}
+
+// matchRest matches a call whose last pattern argument is $name..., binding
+// name to the remaining arguments.
+func (m *matcher) matchRest(name string, p, n *ast.CallExpr) bool {
+	fixed := len(p.Args) - 1
+	if len(n.Args) < fixed || !m.match(p.Fun, n.Fun) {
+		return false
+	}
+	for i := 0; i < fixed; i++ {
+		if !m.match(p.Args[i], n.Args[i]) {
+			return false
+		}
+	}
+	m.rests[name] = restBinding{args: n.Args[fixed:], ellipsis: n.Ellipsis}
+	return true
+}
+
+func (m *matcher) value(p, n reflect.Value) bool {
+	switch p.Type() {
+	case posType, objectType, scopeType, commentGroupType:
+		return true
+	}
+
+	switch p.Kind() {
+	case reflect.Ptr, reflect.Interface:
+		if p.IsNil() || n.IsNil() {
+			return p.IsNil() && n.IsNil()
+		}
+		if pn, ok := p.Interface().(ast.Node); ok {
+			nn, ok := n.Interface().(ast.Node)
+			return ok && m.match(pn, nn)
+		}
+		if p.Elem().Type() != n.Elem().Type() {
+			return false
+		}
+		return m.value(p.Elem(), n.Elem())
+	case reflect.Slice:
+		if p.Len() != n.Len() {
+			return false
+		}
+		for i := 0; i < p.Len(); i++ {
+			if !m.value(p.Index(i), n.Index(i)) {
+				return false
+			}
+		}
+		return true
+	case reflect.Struct:
+		for i := 0; i < p.NumField(); i++ {
+			if !m.value(p.Field(i), n.Field(i)) {
+				return false
+			}
+		}
+		return true
+	default:
+		return p.Interface() == n.Interface()
+	}
+}
@@ -0,0 +1,501 @@
+package rewrite
+
+import (
+	"bytes"
+	"fmt"
+	"go/ast"
+	"go/format"
+	"go/parser"
+	"go/token"
+	"path"
+	"reflect"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/genkit-migrate/genkit-migrate/pkg/models"
+)
+
+type Rule interface {
+	Name() string
+	Apply(file *File) error
+}
+
+type File struct {
+	Path    string
+	Fset    *token.FileSet
+	AST     *ast.File
+	Changes []*models.Change
+	// expanded are the composite literals Format lays out one element per
+	// line.
+	expanded map[*ast.CompositeLit]bool
+	// merged are the lines of deleted imports, merged when the file is
+	// formatted so changes reported after the deletion keep their lines.
+	merged []int
+}
+
+var versionedElement = regexp.MustCompile(`^(apiv\d+.*|v\d+)$`)
+
+func ParseFile(filePath string) (*File, error) {
+	return ParseSource(filePath, nil)
+}
+
+func ParseSource(filePath string, src []byte) (*File, error) {
+	fset := token.NewFileSet()
+
+	var source interface{}
+	if src != nil {
+		source = src
+	}
+
+	node, err := parser.ParseFile(fset, filePath, source, parser.ParseComments)
+	if err != nil {
+		return nil, err
+	}
+
+	return &File{
+		Path:    filePath,
+		Fset:    fset,
+		AST:     node,
+		Changes: make([]*models.Change, 0),
+	}, nil
+}
+
+func Apply(file *File, rules []Rule) error {
+	for _, rule := range rules {
+		reported := len(file.Changes)
+		if err := rule.Apply(file); err != nil {
+			return fmt.Errorf("rule %s: %w", rule.Name(), err)
+		}
+		for _, change := range file.Changes[reported:] {
+			change.Rule = rule.Name()
+		}
+	}
+	return nil
+}
+
+func (f *File) Format() (string, error) {
+	f.mergeLines()
+
+	var buf bytes.Buffer
+	if err := format.Node(&buf, f.Fset, f.AST); err != nil {
+		return "", err
+	}
+
+	src, err := f.expand(buf.Bytes())
+	if err != nil {
+		return "", err
+	}
+
+	out, err := format.Source(src)
+	if err != nil {
+		return "", err
+	}
+	return string(out), nil
+}
+
+// Expand lays out a composite literal one element per line when the file is
+// formatted. The printer keeps a literal written on one line on one line,
+// however many fields rules add to it.
+func (f *File) Expand(lit *ast.CompositeLit) {
+	if f.expanded == nil {
+		f.expanded = make(map[*ast.CompositeLit]bool)
+	}
+	f.expanded[lit] = true
+}
+
+// expand breaks the lines of the expanded literals in the printed source.
+// Printing keeps the literals of the AST, so they are found in the reparsed
+// source by their order.
+func (f *File) expand(src []byte) ([]byte, error) {
+	if len(f.expanded) == 0 {
+		return src, nil
+	}
+
+	indexes := make(map[int]bool)
+	compositeLits(f.AST, func(i int, lit *ast.CompositeLit) {
+		if f.expanded[lit] {
+			indexes[i] = true
+		}
+	})
+
+	fset := token.NewFileSet()
+	node, err := parser.ParseFile(fset, f.Path, src, parser.ParseComments)
+	if err != nil {
+		return nil, err
+	}
+	// Literals nested in an expanded one or holding a comment, which the
+	// splice would drop, are left as printed.
+	commented := func(lit *ast.CompositeLit) bool {
+		for _, group := range node.Comments {
+			if group.Pos() > lit.Lbrace && group.End() < lit.Rbrace {
+				return true
+			}
+		}
+		return false
+	}
+	var lits []*ast.CompositeLit
+	compositeLits(node, func(i int, lit *ast.CompositeLit) {
+		if !indexes[i] || len(lit.Elts) == 0 || fset.Position(lit.Lbrace).Line != fset.Position(lit.Rbrace).Line || commented(lit) {
+			return
+		}
+		if n := len(lits); n > 0 && lit.Pos() < lits[n-1].End() {
+			return
+		}
+		lits = append(lits, lit)
+	})
+
+	// Splice from the end, so the offsets of earlier literals still hold.
+	offset := func(pos token.Pos) int { return fset.Position(pos).Offset }
+	for i := len(lits) - 1; i >= 0; i-- {
+		lit := lits[i]
+		var expanded bytes.Buffer
+		expanded.Write(src[:offset(lit.Lbrace)+1])
+		for _, elt := range lit.Elts {
+			expanded.WriteString("\n")
+			expanded.Write(src[offset(elt.Pos()):offset(elt.End())])
+			expanded.WriteString(",")
+		}
+		expanded.WriteString("\n")
+		expanded.Write(src[offset(lit.Rbrace):])
+		src = expanded.Bytes()
+	}
+	return src, nil
+}
+
+func compositeLits(node ast.Node, visit func(int, *ast.CompositeLit)) {
+	i := 0
+	ast.Inspect(node, func(n ast.Node) bool {
+		if lit, ok := n.(*ast.CompositeLit); ok {
+			visit(i, lit)
+			i++
+		}
+		return true
+	})
+}
+
+func (f *File) Position(node ast.Node) token.Position {
+	return f.Fset.Position(node.Pos())
+}
+
+func (f *File) Report(node ast.Node, change *models.Change) {
+	change.File = f.Path
+	if node != nil && node.Pos().IsValid() {
+		position := f.Position(node)
+		change.Line = position.Line
+		change.Column = position.Column
+	}
+	f.Changes = append(f.Changes, change)
+}
+
+func DefaultImportName(importPath string) string {
+	elements := strings.Split(importPath, "/")
+	for i := len(elements) - 1; i > 0; i-- {
+		if !versionedElement.MatchString(elements[i]) {
+			return elements[i]
+		}
+	}
+	return path.Base(importPath)
+}
+
+func (f *File) findImport(importPath string) *ast.ImportSpec {
+	for _, imp := range f.AST.Imports {
+		if value, err := strconv.Unquote(imp.Path.Value); err == nil && value == importPath {
+			return imp
+		}
+	}
+	return nil
+}
+
+func (f *File) ImportName(importPath string) (string, bool) {
+	imp := f.findImport(importPath)
+	if imp == nil {
+		return "", false
+	}
+	if imp.Name != nil {
+		return imp.Name.Name, true
+	}
+	return DefaultImportName(importPath), true
+}
+
+func (f *File) ImportPath(name string) (string, bool) {
+	for _, imp := range f.AST.Imports {
+		importPath, err := strconv.Unquote(imp.Path.Value)
+		if err != nil {
+			continue
+		}
+		if localName, _ := f.ImportName(importPath); localName == name {
+			return importPath, true
+		}
+	}
+	return "", false
+}
+
+func (f *File) AddImport(name, importPath string) {
+	if f.findImport(importPath) != nil {
+		return
+	}
+
+	spec := &ast.ImportSpec{
+		Path: &ast.BasicLit{Kind: token.STRING, Value: strconv.Quote(importPath)},
+	}
+	if name != "" && name != DefaultImportName(importPath) {
+		spec.Name = ast.NewIdent(name)
+	}
+
+	var decl *ast.GenDecl
+	for _, d := range f.AST.Decls {
+		if gen, ok := d.(*ast.GenDecl); ok && gen.Tok == token.IMPORT {
+			decl = gen
+			break
+		}
+	}
+
+	if decl == nil {
+		decl = &ast.GenDecl{Tok: token.IMPORT}
+		f.AST.Decls = append([]ast.Decl{decl}, f.AST.Decls...)
+	}
+	if len(decl.Specs) > 0 && !decl.Lparen.IsValid() {
+		decl.Lparen = decl.Pos()
+		decl.Rparen = decl.End()
+	}
+
+	// Insert after the last import of the same kind so the printer keeps
+	// standard library and third-party imports in their own groups.
+	insert := len(decl.Specs)
+	for i := len(decl.Specs) - 1; i >= 0; i-- {
+		if existing, ok := decl.Specs[i].(*ast.ImportSpec); ok {
+			if existingPath, err := strconv.Unquote(existing.Path.Value); err == nil && isStandardLibrary(existingPath) == isStandardLibrary(importPath) {
+				insert = i + 1
+				spec.Path.ValuePos = existing.Pos()
+				if spec.Name != nil {
+					spec.Name.NamePos = existing.Pos()
+				}
+				break
+			}
+		}
+	}
+
+	decl.Specs = append(decl.Specs[:insert], append([]ast.Spec{spec}, decl.Specs[insert:]...)...)
+	f.AST.Imports = append(f.AST.Imports, spec)
+}
+
+func isStandardLibrary(importPath string) bool {
+	return !strings.Contains(strings.Split(importPath, "/")[0], ".")
+}
+
+func (f *File) DeleteImport(importPath string) bool {
+	spec := f.findImport(importPath)
+	if spec == nil {
+		return false
+	}
+
+	for i, imp := range f.AST.Imports {
+		if imp == spec {
+			f.AST.Imports = append(f.AST.Imports[:i], f.AST.Imports[i+1:]...)
+			break
+		}
+	}
+
+	decls := make([]ast.Decl, 0, len(f.AST.Decls))
+	for _, d := range f.AST.Decls {
+		gen, ok := d.(*ast.GenDecl)
+		if !ok || gen.Tok != token.IMPORT {
+			decls = append(decls, d)
+			continue
+		}
+
+		specs := make([]ast.Spec, 0, len(gen.Specs))
+		for i, s := range gen.Specs {
+			if s != spec {
+				specs = append(specs, s)
+				continue
+			}
+			if i > 0 {
+				f.closeImportLine(gen, gen.Specs[i-1], spec)
+			}
+		}
+		gen.Specs = specs
+
+		if len(specs) > 0 {
+			decls = append(decls, gen)
+		}
+	}
+	f.AST.Decls = decls
+
+	return true
+}
+
+// closeImportLine records the line of a deleted import for merging into the
+// next one, so the printer does not leave a blank line that would split the
+// import group.
+func (f *File) closeImportLine(gen *ast.GenDecl, previous ast.Spec, deleted *ast.ImportSpec) {
+	if !gen.Rparen.IsValid() || !deleted.Pos().IsValid() || !previous.End().IsValid() {
+		return
+	}
+
+	tokFile := f.Fset.File(gen.Rparen)
+	line := tokFile.Line(deleted.Pos())
+	if line-tokFile.Line(previous.End()) != 1 || line >= tokFile.LineCount() || line >= tokFile.Line(gen.Rparen) {
+		return
+	}
+	f.merged = append(f.merged, line)
+}
+
+// mergeLines merges the recorded lines from the last, so merging one does not
+// move the others.
+func (f *File) mergeLines() {
+	if len(f.merged) == 0 {
+		return
+	}
+	tokFile := f.Fset.File(f.AST.Pos())
+	slices.Sort(f.merged)
+	for i := len(f.merged) - 1; i >= 0; i-- {
+		if i < len(f.merged)-1 && f.merged[i] == f.merged[i+1] {
+			continue
+		}
+		tokFile.MergeLine(f.merged[i])
+	}
+	f.merged = nil
+}
+
+func (f *File) UsesImport(importPath string) bool {
+	name, exists := f.ImportName(importPath)
+	if !exists {
+		return false
+	}
+	if name == "_" || name == "." {
+		return true
+	}
+
+	used := false
+	ast.Inspect(f.AST, func(n ast.Node) bool {
+		if sel, ok := n.(*ast.SelectorExpr); ok {
+			if ident, ok := sel.X.(*ast.Ident); ok && ident.Name == name {
+				used = true
+			}
+		}
+		return !used
+	})
+	return used
+}
+
+func (f *File) DeleteUnusedImport(importPath string) bool {
+	if f.UsesImport(importPath) {
+		return false
+	}
+	return f.DeleteImport(importPath)
+}
+
+// SelectorPackage returns the import path of the package a qualified
+// identifier such as googleai.Init refers to.
+func (f *File) SelectorPackage(expr ast.Expr) (string, string, bool) {
+	sel, ok := expr.(*ast.SelectorExpr)
+	if !ok {
+		return "", "", false
+	}
+	ident, ok := sel.X.(*ast.Ident)
+	if !ok {
+		return "", "", false
+	}
+	importPath, exists := f.ImportPath(ident.Name)
+	if !exists {
+		return "", "", false
+	}
+	return importPath, sel.Sel.Name, true
+}
+
+func StringLiteral(expr ast.Expr) (string, bool) {
+	lit, ok := expr.(*ast.BasicLit)
+	if !ok || lit.Kind != token.STRING {
+		return "", false
+	}
+	value, err := strconv.Unquote(lit.Value)
+	if err != nil {
+		return "", false
+	}
+	return value, true
+}
+
+// ParseExpr parses a replacement expression with all positions cleared, so the
+// printer lays it out relative to the node it replaces.
+func ParseExpr(src string) (ast.Expr, error) {
+	expr, err := parser.ParseExpr(src)
+	if err != nil {
+		return nil, fmt.Errorf("invalid expression %q: %w", src, err)
+	}
+	ClearPositions(expr)
+	return expr, nil
+}
+
+func ClearPositions(node ast.Node) {
+	ast.Inspect(node, func(n ast.Node) bool {
+		if n == nil {
+			return false
+		}
+		value := reflect.ValueOf(n)
+		if value.Kind() != reflect.Ptr || value.IsNil() {
+			return true
+		}
+		value = value.Elem()
+		if value.Kind() != reflect.Struct {
+			return true
+		}
+		for i := 0; i < value.NumField(); i++ {
+			field := value.Field(i)
+			if field.Type() == posType && field.CanSet() {
+				field.SetInt(0)
+			}
+		}
+		return true
+	})
+}
+
+var (
+	exprType         = reflect.TypeOf((*ast.Expr)(nil)).Elem()
+	posType          = reflect.TypeOf(token.NoPos)
+	objectType       = reflect.TypeOf((*ast.Object)(nil))
+	scopeType        = reflect.TypeOf((*ast.Scope)(nil))
+	commentGroupType = reflect.TypeOf((*ast.CommentGroup)(nil))
+)
+
+// Rewrite walks node depth-first and replaces every expression for which fn
+// returns a non-nil result. Children are visited before their parents, so fn
+// always sees already rewritten subexpressions.
+func Rewrite(node ast.Node, fn func(ast.Expr) ast.Expr) {
+	rewriteValue(reflect.ValueOf(node), fn)
+}
+
+func rewriteValue(value reflect.Value, fn func(ast.Expr) ast.Expr) {
+	switch value.Kind() {
+	case reflect.Ptr, reflect.Interface:
+		if value.IsNil() || value.Type() == objectType || value.Type() == scopeType {
+			return
+		}
+		rewriteValue(value.Elem(), fn)
+	case reflect.Slice:
+		for i := 0; i < value.Len(); i++ {
+			rewriteField(value.Index(i), fn)
+		}
+	case reflect.Struct:
+		for i := 0; i < value.NumField(); i++ {
+			rewriteField(value.Field(i), fn)
+		}
+	}
+}
+
+func rewriteField(field reflect.Value, fn func(ast.Expr) ast.Expr) {
+	if !field.CanSet() {
+		return
+	}
+
+	rewriteValue(field, fn)
+
+	if field.Type() != exprType || field.IsNil() {
+		return
+	}
+	if replacement := fn(field.Interface().(ast.Expr)); replacement != nil {
+		field.Set(reflect.ValueOf(replacement))
+	}
+}
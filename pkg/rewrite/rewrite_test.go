@@ -0,0 +1,255 @@
+package rewrite
+
+import (
+	"go/ast"
+	"go/token"
+	"strconv"
+	"testing"
+
+	"github.com/genkit-migrate/genkit-migrate/pkg/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type renameRule struct {
+	from, to string
+}
+
+func (r *renameRule) Name() string {
+	return "rename"
+}
+
+func (r *renameRule) Apply(file *File) error {
+	Rewrite(file.AST, func(expr ast.Expr) ast.Expr {
+		if value, ok := StringLiteral(expr); ok && value == r.from {
+			file.Report(expr, &models.Change{Type: "model", OldValue: r.from, NewValue: r.to})
+			return &ast.BasicLit{ValuePos: expr.Pos(), Kind: token.STRING, Value: strconv.Quote(r.to)}
+		}
+		return nil
+	})
+	return nil
+}
+
+func TestApply(t *testing.T) {
+	src := `package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+func main() {
+	fmt.Println("gemini", "other")
+}
+`
+	file, err := ParseSource("main.go", []byte(src))
+	require.NoError(t, err)
+
+	err = Apply(file, []Rule{&renameRule{from: "gemini", to: "claude"}})
+	require.NoError(t, err)
+
+	file.AddImport("genkitaws", "github.com/scttfrdmn/genkit-aws/pkg/genkit-aws")
+	assert.True(t, file.DeleteUnusedImport("strings"))
+	assert.False(t, file.DeleteUnusedImport("fmt"))
+
+	content, err := file.Format()
+	require.NoError(t, err)
+
+	assert.Contains(t, content, `fmt.Println("claude", "other")`)
+	assert.Contains(t, content, `genkitaws "github.com/scttfrdmn/genkit-aws/pkg/genkit-aws"`)
+	assert.NotContains(t, content, `"strings"`)
+
+	require.Len(t, file.Changes, 1)
+	assert.Equal(t, "main.go", file.Changes[0].File)
+	assert.Equal(t, 9, file.Changes[0].Line)
+	assert.Equal(t, 14, file.Changes[0].Column)
+	assert.Equal(t, "rename", file.Changes[0].Rule)
+}
+
+func TestExpand(t *testing.T) {
+	src := `package main
+
+var config = &Config{Name: "a"}
+
+var nested = &Config{Inner: &Config{Name: "b"}, Name: "c"}
+
+var commented = &Config{Name: "d" /* kept */}
+`
+	file, err := ParseSource("main.go", []byte(src))
+	require.NoError(t, err)
+
+	ast.Inspect(file.AST, func(n ast.Node) bool {
+		if lit, ok := n.(*ast.CompositeLit); ok {
+			lit.Elts = append(lit.Elts, &ast.KeyValueExpr{Key: ast.NewIdent("Mode"), Value: ast.NewIdent("On")})
+			file.Expand(lit)
+			return false
+		}
+		return true
+	})
+
+	content, err := file.Format()
+	require.NoError(t, err)
+
+	assert.Contains(t, content, "var config = &Config{\n\tName: \"a\",\n\tMode: On,\n}")
+	// The inner literal is not expanded, being nested in an expanded one.
+	assert.Contains(t, content, "var nested = &Config{\n\tInner: &Config{Name: \"b\"},\n\tName:  \"c\",\n\tMode:  On,\n}")
+	// A literal holding a comment stays on its line, comment and all.
+	assert.Regexp(t, `var commented = &Config\{Name: "d".*/\* kept \*/.*On\}\n`, content)
+}
+
+func TestDefaultImportName(t *testing.T) {
+	tests := []struct {
+		importPath string
+		expected   string
+	}{
+		{"cloud.google.com/go/vertexai/genai", "genai"},
+		{"cloud.google.com/go/storage/apiv2", "storage"},
+		{"cloud.google.com/go/firestore", "firestore"},
+		{"cloud.google.com/go/secretmanager/apiv1", "secretmanager"},
+		{"cloud.google.com/go/pubsub/v2", "pubsub"},
+		{"cloud.google.com/go/logging/apiv2/loggingpb", "loggingpb"},
+		{"github.com/scttfrdmn/genkit-aws/pkg/genkit-aws", "genkit-aws"},
+	}
+
+	for _, test := range tests {
+		assert.Equal(t, test.expected, DefaultImportName(test.importPath), "Import: %s", test.importPath)
+	}
+}
+
+func TestReplaceImportKeepsGroups(t *testing.T) {
+	src := `package main
+
+import (
+	"context"
+
+	"github.com/firebase/genkit/go/genkit"
+	"github.com/firebase/genkit/go/plugins/googleai"
+)
+
+var _ = context.TODO
+var _ = genkit.Init
+`
+	file, err := ParseSource("main.go", []byte(src))
+	require.NoError(t, err)
+
+	file.AddImport("bedrock", "github.com/scttfrdmn/genkit-aws/pkg/bedrock")
+	file.AddImport("os", "os")
+	assert.True(t, file.DeleteUnusedImport("github.com/firebase/genkit/go/plugins/googleai"))
+
+	content, err := file.Format()
+	require.NoError(t, err)
+
+	assert.Contains(t, content, `import (
+	"context"
+	"os"
+
+	"github.com/firebase/genkit/go/genkit"
+	"github.com/scttfrdmn/genkit-aws/pkg/bedrock"
+)`)
+}
+
+func TestDeclarativeRules(t *testing.T) {
+	rules, err := ParseRules([]byte(`
+rules:
+  - name: ourai-client
+    match: ourai.NewClient($model, $opts...)
+    replace: genkit.LookupModel(g, $model, $opts...)
+    imports:
+      add: {genkit: github.com/firebase/genkit/go/genkit}
+      remove: {ourai: github.com/acme/platform/ourai}
+    review: true
+  - name: ourai-embed
+    description: use the GenKit embedder
+    match: ourai.Embed($ctx, $text, $text)
+    replace: embed($ctx, $text)
+    imports:
+      remove: {ourai: github.com/acme/platform/ourai}
+`))
+	require.NoError(t, err)
+	assert.Equal(t, []string{"github.com/acme/platform/ourai"}, RulePackages(rules))
+
+	src := `package main
+
+import (
+	ai "github.com/acme/platform/ourai"
+	gk "github.com/firebase/genkit/go/genkit"
+)
+
+var g = gk.Init()
+
+func main() {
+	m := ai.NewClient("googleai/gemini-1.5-pro", ai.WithRetries(3), ai.WithTimeout(10))
+	ai.Embed(ctx, doc, doc)
+	ai.Embed(ctx, doc, other)
+	ourai.NewClient("googleai/gemini-1.5-flash")
+}
+`
+	file, err := ParseSource("main.go", []byte(src))
+	require.NoError(t, err)
+	require.NoError(t, Apply(file, rules))
+
+	content, err := file.Format()
+	require.NoError(t, err)
+
+	assert.Contains(t, content, `m := gk.LookupModel(g, "googleai/gemini-1.5-pro", ai.WithRetries(3), ai.WithTimeout(10))`)
+	assert.Contains(t, content, `embed(ctx, doc)`)
+	assert.Contains(t, content, `ai.Embed(ctx, doc, other)`)
+	assert.Contains(t, content, `ourai.NewClient("googleai/gemini-1.5-flash")`)
+	assert.Contains(t, content, `ai "github.com/acme/platform/ourai"`)
+
+	require.Len(t, file.Changes, 2)
+	assert.Equal(t, "rule", file.Changes[0].Type)
+	assert.Equal(t, "ourai-client", file.Changes[0].Rule)
+	assert.Equal(t, 11, file.Changes[0].Line)
+	assert.True(t, file.Changes[0].ManualReview)
+	assert.Equal(t, `ai.NewClient("googleai/gemini-1.5-pro", ai.WithRetries(3), ai.WithTimeout(10))`, file.Changes[0].OldValue)
+	assert.Equal(t, "Applied rule ourai-embed: use the GenKit embedder", file.Changes[1].Description)
+}
+
+func TestDeclarativeRuleImports(t *testing.T) {
+	rule, err := NewRule(RuleSpec{
+		Name:    "ourai-client",
+		Match:   "ourai.NewClient($model)",
+		Replace: "genkit.LookupModel(g, $model)",
+		Imports: RuleImports{
+			Add:    map[string]string{"genkit": "github.com/firebase/genkit/go/genkit"},
+			Remove: map[string]string{"ourai": "github.com/acme/platform/ourai"},
+		},
+	})
+	require.NoError(t, err)
+
+	src := `package main
+
+import "github.com/acme/platform/ourai"
+
+var m = ourai.NewClient("googleai/gemini-1.5-pro")
+`
+	file, err := ParseSource("main.go", []byte(src))
+	require.NoError(t, err)
+	require.NoError(t, Apply(file, []Rule{rule}))
+
+	content, err := file.Format()
+	require.NoError(t, err)
+
+	assert.Contains(t, content, `"github.com/firebase/genkit/go/genkit"`)
+	assert.NotContains(t, content, `"github.com/acme/platform/ourai"`)
+	assert.Contains(t, content, `var m = genkit.LookupModel(g, "googleai/gemini-1.5-pro")`)
+}
+
+func TestNewRuleErrors(t *testing.T) {
+	tests := []struct {
+		spec     RuleSpec
+		expected string
+	}{
+		{RuleSpec{Match: "f($x)", Replace: "g($x)"}, "missing rule name"},
+		{RuleSpec{Name: "r", Replace: "g($x)"}, "missing match pattern"},
+		{RuleSpec{Name: "r", Match: "f($x", Replace: "g($x)"}, "invalid expression"},
+		{RuleSpec{Name: "r", Match: "f($x)", Replace: "g($y)"}, "metavariable $y is not bound"},
+		{RuleSpec{Name: "r", Match: "f($xs..., 1)", Replace: "g()"}, "$xs... can only be the last call argument"},
+	}
+
+	for _, test := range tests {
+		_, err := NewRule(test.spec)
+		assert.ErrorContains(t, err, test.expected, "Rule: %+v", test.spec)
+	}
+}
@@ -0,0 +1,46 @@
+package transformer
+
+import (
+	"fmt"
+	"slices"
+
+	"github.com/genkit-migrate/genkit-migrate/pkg/models"
+	"github.com/genkit-migrate/genkit-migrate/pkg/provider"
+)
+
+// transformBlockers records each use of a source-specific feature the target
+// has no equivalent of as a blocker, suggesting the target's alternative.
+func (t *Transformer) transformBlockers(migration *models.Migration) error {
+	if t.target == nil {
+		return nil
+	}
+
+	// A target that is itself the feature's provider migrates it as is.
+	var owned []provider.Feature
+	if source := t.target.Source(); source != nil {
+		owned = source.Features
+	}
+
+	catalog := t.catalog(migration.Project)
+	for _, usage := range migration.Project.Features {
+		if slices.ContainsFunc(owned, func(feature provider.Feature) bool { return feature.Name == usage.Feature }) {
+			continue
+		}
+
+		alternative, exists := catalog.Alternatives[usage.Feature]
+		if !exists {
+			alternative = fmt.Sprintf("remove it or reimplement it for %s", t.target.Title())
+		}
+		migration.Changes = append(migration.Changes, &models.Change{
+			Type:         "blocker",
+			Description:  fmt.Sprintf("%s has no %s equivalent: %s", usage.Title, t.target.Title(), alternative),
+			File:         usage.Position.Filename,
+			Line:         usage.Position.Line,
+			OldValue:     usage.Detail,
+			ManualReview: true,
+			Blocking:     true,
+		})
+	}
+
+	return nil
+}
@@ -3,14 +3,22 @@ package transformer
 import (
 	"context"
 	"fmt"
+	"go/parser"
+	"go/token"
+	"path"
+	"slices"
 	"strings"
 	"text/template"
 
 	"github.com/genkit-migrate/genkit-migrate/pkg/models"
+	"github.com/genkit-migrate/genkit-migrate/pkg/provider"
+	"github.com/genkit-migrate/genkit-migrate/pkg/rewrite"
 )
 
 type Transformer struct {
 	config *Config
+	source provider.Provider // nil when the source provider is unknown
+	target provider.Provider // nil when the target provider is unknown
 }
 
 type Config struct {
@@ -18,13 +26,30 @@ type Config struct {
 	TargetProvider string
 	TargetPath     string
 	DryRun         bool
+	// Options are provider options such as "vector-store", keyed by
+	// name.
+	Options map[string]string
+	// Rules are user-supplied rewrite rules, applied to Go sources before
+	// the built-in rules.
+	Rules []rewrite.Rule
 }
 
 func New(config *Config) *Transformer {
-	return &Transformer{config: config}
+	t := &Transformer{config: config}
+	if source, err := provider.Get(config.SourceProvider); err == nil && source.Source() != nil {
+		t.source = source
+	}
+	if target, err := provider.Get(config.TargetProvider); err == nil {
+		t.target = target
+	}
+	return t
 }
 
 func (t *Transformer) TransformProject(ctx context.Context, project *models.Project) (*models.Migration, error) {
+	if _, err := provider.Get(t.config.TargetProvider); err != nil {
+		return nil, err
+	}
+
 	migration := &models.Migration{
 		Project:     project,
 		Changes:     make([]*models.Change, 0),
@@ -33,12 +58,7 @@ func (t *Transformer) TransformProject(ctx context.Context, project *models.Proj
 		Commands:    make([]string, 0),
 	}
 
-	err := t.transformDependencies(migration)
-	if err != nil {
-		return nil, fmt.Errorf("failed to transform dependencies: %w", err)
-	}
-
-	err = t.transformSourceFiles(migration)
+	err := t.transformSourceFiles(migration)
 	if err != nil {
 		return nil, fmt.Errorf("failed to transform source files: %w", err)
 	}
@@ -48,6 +68,31 @@ func (t *Transformer) TransformProject(ctx context.Context, project *models.Proj
 		return nil, fmt.Errorf("failed to transform models: %w", err)
 	}
 
+	err = t.transformEmbedders(migration)
+	if err != nil {
+		return nil, fmt.Errorf("failed to transform embedders: %w", err)
+	}
+
+	err = t.transformPrompts(migration)
+	if err != nil {
+		return nil, fmt.Errorf("failed to transform prompts: %w", err)
+	}
+
+	err = t.transformStructuredOutputs(migration)
+	if err != nil {
+		return nil, fmt.Errorf("failed to transform structured outputs: %w", err)
+	}
+
+	err = t.transformBlockers(migration)
+	if err != nil {
+		return nil, fmt.Errorf("failed to transform blockers: %w", err)
+	}
+
+	err = t.transformCloudServices(migration)
+	if err != nil {
+		return nil, fmt.Errorf("failed to transform cloud services: %w", err)
+	}
+
 	err = t.transformConfiguration(migration)
 	if err != nil {
 		return nil, fmt.Errorf("failed to transform configuration: %w", err)
@@ -58,6 +103,13 @@ func (t *Transformer) TransformProject(ctx context.Context, project *models.Proj
 		return nil, fmt.Errorf("failed to generate deployment files: %w", err)
 	}
 
+	// go.mod is written last, so it keeps the modules the migrated and
+	// generated code still imports.
+	err = t.transformDependencies(migration)
+	if err != nil {
+		return nil, fmt.Errorf("failed to transform dependencies: %w", err)
+	}
+
 	return migration, nil
 }
 
@@ -70,8 +122,8 @@ go {{ .GoVersion }}
 
 require (
 	github.com/firebase/genkit/go v1.0.2
-{{- if eq .TargetProvider "aws" }}
-	github.com/scttfrdmn/genkit-aws v0.1.0
+{{- range .Requires }}
+	{{ index . 0 }} {{ index . 1 }}
 {{- end }}
 {{- range .Dependencies }}
 	{{ .Name }} {{ .Version }}
@@ -84,12 +136,22 @@ require (
 		return err
 	}
 
+	var requires [][2]string
+	if t.target != nil {
+		requires = t.target.Rewrite(t.context(project, migration)).Requires
+	}
+
+	// GenKit and the target's modules are required at their new versions.
+	dependencies := slices.DeleteFunc(t.filterDependencies(project.Dependencies, importedPaths(migration)), func(dep map[string]string) bool {
+		return dep["Name"] == "github.com/firebase/genkit/go" || slices.ContainsFunc(requires, func(require [2]string) bool { return require[0] == dep["Name"] })
+	})
+
 	var content strings.Builder
 	err = tmpl.Execute(&content, map[string]interface{}{
-		"ModuleName":     t.extractModuleName(project),
-		"GoVersion":      "1.23",
-		"TargetProvider": t.config.TargetProvider,
-		"Dependencies":   t.filterDependencies(project.Dependencies),
+		"ModuleName":   t.extractModuleName(project),
+		"GoVersion":    "1.23",
+		"Requires":     requires,
+		"Dependencies": dependencies,
 	})
 	if err != nil {
 		return err
@@ -110,11 +172,11 @@ func (t *Transformer) transformSourceFiles(migration *models.Migration) error {
 	project := migration.Project
 
 	for filePath, sourceFile := range project.Files {
-		if !sourceFile.HasGenKit {
+		if !sourceFile.HasGenKit && len(sourceFile.DirectCalls) == 0 {
 			continue
 		}
 
-		newContent, changes, err := t.transformGoFile(sourceFile)
+		newContent, changes, err := t.transformGoFile(project, sourceFile)
 		if err != nil {
 			return fmt.Errorf("failed to transform %s: %w", filePath, err)
 		}
@@ -128,40 +190,30 @@ func (t *Transformer) transformSourceFiles(migration *models.Migration) error {
 	return nil
 }
 
-func (t *Transformer) transformGoFile(sourceFile *models.SourceFile) (string, []*models.Change, error) {
-	changes := make([]*models.Change, 0)
-
-	content := `package ` + sourceFile.PackageName + `
-
-import (
-	"context"
-	"github.com/firebase/genkit/go/genkit"
-`
-
-	if t.config.TargetProvider == "aws" {
-		content += `	genkitaws "github.com/scttfrdmn/genkit-aws/pkg/genkit-aws"
-	"github.com/scttfrdmn/genkit-aws/pkg/bedrock"
-	"github.com/scttfrdmn/genkit-aws/pkg/monitoring"
-`
+func (t *Transformer) transformGoFile(project *models.Project, sourceFile *models.SourceFile) (string, []*models.Change, error) {
+	file, err := rewrite.ParseFile(sourceFile.Path)
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to parse file: %w", err)
 	}
 
-	content += `)
+	if err := rewrite.Apply(file, t.goRewriteRules(project)); err != nil {
+		return "", nil, fmt.Errorf("failed to apply rewrite rules: %w", err)
+	}
 
-// TODO: Implement actual AST transformation
-// This is a simplified example showing the structure
-`
+	if len(file.Changes) == 0 {
+		return "", nil, nil
+	}
 
-	changes = append(changes, &models.Change{
-		Type:        "import",
-		Description: fmt.Sprintf("Added %s imports", t.config.TargetProvider),
-		File:        sourceFile.Path,
-	})
+	content, err := file.Format()
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to format file: %w", err)
+	}
 
-	return content, changes, nil
+	return content, file.Changes, nil
 }
 
 func (t *Transformer) transformModels(migration *models.Migration) error {
-	modelMappings := t.getModelMappings()
+	modelMappings := t.getModelMappings(migration.Project)
 
 	for _, model := range migration.Project.Models {
 		if newModel, exists := modelMappings[model.Name]; exists {
@@ -169,6 +221,8 @@ func (t *Transformer) transformModels(migration *models.Migration) error {
 				Type:        "model",
 				Description: fmt.Sprintf("Map model %s -> %s", model.Name, newModel),
 				File:        model.Position.Filename,
+				OldValue:    model.Name,
+				NewValue:    newModel,
 			})
 		}
 	}
@@ -176,252 +230,97 @@ func (t *Transformer) transformModels(migration *models.Migration) error {
 	return nil
 }
 
-func (t *Transformer) getModelMappings() map[string]string {
-	if t.config.SourceProvider == "gcp" && t.config.TargetProvider == "aws" {
-		return map[string]string{
-			"googleai/gemini-1.5-flash": "anthropic.claude-3-haiku-20240307-v1:0",
-			"googleai/gemini-1.5-pro":   "anthropic.claude-3-sonnet-20240229-v1:0",
-			"googleai/gemini-2.0-flash": "anthropic.claude-3-5-sonnet-20241022-v2:0",
-			"vertexai/gemini-pro":       "anthropic.claude-3-sonnet-20240229-v1:0",
-			"vertexai/gemini-1.5-pro":   "anthropic.claude-3-sonnet-20240229-v1:0",
-			"vertexai/gemini-1.5-flash": "anthropic.claude-3-haiku-20240307-v1:0",
-			// Map some models to Amazon Nova for variety
-			"googleai/gemini-1.5-flash-8b": "amazon.nova-lite-v1:0",
-			"googleai/text-bison":          "amazon.nova-micro-v1:0",
-		}
-	}
-	return make(map[string]string)
+func (t *Transformer) getModelMappings(project *models.Project) map[string]string {
+	return t.catalog(project).Models
 }
 
-func (t *Transformer) transformConfiguration(migration *models.Migration) error {
-	if t.config.TargetProvider == "aws" {
-		configTemplate := `# AWS Configuration for GenKit
-region: us-east-1
-profile: default
-
-bedrock:
-  models:
-    - anthropic.claude-3-sonnet-20240229-v1:0
-    - amazon.nova-pro-v1:0
-  
-cloudwatch:
-  namespace: "GenKit/{{ .ProjectName }}"
-  enabled: true
-
-# Environment variables
-environment:
-  - GENKIT_ENV=production
-  - AWS_REGION=us-east-1
-`
-
-		tmpl, err := template.New("config").Parse(configTemplate)
-		if err != nil {
-			return err
-		}
-
-		var content strings.Builder
-		err = tmpl.Execute(&content, map[string]interface{}{
-			"ProjectName": t.extractProjectName(migration.Project),
-		})
-		if err != nil {
-			return err
-		}
-
-		migration.NewFiles["config.yaml"] = content.String()
-	}
-
-	return nil
+// targetModelRef returns the registered name of a mapped model.
+func (t *Transformer) targetModelRef(project *models.Project, model string) string {
+	return t.catalog(project).Ref(model)
 }
 
-func (t *Transformer) generateDeploymentFiles(migration *models.Migration) error {
-	if t.config.TargetProvider == "aws" {
-		err := t.generateTerraform(migration)
-		if err != nil {
-			return err
-		}
-
-		err = t.generateDockerfile(migration)
-		if err != nil {
-			return err
-		}
-
-		err = t.generateGitHubActions(migration)
-		if err != nil {
-			return err
-		}
+func (t *Transformer) transformCloudServices(migration *models.Migration) error {
+	for _, service := range migration.Project.CloudServices {
+		migration.Changes = append(migration.Changes, &models.Change{
+			Type: "service",
+			Description: fmt.Sprintf("Migrate %s (%s, %d call sites) -> %s [effort: %s]",
+				service.Service, service.Package, len(service.CallSites), service.Alternative, service.Effort),
+			File: service.Position.Filename,
+		})
 	}
 
 	return nil
 }
 
-func (t *Transformer) generateTerraform(migration *models.Migration) error {
-	terraformMain := `# Terraform configuration for GenKit on AWS
-terraform {
-  required_version = ">= 1.0"
-  required_providers {
-    aws = {
-      source  = "hashicorp/aws"
-      version = "~> 5.0"
-    }
-  }
-}
-
-provider "aws" {
-  region = var.aws_region
-}
-
-# Lambda function for GenKit app
-resource "aws_lambda_function" "genkit_app" {
-  filename         = "genkit-app.zip"
-  function_name    = "genkit-app"
-  role            = aws_iam_role.lambda_role.arn
-  handler         = "main"
-  runtime         = "provided.al2"
-  
-  environment {
-    variables = {
-      GENKIT_ENV = "production"
-      AWS_REGION = var.aws_region
-    }
-  }
-}
-
-# IAM role for Lambda
-resource "aws_iam_role" "lambda_role" {
-  name = "genkit-lambda-role"
-
-  assume_role_policy = jsonencode({
-    Version = "2012-10-17"
-    Statement = [
-      {
-        Action = "sts:AssumeRole"
-        Effect = "Allow"
-        Principal = {
-          Service = "lambda.amazonaws.com"
-        }
-      }
-    ]
-  })
-}
-
-# IAM policy for Bedrock access
-resource "aws_iam_role_policy" "bedrock_policy" {
-  name = "genkit-bedrock-policy"
-  role = aws_iam_role.lambda_role.id
-
-  policy = jsonencode({
-    Version = "2012-10-17"
-    Statement = [
-      {
-        Effect = "Allow"
-        Action = [
-          "bedrock:InvokeModel",
-          "bedrock:InvokeModelWithResponseStream"
-        ]
-        Resource = "*"
-      }
-    ]
-  })
+func (t *Transformer) transformConfiguration(migration *models.Migration) error {
+	return t.target.Configure(t.context(migration.Project, migration))
 }
-`
 
-	migration.NewFiles["terraform/main.tf"] = terraformMain
-
-	terraformVars := `variable "aws_region" {
-  description = "AWS region"
-  type        = string
-  default     = "us-east-1"
+func (t *Transformer) generateDeploymentFiles(migration *models.Migration) error {
+	return t.target.Deploy(t.context(migration.Project, migration))
 }
 
-variable "project_name" {
-  description = "Project name"
-  type        = string
-  default     = "genkit-app"
-}
-`
-
-	migration.NewFiles["terraform/variables.tf"] = terraformVars
-
-	return nil
+func (t *Transformer) extractModuleName(project *models.Project) string {
+	return "genkit-app"
 }
 
-func (t *Transformer) generateDockerfile(migration *models.Migration) error {
-	dockerfile := `# Multi-stage build for GenKit Go app
-FROM golang:1.23-alpine AS builder
-
-WORKDIR /app
-COPY go.mod go.sum ./
-RUN go mod download
-
-COPY . .
-RUN CGO_ENABLED=0 GOOS=linux go build -o main .
-
-FROM alpine:latest
-RUN apk --no-cache add ca-certificates
-WORKDIR /root/
-
-COPY --from=builder /app/main .
-
-EXPOSE 8080
-CMD ["./main"]
-`
-
-	migration.NewFiles["Dockerfile"] = dockerfile
-	return nil
+func (t *Transformer) extractProjectName(project *models.Project) string {
+	return "GenKitApp"
 }
 
-func (t *Transformer) generateGitHubActions(migration *models.Migration) error {
-	workflow := `name: Deploy to AWS
-
-on:
-  push:
-    branches: [ main ]
-
-jobs:
-  test:
-    runs-on: ubuntu-latest
-    steps:
-    - uses: actions/checkout@v4
-    - uses: actions/setup-go@v4
-      with:
-        go-version: '1.23'
-    - run: go test -v ./...
-
-  deploy:
-    needs: test
-    runs-on: ubuntu-latest
-    steps:
-    - uses: actions/checkout@v4
-    - uses: aws-actions/configure-aws-credentials@v4
-      with:
-        aws-access-key-id: ${{ "{{ secrets.AWS_ACCESS_KEY_ID }}" }}
-        aws-secret-access-key: ${{ "{{ secrets.AWS_SECRET_ACCESS_KEY }}" }}
-        aws-region: us-east-1
-    
-    - name: Deploy with Terraform
-      run: |
-        cd terraform
-        terraform init
-        terraform plan
-        terraform apply -auto-approve
-`
+// importedPaths returns the import paths of the app's Go files after the
+// migration, leaving out generated modules with a go.mod of their own.
+func importedPaths(migration *models.Migration) map[string]bool {
+	modules := make([]string, 0)
+	for filePath := range migration.NewFiles {
+		if path.Base(filePath) == "go.mod" && path.Dir(filePath) != "." {
+			modules = append(modules, path.Dir(filePath)+"/")
+		}
+	}
 
-	migration.NewFiles[".github/workflows/deploy.yml"] = workflow
-	return nil
+	imports := make(map[string]bool)
+	for filePath, sourceFile := range migration.Project.Files {
+		if _, migrated := migration.NewFiles[filePath]; !migrated {
+			for _, importPath := range sourceFile.Imports {
+				imports[importPath] = true
+			}
+		}
+	}
+	for filePath, content := range migration.NewFiles {
+		if !strings.HasSuffix(filePath, ".go") || slices.ContainsFunc(modules, func(module string) bool { return strings.HasPrefix(filePath, module) }) {
+			continue
+		}
+		file, err := parser.ParseFile(token.NewFileSet(), filePath, content, parser.ImportsOnly)
+		if err != nil {
+			continue
+		}
+		for _, imp := range file.Imports {
+			imports[strings.Trim(imp.Path.Value, `"`)] = true
+		}
+	}
+	return imports
 }
 
-func (t *Transformer) extractModuleName(project *models.Project) string {
-	return "genkit-app"
-}
+// filterDependencies drops the source provider's SDKs, except modules that
+// imports still uses; the target plugin is added by the go.mod template.
+func (t *Transformer) filterDependencies(deps map[string]string, imports map[string]bool) []map[string]string {
+	var sourceSDKs []string
+	if t.source != nil {
+		sourceSDKs = t.source.Source().SDKs
+	}
 
-func (t *Transformer) extractProjectName(project *models.Project) string {
-	return "GenKitApp"
-}
+	imported := func(module string) bool {
+		for importPath := range imports {
+			if importPath == module || strings.HasPrefix(importPath, module+"/") {
+				return true
+			}
+		}
+		return false
+	}
 
-func (t *Transformer) filterDependencies(deps map[string]string) []map[string]string {
 	filtered := make([]map[string]string, 0)
 	for name, version := range deps {
-		if !strings.Contains(name, "firebase") && !strings.Contains(name, "google") {
+		if imported(name) || !slices.ContainsFunc(sourceSDKs, func(sdk string) bool { return strings.Contains(name, sdk) }) {
 			filtered = append(filtered, map[string]string{
 				"Name":    name,
 				"Version": version,
@@ -0,0 +1,438 @@
+package transformer
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/genkit-migrate/genkit-migrate/pkg/models"
+	"github.com/genkit-migrate/genkit-migrate/pkg/provider"
+	"gopkg.in/yaml.v3"
+)
+
+type translatedSetting struct {
+	key      string
+	value    string
+	keep     bool
+	secret   bool
+	provider bool
+	note     string
+}
+
+// sourceTranslation reports whether a setting belongs to the source provider
+// and, if so, how it translates to the target provider.
+func (t *Transformer) sourceTranslation(project *models.Project, setting *models.ConfigSetting) (provider.SettingKey, bool, bool) {
+	name := strings.ToUpper(setting.Key[strings.LastIndex(setting.Key, ".")+1:])
+	translation, exists := t.settings(project).Keys[name]
+
+	// Settings of a provider migrated to itself are carried over as they are.
+	if t.source == nil || t.source == t.target {
+		return translation, exists, exists
+	}
+	return translation, t.source.Source().Owns(name, exists, setting), exists
+}
+
+func (t *Transformer) translateSetting(project *models.Project, setting *models.ConfigSetting) *translatedSetting {
+	name := settingName(setting.Key)
+
+	if translation, owned, exists := t.sourceTranslation(project, setting); owned {
+		if !exists {
+			return &translatedSetting{key: name, provider: true, note: fmt.Sprintf("no %s equivalent", t.target.Title())}
+		}
+		if translation.Key == "" {
+			return &translatedSetting{key: name, provider: true, note: translation.Note}
+		}
+
+		// Settings translated to the same key take the value of the first,
+		// the one the generated config and deployment use.
+		value, _ := t.migratedSetting(project, translation.Key)
+		return &translatedSetting{key: matchKeyCase(name, translation.Key), value: value, keep: true, provider: true, note: translation.Note}
+	}
+
+	if setting.Secret {
+		value := ""
+		if secret := t.secret(project, name, setting.Key); secret != nil {
+			value = secret.Reference
+		}
+		return &translatedSetting{key: name, value: value, keep: true, secret: true}
+	}
+
+	return &translatedSetting{key: name, value: setting.Value, keep: true}
+}
+
+// secret returns where the target stores the secret setting key, or nil when
+// the app reads its secrets from the environment.
+func (t *Transformer) secret(project *models.Project, key, settingKey string) *provider.Secret {
+	settings := t.settings(project)
+	if settings.Secret == nil {
+		return nil
+	}
+	return settings.Secret(key, fmt.Sprintf("<value of %s>", settingKey))
+}
+
+// settingName returns the last segment of a setting key. Items of a sequence
+// are named after the sequence and their index, so api_keys.0 is api_keys_0.
+func settingName(key string) string {
+	segments := strings.Split(key, ".")
+	last := len(segments) - 1
+	for last > 0 && isIndex(segments[last]) {
+		last--
+	}
+	return strings.Join(segments[last:], "_")
+}
+
+func isIndex(segment string) bool {
+	_, err := strconv.Atoi(segment)
+	return err == nil
+}
+
+func matchKeyCase(original, key string) string {
+	if original == strings.ToLower(original) {
+		return strings.ToLower(key)
+	}
+	return key
+}
+
+func (t *Transformer) recordSettingChange(migration *models.Migration, file string, setting *models.ConfigSetting, translated *translatedSetting) {
+	change := &models.Change{
+		Type: "config",
+		File: file,
+	}
+
+	switch {
+	case !translated.keep:
+		change.Description = fmt.Sprintf("Removed %s: %s", setting.Key, translated.note)
+	case translated.secret:
+		secret := t.secret(migration.Project, translated.key, setting.Key)
+		if secret == nil {
+			change.Description = fmt.Sprintf("Cleared secret %s; set it in the app's environment before running it", setting.Key)
+			change.ManualReview = true
+			break
+		}
+		migration.Commands = append(migration.Commands, secret.Command)
+		if secret.Reference == "" {
+			change.Description = fmt.Sprintf("Cleared secret %s; store it in %s %s and have the app read it from there, or from an environment variable set from it", setting.Key, secret.Store, secret.Name)
+			change.ManualReview = true
+			break
+		}
+		change.Description = fmt.Sprintf("Replaced secret %s with a reference to %s %s", setting.Key, secret.Store, secret.Name)
+	case translated.provider:
+		change.Description = fmt.Sprintf("Translated %s -> %s", setting.Key, translated.key)
+		if !setting.Secret {
+			change.OldValue = setting.Value
+		}
+		change.NewValue = translated.value
+		if translated.note != "" {
+			change.Description += ": " + translated.note
+			change.ManualReview = true
+		}
+	default:
+		return
+	}
+
+	migration.Changes = append(migration.Changes, change)
+}
+
+// migratedSetting returns the translated value of the first source setting
+// that maps to the given target key.
+func (t *Transformer) migratedSetting(project *models.Project, targetKey string) (string, bool) {
+	for _, filename := range []string{".env", "app.yaml", "config.yaml", "config.json"} {
+		configFile, exists := project.ConfigFiles[filename]
+		if !exists {
+			continue
+		}
+		for _, setting := range configFile.Settings {
+			translation, owned, exists := t.sourceTranslation(project, setting)
+			if !owned || !exists || translation.Key != targetKey {
+				continue
+			}
+			if translation.Value != nil {
+				return translation.Value(setting.Value), true
+			}
+			return setting.Value, true
+		}
+	}
+	return "", false
+}
+
+func (t *Transformer) transformEnvFile(migration *models.Migration) {
+	envFile, hasEnv := migration.Project.ConfigFiles[".env"]
+	appFile, hasApp := migration.Project.ConfigFiles["app.yaml"]
+	if !hasEnv && !hasApp {
+		return
+	}
+
+	var content strings.Builder
+	content.WriteString("# Environment migrated by genkit-migrate\n")
+
+	write := func(file string, setting *models.ConfigSetting, key string) {
+		translated := t.translateSetting(migration.Project, &models.ConfigSetting{
+			Key:    key,
+			Value:  setting.Value,
+			Secret: setting.Secret,
+			GCP:    setting.GCP,
+		})
+		t.recordSettingChange(migration, file, setting, translated)
+
+		if !translated.keep {
+			fmt.Fprintf(&content, "# %s removed: %s\n", key, translated.note)
+			return
+		}
+		fmt.Fprintf(&content, "%s=%s\n", translated.key, translated.value)
+	}
+
+	if hasEnv {
+		for _, setting := range envFile.Settings {
+			write(".env", setting, setting.Key)
+		}
+	}
+
+	if hasApp {
+		for _, setting := range appFile.Settings {
+			if key, found := strings.CutPrefix(setting.Key, "env_variables."); found {
+				write("app.yaml", setting, key)
+			}
+		}
+	}
+
+	migration.NewFiles[".env"] = content.String()
+}
+
+func (t *Transformer) rewriteConfigDocument(migration *models.Migration, filename string) (*yaml.Node, error) {
+	configFile := migration.Project.ConfigFiles[filename]
+
+	content, err := os.ReadFile(configFile.Path)
+	if err != nil {
+		return nil, err
+	}
+
+	var root yaml.Node
+	if err := yaml.Unmarshal(content, &root); err != nil {
+		return nil, err
+	}
+
+	settings := make(map[string]*models.ConfigSetting)
+	for _, setting := range configFile.Settings {
+		settings[setting.Key] = setting
+	}
+
+	t.rewriteConfigNode(migration, filename, "", &root, settings)
+	return &root, nil
+}
+
+func (t *Transformer) rewriteConfigNode(migration *models.Migration, filename, prefix string, node *yaml.Node, settings map[string]*models.ConfigSetting) {
+	switch node.Kind {
+	case yaml.DocumentNode:
+		for _, child := range node.Content {
+			t.rewriteConfigNode(migration, filename, prefix, child, settings)
+		}
+	case yaml.SequenceNode:
+		content := make([]*yaml.Node, 0, len(node.Content))
+		for i, child := range node.Content {
+			key := configKey(prefix, fmt.Sprint(i))
+			if child.Kind != yaml.ScalarNode {
+				t.rewriteConfigNode(migration, filename, key, child, settings)
+				content = append(content, child)
+				continue
+			}
+			if translated := t.rewriteConfigScalar(migration, filename, key, child, settings); translated != nil && !translated.keep {
+				continue
+			}
+			content = append(content, child)
+		}
+		node.Content = content
+	case yaml.MappingNode:
+		content := make([]*yaml.Node, 0, len(node.Content))
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			keyNode, valueNode := node.Content[i], node.Content[i+1]
+			key := configKey(prefix, keyNode.Value)
+
+			if valueNode.Kind != yaml.ScalarNode {
+				t.rewriteConfigNode(migration, filename, key, valueNode, settings)
+				if t.isSourceSection(keyNode.Value) {
+					keyNode.Value = matchKeyCase(keyNode.Value, strings.ToUpper(t.config.TargetProvider))
+				}
+				content = append(content, keyNode, valueNode)
+				continue
+			}
+
+			translated := t.rewriteConfigScalar(migration, filename, key, valueNode, settings)
+			if translated != nil {
+				if !translated.keep {
+					continue
+				}
+				keyNode.Value = translated.key
+			}
+			content = append(content, keyNode, valueNode)
+		}
+		node.Content = content
+	}
+}
+
+// rewriteConfigScalar translates the value of the setting at key, returning
+// nil when the setting is unknown.
+func (t *Transformer) rewriteConfigScalar(migration *models.Migration, filename, key string, node *yaml.Node, settings map[string]*models.ConfigSetting) *translatedSetting {
+	setting, exists := settings[key]
+	if !exists {
+		return nil
+	}
+
+	translated := t.translateSetting(migration.Project, setting)
+	t.recordSettingChange(migration, filename, setting, translated)
+	if !translated.keep {
+		return translated
+	}
+
+	node.Value = translated.value
+	if translated.secret {
+		node.Tag = "!!str"
+		node.Style = yaml.DoubleQuotedStyle
+	}
+	return translated
+}
+
+func (t *Transformer) isSourceSection(key string) bool {
+	return t.source != nil && slices.Contains(t.source.Source().Sections, strings.ToUpper(key))
+}
+
+func configKey(prefix, key string) string {
+	if prefix == "" {
+		return key
+	}
+	return prefix + "." + key
+}
+
+func (t *Transformer) migratedConfigYAML(migration *models.Migration) (string, error) {
+	if _, exists := migration.Project.ConfigFiles["config.yaml"]; !exists {
+		return "", nil
+	}
+
+	root, err := t.rewriteConfigDocument(migration, "config.yaml")
+	if err != nil {
+		return "", err
+	}
+	if len(root.Content) == 0 || root.Content[0].Kind != yaml.MappingNode {
+		return "", nil
+	}
+
+	// Keys owned by the generated config.yaml; source settings with the same
+	// top-level name are dropped rather than emitted twice.
+	reserved := t.settings(migration.Project).Reserved
+
+	mapping := root.Content[0]
+	content := make([]*yaml.Node, 0, len(mapping.Content))
+	for i := 0; i+1 < len(mapping.Content); i += 2 {
+		if slices.Contains(reserved, mapping.Content[i].Value) {
+			migration.Changes = append(migration.Changes, &models.Change{
+				Type:        "config",
+				Description: fmt.Sprintf("Replaced %s with the generated %s setting", mapping.Content[i].Value, t.target.Title()),
+				File:        "config.yaml",
+			})
+			continue
+		}
+		content = append(content, mapping.Content[i], mapping.Content[i+1])
+	}
+	if len(content) == 0 {
+		return "", nil
+	}
+	mapping.Content = content
+
+	var out strings.Builder
+	encoder := yaml.NewEncoder(&out)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(root); err != nil {
+		return "", err
+	}
+	if err := encoder.Close(); err != nil {
+		return "", err
+	}
+
+	return "\n# Application settings migrated from the source config.yaml\n" + out.String(), nil
+}
+
+func (t *Transformer) transformConfigJSON(migration *models.Migration) error {
+	if _, exists := migration.Project.ConfigFiles["config.json"]; !exists {
+		return nil
+	}
+
+	root, err := t.rewriteConfigDocument(migration, "config.json")
+	if err != nil {
+		return err
+	}
+
+	var out strings.Builder
+	if len(root.Content) > 0 {
+		if err := writeJSONNode(&out, root.Content[0], ""); err != nil {
+			return err
+		}
+	}
+	out.WriteString("\n")
+
+	migration.NewFiles["config.json"] = out.String()
+	return nil
+}
+
+// writeJSONNode renders a YAML node tree as indented JSON, keeping the key
+// order of the source file (encoding/json would sort map keys).
+func writeJSONNode(out *strings.Builder, node *yaml.Node, indent string) error {
+	inner := indent + "  "
+
+	switch node.Kind {
+	case yaml.MappingNode:
+		if len(node.Content) == 0 {
+			out.WriteString("{}")
+			return nil
+		}
+		out.WriteString("{\n")
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			key, err := json.Marshal(node.Content[i].Value)
+			if err != nil {
+				return err
+			}
+			fmt.Fprintf(out, "%s%s: ", inner, key)
+			if err := writeJSONNode(out, node.Content[i+1], inner); err != nil {
+				return err
+			}
+			if i+2 < len(node.Content) {
+				out.WriteString(",")
+			}
+			out.WriteString("\n")
+		}
+		out.WriteString(indent + "}")
+	case yaml.SequenceNode:
+		if len(node.Content) == 0 {
+			out.WriteString("[]")
+			return nil
+		}
+		out.WriteString("[\n")
+		for i, child := range node.Content {
+			out.WriteString(inner)
+			if err := writeJSONNode(out, child, inner); err != nil {
+				return err
+			}
+			if i+1 < len(node.Content) {
+				out.WriteString(",")
+			}
+			out.WriteString("\n")
+		}
+		out.WriteString(indent + "]")
+	case yaml.AliasNode:
+		return writeJSONNode(out, node.Alias, indent)
+	default:
+		switch node.Tag {
+		case "!!int", "!!float", "!!bool", "!!null":
+			out.WriteString(node.Value)
+		default:
+			value, err := json.Marshal(node.Value)
+			if err != nil {
+				return err
+			}
+			out.Write(value)
+		}
+	}
+
+	return nil
+}
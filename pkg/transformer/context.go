@@ -0,0 +1,97 @@
+package transformer
+
+import (
+	"fmt"
+
+	"github.com/genkit-migrate/genkit-migrate/pkg/models"
+	"github.com/genkit-migrate/genkit-migrate/pkg/provider"
+)
+
+// migrationContext implements provider.Context for the providers the
+// transformer migrates between.
+type migrationContext struct {
+	t         *Transformer
+	project   *models.Project
+	migration *models.Migration
+}
+
+func (t *Transformer) context(project *models.Project, migration *models.Migration) *migrationContext {
+	return &migrationContext{t: t, project: project, migration: migration}
+}
+
+func (c *migrationContext) Source() string {
+	return c.t.config.SourceProvider
+}
+
+func (c *migrationContext) Project() *models.Project {
+	return c.project
+}
+
+func (c *migrationContext) Migration() *models.Migration {
+	return c.migration
+}
+
+func (c *migrationContext) Option(name string) string {
+	return c.t.config.Options[name]
+}
+
+func (c *migrationContext) Catalog() *provider.Catalog {
+	return c.t.catalog(c.project)
+}
+
+func (c *migrationContext) ModuleName() string {
+	return c.t.extractModuleName(c.project)
+}
+
+func (c *migrationContext) ProjectName() string {
+	return c.t.extractProjectName(c.project)
+}
+
+func (c *migrationContext) Setting(key string) (string, bool) {
+	if c.project == nil {
+		return "", false
+	}
+	return c.t.migratedSetting(c.project, key)
+}
+
+func (c *migrationContext) WriteConfig(header string) error {
+	migrated, err := c.t.migratedConfigYAML(c.migration)
+	if err != nil {
+		return fmt.Errorf("failed to rewrite config.yaml: %w", err)
+	}
+	c.migration.NewFiles["config.yaml"] = header + migrated
+
+	if err := c.t.transformConfigJSON(c.migration); err != nil {
+		return fmt.Errorf("failed to rewrite config.json: %w", err)
+	}
+
+	c.t.transformEnvFile(c.migration)
+
+	return nil
+}
+
+// catalog returns the target provider's mappings for the source provider,
+// with nil maps replaced by empty ones. The project's settings may pick
+// mappings, such as the models served in the target region.
+func (t *Transformer) catalog(project *models.Project) *provider.Catalog {
+	catalog := &provider.Catalog{}
+	if t.target != nil {
+		catalog = t.target.Catalog(t.context(project, nil))
+	}
+	if catalog.Models == nil {
+		catalog.Models = make(map[string]string)
+	}
+	if catalog.Embedders == nil {
+		catalog.Embedders = make(map[string]provider.Embedder)
+	}
+	return catalog
+}
+
+// settings returns how the source provider's settings translate, or no
+// translations when the target is unknown.
+func (t *Transformer) settings(project *models.Project) *provider.Settings {
+	if t.target == nil {
+		return &provider.Settings{}
+	}
+	return t.target.Settings(t.context(project, nil))
+}
@@ -0,0 +1,352 @@
+package transformer
+
+import (
+	"fmt"
+	"go/ast"
+	"go/token"
+	"slices"
+	"strconv"
+
+	"github.com/genkit-migrate/genkit-migrate/pkg/models"
+	"github.com/genkit-migrate/genkit-migrate/pkg/provider"
+	"github.com/genkit-migrate/genkit-migrate/pkg/rewrite"
+)
+
+const genkitPackage = "github.com/firebase/genkit/go/genkit"
+
+// directCallRule rewrites text generations made directly through a model SDK
+// into genkit.Generate calls on a *genkit.Genkit in scope. The model keeps its
+// source reference, which the model reference rule then maps to the target.
+// With no instance in scope, per-call generations go to the target's client
+// with the mapped model instead.
+type directCallRule struct {
+	clients []provider.ClientSource
+	target  *provider.Client
+	models  map[string]string
+	flagged map[ast.Node]bool
+}
+
+func (r *directCallRule) Name() string {
+	return "direct-call"
+}
+
+func (r *directCallRule) Apply(file *rewrite.File) error {
+	r.flagged = make(map[ast.Node]bool)
+
+	for _, client := range r.clients {
+		clientName, imported := file.ImportName(client.Path)
+		if !imported {
+			continue
+		}
+
+		instances := genkitInstances(file)
+		rewrite.Rewrite(file.AST, func(expr ast.Expr) ast.Expr {
+			call, ok := expr.(*ast.CallExpr)
+			if !ok {
+				return nil
+			}
+			return r.rewriteGenerate(file, client, clientName, instances, call)
+		})
+
+		if file.DeleteUnusedImport(client.Path) {
+			file.Report(nil, &models.Change{
+				Type:        "import",
+				Description: fmt.Sprintf("Removed %s; its generations now go through GenKit", client.Path),
+				OldValue:    client.Path,
+			})
+			continue
+		}
+
+		// Flag the client calls and package references left behind.
+		ast.Inspect(file.AST, func(n ast.Node) bool {
+			switch node := n.(type) {
+			case *ast.CallExpr:
+				sel, ok := node.Fun.(*ast.SelectorExpr)
+				if ok && slices.Contains(client.Methods, sel.Sel.Name) {
+					r.flag(file, node, fmt.Sprintf("No automatic translation for %s call %s; port it to genkit.Generate", client.Path, sel.Sel.Name))
+				}
+			case *ast.SelectorExpr:
+				if ident, ok := node.X.(*ast.Ident); ok && ident.Name == clientName {
+					r.flag(file, node, fmt.Sprintf("No automatic translation for %s.%s", clientName, node.Sel.Name))
+				}
+			}
+			return true
+		})
+	}
+
+	return nil
+}
+
+func (r *directCallRule) flag(file *rewrite.File, node ast.Node, description string) {
+	if r.flagged[node] {
+		return
+	}
+	r.flagged[node] = true
+	file.Report(node, &models.Change{
+		Type:         "service",
+		Description:  description,
+		ManualReview: true,
+	})
+}
+
+// rewriteGenerate translates a text-only GenerateContent call, leaving
+// multi-part, streaming and chat calls to be flagged.
+func (r *directCallRule) rewriteGenerate(file *rewrite.File, client provider.ClientSource, clientName string, instances []genkitInstance, call *ast.CallExpr) ast.Expr {
+	sel, ok := call.Fun.(*ast.SelectorExpr)
+	if !ok || sel.Sel.Name != "GenerateContent" {
+		return nil
+	}
+
+	var model string
+	var contents, config ast.Expr
+	switch {
+	case client.Handles && len(call.Args) == 2:
+		model, ok = handleModel(sel.X)
+		contents = call.Args[1]
+	case !client.Handles && len(call.Args) == 4:
+		model, ok = rewrite.StringLiteral(call.Args[1])
+		contents = call.Args[2]
+		if ident, isIdent := call.Args[3].(*ast.Ident); !isIdent || ident.Name != "nil" {
+			config = call.Args[3]
+		}
+	default:
+		return nil
+	}
+	if !ok {
+		return nil
+	}
+
+	prompt, ok := textContent(file, client, contents)
+	if !ok {
+		return nil
+	}
+
+	g := genkitInstanceAt(instances, call.Pos())
+	if g == "" {
+		return r.rewriteClient(file, client, clientName, call, model, prompt, config)
+	}
+
+	genkitName := importName(file, "genkit", genkitPackage)
+	aiName := importName(file, "ai", genkitAIPackage)
+	option := func(name string, arg ast.Expr) ast.Expr {
+		return &ast.CallExpr{Fun: selector(aiName, name), Args: []ast.Expr{arg}}
+	}
+
+	args := []ast.Expr{
+		call.Args[0],
+		ast.NewIdent(g),
+		option("WithModelName", &ast.BasicLit{Kind: token.STRING, Value: strconv.Quote(client.Prefix + model)}),
+		option("WithPrompt", prompt),
+	}
+	if config != nil {
+		args = append(args, option("WithConfig", config))
+	}
+
+	description := fmt.Sprintf("Replaced %s.GenerateContent with genkit.Generate", clientName)
+	if client.Handles {
+		// Handle settings such as SetTemperature are not carried over, and
+		// the response is read through Text() instead of Candidates.
+		description += "; move settings made on the model handle to ai.WithConfig and read the response with Text()"
+	}
+	file.Report(call, &models.Change{
+		Type:         "service",
+		Description:  description,
+		OldValue:     client.Prefix + model,
+		ManualReview: client.Handles,
+	})
+	return &ast.CallExpr{Fun: selector(genkitName, "Generate"), Lparen: call.Lparen, Args: args, Rparen: call.Rparen}
+}
+
+// rewriteClient translates a generation with no *genkit.Genkit in scope to
+// the target's client. Handle responses are read through Candidates, which
+// the client's response lacks, and configs are not translated, so those
+// calls are flagged.
+func (r *directCallRule) rewriteClient(file *rewrite.File, client provider.ClientSource, clientName string, call *ast.CallExpr, model string, prompt, config ast.Expr) ast.Expr {
+	target, mapped := r.models[client.Prefix+model]
+	if r.target == nil || client.Handles || config != nil || !mapped {
+		r.flag(file, call, fmt.Sprintf("No *genkit.Genkit in scope to rewrite %s.GenerateContent to genkit.Generate", clientName))
+		return nil
+	}
+
+	file.Report(call, &models.Change{
+		Type:        "service",
+		Description: fmt.Sprintf("Replaced %s.GenerateContent with %s, a %s call", clientName, r.target.Func, r.target.Title),
+		OldValue:    client.Prefix + model,
+		NewValue:    target,
+	})
+	fun := ast.NewIdent(r.target.Func)
+	fun.NamePos = call.Fun.Pos()
+	args := []ast.Expr{
+		call.Args[0],
+		&ast.BasicLit{Kind: token.STRING, Value: strconv.Quote(target)},
+		prompt,
+	}
+	return &ast.CallExpr{Fun: fun, Lparen: call.Lparen, Args: args, Rparen: call.Rparen}
+}
+
+// handleModel resolves the model bound to a handle, either inline as in
+// client.GenerativeModel("gemini-1.5-pro").GenerateContent(...) or through a
+// variable assigned from it in this file.
+func handleModel(handle ast.Expr) (string, bool) {
+	if ident, ok := handle.(*ast.Ident); ok && ident.Obj != nil {
+		switch decl := ident.Obj.Decl.(type) {
+		case *ast.AssignStmt:
+			handle = assignedValue(ident.Name, decl.Lhs, decl.Rhs)
+		case *ast.ValueSpec:
+			handle = assignedValue(ident.Name, identExprs(decl.Names), decl.Values)
+		}
+	}
+
+	call, ok := handle.(*ast.CallExpr)
+	if !ok || len(call.Args) != 1 {
+		return "", false
+	}
+	sel, ok := call.Fun.(*ast.SelectorExpr)
+	if !ok || sel.Sel.Name != "GenerativeModel" {
+		return "", false
+	}
+	return rewrite.StringLiteral(call.Args[0])
+}
+
+func assignedValue(name string, lhs, rhs []ast.Expr) ast.Expr {
+	if len(lhs) != len(rhs) {
+		return nil
+	}
+	for i, expr := range lhs {
+		if ident, ok := expr.(*ast.Ident); ok && ident.Name == name {
+			return rhs[i]
+		}
+	}
+	return nil
+}
+
+func identExprs(idents []*ast.Ident) []ast.Expr {
+	exprs := make([]ast.Expr, len(idents))
+	for i, ident := range idents {
+		exprs[i] = ident
+	}
+	return exprs
+}
+
+// textContent returns the prompt of a single genai.Text(...) content.
+func textContent(file *rewrite.File, client provider.ClientSource, contents ast.Expr) (ast.Expr, bool) {
+	call, ok := contents.(*ast.CallExpr)
+	if !ok || len(call.Args) != 1 {
+		return nil, false
+	}
+	pkg, name, ok := file.SelectorPackage(call.Fun)
+	if !ok || pkg != client.Path || name != "Text" {
+		return nil, false
+	}
+	return call.Args[0], true
+}
+
+// genkitInstance is a *genkit.Genkit variable and the source range it is
+// visible in.
+type genkitInstance struct {
+	name     string
+	from, to token.Pos
+}
+
+// genkitInstances finds the results of genkit.Init and *genkit.Genkit
+// parameters and variables.
+func genkitInstances(file *rewrite.File) []genkitInstance {
+	isInit := func(expr ast.Expr) bool {
+		call, ok := expr.(*ast.CallExpr)
+		if !ok {
+			return false
+		}
+		pkg, name, ok := file.SelectorPackage(call.Fun)
+		return ok && pkg == genkitPackage && name == "Init"
+	}
+	isType := func(expr ast.Expr) bool {
+		star, ok := expr.(*ast.StarExpr)
+		if !ok {
+			return false
+		}
+		pkg, name, ok := file.SelectorPackage(star.X)
+		return ok && pkg == genkitPackage && name == "Genkit"
+	}
+
+	var instances []genkitInstance
+	add := func(ident *ast.Ident, from, to token.Pos) {
+		if ident.Name != "_" {
+			instances = append(instances, genkitInstance{name: ident.Name, from: from, to: to})
+		}
+	}
+
+	var stack []ast.Node
+	ast.Inspect(file.AST, func(n ast.Node) bool {
+		if n == nil {
+			stack = stack[:len(stack)-1]
+			return true
+		}
+
+		// The innermost enclosing function body or block bounds a local.
+		scope := ast.Node(file.AST)
+		for i := len(stack) - 1; i >= 0; i-- {
+			if _, ok := stack[i].(*ast.BlockStmt); ok {
+				scope = stack[i]
+				break
+			}
+		}
+
+		switch node := n.(type) {
+		case *ast.AssignStmt:
+			if node.Tok == token.DEFINE && len(node.Rhs) == 1 && isInit(node.Rhs[0]) {
+				if ident, ok := node.Lhs[0].(*ast.Ident); ok {
+					add(ident, node.End(), scope.End())
+				}
+			}
+		case *ast.ValueSpec:
+			// Package-level variables are visible throughout the file.
+			from := node.End()
+			if scope == file.AST {
+				from = file.AST.Pos()
+			}
+			for i, ident := range node.Names {
+				if isType(node.Type) || (i < len(node.Values) && isInit(node.Values[i])) {
+					add(ident, from, scope.End())
+				}
+			}
+		case *ast.FuncType:
+			if node.Params != nil && len(stack) > 0 {
+				parent := stack[len(stack)-1]
+				for _, field := range node.Params.List {
+					if isType(field.Type) {
+						for _, ident := range field.Names {
+							add(ident, parent.Pos(), parent.End())
+						}
+					}
+				}
+			}
+		}
+
+		stack = append(stack, n)
+		return true
+	})
+
+	return instances
+}
+
+// genkitInstanceAt returns the innermost instance visible at pos.
+func genkitInstanceAt(instances []genkitInstance, pos token.Pos) string {
+	name := ""
+	var from token.Pos
+	for _, instance := range instances {
+		if instance.from <= pos && pos < instance.to && instance.from >= from {
+			name, from = instance.name, instance.from
+		}
+	}
+	return name
+}
+
+// importName returns the local name of importPath, importing it under name
+// when the file does not import it yet.
+func importName(file *rewrite.File, name, importPath string) string {
+	if local, exists := file.ImportName(importPath); exists {
+		return local
+	}
+	file.AddImport(name, importPath)
+	return name
+}
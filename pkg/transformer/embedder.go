@@ -0,0 +1,277 @@
+package transformer
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/template"
+
+	"github.com/genkit-migrate/genkit-migrate/pkg/models"
+)
+
+type reindexEmbedder struct {
+	Source           string
+	SourceDimensions int
+	Target           string
+	TargetDimensions int
+}
+
+func (t *Transformer) transformEmbedders(migration *models.Migration) error {
+	mappings := t.catalog(migration.Project).Embedders
+
+	seen := make(map[string]bool)
+	mismatched := make([]reindexEmbedder, 0)
+	for _, embedder := range migration.Project.Embedders {
+		if seen[embedder.Name] {
+			continue
+		}
+		seen[embedder.Name] = true
+
+		mapping, exists := mappings[embedder.Name]
+		if !exists {
+			migration.Changes = append(migration.Changes, &models.Change{
+				Type:         "embedder",
+				Description:  fmt.Sprintf("No %s embedder mapping for %s", t.config.TargetProvider, embedder.Name),
+				File:         embedder.Position.Filename,
+				Line:         embedder.Position.Line,
+				OldValue:     embedder.Name,
+				ManualReview: true,
+			})
+			continue
+		}
+
+		if embedder.Dimensions == mapping.Dimensions {
+			migration.Changes = append(migration.Changes, &models.Change{
+				Type: "embedder",
+				Description: fmt.Sprintf("Embedder %s -> %s keeps %d dimensions, but vectors from different models are not comparable; re-embed stored documents",
+					embedder.Name, mapping.Target, mapping.Dimensions),
+				File:         embedder.Position.Filename,
+				Line:         embedder.Position.Line,
+				OldValue:     embedder.Name,
+				NewValue:     mapping.Target,
+				ManualReview: true,
+			})
+			continue
+		}
+
+		sourceDimensions := "an unknown number of"
+		if embedder.Dimensions > 0 {
+			sourceDimensions = fmt.Sprintf("%d", embedder.Dimensions)
+		}
+		description := fmt.Sprintf("Embedder %s -> %s changes vector dimensions from %s to %d; every stored vector must be re-embedded before the migrated app serves traffic",
+			embedder.Name, mapping.Target, sourceDimensions, mapping.Dimensions)
+		if mapping.Note != "" {
+			description += " (" + mapping.Note + ")"
+		}
+
+		migration.Changes = append(migration.Changes, &models.Change{
+			Type:        "embedder",
+			Description: description,
+			File:        embedder.Position.Filename,
+			Line:        embedder.Position.Line,
+			OldValue:    embedder.Name,
+			NewValue:    mapping.Target,
+			Blocking:    true,
+		})
+		mismatched = append(mismatched, reindexEmbedder{
+			Source:           embedder.Name,
+			SourceDimensions: embedder.Dimensions,
+			Target:           mapping.Target,
+			TargetDimensions: mapping.Dimensions,
+		})
+	}
+
+	if len(mismatched) == 0 {
+		return nil
+	}
+
+	return t.generateReindexJob(migration, mismatched)
+}
+
+func (t *Transformer) generateReindexJob(migration *models.Migration, embedders []reindexEmbedder) error {
+	project := migration.Project
+
+	type reindexPackage struct {
+		Package  string
+		Indexers []*models.Indexer
+	}
+
+	packages := make(map[string]*reindexPackage)
+	for _, indexer := range project.Indexers {
+		dir, err := filepath.Rel(project.Path, filepath.Dir(indexer.Position.Filename))
+		if err != nil {
+			dir = "."
+		}
+		if packages[dir] == nil {
+			packages[dir] = &reindexPackage{Package: indexer.Package}
+		}
+		packages[dir].Indexers = append(packages[dir].Indexers, indexer)
+	}
+	if len(packages) == 0 {
+		packages["."] = &reindexPackage{Package: "main"}
+	}
+
+	dirs := make([]string, 0, len(packages))
+	for dir := range packages {
+		dirs = append(dirs, dir)
+	}
+	sort.Strings(dirs)
+
+	tmpl, err := template.New("reindex").Parse(reindexTemplate)
+	if err != nil {
+		return err
+	}
+
+	for _, dir := range dirs {
+		pkg := packages[dir]
+
+		var content strings.Builder
+		err := tmpl.Execute(&content, map[string]interface{}{
+			"Package":   pkg.Package,
+			"Embedders": embedders,
+			"Indexers":  pkg.Indexers,
+		})
+		if err != nil {
+			return err
+		}
+
+		filePath := filepath.Join(dir, "reindex.go")
+		migration.NewFiles[filePath] = content.String()
+
+		names := make([]string, 0, len(pkg.Indexers))
+		for _, indexer := range pkg.Indexers {
+			names = append(names, indexer.Provider+"/"+indexer.Name)
+		}
+		description := "Generated re-indexing job scaffold; register your indexer in reindexTargets"
+		if len(names) > 0 {
+			description = fmt.Sprintf("Generated re-indexing job that re-embeds documents through %s", strings.Join(names, ", "))
+		}
+
+		migration.Changes = append(migration.Changes, &models.Change{
+			Type:        "embedder",
+			Description: description,
+			File:        filePath,
+		})
+	}
+
+	return nil
+}
+
+const reindexTemplate = `// Code generated by genkit-migrate. Review before running.
+
+package {{ .Package }}
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/firebase/genkit/go/ai"
+	"github.com/firebase/genkit/go/genkit"
+)
+
+// The migration changed the embedding model:
+{{- range .Embedders }}
+//   - {{ .Source }} ({{ if .SourceDimensions }}{{ .SourceDimensions }}{{ else }}unknown{{ end }} dimensions) -> {{ .Target }} ({{ .TargetDimensions }} dimensions)
+{{- end }}
+//
+// Vectors written with the old embedder cannot be searched with the new one,
+// so every document has to be re-embedded through the indexers below before
+// the app serves traffic. Export the source documents as JSON lines, one
+// {"content": "...", "metadata": {...}} object per line, then call
+// reindexFromEnv after genkit.Init and run the app with REINDEX_SOURCE set to
+// the export file.
+
+const reindexBatchSize = 100
+
+// Provider and name of every indexer passed to DefineIndexer.
+var reindexTargets = [][2]string{
+{{- range .Indexers }}
+	{"{{ .Provider }}", "{{ .Name }}"},
+{{- else }}
+	// TODO: add the provider and name of the app's indexer.
+{{- end }}
+}
+
+type reindexRecord struct {
+	Content  string         ` + "`" + `json:"content"` + "`" + `
+	Metadata map[string]any ` + "`" + `json:"metadata,omitempty"` + "`" + `
+}
+
+func reindexFromEnv(ctx context.Context, g *genkit.Genkit) error {
+	source := os.Getenv("REINDEX_SOURCE")
+	if source == "" {
+		return nil
+	}
+	return reindexDocuments(ctx, g, source)
+}
+
+func reindexDocuments(ctx context.Context, g *genkit.Genkit, exportPath string) error {
+	if len(reindexTargets) == 0 {
+		return fmt.Errorf("no indexers configured in reindexTargets")
+	}
+
+	indexers := make([]ai.Indexer, 0, len(reindexTargets))
+	for _, target := range reindexTargets {
+		indexer := genkit.LookupIndexer(g, target[0], target[1])
+		if indexer == nil {
+			return fmt.Errorf("indexer %s/%s is not defined", target[0], target[1])
+		}
+		indexers = append(indexers, indexer)
+	}
+
+	file, err := os.Open(exportPath)
+	if err != nil {
+		return fmt.Errorf("failed to open export: %w", err)
+	}
+	defer file.Close()
+
+	batch := make([]*ai.Document, 0, reindexBatchSize)
+	flush := func() error {
+		for _, indexer := range indexers {
+			if err := indexer.Index(ctx, &ai.IndexerRequest{Documents: batch}); err != nil {
+				return fmt.Errorf("failed to index documents: %w", err)
+			}
+		}
+		batch = batch[:0]
+		return nil
+	}
+
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	line, total := 0, 0
+	for scanner.Scan() {
+		line++
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+
+		var record reindexRecord
+		if err := json.Unmarshal(scanner.Bytes(), &record); err != nil {
+			return fmt.Errorf("invalid record on line %d: %w", line, err)
+		}
+
+		batch = append(batch, ai.DocumentFromText(record.Content, record.Metadata))
+		total++
+		if len(batch) == reindexBatchSize {
+			if err := flush(); err != nil {
+				return err
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("failed to read export: %w", err)
+	}
+	if len(batch) > 0 {
+		if err := flush(); err != nil {
+			return err
+		}
+	}
+
+	fmt.Printf("Re-indexed %d documents\n", total)
+	return nil
+}
+`
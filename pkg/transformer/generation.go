@@ -0,0 +1,346 @@
+package transformer
+
+import (
+	"fmt"
+	"go/ast"
+	"go/token"
+	"slices"
+	"strconv"
+
+	"github.com/genkit-migrate/genkit-migrate/pkg/models"
+	"github.com/genkit-migrate/genkit-migrate/pkg/provider"
+	"github.com/genkit-migrate/genkit-migrate/pkg/rewrite"
+)
+
+const genkitAIPackage = "github.com/firebase/genkit/go/ai"
+
+// commonGeneration is GenKit's provider-neutral ai.GenerationCommonConfig,
+// used when the target has no generation config of its own.
+var commonGeneration = &provider.Generation{
+	Package: genkitAIPackage,
+	Name:    "ai",
+	Type:    "GenerationCommonConfig",
+}
+
+func clamp(option provider.GenerationOption, value float64) float64 {
+	if !option.Limited {
+		return value
+	}
+	if value < option.Min {
+		return option.Min
+	}
+	if value > option.Max {
+		return option.Max
+	}
+	return value
+}
+
+func limitDescription(option provider.GenerationOption) string {
+	return fmt.Sprintf("[%s, %s]", formatNumber(option.Min), formatNumber(option.Max))
+}
+
+func formatNumber(value float64) string {
+	return strconv.FormatFloat(value, 'f', -1, 64)
+}
+
+// generationConfigRule translates the source provider's generation configs to
+// the target's, through the ai.GenerationCommonConfig field each option maps
+// to. A nil target translates to ai.GenerationCommonConfig itself.
+type generationConfigRule struct {
+	source *provider.Generation
+	target *provider.Generation
+}
+
+func (r *generationConfigRule) Name() string {
+	return "generation-config"
+}
+
+func (r *generationConfigRule) Apply(file *rewrite.File) error {
+	touched := make(map[string]bool)
+	var applyErr error
+
+	rewrite.Rewrite(file.AST, func(expr ast.Expr) ast.Expr {
+		lit, ok := expr.(*ast.CompositeLit)
+		if !ok || applyErr != nil {
+			return nil
+		}
+
+		pkg, typeName, ok := file.SelectorPackage(lit.Type)
+		if !ok || !r.matches(pkg, typeName) {
+			return nil
+		}
+		touched[pkg] = true
+
+		sourceName, _ := file.ImportName(pkg)
+		target := r.target
+		if target == nil {
+			target = commonGeneration
+		}
+		targetName, targetType := target.Name, target.Type
+		file.AddImport(targetName, target.Package)
+
+		translated := &ast.CompositeLit{
+			Type:   &ast.SelectorExpr{X: &ast.Ident{Name: targetName, NamePos: lit.Type.Pos()}, Sel: ast.NewIdent(targetType)},
+			Lbrace: lit.Lbrace,
+			Rbrace: lit.Rbrace,
+		}
+
+		for _, elt := range lit.Elts {
+			kv, ok := elt.(*ast.KeyValueExpr)
+			var key *ast.Ident
+			if ok {
+				key, _ = kv.Key.(*ast.Ident)
+			}
+			if key == nil {
+				file.Report(elt, &models.Change{
+					Type:         "config",
+					Description:  fmt.Sprintf("Unkeyed %s.%s field cannot be translated", sourceName, typeName),
+					ManualReview: true,
+				})
+				continue
+			}
+
+			field := key.Name
+			if replacement := r.replacement(field); replacement != nil {
+				value, err := replacementValue(file, replacement)
+				if err != nil {
+					applyErr = err
+					return nil
+				}
+				translated.Elts = append(translated.Elts, &ast.KeyValueExpr{
+					Key:   &ast.Ident{Name: replacement.field, NamePos: key.NamePos},
+					Colon: kv.Colon,
+					Value: value,
+				})
+				description := fmt.Sprintf("Replaced %s with %s", field, replacement.title)
+				if replacement.note != "" {
+					description += "; " + replacement.note
+				}
+				file.Report(kv, &models.Change{
+					Type:         "config",
+					Description:  description,
+					OldValue:     field,
+					NewValue:     replacement.field,
+					ManualReview: replacement.note != "",
+				})
+				continue
+			}
+
+			targetField, option, note := r.translateField(field)
+			if targetField == "" {
+				file.Report(kv, &models.Change{
+					Type:         "config",
+					Description:  fmt.Sprintf("Dropped generation option %s: %s", field, note),
+					OldValue:     field,
+					ManualReview: true,
+				})
+				continue
+			}
+
+			translated.Elts = append(translated.Elts, &ast.KeyValueExpr{
+				Key:   &ast.Ident{Name: targetField, NamePos: key.NamePos},
+				Colon: kv.Colon,
+				Value: r.translateValue(file, field, option, kv.Value),
+			})
+		}
+
+		file.Report(lit, &models.Change{
+			Type:        "config",
+			Description: fmt.Sprintf("Translated %s.%s to %s.%s", sourceName, typeName, targetName, targetType),
+			OldValue:    sourceName + "." + typeName,
+			NewValue:    targetName + "." + targetType,
+		})
+
+		expandConfig(file, translated)
+		return translated
+	})
+
+	if applyErr != nil {
+		return applyErr
+	}
+
+	for pkg := range touched {
+		file.DeleteUnusedImport(pkg)
+	}
+
+	return nil
+}
+
+// expandConfig lays out a config one field per line once it holds a nested
+// literal, such as the guardrail, next to other fields.
+func expandConfig(file *rewrite.File, lit *ast.CompositeLit) {
+	if len(lit.Elts) < 2 {
+		return
+	}
+	for _, elt := range lit.Elts {
+		kv, ok := elt.(*ast.KeyValueExpr)
+		if !ok {
+			continue
+		}
+		value := kv.Value
+		if unary, ok := value.(*ast.UnaryExpr); ok {
+			value = unary.X
+		}
+		if _, ok := value.(*ast.CompositeLit); ok {
+			file.Expand(lit)
+			return
+		}
+	}
+}
+
+// configField is a target config field set in place of a source field the
+// target enforces some other way.
+type configField struct {
+	title, field, expr string
+	imports            [][2]string
+	note               string // what is left to do by hand, if anything
+}
+
+// replacement returns the target field that replaces a safety or schema
+// field, or nil when the field is translated or dropped.
+func (r *generationConfigRule) replacement(field string) *configField {
+	option, known := r.source.ByField(field)
+	if !known || r.target == nil {
+		return nil
+	}
+
+	guardrail, structured := r.target.Guardrail, r.target.StructuredOutput
+	switch {
+	case option.Safety && guardrail != nil:
+		return &configField{title: guardrail.Title, field: guardrail.Field, expr: guardrail.Expr, imports: guardrail.Imports}
+	case option.Schema && structured != nil:
+		return &configField{
+			title:   structured.Title,
+			field:   structured.Field,
+			expr:    structured.Expr,
+			imports: structured.Imports,
+			note:    "declare the schema as a Go type and pass it to ai.WithOutputType",
+		}
+	}
+	return nil
+}
+
+func replacementValue(file *rewrite.File, replacement *configField) (ast.Expr, error) {
+	value, err := rewrite.ParseExpr(replacement.expr)
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s value: %w", replacement.field, err)
+	}
+	for _, imp := range replacement.imports {
+		file.AddImport(imp[0], imp[1])
+	}
+	return value, nil
+}
+
+func (r *generationConfigRule) matches(pkg, typeName string) bool {
+	return r.source != nil && slices.Contains(r.source.Packages, pkg) && slices.Contains(r.source.Types, typeName)
+}
+
+// translateField returns the target field of a source field and its target
+// option, or an empty field and the reason the field is dropped.
+func (r *generationConfigRule) translateField(field string) (string, provider.GenerationOption, string) {
+	option, known := r.source.ByField(field)
+	if r.target == nil {
+		switch {
+		case !known:
+			return "", option, "no ai.GenerationCommonConfig equivalent"
+		case option.Common == "":
+			return "", option, "not part of ai.GenerationCommonConfig"
+		}
+		return option.Common, provider.GenerationOption{}, ""
+	}
+
+	targetOption, supported := r.target.ByCommon(option.Common)
+	if known && supported {
+		return targetOption.Field, targetOption, ""
+	}
+	if note, exists := r.target.Unsupported[field]; exists && known {
+		return "", option, note
+	}
+	return "", option, fmt.Sprintf("no %s.%s equivalent", r.target.Name, r.target.Type)
+}
+
+// translateValue checks constants against the limits of the target option;
+// ai.GenerationCommonConfig has none, so values are kept as they are.
+func (r *generationConfigRule) translateValue(file *rewrite.File, field string, option provider.GenerationOption, value ast.Expr) ast.Expr {
+	value = unwrapPointer(value)
+	if !option.Limited {
+		return value
+	}
+
+	number, ok := numericLiteral(value)
+	if !ok {
+		file.Report(value, &models.Change{
+			Type: "config",
+			Description: fmt.Sprintf("%s is not a constant and cannot be checked against the %s range %s",
+				field, r.target.Title, limitDescription(option)),
+			ManualReview: true,
+		})
+		return value
+	}
+
+	clamped := clamp(option, number)
+	if clamped == number {
+		return value
+	}
+
+	file.Report(value, &models.Change{
+		Type:        "config",
+		Description: fmt.Sprintf("Clamped %s to the %s range %s", field, r.target.Title, limitDescription(option)),
+		OldValue:    formatNumber(number),
+		NewValue:    formatNumber(clamped),
+	})
+
+	kind := token.INT
+	if clamped != float64(int64(clamped)) {
+		kind = token.FLOAT
+	}
+	return &ast.BasicLit{ValuePos: value.Pos(), Kind: kind, Value: formatNumber(clamped)}
+}
+
+// unwrapPointer strips pointer helpers such as genai.Ptr[float32](0.5); the
+// target configs use plain values.
+func unwrapPointer(expr ast.Expr) ast.Expr {
+	call, ok := expr.(*ast.CallExpr)
+	if !ok || len(call.Args) != 1 {
+		return expr
+	}
+
+	fun := call.Fun
+	switch index := fun.(type) {
+	case *ast.IndexExpr:
+		fun = index.X
+	case *ast.IndexListExpr:
+		fun = index.X
+	}
+
+	switch f := fun.(type) {
+	case *ast.SelectorExpr:
+		if f.Sel.Name == "Ptr" {
+			return call.Args[0]
+		}
+	case *ast.Ident:
+		if f.Name == "Ptr" {
+			return call.Args[0]
+		}
+	}
+	return expr
+}
+
+func numericLiteral(expr ast.Expr) (float64, bool) {
+	sign := 1.0
+	if unary, ok := expr.(*ast.UnaryExpr); ok && unary.Op == token.SUB {
+		sign = -1
+		expr = unary.X
+	}
+
+	lit, ok := expr.(*ast.BasicLit)
+	if !ok || (lit.Kind != token.INT && lit.Kind != token.FLOAT) {
+		return 0, false
+	}
+
+	value, err := strconv.ParseFloat(lit.Value, 64)
+	if err != nil {
+		return 0, false
+	}
+	return sign * value, true
+}
@@ -0,0 +1,176 @@
+package transformer
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/genkit-migrate/genkit-migrate/internal/utils"
+	"github.com/genkit-migrate/genkit-migrate/pkg/models"
+	"github.com/genkit-migrate/genkit-migrate/pkg/provider"
+	"gopkg.in/yaml.v3"
+)
+
+func (t *Transformer) transformPrompts(migration *models.Migration) error {
+	for _, prompt := range migration.Project.Prompts {
+		content, changes, err := t.transformPromptFile(migration.Project, prompt)
+		if err != nil {
+			return fmt.Errorf("failed to transform %s: %w", prompt.Path, err)
+		}
+
+		// Prompts are not Go sources, so they are always written out to carry
+		// them over to the target project, even when nothing changed.
+		migration.NewFiles[prompt.Path] = content
+		migration.Changes = append(migration.Changes, changes...)
+	}
+
+	return nil
+}
+
+func (t *Transformer) transformPromptFile(project *models.Project, prompt *models.Prompt) (string, []*models.Change, error) {
+	changes := make([]*models.Change, 0)
+
+	content, err := os.ReadFile(filepath.Join(project.Path, prompt.Path))
+	if err != nil {
+		return "", nil, err
+	}
+
+	if t.target == nil {
+		return string(content), changes, nil
+	}
+
+	frontmatter, body, ok := utils.SplitFrontmatter(content)
+	if !ok {
+		return string(content), changes, nil
+	}
+
+	var root yaml.Node
+	if err := yaml.Unmarshal(frontmatter, &root); err != nil {
+		return "", nil, err
+	}
+	if len(root.Content) == 0 || root.Content[0].Kind != yaml.MappingNode {
+		return string(content), changes, nil
+	}
+
+	modelMappings := t.getModelMappings(project)
+
+	mapping := root.Content[0]
+	for i := 0; i+1 < len(mapping.Content); i += 2 {
+		key, value := mapping.Content[i], mapping.Content[i+1]
+
+		switch {
+		case key.Value == "model" && value.Kind == yaml.ScalarNode:
+			if newModel, exists := modelMappings[value.Value]; exists {
+				value.Value = t.targetModelRef(project, newModel)
+			}
+		case key.Value == "config" && value.Kind == yaml.MappingNode:
+			changes = append(changes, t.translatePromptConfig(prompt, value)...)
+		}
+	}
+
+	var out strings.Builder
+	out.WriteString("---\n")
+	encoder := yaml.NewEncoder(&out)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(&root); err != nil {
+		return "", nil, err
+	}
+	if err := encoder.Close(); err != nil {
+		return "", nil, err
+	}
+	out.WriteString("---\n")
+	out.Write(body)
+
+	return out.String(), changes, nil
+}
+
+func (t *Transformer) translatePromptConfig(prompt *models.Prompt, config *yaml.Node) []*models.Change {
+	changes := make([]*models.Change, 0)
+	if t.source == nil || t.source.Source().Generation == nil {
+		return changes
+	}
+	source := t.source.Source().Generation
+	target := t.target.Rewrite(t.context(nil, nil)).Generation
+
+	content := make([]*yaml.Node, 0, len(config.Content))
+	for i := 0; i+1 < len(config.Content); i += 2 {
+		key, value := config.Content[i], config.Content[i+1]
+
+		option, known := source.ByPrompt(key.Value)
+		if !known || (target == nil && option.Common == "") {
+			content = append(content, key, value)
+			continue
+		}
+
+		// Without a target dialect the option takes GenKit's common name,
+		// which is the Common field with a lower-case first letter.
+		targetKey := lowerFirst(option.Common)
+		targetOption, supported := provider.GenerationOption{}, true
+		if target != nil {
+			targetOption, supported = target.ByCommon(option.Common)
+			targetKey = targetOption.Prompt
+		}
+
+		switch {
+		case !supported:
+			note, exists := target.Unsupported[option.Field]
+			if !exists {
+				note = fmt.Sprintf("no %s equivalent", target.Title)
+			}
+			switch {
+			case option.Safety && target.Guardrail != nil:
+				note = fmt.Sprintf("moved to %s; set %s on the prompt's generations", target.Guardrail.Title, target.Guardrail.Field)
+			case option.Schema && target.StructuredOutput != nil:
+				note = fmt.Sprintf("declare the schema under output and set %s on the prompt's generations to enable %s",
+					target.StructuredOutput.Field, target.StructuredOutput.Title)
+			}
+			changes = append(changes, &models.Change{
+				Type:         "config",
+				Description:  fmt.Sprintf("Removed prompt option %s: %s", key.Value, note),
+				File:         prompt.Path,
+				OldValue:     key.Value,
+				ManualReview: true,
+			})
+			continue
+		case targetKey == key.Value:
+			content = append(content, key, value)
+		default:
+			changes = append(changes, &models.Change{
+				Type:        "config",
+				Description: fmt.Sprintf("Renamed prompt option %s -> %s", key.Value, targetKey),
+				File:        prompt.Path,
+				OldValue:    key.Value,
+				NewValue:    targetKey,
+			})
+			key.Value = targetKey
+			content = append(content, key, value)
+		}
+
+		if number, err := strconv.ParseFloat(value.Value, 64); err == nil && value.Kind == yaml.ScalarNode {
+			if clamped := clamp(targetOption, number); clamped != number {
+				changes = append(changes, &models.Change{
+					Type:        "config",
+					Description: fmt.Sprintf("Clamped prompt option %s to the %s range %s", key.Value, target.Title, limitDescription(targetOption)),
+					File:        prompt.Path,
+					OldValue:    value.Value,
+					NewValue:    formatNumber(clamped),
+				})
+				// The source tag would keep 1 as !!float 1.
+				value.Value = formatNumber(clamped)
+				value.Tag = ""
+			}
+		}
+	}
+	config.Content = content
+
+	return changes
+}
+
+func lowerFirst(s string) string {
+	if s == "" {
+		return s
+	}
+	return strings.ToLower(s[:1]) + s[1:]
+}
@@ -0,0 +1,316 @@
+package transformer
+
+import (
+	"fmt"
+	"go/ast"
+	"go/token"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/genkit-migrate/genkit-migrate/pkg/models"
+	"github.com/genkit-migrate/genkit-migrate/pkg/provider"
+	"github.com/genkit-migrate/genkit-migrate/pkg/rewrite"
+)
+
+func (t *Transformer) goRewriteRules(project *models.Project) []rewrite.Rule {
+	if t.target == nil {
+		return nil
+	}
+
+	target := t.target.Rewrite(t.context(project, nil))
+	catalog := t.catalog(project)
+
+	var sources []provider.PluginSource
+	var clients []provider.ClientSource
+	var generation *provider.Generation
+	var prefixes []string
+	if t.source != nil {
+		sources = t.source.Source().Plugins
+		clients = t.source.Source().Clients
+		generation = t.source.Source().Generation
+	}
+	if t.source != nil && t.source != t.target {
+		for _, plugin := range sources {
+			prefixes = append(prefixes, plugin.Prefixes...)
+		}
+	}
+
+	// User rules run first so the model references they produce are mapped by
+	// the built-in rules.
+	rules := append([]rewrite.Rule{}, t.config.Rules...)
+	rules = append(rules,
+		&generationConfigRule{
+			source: generation,
+			target: target.Generation,
+		},
+		&pluginRule{
+			sources:   sources,
+			target:    target.Plugin,
+			mappings:  catalog.Models,
+			embedders: catalog.EmbedderTargets(),
+		},
+		&directCallRule{clients: clients, target: target.Client, models: catalog.Models},
+		&modelReferenceRule{
+			kind:     "model",
+			mappings: catalog.Models,
+			ref:      catalog.Ref,
+			prefixes: prefixes,
+			known:    catalog.EmbedderTargets(),
+		},
+		&modelReferenceRule{
+			kind:     "embedder",
+			mappings: catalog.EmbedderTargets(),
+			ref:      catalog.Ref,
+		},
+	)
+
+	// Structured output is enabled on the configs the generation config rule
+	// has translated.
+	if target.Generation != nil && target.Generation.StructuredOutput != nil {
+		rules = append(rules, &structuredOutputRule{target: target.Generation})
+	}
+
+	return append(rules, target.Rules...)
+}
+
+type pluginRule struct {
+	sources   []provider.PluginSource
+	target    *provider.Plugin
+	mappings  map[string]string
+	embedders map[string]string
+	replaced  map[ast.Expr]bool
+}
+
+func (r *pluginRule) Name() string {
+	return "plugin"
+}
+
+func (r *pluginRule) Apply(file *rewrite.File) error {
+	r.replaced = make(map[ast.Expr]bool)
+
+	for _, plugin := range r.sources {
+		pluginName, imported := file.ImportName(plugin.Path)
+		if !imported {
+			continue
+		}
+
+		var applyErr error
+		rewrite.Rewrite(file.AST, func(expr ast.Expr) ast.Expr {
+			replacement, err := r.rewritePluginExpr(file, plugin, pluginName, expr)
+			if err != nil {
+				applyErr = err
+				return nil
+			}
+			return replacement
+		})
+		if applyErr != nil {
+			return applyErr
+		}
+
+		if file.DeleteUnusedImport(plugin.Path) {
+			file.Report(nil, &models.Change{
+				Type:        "import",
+				Description: fmt.Sprintf("Replaced %s plugin with %s", pluginName, r.target.Name),
+				OldValue:    plugin.Path,
+				NewValue:    r.target.Path,
+			})
+			continue
+		}
+
+		ast.Inspect(file.AST, func(n ast.Node) bool {
+			if sel, ok := n.(*ast.SelectorExpr); ok {
+				if ident, ok := sel.X.(*ast.Ident); ok && ident.Name == pluginName {
+					file.Report(sel, &models.Change{
+						Type:         "import",
+						Description:  fmt.Sprintf("No automatic translation for %s.%s", pluginName, sel.Sel.Name),
+						ManualReview: true,
+					})
+				}
+			}
+			return true
+		})
+	}
+
+	return nil
+}
+
+func (r *pluginRule) rewritePluginExpr(file *rewrite.File, plugin provider.PluginSource, pluginName string, expr ast.Expr) (ast.Expr, error) {
+	switch node := expr.(type) {
+	case *ast.CallExpr:
+		pkg, name, ok := file.SelectorPackage(node.Fun)
+		if !ok || pkg != plugin.Path {
+			return nil, nil
+		}
+
+		switch {
+		case name == "Init" && plugin.Init:
+			return r.rewriteInit(file, pluginName, node)
+		case slices.Contains(plugin.Constructors, name):
+			return r.newPlugin(file, pluginName, node)
+		case name == "Model" || name == "GoogleAIModel" || name == "VertexAIModel":
+			return r.rewriteLookup(file, node, plugin.Prefixes, r.mappings, "model", "Model"), nil
+		case name == "Embedder" || name == "GoogleAIEmbedder" || name == "VertexAIEmbedder":
+			return r.rewriteLookup(file, node, plugin.Prefixes, r.embedders, "embedder", "Embedder"), nil
+		}
+	case *ast.UnaryExpr:
+		// &googleai.GoogleAI{} has already been replaced by its child literal.
+		if node.Op == token.AND && r.replaced[node.X] {
+			return node.X, nil
+		}
+	case *ast.CompositeLit:
+		pkg, name, ok := file.SelectorPackage(node.Type)
+		if ok && pkg == plugin.Path && slices.Contains(plugin.Literals, name) {
+			return r.newPlugin(file, pluginName, node)
+		}
+	}
+
+	return nil, nil
+}
+
+// rewriteInit replaces the trailing config argument of the source Init and
+// keeps the rest, so both Init(ctx, cfg) and Init(ctx, g, cfg) carry over.
+func (r *pluginRule) rewriteInit(file *rewrite.File, pluginName string, call *ast.CallExpr) (ast.Expr, error) {
+	if r.target.Init == "" {
+		file.Report(call, &models.Change{
+			Type:         "import",
+			Description:  fmt.Sprintf("The %s plugin has no Init function; register it with genkit.WithPlugins instead of %s.Init", r.target.Name, pluginName),
+			ManualReview: true,
+		})
+		return nil, nil
+	}
+
+	config, err := r.parseTarget(file, r.target.InitConfig)
+	if err != nil {
+		return nil, err
+	}
+	fun, err := rewrite.ParseExpr(r.target.Init)
+	if err != nil {
+		return nil, err
+	}
+
+	args := []ast.Expr{config}
+	if len(call.Args) > 1 {
+		args = append(append([]ast.Expr{}, call.Args[:len(call.Args)-1]...), config)
+	} else if len(call.Args) == 1 {
+		args = []ast.Expr{call.Args[0], config}
+	}
+
+	file.Report(call, &models.Change{
+		Type:        "import",
+		Description: fmt.Sprintf("Replaced %s.Init with %s", pluginName, r.target.Init),
+	})
+	return &ast.CallExpr{Fun: fun, Lparen: call.Lparen, Args: args, Rparen: call.Rparen}, nil
+}
+
+// rewriteLookup translates plugin helpers such as googleai.Model(g, "gemini-1.5-pro")
+// into their counterpart in the target plugin.
+func (r *pluginRule) rewriteLookup(file *rewrite.File, call *ast.CallExpr, prefixes []string, mappings map[string]string, kind, fn string) ast.Expr {
+	if len(call.Args) == 0 {
+		return nil
+	}
+	last := len(call.Args) - 1
+	name, ok := rewrite.StringLiteral(call.Args[last])
+	if !ok {
+		return nil
+	}
+
+	for _, prefix := range prefixes {
+		target, exists := mappings[prefix+name]
+		if !exists {
+			continue
+		}
+
+		file.AddImport(r.target.Lookup, r.target.LookupPath)
+		args := append([]ast.Expr{}, call.Args[:last]...)
+		if r.target.LookupProvider != "" {
+			fn = "Lookup" + fn
+			args = append(args, &ast.BasicLit{Kind: token.STRING, Value: strconv.Quote(r.target.LookupProvider)})
+		}
+		args = append(args, &ast.BasicLit{
+			ValuePos: call.Args[last].Pos(),
+			Kind:     token.STRING,
+			Value:    strconv.Quote(strings.TrimPrefix(target, r.target.LookupPrefix)),
+		})
+		file.Report(call, &models.Change{
+			Type:        kind,
+			Description: fmt.Sprintf("Map %s %s -> %s", kind, prefix+name, target),
+			OldValue:    prefix + name,
+			NewValue:    target,
+		})
+		return &ast.CallExpr{Fun: selector(r.target.Lookup, fn), Lparen: call.Lparen, Args: args, Rparen: call.Rparen}
+	}
+
+	return nil
+}
+
+func (r *pluginRule) newPlugin(file *rewrite.File, pluginName string, node ast.Expr) (ast.Expr, error) {
+	plugin, err := r.parseTarget(file, r.target.Expr)
+	if err != nil {
+		return nil, err
+	}
+	file.Report(node, &models.Change{
+		Type:        "import",
+		Description: fmt.Sprintf("Replaced %s plugin registration with %s", pluginName, r.target.Name),
+	})
+	r.replaced[plugin] = true
+	return plugin, nil
+}
+
+func (r *pluginRule) parseTarget(file *rewrite.File, expr string) (ast.Expr, error) {
+	for _, imp := range r.target.Imports {
+		file.AddImport(imp[0], imp[1])
+	}
+	return rewrite.ParseExpr(expr)
+}
+
+type modelReferenceRule struct {
+	kind     string
+	mappings map[string]string
+	ref      func(string) string
+	// prefixes are the names the replaced plugins register models under; a
+	// literal with one that neither mappings nor known maps names a model
+	// no plugin serves anymore.
+	prefixes []string
+	known    map[string]string
+}
+
+func (r *modelReferenceRule) Name() string {
+	return r.kind + "-reference"
+}
+
+func (r *modelReferenceRule) Apply(file *rewrite.File) error {
+	rewrite.Rewrite(file.AST, func(expr ast.Expr) ast.Expr {
+		value, ok := rewrite.StringLiteral(expr)
+		if !ok {
+			return nil
+		}
+		newModel, exists := r.mappings[value]
+		if !exists {
+			if _, known := r.known[value]; !known && slices.ContainsFunc(r.prefixes, func(prefix string) bool { return strings.HasPrefix(value, prefix) }) {
+				file.Report(expr, &models.Change{
+					Type:         r.kind,
+					Description:  fmt.Sprintf("No mapping for %s %s, whose plugin is replaced; choose a %s the target serves", r.kind, value, r.kind),
+					OldValue:     value,
+					ManualReview: true,
+				})
+			}
+			return nil
+		}
+
+		ref := r.ref(newModel)
+		file.Report(expr, &models.Change{
+			Type:        r.kind,
+			Description: fmt.Sprintf("Replaced %s reference %s -> %s", r.kind, value, ref),
+			OldValue:    value,
+			NewValue:    ref,
+		})
+		return &ast.BasicLit{ValuePos: expr.Pos(), Kind: token.STRING, Value: strconv.Quote(ref)}
+	})
+
+	return nil
+}
+
+func selector(pkg, name string) *ast.SelectorExpr {
+	return &ast.SelectorExpr{X: ast.NewIdent(pkg), Sel: ast.NewIdent(name)}
+}
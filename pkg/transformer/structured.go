@@ -0,0 +1,390 @@
+package transformer
+
+import (
+	"fmt"
+	"go/ast"
+	"go/token"
+	"go/types"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/template"
+	"unicode"
+
+	"github.com/genkit-migrate/genkit-migrate/pkg/models"
+	"github.com/genkit-migrate/genkit-migrate/pkg/provider"
+	"github.com/genkit-migrate/genkit-migrate/pkg/rewrite"
+)
+
+// structuredOutputRule turns on the target's schema-constrained output for
+// generations that ask for an output type, through the config they pass to
+// ai.WithConfig.
+type structuredOutputRule struct {
+	target *provider.Generation
+}
+
+func (r *structuredOutputRule) Name() string {
+	return "structured-output"
+}
+
+func (r *structuredOutputRule) Apply(file *rewrite.File) error {
+	structured := r.target.StructuredOutput
+	aiName, imported := file.ImportName(genkitAIPackage)
+	if !imported {
+		return nil
+	}
+
+	var applyErr error
+	ast.Inspect(file.AST, func(n ast.Node) bool {
+		call, ok := n.(*ast.CallExpr)
+		if !ok || applyErr != nil || !r.structured(file, call) {
+			return applyErr == nil
+		}
+
+		var config ast.Expr
+		for _, arg := range call.Args {
+			option, ok := arg.(*ast.CallExpr)
+			if !ok || len(option.Args) != 1 {
+				continue
+			}
+			if pkg, name, ok := file.SelectorPackage(option.Fun); ok && pkg == genkitAIPackage && name == "WithConfig" {
+				config = option.Args[0]
+			}
+		}
+
+		if config == nil {
+			value, err := rewrite.ParseExpr(fmt.Sprintf("&%s.%s{%s: %s}", r.target.Name, r.target.Type, structured.Field, structured.Expr))
+			if err != nil {
+				applyErr = fmt.Errorf("invalid %s value: %w", structured.Field, err)
+				return false
+			}
+			file.AddImport(r.target.Name, r.target.Package)
+			for _, imp := range structured.Imports {
+				file.AddImport(imp[0], imp[1])
+			}
+			// Anchored at the closing parenthesis, the option goes on a line
+			// of its own in calls that list one option per line.
+			option := &ast.CallExpr{
+				Fun:  &ast.SelectorExpr{X: &ast.Ident{Name: aiName, NamePos: call.Rparen}, Sel: ast.NewIdent("WithConfig")},
+				Args: []ast.Expr{value},
+			}
+			call.Args = append(call.Args, option)
+			file.Report(call, &models.Change{
+				Type:        "config",
+				Description: fmt.Sprintf("Enabled %s for structured output", structured.Title),
+				NewValue:    structured.Field,
+			})
+			return true
+		}
+
+		lit, ok := config.(*ast.CompositeLit)
+		if unary, isUnary := config.(*ast.UnaryExpr); isUnary && unary.Op == token.AND {
+			lit, ok = unary.X.(*ast.CompositeLit)
+		}
+		if ok {
+			if pkg, name, isTarget := file.SelectorPackage(lit.Type); isTarget && pkg == r.target.Package && name == r.target.Type {
+				if !hasKey(lit, structured.Field) {
+					value, err := replacementValue(file, &configField{field: structured.Field, expr: structured.Expr, imports: structured.Imports})
+					if err != nil {
+						applyErr = err
+						return false
+					}
+					lit.Elts = append(lit.Elts, &ast.KeyValueExpr{Key: ast.NewIdent(structured.Field), Value: value})
+					expandConfig(file, lit)
+					file.Report(lit, &models.Change{
+						Type:        "config",
+						Description: fmt.Sprintf("Enabled %s for structured output", structured.Title),
+						NewValue:    structured.Field,
+					})
+				}
+				return true
+			}
+		}
+
+		file.Report(config, &models.Change{
+			Type: "config",
+			Description: fmt.Sprintf("Set %s: %s on the config of this structured-output generation to enable %s",
+				structured.Field, structured.Expr, structured.Title),
+			ManualReview: true,
+		})
+		return true
+	})
+
+	return applyErr
+}
+
+// structured reports whether a call generates structured output, either
+// through genkit.GenerateData or with an ai.WithOutputType option.
+func (r *structuredOutputRule) structured(file *rewrite.File, call *ast.CallExpr) bool {
+	if index, ok := call.Fun.(*ast.IndexExpr); ok {
+		if pkg, name, ok := file.SelectorPackage(index.X); ok && pkg == genkitPackage && name == "GenerateData" {
+			return true
+		}
+	}
+	for _, arg := range call.Args {
+		option, ok := arg.(*ast.CallExpr)
+		if !ok {
+			continue
+		}
+		if pkg, name, ok := file.SelectorPackage(option.Fun); ok && pkg == genkitAIPackage && name == "WithOutputType" {
+			return true
+		}
+	}
+	return false
+}
+
+func hasKey(lit *ast.CompositeLit, field string) bool {
+	for _, elt := range lit.Elts {
+		if kv, ok := elt.(*ast.KeyValueExpr); ok {
+			if key, ok := kv.Key.(*ast.Ident); ok && key.Name == field {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// transformStructuredOutputs checks that the models of structured-output
+// generations support it on the target, and generates a test per flow that
+// checks recorded output still unmarshals into the flow's output type.
+func (t *Transformer) transformStructuredOutputs(migration *models.Migration) error {
+	project := migration.Project
+	if t.target == nil || len(project.StructuredOutputs) == 0 {
+		return nil
+	}
+
+	if generation := t.target.Rewrite(t.context(project, migration)).Generation; generation != nil && generation.StructuredOutput != nil {
+		migration.Changes = append(migration.Changes, t.checkStructuredOutputModels(project, generation.StructuredOutput)...)
+	}
+
+	return t.generateOutputTests(migration)
+}
+
+func (t *Transformer) checkStructuredOutputModels(project *models.Project, structured *provider.StructuredOutput) []*models.Change {
+	changes := make([]*models.Change, 0)
+	catalog := t.catalog(project)
+
+	for _, output := range project.StructuredOutputs {
+		if output.Model == "" {
+			continue
+		}
+
+		subject := "Structured output"
+		if output.Flow != "" {
+			subject += " of flow " + output.Flow
+		}
+		change := &models.Change{
+			Type:     "model",
+			File:     output.Position.Filename,
+			Line:     output.Position.Line,
+			OldValue: output.Model,
+		}
+
+		target, mapped := catalog.Models[output.Model]
+		if !mapped {
+			target = strings.TrimPrefix(output.Model, catalog.Prefix)
+		}
+		switch {
+		case structured.Supports(target):
+			continue
+		case !mapped:
+			change.Description = fmt.Sprintf("%s uses %s, which has no %s mapping; use a model that supports %s",
+				subject, output.Model, t.target.Title(), structured.Title)
+			change.ManualReview = true
+		default:
+			change.Description = fmt.Sprintf("%s uses %s, mapped to %s, which does not support %s; map it to a model that does",
+				subject, output.Model, target, structured.Title)
+			change.NewValue = target
+			change.ManualReview = true
+			change.Blocking = true
+		}
+		changes = append(changes, change)
+	}
+
+	return changes
+}
+
+const outputTestTemplate = `package {{ .Package }}
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// These flows asked the source model for schema-constrained output, which
+// the migrated model returns as the input of a tool whose schema is derived
+// from the flow's output type. Check that the type is a JSON object and that
+// a value with every field set survives the trip through JSON.
+{{ range .Flows }}
+func Test{{ .Test }}Output(t *testing.T) {
+	checkStructuredOutput(t, reflect.TypeFor[{{ .Type }}]())
+}
+{{ end }}
+func checkStructuredOutput(t *testing.T, typ reflect.Type) {
+	t.Helper()
+
+	sent := reflect.New(typ)
+	fillStructuredOutput(sent.Elem(), 0)
+	data, err := json.Marshal(sent.Interface())
+	if err != nil {
+		t.Fatalf("%s does not marshal to JSON: %v", typ, err)
+	}
+	if !bytes.HasPrefix(data, []byte("{")) {
+		t.Fatalf("%s marshals to %s, but tool input is a JSON object", typ, data)
+	}
+
+	received := reflect.New(typ)
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(received.Interface()); err != nil {
+		t.Fatalf("%s does not unmarshal from its own JSON %s: %v", typ, data, err)
+	}
+	if !reflect.DeepEqual(sent.Interface(), received.Interface()) {
+		t.Errorf("%s loses fields through JSON %s", typ, data)
+	}
+}
+
+// fillStructuredOutput sets the exported fields reachable from v, stopping
+// at recursive types.
+func fillStructuredOutput(v reflect.Value, depth int) {
+	if depth > 4 {
+		return
+	}
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString("x")
+	case reflect.Bool:
+		v.SetBool(true)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		v.SetInt(1)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		v.SetUint(1)
+	case reflect.Float32, reflect.Float64:
+		v.SetFloat(1.5)
+	case reflect.Pointer:
+		v.Set(reflect.New(v.Type().Elem()))
+		fillStructuredOutput(v.Elem(), depth+1)
+	case reflect.Slice:
+		v.Set(reflect.MakeSlice(v.Type(), 1, 1))
+		fillStructuredOutput(v.Index(0), depth+1)
+	case reflect.Array:
+		for i := 0; i < v.Len(); i++ {
+			fillStructuredOutput(v.Index(i), depth+1)
+		}
+	case reflect.Map:
+		if v.Type().Key().Kind() != reflect.String {
+			return
+		}
+		key := reflect.New(v.Type().Key()).Elem()
+		key.SetString("x")
+		value := reflect.New(v.Type().Elem()).Elem()
+		fillStructuredOutput(value, depth+1)
+		v.Set(reflect.MakeMap(v.Type()))
+		v.SetMapIndex(key, value)
+	case reflect.Struct:
+		for i := 0; i < v.NumField(); i++ {
+			field := v.Type().Field(i)
+			name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+			if field.IsExported() && name != "-" {
+				fillStructuredOutput(v.Field(i), depth+1)
+			}
+		}
+	}
+}
+`
+
+type outputTest struct {
+	Name string
+	Test string
+	Type string
+}
+
+func (t *Transformer) generateOutputTests(migration *models.Migration) error {
+	tmpl, err := template.New("structured_output_test.go").Parse(outputTestTemplate)
+	if err != nil {
+		return err
+	}
+
+	paths := make([]string, 0, len(migration.Project.Files))
+	for path := range migration.Project.Files {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+
+	packages := make(map[string]string)
+	tests := make(map[string][]outputTest)
+	for _, path := range paths {
+		sourceFile := migration.Project.Files[path]
+		for _, flow := range sourceFile.Flows {
+			if !flow.StructuredOutput {
+				continue
+			}
+
+			// The test declares a value of the output type, so it must be a
+			// named type of the flow's own package.
+			outputType := strings.TrimPrefix(flow.OutputType, "*")
+			if !token.IsIdentifier(outputType) || types.Universe.Lookup(outputType) != nil {
+				migration.Changes = append(migration.Changes, &models.Change{
+					Type:         "test",
+					Description:  fmt.Sprintf("Flow %s returns %s; check its structured output by hand", flow.Name, flow.OutputType),
+					File:         path,
+					Line:         flow.Position.Line,
+					ManualReview: true,
+				})
+				continue
+			}
+
+			dir := filepath.Dir(path)
+			packages[dir] = sourceFile.PackageName
+			tests[dir] = append(tests[dir], outputTest{Name: flow.Name, Test: exportedName(flow.Name), Type: outputType})
+		}
+	}
+
+	for dir, flows := range tests {
+		var content strings.Builder
+		err := tmpl.Execute(&content, map[string]interface{}{
+			"Package": packages[dir],
+			"Flows":   flows,
+		})
+		if err != nil {
+			return err
+		}
+
+		names := make([]string, len(flows))
+		for i, flow := range flows {
+			names[i] = flow.Name
+		}
+
+		testPath := filepath.Join(dir, "structured_output_test.go")
+		migration.NewFiles[testPath] = content.String()
+		migration.Changes = append(migration.Changes, &models.Change{
+			Type: "test",
+			Description: fmt.Sprintf("Generated structured output tests for %s, checking their output types round-trip through JSON objects",
+				strings.Join(names, ", ")),
+			File: testPath,
+		})
+	}
+
+	return nil
+}
+
+// exportedName turns a flow name such as "recipe-flow" into RecipeFlow.
+func exportedName(name string) string {
+	var b strings.Builder
+	upper := true
+	for _, r := range name {
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) {
+			upper = true
+			continue
+		}
+		if upper {
+			r = unicode.ToUpper(r)
+			upper = false
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
@@ -2,15 +2,47 @@ package transformer
 
 import (
 	"context"
+	"fmt"
+	"go/ast"
+	"go/importer"
+	"go/parser"
+	"go/types"
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 
 	"github.com/genkit-migrate/genkit-migrate/pkg/models"
+	_ "github.com/genkit-migrate/genkit-migrate/pkg/provider/all"
+	"github.com/genkit-migrate/genkit-migrate/pkg/provider/aws"
+	"github.com/genkit-migrate/genkit-migrate/pkg/rewrite"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	"go/token"
+	"gopkg.in/yaml.v3"
 )
 
 func TestTransformProject(t *testing.T) {
+	sourceDir := t.TempDir()
+
+	mainContent := `package main
+
+import (
+	"context"
+
+	"github.com/firebase/genkit/go/genkit"
+	"github.com/firebase/genkit/go/plugins/googleai"
+)
+
+func main() {
+	ctx := context.Background()
+	g, _ := genkit.Init(ctx, genkit.WithPlugins(&googleai.GoogleAI{}))
+	_ = googleai.Model(g, "gemini-1.5-pro")
+}
+`
+	err := os.WriteFile(filepath.Join(sourceDir, "main.go"), []byte(mainContent), 0644)
+	require.NoError(t, err)
+
 	transformer := New(&Config{
 		SourceProvider: "gcp",
 		TargetProvider: "aws",
@@ -19,12 +51,12 @@ func TestTransformProject(t *testing.T) {
 	})
 
 	project := &models.Project{
-		Path:           "/tmp/test-source",
+		Path:           sourceDir,
 		SourceProvider: "gcp",
 		TargetProvider: "aws",
 		Files: map[string]*models.SourceFile{
 			"main.go": {
-				Path:        "/tmp/test-source/main.go",
+				Path:        filepath.Join(sourceDir, "main.go"),
 				PackageName: "main",
 				HasGenKit:   true,
 				Flows: []*models.Flow{
@@ -34,11 +66,18 @@ func TestTransformProject(t *testing.T) {
 					{Name: "googleai/gemini-1.5-pro", Provider: "gcp", Position: token.Position{}},
 				},
 			},
+			"store.go": {
+				Path:        filepath.Join(sourceDir, "store.go"),
+				PackageName: "main",
+				Imports:     []string{"cloud.google.com/go/firestore"},
+			},
 		},
 		Dependencies: map[string]string{
 			"github.com/firebase/genkit/go":                  "v0.5.8",
 			"github.com/firebase/genkit/go/plugins/googleai": "v0.5.8",
 			"github.com/spf13/cobra":                         "v1.8.1",
+			"cloud.google.com/go/firestore":                  "v1.15.0",
+			"cloud.google.com/go/storage":                    "v1.40.0",
 		},
 		Flows: []*models.Flow{
 			{Name: "summarize", Position: token.Position{}},
@@ -53,15 +92,403 @@ func TestTransformProject(t *testing.T) {
 	require.NoError(t, err)
 
 	assert.NotNil(t, migration)
+	assert.Contains(t, migration.NewFiles["main.go"], `bedrock.Model(g, "anthropic.claude-3-sonnet-20240229-v1:0")`)
+	assert.Contains(t, migration.NewFiles["main.go"], "genkitaws.New(")
+	assert.NotContains(t, migration.NewFiles["main.go"], "plugins/googleai")
 	assert.Equal(t, project, migration.Project)
 	assert.Greater(t, len(migration.Changes), 0)
 	assert.Greater(t, len(migration.NewFiles), 0)
 
 	assert.Contains(t, migration.NewFiles, "go.mod")
+	goMod := migration.NewFiles["go.mod"]
+	assert.Contains(t, goMod, "cloud.google.com/go/firestore v1.15.0", "store.go still imports Firestore")
+	assert.NotContains(t, goMod, "cloud.google.com/go/storage")
+	assert.NotContains(t, goMod, "plugins/googleai")
+	assert.Equal(t, 1, strings.Count(goMod, "github.com/firebase/genkit/go "))
 	assert.Contains(t, migration.NewFiles, "main.go")
 	assert.Contains(t, migration.NewFiles, "config.yaml")
 	assert.Contains(t, migration.NewFiles, "terraform/main.tf")
+	// Lambda runs the zipped binary, not a container image.
+	assert.NotContains(t, migration.NewFiles, "Dockerfile")
+}
+
+func TestTransformProjectToGCP(t *testing.T) {
+	sourceDir := t.TempDir()
+
+	mainContent := `package main
+
+import (
+	"context"
+
+	"github.com/firebase/genkit/go/ai"
+	"github.com/firebase/genkit/go/genkit"
+	"github.com/scttfrdmn/genkit-aws/pkg/bedrock"
+	genkitaws "github.com/scttfrdmn/genkit-aws/pkg/genkit-aws"
+)
+
+func main() {
+	ctx := context.Background()
+	g, _ := genkit.Init(ctx, genkit.WithPlugins(genkitaws.New(&genkitaws.Config{
+		Region:  "eu-west-1",
+		Bedrock: &bedrock.Config{Models: []string{"anthropic.claude-3-haiku-20240307-v1:0"}},
+	})))
+	model := bedrock.Model(g, "anthropic.claude-3-haiku-20240307-v1:0")
+	_ = ai.WithModel(model)
+	_ = ai.WithConfig(&bedrock.GenerationConfig{MaxTokens: 1024})
+	_ = ai.WithModelName("bedrock/amazon.nova-pro-v1:0")
+}
+`
+	err := os.WriteFile(filepath.Join(sourceDir, "main.go"), []byte(mainContent), 0644)
+	require.NoError(t, err)
+
+	transformer := New(&Config{
+		SourceProvider: "aws",
+		TargetProvider: "gcp",
+	})
+
+	project := &models.Project{
+		Path:           sourceDir,
+		SourceProvider: "aws",
+		TargetProvider: "gcp",
+		Files: map[string]*models.SourceFile{
+			"main.go": {
+				Path:        filepath.Join(sourceDir, "main.go"),
+				PackageName: "main",
+				HasGenKit:   true,
+			},
+		},
+		Dependencies: map[string]string{
+			"github.com/scttfrdmn/genkit-aws": "v0.1.0",
+			"github.com/spf13/cobra":          "v1.8.1",
+		},
+		Models: []*models.Model{
+			{Name: "bedrock/anthropic.claude-3-haiku-20240307-v1:0", Provider: "aws"},
+		},
+		ConfigFiles: map[string]*models.ConfigFile{
+			".env": {
+				Path:   filepath.Join(sourceDir, ".env"),
+				Format: "env",
+				Settings: []*models.ConfigSetting{
+					{Key: "AWS_REGION", Value: "eu-west-1"},
+					{Key: "AWS_ACCESS_KEY_ID", Value: "AKIA123", Secret: true},
+				},
+			},
+		},
+		Configuration: make(map[string]interface{}),
+	}
+
+	migration, err := transformer.TransformProject(context.Background(), project)
+	require.NoError(t, err)
+
+	mainGo := migration.NewFiles["main.go"]
+	assert.Contains(t, mainGo, `&vertexai.VertexAI{Location: "europe-west1"}`)
+	assert.Contains(t, mainGo, `vertexai.Model(g, "gemini-1.5-flash")`)
+	assert.Contains(t, mainGo, `&ai.GenerationCommonConfig{MaxOutputTokens: 1024}`)
+	assert.Contains(t, mainGo, `"vertexai/gemini-1.5-pro"`)
+	assert.NotContains(t, mainGo, "genkit-aws")
+
+	assert.NotContains(t, migration.NewFiles["go.mod"], "genkit-aws")
+	assert.Contains(t, migration.NewFiles["go.mod"], "github.com/spf13/cobra")
+	assert.Contains(t, migration.NewFiles[".env"], "GOOGLE_CLOUD_LOCATION=europe-west1")
+	assert.NotContains(t, migration.NewFiles[".env"], "AKIA123")
+	assert.Contains(t, migration.NewFiles["config.yaml"], "- gemini-1.5-flash")
+	assert.Contains(t, migration.NewFiles["terraform/main.tf"], `resource "google_cloud_run_v2_service" "genkit_app"`)
+	assert.Contains(t, migration.NewFiles["terraform/main.tf"], "roles/aiplatform.user")
+	assert.Contains(t, migration.NewFiles["terraform/variables.tf"], `default     = "europe-west1"`)
+	assert.Contains(t, migration.NewFiles["cloudbuild.yaml"], "_REGION: europe-west1")
+	assert.Contains(t, migration.NewFiles, "Dockerfile")
+	assert.NotContains(t, migration.NewFiles, ".github/workflows/deploy.yml")
+}
+
+func TestTransformProjectToAzure(t *testing.T) {
+	sourceDir := t.TempDir()
+
+	mainContent := `package main
+
+import (
+	"context"
+
+	"github.com/firebase/genkit/go/ai"
+	"github.com/firebase/genkit/go/genkit"
+	"github.com/firebase/genkit/go/plugins/googlegenai"
+	"google.golang.org/genai"
+)
+
+func main() {
+	ctx := context.Background()
+	g, _ := genkit.Init(ctx, genkit.WithPlugins(&googlegenai.GoogleAI{}),
+		genkit.WithDefaultModel("googleai/gemini-1.5-flash"))
+	model := googlegenai.GoogleAIModel(g, "gemini-1.5-pro")
+	_ = ai.WithModel(model)
+	_ = ai.WithConfig(&genai.GenerateContentConfig{Temperature: genai.Ptr[float32](0.3), Seed: genai.Ptr[int32](7)})
+}
+`
+	err := os.WriteFile(filepath.Join(sourceDir, "main.go"), []byte(mainContent), 0644)
+	require.NoError(t, err)
+
+	transformer := New(&Config{
+		SourceProvider: "gcp",
+		TargetProvider: "azure",
+		Options:        map[string]string{"azure-subscription-id": "0000-1111"},
+	})
+
+	project := &models.Project{
+		Path:           sourceDir,
+		SourceProvider: "gcp",
+		TargetProvider: "azure",
+		Files: map[string]*models.SourceFile{
+			"main.go": {
+				Path:        filepath.Join(sourceDir, "main.go"),
+				PackageName: "main",
+				HasGenKit:   true,
+			},
+		},
+		Dependencies: map[string]string{
+			"github.com/firebase/genkit/go": "v1.0.2",
+		},
+		Models: []*models.Model{
+			{Name: "googleai/gemini-1.5-pro", Provider: "google"},
+		},
+		ConfigFiles: map[string]*models.ConfigFile{
+			".env": {
+				Path:   filepath.Join(sourceDir, ".env"),
+				Format: "env",
+				Settings: []*models.ConfigSetting{
+					{Key: "GOOGLE_CLOUD_LOCATION", Value: "europe-west1", GCP: true},
+					{Key: "GOOGLE_API_KEY", Value: "AIza123", Secret: true, GCP: true},
+				},
+			},
+		},
+		Configuration: make(map[string]interface{}),
+	}
+
+	migration, err := transformer.TransformProject(context.Background(), project)
+	require.NoError(t, err)
+
+	mainGo := migration.NewFiles["main.go"]
+	assert.Contains(t, mainGo, `&openai.OpenAI{APIKey: os.Getenv("AZURE_OPENAI_API_KEY")`)
+	assert.Contains(t, mainGo, `azure.WithEndpoint(os.Getenv("AZURE_OPENAI_ENDPOINT"), "2024-10-21")`)
+	assert.Contains(t, mainGo, `genkit.LookupModel(g, "openai", "gpt-4o")`)
+	assert.Contains(t, mainGo, `"openai/gpt-4o-mini"`)
+	assert.Contains(t, mainGo, `&ai.GenerationCommonConfig{Temperature: 0.3}`)
+	assert.NotContains(t, mainGo, "googlegenai")
+
+	assert.Contains(t, migration.NewFiles["go.mod"], "github.com/openai/openai-go")
+	assert.Contains(t, migration.NewFiles[".env"], "AZURE_LOCATION=westeurope")
+	assert.NotContains(t, migration.NewFiles[".env"], "AIza123")
+
+	configYAML := migration.NewFiles["config.yaml"]
+	assert.Contains(t, configYAML, `subscription_id: "0000-1111"`)
+	assert.Contains(t, configYAML, "- gpt-4o\n")
+	assert.Contains(t, configYAML, "- gpt-4o-mini\n")
+	assert.NotContains(t, configYAML, "gemini")
+
+	mainTF := migration.NewFiles["terraform/main.tf"]
+	assert.Contains(t, mainTF, `kind                  = "OpenAI"`)
+	assert.Contains(t, mainTF, `resource "azurerm_cognitive_deployment" "gpt_4o_mini"`)
+	assert.Contains(t, mainTF, `resource "azurerm_container_app" "genkit_app"`)
+	assert.Contains(t, migration.NewFiles["terraform/variables.tf"], `default     = "westeurope"`)
 	assert.Contains(t, migration.NewFiles, "Dockerfile")
+	assert.NotContains(t, migration.NewFiles, "cloudbuild.yaml")
+}
+
+func TestTransformProjectToOllama(t *testing.T) {
+	sourceDir := t.TempDir()
+
+	mainContent := `package main
+
+import (
+	"context"
+
+	"github.com/firebase/genkit/go/ai"
+	"github.com/firebase/genkit/go/genkit"
+	"github.com/firebase/genkit/go/plugins/googleai"
+)
+
+func main() {
+	ctx := context.Background()
+	g, _ := genkit.Init(ctx, genkit.WithPlugins(&googleai.GoogleAI{}),
+		genkit.WithDefaultModel("googleai/gemini-1.5-flash"))
+	model := googleai.Model(g, "gemini-1.5-pro")
+	embedder := googleai.Embedder(g, "text-embedding-004")
+	_, _ = ai.WithModel(model), embedder
+}
+`
+	err := os.WriteFile(filepath.Join(sourceDir, "main.go"), []byte(mainContent), 0644)
+	require.NoError(t, err)
+
+	transformer := New(&Config{
+		SourceProvider: "gcp",
+		TargetProvider: "ollama",
+		Options:        map[string]string{"ollama-model": "mistral"},
+	})
+
+	project := &models.Project{
+		Path:           sourceDir,
+		SourceProvider: "gcp",
+		TargetProvider: "ollama",
+		Files: map[string]*models.SourceFile{
+			"main.go": {
+				Path:        filepath.Join(sourceDir, "main.go"),
+				PackageName: "main",
+				HasGenKit:   true,
+			},
+		},
+		Dependencies: make(map[string]string),
+		Models: []*models.Model{
+			{Name: "googleai/gemini-1.5-pro", Provider: "google"},
+		},
+		Embedders: []*models.Embedder{
+			{Name: "googleai/text-embedding-004", Dimensions: 768},
+		},
+		ConfigFiles: map[string]*models.ConfigFile{
+			".env": {
+				Path:   filepath.Join(sourceDir, ".env"),
+				Format: "env",
+				Settings: []*models.ConfigSetting{
+					{Key: "GOOGLE_API_KEY", Value: "AIza123", Secret: true, GCP: true},
+					{Key: "STRIPE_KEY", Value: "sk_live", Secret: true},
+				},
+			},
+		},
+		Configuration: make(map[string]interface{}),
+	}
+
+	migration, err := transformer.TransformProject(context.Background(), project)
+	require.NoError(t, err)
+
+	mainGo := migration.NewFiles["main.go"]
+	assert.Contains(t, mainGo, "genkit.WithPlugins(newOllamaPlugin())")
+	assert.Contains(t, mainGo, `"ollama/mistral"`)
+	assert.Contains(t, mainGo, `genkit.LookupModel(g, "ollama", "mistral")`)
+	assert.Contains(t, mainGo, `genkit.LookupEmbedder(g, "ollama", "nomic-embed-text")`)
+	assert.NotContains(t, mainGo, "googleai")
+
+	plugin := migration.NewFiles["genkit_ollama.go"]
+	assert.Contains(t, plugin, "package main")
+	assert.Contains(t, plugin, `ollama.ModelDefinition{Name: "mistral", Type: "chat"}`)
+	assert.Contains(t, plugin, `p.DefineEmbedder(g, p.ServerAddress, "nomic-embed-text", nil)`)
+
+	compose := migration.NewFiles["docker-compose.yml"]
+	assert.Contains(t, compose, "ollama pull mistral && ollama pull nomic-embed-text")
+	assert.Contains(t, compose, "OLLAMA_HOST: http://ollama:11434")
+	assert.Contains(t, compose, "env_file: .env")
+
+	assert.Contains(t, migration.NewFiles[".env"], "# GOOGLE_API_KEY removed")
+	assert.NotContains(t, migration.NewFiles[".env"], "AIza123")
+	assert.Contains(t, migration.NewFiles[".env"], "STRIPE_KEY=\n")
+	assert.Contains(t, migration.NewFiles["config.yaml"], "- nomic-embed-text")
+	assert.NotContains(t, migration.NewFiles, "terraform/main.tf")
+
+	for _, change := range migration.Changes {
+		assert.False(t, change.Blocking, "text-embedding-004 and nomic-embed-text both produce 768 dimensions")
+	}
+}
+
+func TestTransformProjectToModelAPI(t *testing.T) {
+	sourceDir := t.TempDir()
+
+	mainContent := `package main
+
+import (
+	"context"
+
+	"github.com/firebase/genkit/go/genkit"
+	"github.com/firebase/genkit/go/plugins/googleai"
+)
+
+func main() {
+	ctx := context.Background()
+	g, _ := genkit.Init(ctx, genkit.WithPlugins(&googleai.GoogleAI{}))
+	_ = googleai.Model(g, "gemini-1.5-pro")
+}
+`
+	err := os.WriteFile(filepath.Join(sourceDir, "main.go"), []byte(mainContent), 0644)
+	require.NoError(t, err)
+
+	tests := []struct {
+		target string
+		plugin string
+		lookup string
+		keyEnv string
+	}{
+		{
+			target: "openai",
+			plugin: `&openai.OpenAI{APIKey: os.Getenv("OPENAI_API_KEY")}`,
+			lookup: `genkit.LookupModel(g, "openai", "gpt-4o")`,
+			keyEnv: "OPENAI_API_KEY",
+		},
+		{
+			target: "anthropic",
+			plugin: `&anthropic.Anthropic{Opts: []option.RequestOption{option.WithAPIKey(os.Getenv("ANTHROPIC_API_KEY"))}}`,
+			lookup: `genkit.LookupModel(g, "anthropic", "claude-3-5-sonnet-20241022")`,
+			keyEnv: "ANTHROPIC_API_KEY",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.target, func(t *testing.T) {
+			transformer := New(&Config{
+				SourceProvider: "gcp",
+				TargetProvider: tt.target,
+			})
+
+			project := &models.Project{
+				Path:           sourceDir,
+				SourceProvider: "gcp",
+				TargetProvider: tt.target,
+				Files: map[string]*models.SourceFile{
+					"main.go": {
+						Path:        filepath.Join(sourceDir, "main.go"),
+						PackageName: "main",
+						HasGenKit:   true,
+					},
+				},
+				Dependencies: make(map[string]string),
+				Models: []*models.Model{
+					{Name: "googleai/gemini-1.5-pro", Provider: "google"},
+				},
+				ConfigFiles: map[string]*models.ConfigFile{
+					".env": {
+						Path:   filepath.Join(sourceDir, ".env"),
+						Format: "env",
+						Settings: []*models.ConfigSetting{
+							{Key: "GOOGLE_API_KEY", Value: "AIza123", Secret: true, GCP: true},
+							{Key: "GOOGLE_CLOUD_PROJECT", Value: "my-project", GCP: true},
+						},
+					},
+				},
+				Configuration: make(map[string]interface{}),
+			}
+
+			migration, err := transformer.TransformProject(context.Background(), project)
+			require.NoError(t, err)
+
+			mainGo := migration.NewFiles["main.go"]
+			assert.Contains(t, mainGo, tt.plugin)
+			assert.Contains(t, mainGo, tt.lookup)
+			assert.NotContains(t, mainGo, "googleai")
+
+			env := migration.NewFiles[".env"]
+			assert.Contains(t, env, tt.keyEnv+"=\n")
+			assert.Contains(t, env, "# GOOGLE_CLOUD_PROJECT removed")
+			assert.NotContains(t, env, "AIza123")
+
+			var keyChange *models.Change
+			for _, change := range migration.Changes {
+				if change.Type == "config" && change.NewValue == "" && strings.Contains(change.Description, tt.keyEnv) {
+					keyChange = change
+				}
+			}
+			require.NotNil(t, keyChange)
+			assert.True(t, keyChange.ManualReview)
+
+			assert.Contains(t, migration.NewFiles["config.yaml"], tt.keyEnv)
+			assert.Contains(t, migration.NewFiles, "Dockerfile")
+			assert.Contains(t, migration.NewFiles, ".github/workflows/ci.yml")
+			assert.NotContains(t, migration.NewFiles, "terraform/main.tf")
+		})
+	}
 }
 
 func TestGetModelMappings(t *testing.T) {
@@ -70,17 +497,33 @@ func TestGetModelMappings(t *testing.T) {
 		TargetProvider: "aws",
 	})
 
-	mappings := transformer.getModelMappings()
+	mappings := transformer.getModelMappings(nil)
 
 	assert.Contains(t, mappings, "googleai/gemini-1.5-flash")
 	assert.Equal(t, "anthropic.claude-3-haiku-20240307-v1:0", mappings["googleai/gemini-1.5-flash"])
 
 	assert.Contains(t, mappings, "googleai/gemini-1.5-pro")
 	assert.Equal(t, "anthropic.claude-3-sonnet-20240229-v1:0", mappings["googleai/gemini-1.5-pro"])
+
+	// Claude 3.5 Sonnet v2 is served on demand in us-west-2 only.
+	assert.Equal(t, "us.anthropic.claude-3-5-sonnet-20241022-v2:0", mappings["googleai/gemini-2.0-flash"])
+
+	project := &models.Project{
+		ConfigFiles: map[string]*models.ConfigFile{
+			".env": {
+				Path: "/test/project/.env",
+				Settings: []*models.ConfigSetting{
+					{Key: "GOOGLE_CLOUD_LOCATION", Value: "us-west1", GCP: true},
+				},
+			},
+		},
+	}
+	mappings = transformer.getModelMappings(project)
+	assert.Equal(t, "anthropic.claude-3-5-sonnet-20241022-v2:0", mappings["googleai/gemini-2.0-flash"])
 }
 
 func TestFilterDependencies(t *testing.T) {
-	transformer := New(&Config{})
+	transformer := New(&Config{SourceProvider: "gcp", TargetProvider: "aws"})
 
 	deps := map[string]string{
 		"github.com/firebase/genkit/go":                  "v0.5.8",
@@ -88,20 +531,1554 @@ func TestFilterDependencies(t *testing.T) {
 		"github.com/spf13/cobra":                         "v1.8.1",
 		"github.com/google/uuid":                         "v1.3.0",
 		"gopkg.in/yaml.v3":                               "v3.0.1",
+		"cloud.google.com/go/firestore":                  "v1.15.0",
+		"cloud.google.com/go/storage":                    "v1.40.0",
+		"google.golang.org/genai":                        "v1.0.0",
 	}
 
-	filtered := transformer.filterDependencies(deps)
+	// Firestore and genai calls are left for manual migration.
+	imports := map[string]bool{
+		"cloud.google.com/go/firestore": true,
+		"google.golang.org/genai":       true,
+	}
+	filtered := transformer.filterDependencies(deps, imports)
 
-	var foundCobra, foundYaml bool
+	names := make([]string, 0, len(filtered))
 	for _, dep := range filtered {
-		if dep["Name"] == "github.com/spf13/cobra" {
-			foundCobra = true
+		names = append(names, dep["Name"])
+	}
+
+	assert.Contains(t, names, "github.com/spf13/cobra", "Should include non-Google/Firebase dependencies")
+	assert.Contains(t, names, "gopkg.in/yaml.v3", "Should include non-Google/Firebase dependencies")
+	assert.Contains(t, names, "cloud.google.com/go/firestore", "Should keep SDKs the code still imports")
+	assert.Contains(t, names, "google.golang.org/genai", "Should keep SDKs the code still imports")
+	assert.NotContains(t, names, "cloud.google.com/go/storage")
+}
+
+func TestTransformConfiguration(t *testing.T) {
+	sourceDir := t.TempDir()
+
+	configContent := `app_name: summarizer
+gcp:
+  project: my-project
+  location: us-central1
+openai_api_key: sk-live-secret
+region: us-central1
+webhook_tokens:
+  - tok-live-secret
+`
+	err := os.WriteFile(filepath.Join(sourceDir, "config.yaml"), []byte(configContent), 0644)
+	require.NoError(t, err)
+
+	transformer := New(&Config{
+		SourceProvider: "gcp",
+		TargetProvider: "aws",
+	})
+
+	migration := &models.Migration{
+		Project: &models.Project{
+			Path: sourceDir,
+			ConfigFiles: map[string]*models.ConfigFile{
+				".env": {
+					Path:   filepath.Join(sourceDir, ".env"),
+					Format: "env",
+					Settings: []*models.ConfigSetting{
+						{Key: "GOOGLE_API_KEY", Secret: true, GCP: true},
+						{Key: "GCLOUD_LOCATION", Value: "europe-west1", GCP: true},
+						{Key: "GOOGLE_APPLICATION_CREDENTIALS", Secret: true, GCP: true},
+						{Key: "LOG_LEVEL", Value: "debug"},
+						{Key: "DB_PASSWORD", Secret: true},
+					},
+				},
+				"config.yaml": {
+					Path:   filepath.Join(sourceDir, "config.yaml"),
+					Format: "yaml",
+					Settings: []*models.ConfigSetting{
+						{Key: "app_name", Value: "summarizer"},
+						{Key: "gcp.project", Value: "my-project", GCP: true},
+						{Key: "gcp.location", Value: "us-central1", GCP: true},
+						{Key: "openai_api_key", Secret: true},
+						{Key: "region", Value: "us-central1"},
+						{Key: "webhook_tokens.0", Secret: true},
+					},
+				},
+			},
+			Models: []*models.Model{
+				{Name: "googleai/gemini-1.5-flash", Provider: "googleai"},
+			},
+		},
+		Changes:  make([]*models.Change, 0),
+		NewFiles: make(map[string]string),
+	}
+
+	err = transformer.transformConfiguration(migration)
+	require.NoError(t, err)
+
+	env := migration.NewFiles[".env"]
+	assert.Contains(t, env, "# GOOGLE_API_KEY removed: Bedrock authenticates with IAM credentials")
+	assert.Contains(t, env, "AWS_REGION=eu-west-1")
+	assert.Contains(t, env, "AWS_PROFILE=default")
+	assert.Contains(t, env, "LOG_LEVEL=debug")
+	assert.Contains(t, env, "DB_PASSWORD=\n")
+
+	config := migration.NewFiles["config.yaml"]
+	assert.Contains(t, config, "region: eu-west-1")
+	assert.Contains(t, config, "app_name: summarizer")
+	// The .env location is the region of the whole app.
+	assert.Contains(t, config, "aws:\n  project_name: my-project\n  aws_region: eu-west-1")
+	assert.NotContains(t, config, "us-east-1")
+	assert.Contains(t, config, `openai_api_key: ""`)
+	assert.NotContains(t, config, "sk-live-secret")
+	assert.Contains(t, config, `webhook_tokens:
+  - ""`)
+	assert.NotContains(t, config, "resolve:secretsmanager")
+	assert.NotContains(t, config, "tok-live-secret")
+	assert.NotContains(t, config, "us-central1")
+	assert.Contains(t, config, "bedrock:\n  models:\n    - anthropic.claude-3-haiku-20240307-v1:0\n\n")
+	assert.NotContains(t, config, "amazon.nova-pro-v1:0")
+
+	assert.Contains(t, migration.Commands,
+		`aws secretsmanager create-secret --name genkit-app/DB_PASSWORD --secret-string "<value of DB_PASSWORD>"`)
+	secrets := 0
+	for _, change := range migration.Changes {
+		if strings.HasPrefix(change.Description, "Cleared secret") {
+			secrets++
+			assert.True(t, change.ManualReview, change.Description)
+			assert.Contains(t, change.Description, "AWS Secrets Manager secret genkit-app/")
+		}
+	}
+	assert.Equal(t, 3, secrets, "DB_PASSWORD, openai_api_key and webhook_tokens.0")
+}
+
+func TestTransformGenerationConfig(t *testing.T) {
+	sourceDir := t.TempDir()
+
+	mainContent := `package main
+
+import (
+	"google.golang.org/genai"
+)
+
+var config = &genai.GenerateContentConfig{
+	Temperature:     genai.Ptr[float32](0.4),
+	MaxOutputTokens: 8192,
+	CandidateCount:  2,
+}
+`
+	sourcePath := filepath.Join(sourceDir, "main.go")
+	err := os.WriteFile(sourcePath, []byte(mainContent), 0644)
+	require.NoError(t, err)
+
+	transformer := New(&Config{
+		SourceProvider: "gcp",
+		TargetProvider: "aws",
+	})
+
+	content, changes, err := transformer.transformGoFile(&models.Project{Path: sourceDir}, &models.SourceFile{Path: sourcePath})
+	require.NoError(t, err)
+
+	assert.Contains(t, content, `"github.com/scttfrdmn/genkit-aws/pkg/bedrock"`)
+	assert.NotContains(t, content, "google.golang.org/genai")
+	assert.Contains(t, content, "&bedrock.GenerationConfig{")
+	assert.Contains(t, content, "Temperature: 0.4,")
+	assert.Contains(t, content, "MaxTokens:   4096,")
+	assert.NotContains(t, content, "CandidateCount")
+
+	var clamped, dropped *models.Change
+	for _, change := range changes {
+		switch change.OldValue {
+		case "8192":
+			clamped = change
+		case "CandidateCount":
+			dropped = change
+		}
+	}
+	require.NotNil(t, clamped)
+	assert.Equal(t, "4096", clamped.NewValue)
+	assert.Equal(t, 9, clamped.Line)
+	require.NotNil(t, dropped)
+	assert.True(t, dropped.ManualReview)
+}
+
+func TestTransformSafetySettings(t *testing.T) {
+	sourceDir := t.TempDir()
+
+	mainContent := `package main
+
+import (
+	"google.golang.org/genai"
+)
+
+var config = &genai.GenerateContentConfig{
+	Temperature: genai.Ptr[float32](0.4),
+	SafetySettings: []*genai.SafetySetting{
+		{Category: genai.HarmCategoryDangerousContent, Threshold: genai.HarmBlockThresholdBlockLowAndAbove},
+		{Category: genai.HarmCategoryCivicIntegrity, Threshold: genai.HarmBlockThresholdBlockOnlyHigh},
+	},
+}
+`
+	sourcePath := filepath.Join(sourceDir, "main.go")
+	err := os.WriteFile(sourcePath, []byte(mainContent), 0644)
+	require.NoError(t, err)
+
+	transformer := New(&Config{
+		SourceProvider: "gcp",
+		TargetProvider: "aws",
+	})
+
+	project := &models.Project{
+		Path: sourceDir,
+		SafetySettings: []*models.SafetySetting{
+			{Category: "HARM_CATEGORY_DANGEROUS_CONTENT", Threshold: "BLOCK_LOW_AND_ABOVE", Position: token.Position{Filename: sourcePath, Line: 11}},
+			{Category: "HARM_CATEGORY_CIVIC_INTEGRITY", Threshold: "BLOCK_ONLY_HIGH", Position: token.Position{Filename: sourcePath, Line: 12}},
+			{Category: "HARM_CATEGORY_HARASSMENT", Threshold: "BLOCK_ONLY_HIGH", Position: token.Position{Filename: "hello.prompt", Line: 5}},
+			{Category: "HARM_CATEGORY_DANGEROUS_CONTENT", Threshold: "BLOCK_ONLY_HIGH", Position: token.Position{Filename: "chat.prompt", Line: 5}},
+		},
+	}
+
+	content, changes, err := transformer.transformGoFile(project, &models.SourceFile{Path: sourcePath})
+	require.NoError(t, err)
+
+	assert.Contains(t, content, `Guardrail:   &bedrock.GuardrailConfig{Identifier: os.Getenv("BEDROCK_GUARDRAIL_ID"), Version: os.Getenv("BEDROCK_GUARDRAIL_VERSION")},`)
+	assert.Contains(t, content, `"os"`)
+	assert.NotContains(t, content, "SafetySettings")
+	assert.NotContains(t, content, "google.golang.org/genai")
+
+	var replaced *models.Change
+	for _, change := range changes {
+		if change.OldValue == "SafetySettings" {
+			replaced = change
 		}
-		if dep["Name"] == "gopkg.in/yaml.v3" {
-			foundYaml = true
+	}
+	require.NotNil(t, replaced)
+	assert.False(t, replaced.ManualReview)
+	assert.Equal(t, "Guardrail", replaced.NewValue)
+
+	migration := &models.Migration{
+		Project:  project,
+		Changes:  make([]*models.Change, 0),
+		NewFiles: make(map[string]string),
+	}
+
+	err = transformer.generateDeploymentFiles(migration)
+	require.NoError(t, err)
+
+	guardrail := migration.NewFiles["terraform/guardrail.tf"]
+	assert.Contains(t, guardrail, `resource "aws_bedrock_guardrail" "genkit"`)
+	assert.Contains(t, guardrail, "type            = \"INSULTS\"\n      input_strength  = \"LOW\"")
+	// The strongest of the two dangerous content thresholds wins.
+	assert.Contains(t, guardrail, "type            = \"VIOLENCE\"\n      input_strength  = \"HIGH\"")
+	assert.Contains(t, guardrail, "type            = \"MISCONDUCT\"\n      input_strength  = \"HIGH\"")
+	assert.Equal(t, 3, strings.Count(guardrail, "filters_config"))
+
+	assert.Contains(t, migration.NewFiles["terraform/main.tf"], "BEDROCK_GUARDRAIL_ID = aws_bedrock_guardrail.genkit.guardrail_id")
+	assert.Contains(t, migration.NewFiles["terraform/main.tf"], `"bedrock:ApplyGuardrail"`)
+
+	var civic *models.Change
+	for _, change := range migration.Changes {
+		if change.Line == 12 {
+			civic = change
 		}
 	}
+	require.NotNil(t, civic)
+	assert.True(t, civic.Blocking)
+	assert.Contains(t, civic.Description, "denied topic")
+}
+
+func TestTransformSafetyWithStructuredOutput(t *testing.T) {
+	sourceDir := t.TempDir()
+
+	mainContent := `package main
+
+import (
+	"context"
+
+	"github.com/firebase/genkit/go/ai"
+	"github.com/firebase/genkit/go/genkit"
+	"google.golang.org/genai"
+)
 
-	assert.True(t, foundCobra, "Should include non-Google/Firebase dependencies")
-	assert.True(t, foundYaml, "Should include non-Google/Firebase dependencies")
+type Recipe struct {
+	Title string
+}
+
+func recipe(ctx context.Context, g *genkit.Genkit) (*ai.ModelResponse, error) {
+	return genkit.Generate(ctx, g,
+		ai.WithModelName("googleai/gemini-2.5-pro"),
+		ai.WithOutputType(Recipe{}),
+		ai.WithConfig(&genai.GenerateContentConfig{SafetySettings: []*genai.SafetySetting{{Category: genai.HarmCategoryHarassment, Threshold: genai.HarmBlockThresholdBlockOnlyHigh}}}),
+	)
+}
+`
+	sourcePath := filepath.Join(sourceDir, "main.go")
+	require.NoError(t, os.WriteFile(sourcePath, []byte(mainContent), 0644))
+
+	transformer := New(&Config{
+		SourceProvider: "gcp",
+		TargetProvider: "aws",
+	})
+
+	sourceFile := &models.SourceFile{Path: sourcePath, PackageName: "main", HasGenKit: true}
+	project := &models.Project{
+		Path:  sourceDir,
+		Files: map[string]*models.SourceFile{"main.go": sourceFile},
+		SafetySettings: []*models.SafetySetting{
+			{Category: "HARM_CATEGORY_HARASSMENT", Threshold: "BLOCK_ONLY_HIGH", Position: token.Position{Filename: sourcePath, Line: 19}},
+		},
+		StructuredOutputs: []*models.StructuredOutput{
+			{Method: "WithOutputType", Type: "Recipe", Model: "googleai/gemini-2.5-pro", Position: token.Position{Filename: sourcePath, Line: 16}},
+		},
+	}
+
+	content, changes, err := transformer.transformGoFile(project, sourceFile)
+	require.NoError(t, err)
+
+	assert.Contains(t, content, "ai.WithConfig(&bedrock.GenerationConfig{\n\t\t\tGuardrail:  &bedrock.GuardrailConfig{")
+	assert.Contains(t, content, "\n\t\t\tOutputMode: bedrock.OutputModeToolUse,\n\t\t}),")
+
+	// The plugin serving the model is gone, so the literal needs a Bedrock
+	// model chosen by hand.
+	assert.Contains(t, content, `ai.WithModelName("googleai/gemini-2.5-pro")`)
+	var unmapped *models.Change
+	for _, change := range changes {
+		if change.OldValue == "googleai/gemini-2.5-pro" {
+			unmapped = change
+		}
+	}
+	require.NotNil(t, unmapped)
+	assert.True(t, unmapped.ManualReview)
+	assert.Equal(t, 17, unmapped.Line)
+	assert.Contains(t, unmapped.Description, "No mapping for model googleai/gemini-2.5-pro")
+}
+
+func TestTransformStructuredOutputs(t *testing.T) {
+	sourceDir := t.TempDir()
+
+	mainContent := `package main
+
+import (
+	"context"
+
+	"github.com/firebase/genkit/go/ai"
+	"github.com/firebase/genkit/go/genkit"
+	"google.golang.org/genai"
+)
+
+type Recipe struct {
+	Title string
+}
+
+func flows(g *genkit.Genkit) {
+	genkit.DefineFlow(g, "recipe-flow", func(ctx context.Context, dish string) (*Recipe, error) {
+		recipe, _, err := genkit.GenerateData[Recipe](ctx, g,
+			ai.WithModelName("googleai/gemini-2.0-flash"),
+			ai.WithPrompt("Recipe for %s", dish),
+		)
+		return recipe, err
+	})
+
+	genkit.DefineFlow(g, "titleFlow", func(ctx context.Context, dish string) (string, error) {
+		resp, err := genkit.Generate(ctx, g,
+			ai.WithModelName("googleai/gemini-1.0-ultra"),
+			ai.WithOutputType(Recipe{}),
+			ai.WithConfig(&genai.GenerateContentConfig{Temperature: genai.Ptr[float32](0.2)}),
+		)
+		if err != nil {
+			return "", err
+		}
+		return resp.Text(), nil
+	})
+}
+
+var config = &genai.GenerateContentConfig{
+	ResponseSchema: &genai.Schema{Type: genai.TypeObject},
+}
+`
+	sourcePath := filepath.Join(sourceDir, "main.go")
+	err := os.WriteFile(sourcePath, []byte(mainContent), 0644)
+	require.NoError(t, err)
+
+	transformer := New(&Config{
+		SourceProvider: "gcp",
+		TargetProvider: "aws",
+	})
+
+	sourceFile := &models.SourceFile{
+		Path:        sourcePath,
+		PackageName: "main",
+		HasGenKit:   true,
+		Flows: []*models.Flow{
+			{Name: "recipe-flow", OutputType: "*Recipe", StructuredOutput: true, Position: token.Position{Filename: sourcePath, Line: 16}},
+			{Name: "titleFlow", OutputType: "string", StructuredOutput: true, Position: token.Position{Filename: sourcePath, Line: 24}},
+		},
+	}
+	project := &models.Project{
+		Path:  sourceDir,
+		Files: map[string]*models.SourceFile{"main.go": sourceFile},
+		StructuredOutputs: []*models.StructuredOutput{
+			{Flow: "recipe-flow", Method: "GenerateData", Type: "Recipe", Model: "googleai/gemini-2.0-flash", Position: token.Position{Filename: sourcePath, Line: 17}},
+			{Flow: "titleFlow", Method: "WithOutputType", Type: "Recipe", Model: "googleai/gemini-1.0-ultra", Position: token.Position{Filename: sourcePath, Line: 28}},
+		},
+	}
+
+	content, changes, err := transformer.transformGoFile(project, sourceFile)
+	require.NoError(t, err)
+
+	assert.Contains(t, content, "ai.WithPrompt(\"Recipe for %s\", dish),\n\t\t\tai.WithConfig(&bedrock.GenerationConfig{OutputMode: bedrock.OutputModeToolUse}))")
+	assert.Contains(t, content, "ai.WithConfig(&bedrock.GenerationConfig{Temperature: 0.2, OutputMode: bedrock.OutputModeToolUse})")
+	assert.Contains(t, content, "OutputMode: bedrock.OutputModeToolUse,\n}")
+	assert.NotContains(t, content, "ResponseSchema")
+
+	var replaced *models.Change
+	for _, change := range changes {
+		if change.OldValue == "ResponseSchema" {
+			replaced = change
+		}
+	}
+	require.NotNil(t, replaced)
+	assert.True(t, replaced.ManualReview)
+	assert.Equal(t, "OutputMode", replaced.NewValue)
+
+	migration := &models.Migration{
+		Project:  project,
+		Changes:  make([]*models.Change, 0),
+		NewFiles: make(map[string]string),
+	}
+
+	err = transformer.transformStructuredOutputs(migration)
+	require.NoError(t, err)
+
+	test := migration.NewFiles["structured_output_test.go"]
+	assert.Contains(t, test, "package main")
+	assert.Contains(t, test, "func TestRecipeFlowOutput(t *testing.T) {\n\tcheckStructuredOutput(t, reflect.TypeFor[Recipe]())")
+	assert.NotContains(t, test, "testdata")
+
+	// The test compiles against the flow's package without any fixture.
+	fset := token.NewFileSet()
+	testFile, err := parser.ParseFile(fset, "structured_output_test.go", test, 0)
+	require.NoError(t, err)
+	typesFile, err := parser.ParseFile(fset, "types.go", "package main\n\ntype Recipe struct{ Title string }\n", 0)
+	require.NoError(t, err)
+	config := types.Config{Importer: importer.ForCompiler(fset, "source", nil)}
+	_, err = config.Check("main", fset, []*ast.File{testFile, typesFile}, nil)
+	require.NoError(t, err)
+	assert.NotContains(t, test, "TitleFlow")
+
+	var unmapped, untested *models.Change
+	for _, change := range migration.Changes {
+		switch {
+		case change.OldValue == "googleai/gemini-1.0-ultra":
+			unmapped = change
+		case change.Type == "test" && change.ManualReview:
+			untested = change
+		}
+	}
+	require.NotNil(t, unmapped)
+	assert.True(t, unmapped.ManualReview)
+	assert.False(t, unmapped.Blocking)
+	assert.Contains(t, unmapped.Description, "flow titleFlow")
+	require.NotNil(t, untested)
+	assert.Contains(t, untested.Description, "titleFlow returns string")
+	// Claude models support tool use.
+	for _, change := range migration.Changes {
+		assert.NotEqual(t, "googleai/gemini-2.0-flash", change.OldValue)
+	}
+}
+
+func TestTransformBlockers(t *testing.T) {
+	project := &models.Project{
+		Features: []*models.FeatureUsage{
+			{Feature: "google-search-grounding", Title: "Grounding with Google Search", Detail: "GoogleSearch", Position: token.Position{Filename: "search.go", Line: 16}},
+			{Feature: "context-caching", Title: "Gemini context caching", Detail: "CACHED_CONTENT", Position: token.Position{Filename: ".env", Line: 2}},
+		},
+	}
+
+	for _, test := range []struct {
+		target      string
+		alternative string
+	}{
+		{"aws", "Bedrock Knowledge Base"},
+		{"ollama", "remove it or reimplement it for"},
+	} {
+		migration := &models.Migration{
+			Project: project,
+			Changes: make([]*models.Change, 0),
+		}
+
+		err := New(&Config{SourceProvider: "gcp", TargetProvider: test.target}).transformBlockers(migration)
+		require.NoError(t, err)
+
+		require.Len(t, migration.Changes, 2, "Target: %s", test.target)
+		grounding := migration.Changes[0]
+		assert.Equal(t, "blocker", grounding.Type)
+		assert.True(t, grounding.Blocking)
+		assert.True(t, grounding.ManualReview)
+		assert.Equal(t, "GoogleSearch", grounding.OldValue)
+		assert.Equal(t, "search.go", grounding.File)
+		assert.Equal(t, 16, grounding.Line)
+		assert.Contains(t, grounding.Description, test.alternative, "Target: %s", test.target)
+	}
+
+	migration := &models.Migration{Project: project, Changes: make([]*models.Change, 0)}
+	err := New(&Config{SourceProvider: "gcp", TargetProvider: "aws"}).transformBlockers(migration)
+	require.NoError(t, err)
+	assert.Contains(t, migration.Changes[1].Description, "prompt caching")
+}
+
+func TestTransformBedrockPolicy(t *testing.T) {
+	transformer := New(&Config{
+		SourceProvider: "gcp",
+		TargetProvider: "aws",
+	})
+
+	project := &models.Project{
+		Flows: []*models.Flow{
+			{Name: "summarize"},
+		},
+		Models: []*models.Model{
+			{Name: "googleai/gemini-1.5-pro"},
+			{Name: "vertexai/gemini-pro"},
+			{Name: "bedrock/us.anthropic.claude-3-5-sonnet-20241022-v2:0"},
+		},
+		Embedders: []*models.Embedder{
+			{Name: "googleai/text-embedding-004"},
+		},
+	}
+	migration := &models.Migration{
+		Project:  project,
+		Changes:  make([]*models.Change, 0),
+		NewFiles: make(map[string]string),
+	}
+
+	err := transformer.generateDeploymentFiles(migration)
+	require.NoError(t, err)
+
+	mainTF := migration.NewFiles["terraform/main.tf"]
+	// Only metrics, which have no resource ARNs, are granted on any resource,
+	// and only in the app's namespace.
+	assert.Equal(t, 1, strings.Count(mainTF, `Resource = "*"`))
+	assert.Contains(t, mainTF, `Action   = ["cloudwatch:PutMetricData"]
+        Resource = "*"
+        Condition = {
+          StringEquals = {
+            "cloudwatch:namespace" = "GenKit/GenKitApp"`)
+	assert.Contains(t, mainTF, `data "aws_bedrock_inference_profile" "us_anthropic_claude_3_5_sonnet_20241022_v2_0" {
+  inference_profile_id = "us.anthropic.claude-3-5-sonnet-20241022-v2:0"
+}`)
+	assert.Contains(t, mainTF, `        Resource = concat([
+          "arn:aws:bedrock:${var.aws_region}::foundation-model/amazon.titan-embed-text-v2:0",
+          "arn:aws:bedrock:${var.aws_region}::foundation-model/anthropic.claude-3-sonnet-20240229-v1:0",
+          data.aws_bedrock_inference_profile.us_anthropic_claude_3_5_sonnet_20241022_v2_0.inference_profile_arn,
+        ], data.aws_bedrock_inference_profile.us_anthropic_claude_3_5_sonnet_20241022_v2_0.models[*].model_arn)`)
+	assert.Contains(t, mainTF, `"bedrock:InvokeModel"
+        ]`)
+	assert.NotContains(t, mainTF, "InvokeModelWithResponseStream")
+
+	project.Flows = append(project.Flows, &models.Flow{Name: "chat", Streaming: true})
+	project.Models = project.Models[:1]
+	project.Embedders = nil
+	migration.NewFiles = make(map[string]string)
+
+	err = transformer.generateDeploymentFiles(migration)
+	require.NoError(t, err)
+
+	mainTF = migration.NewFiles["terraform/main.tf"]
+	assert.Contains(t, mainTF, `"bedrock:InvokeModel",
+          "bedrock:InvokeModelWithResponseStream"
+        ]
+        Resource = [
+          "arn:aws:bedrock:${var.aws_region}::foundation-model/anthropic.claude-3-sonnet-20240229-v1:0",
+        ]`)
+	assert.NotContains(t, mainTF, "aws_bedrock_inference_profile")
+}
+
+func TestTransformDeployTargets(t *testing.T) {
+	tests := []struct {
+		name       string
+		option     string
+		deployment *models.Deployment
+		streaming  bool
+		target     string
+		contains   map[string]string
+	}{
+		{
+			name:   "no source deployment",
+			target: "lambda",
+			contains: map[string]string{
+				"terraform/main.tf":     `handler                        = "bootstrap"`,
+				"terraform/outputs.tf":  `output "function_arn"`,
+				"serve_flows_lambda.go": "httpadapter.NewV2(handler).ProxyWithContext",
+			},
+		},
+		{
+			name:       "cloud function",
+			deployment: &models.Deployment{Platform: "cloud-functions", Memory: "1GiB", MaxInstances: 20},
+			target:     "lambda",
+			contains: map[string]string{
+				"terraform/variables.tf": "default     = 1024\n}\n\nvariable \"timeout\"",
+				"terraform/main.tf":      `for_each  = toset(["summarize"])`,
+			},
+		},
+		{
+			name:       "short cloud run requests",
+			deployment: &models.Deployment{Platform: "cloud-run", CPU: "2", Memory: "2Gi", Timeout: "60s", Concurrency: 40},
+			target:     "app-runner",
+			contains: map[string]string{
+				"terraform/main.tf":      `resource "aws_apprunner_service" "app"`,
+				"terraform/variables.tf": "default     = 2048\n}\n\nvariable \"memory\" {\n  description = \"Memory in MiB\"\n  type        = number\n  default     = 4096",
+				"Dockerfile":             "EXPOSE 8080",
+			},
+		},
+		{
+			name:       "long cloud run requests",
+			deployment: &models.Deployment{Platform: "cloud-run", Timeout: "900s"},
+			target:     "ecs-fargate",
+			contains: map[string]string{
+				"terraform/main.tf":      "idle_timeout       = var.timeout",
+				"terraform/variables.tf": "default     = 900",
+			},
+		},
+		{
+			name:       "streaming cloud function",
+			deployment: &models.Deployment{Platform: "cloud-functions"},
+			streaming:  true,
+			target:     "ecs-fargate",
+		},
+		{
+			name:       "eks",
+			option:     "eks",
+			deployment: &models.Deployment{Platform: "cloud-run", CPU: "500m", Memory: "512Mi", MinInstances: 2},
+			target:     "eks",
+			contains: map[string]string{
+				"terraform/main.tf":             "assume_role_policy = data.aws_iam_policy_document.irsa.json",
+				"charts/genkit-app/values.yaml": "requests:\n    cpu: 500m\n    memory: 512Mi",
+				"charts/genkit-app/Chart.yaml":  "name: genkit-app",
+			},
+		},
+	}
+
+	for _, test := range tests {
+		transformer := New(&Config{
+			SourceProvider: "gcp",
+			TargetProvider: "aws",
+			Options:        map[string]string{aws.DeployTargetOption: test.option},
+		})
+		project := &models.Project{
+			Flows:      []*models.Flow{{Name: "summarize", Streaming: test.streaming}},
+			Models:     []*models.Model{{Name: "googleai/gemini-1.5-pro"}},
+			Deployment: test.deployment,
+		}
+		migration := &models.Migration{
+			Project:  project,
+			Changes:  make([]*models.Change, 0),
+			NewFiles: make(map[string]string),
+		}
+
+		err := transformer.generateDeploymentFiles(migration)
+		require.NoError(t, err, test.name)
+
+		var selected string
+		for _, change := range migration.Changes {
+			if strings.HasPrefix(change.Description, "Deploying to") {
+				selected = change.NewValue
+			}
+		}
+		assert.Equal(t, test.target, selected, test.name)
+		assert.Equal(t, 1, strings.Count(migration.NewFiles["terraform/main.tf"], `Resource = "*"`), test.name)
+		_, container := migration.NewFiles["Dockerfile"]
+		assert.Equal(t, test.target != "lambda", container, test.name)
+		_, adapter := migration.NewFiles["serve_flows_lambda.go"]
+		assert.Equal(t, test.target == "lambda", adapter, test.name)
+		for path, content := range test.contains {
+			assert.Contains(t, migration.NewFiles[path], content, "%s: %s", test.name, path)
+		}
+	}
+
+	// Lambda cannot stream through API Gateway.
+	transformer := New(&Config{
+		SourceProvider: "gcp",
+		TargetProvider: "aws",
+		Options:        map[string]string{aws.DeployTargetOption: "lambda"},
+	})
+	migration := &models.Migration{
+		Project:  &models.Project{Flows: []*models.Flow{{Name: "chat", Streaming: true}}},
+		Changes:  make([]*models.Change, 0),
+		NewFiles: make(map[string]string),
+	}
+	require.NoError(t, transformer.generateDeploymentFiles(migration))
+	var streaming *models.Change
+	for _, change := range migration.Changes {
+		if strings.Contains(change.Description, "Flow chat streams") {
+			streaming = change
+		}
+	}
+	require.NotNil(t, streaming)
+	assert.True(t, streaming.ManualReview)
+
+	transformer = New(&Config{
+		SourceProvider: "gcp",
+		TargetProvider: "aws",
+		Options:        map[string]string{aws.DeployTargetOption: "beanstalk"},
+	})
+	migration.NewFiles = make(map[string]string)
+	assert.ErrorContains(t, transformer.generateDeploymentFiles(migration), `unsupported deploy target "beanstalk"`)
+}
+
+func TestTransformHelmChart(t *testing.T) {
+	transformer := New(&Config{SourceProvider: "gcp", TargetProvider: "aws"})
+	migration := &models.Migration{
+		Project: &models.Project{
+			Flows:  []*models.Flow{{Name: "summarize"}},
+			Models: []*models.Model{{Name: "googleai/gemini-1.5-pro"}},
+			Deployment: &models.Deployment{
+				Platform:     "gke",
+				File:         "k8s/app.yaml",
+				CPU:          "250m",
+				Memory:       "512Mi",
+				MinInstances: 3,
+				MaxInstances: 12,
+				Port:         3400,
+				Container: &models.Container{
+					Resources: map[string]map[string]string{
+						"requests": {"cpu": "250m", "memory": "256Mi"},
+						"limits":   {"memory": "512Mi"},
+					},
+					ReadinessProbe: map[string]interface{}{
+						"httpGet":       map[string]interface{}{"path": "/healthz", "port": 3400},
+						"periodSeconds": 5,
+					},
+				},
+			},
+		},
+		Changes: make([]*models.Change, 0),
+		NewFiles: map[string]string{
+			"config.yaml": "region: us-east-1\nbedrock:\n  models:\n    - amazon.nova-pro-v1:0\ncloudwatch:\n  enabled: true\n",
+		},
+	}
+	require.NoError(t, transformer.generateDeploymentFiles(migration))
+
+	// GKE sources deploy to EKS.
+	for _, change := range migration.Changes {
+		if strings.HasPrefix(change.Description, "Deploying to") {
+			assert.Equal(t, "eks", change.NewValue)
+		}
+	}
+	assert.NotContains(t, migration.NewFiles, "k8s/deployment.yaml")
+	for _, name := range []string{"deployment.yaml", "service.yaml", "hpa.yaml", "serviceaccount.yaml", "configmap.yaml", "_helpers.tpl"} {
+		assert.Contains(t, migration.NewFiles, "charts/genkit-app/templates/"+name)
+	}
+	assert.Contains(t, migration.NewFiles["charts/genkit-app/templates/serviceaccount.yaml"], "eks.amazonaws.com/role-arn: {{ required")
+	assert.Contains(t, migration.NewFiles["charts/genkit-app/templates/configmap.yaml"], `set $bedrock "models" .Values.models`)
+
+	var values struct {
+		Image        map[string]string            `yaml:"image"`
+		ReplicaCount int                          `yaml:"replicaCount"`
+		Autoscaling  map[string]interface{}       `yaml:"autoscaling"`
+		Resources    map[string]map[string]string `yaml:"resources"`
+		Readiness    map[string]interface{}       `yaml:"readinessProbe"`
+		Liveness     map[string]interface{}       `yaml:"livenessProbe"`
+		Container    int                          `yaml:"containerPort"`
+		Models       []string                     `yaml:"models"`
+		Config       map[string]interface{}       `yaml:"config"`
+	}
+	require.NoError(t, yaml.Unmarshal([]byte(migration.NewFiles["charts/genkit-app/values.yaml"]), &values))
+	assert.Contains(t, values.Image, "repository")
+	assert.Equal(t, 3, values.ReplicaCount)
+	assert.Equal(t, 12, values.Autoscaling["maxReplicas"])
+	// The GKE container's resources and probes carry over as written.
+	assert.Equal(t, migration.Project.Deployment.Container.Resources, values.Resources)
+	assert.Equal(t, migration.Project.Deployment.Container.ReadinessProbe, values.Readiness)
+	assert.Nil(t, values.Liveness)
+	assert.Equal(t, 3400, values.Container)
+	assert.NotEmpty(t, values.Models)
+	// bedrock.models of config.yaml comes from the models value.
+	assert.Equal(t, map[string]interface{}{"region": "us-east-1", "cloudwatch": map[string]interface{}{"enabled": true}}, values.Config)
+
+	workflow := migration.NewFiles[".github/workflows/deploy.yml"]
+	assert.Contains(t, workflow, "helm upgrade --install genkit-app ../../../charts/genkit-app")
+	assert.Contains(t, workflow, "--namespace prod --create-namespace")
+	assert.Contains(t, workflow, "helm lint charts/genkit-app")
+}
+
+func TestTransformTerraformEnvironments(t *testing.T) {
+	transformer := New(&Config{
+		SourceProvider: "gcp",
+		TargetProvider: "aws",
+		Options:        map[string]string{aws.DeployTargetOption: "ecs-fargate"},
+	})
+	migration := &models.Migration{
+		Project: &models.Project{
+			Flows:          []*models.Flow{{Name: "summarize"}},
+			Models:         []*models.Model{{Name: "googleai/gemini-1.5-pro"}},
+			SafetySettings: []*models.SafetySetting{{Category: "HARM_CATEGORY_HARASSMENT", Threshold: "BLOCK_LOW_AND_ABOVE"}},
+		},
+		Changes:  make([]*models.Change, 0),
+		NewFiles: make(map[string]string),
+	}
+	require.NoError(t, transformer.generateDeploymentFiles(migration))
+
+	module := migration.NewFiles["terraform/main.tf"]
+	assert.NotContains(t, module, `provider "aws"`)
+	assert.Contains(t, module, `name = "${var.project_name}-${var.environment}"`)
+	assert.Contains(t, module, `name = "${local.name}-app-role"`)
+	assert.Contains(t, migration.NewFiles["terraform/variables.tf"], `contains(["dev", "staging", "prod"], var.environment)`)
+
+	for _, environment := range []string{"dev", "staging", "prod"} {
+		dir := "terraform/envs/" + environment + "/"
+		root := migration.NewFiles[dir+"main.tf"]
+		assert.Contains(t, root, `backend "s3" {}`)
+		assert.Contains(t, root, `source = "../.."`)
+		assert.Contains(t, root, "  health_check_path = var.health_check_path\n")
+		assert.Equal(t, migration.NewFiles["terraform/variables.tf"], migration.NewFiles[dir+"variables.tf"])
+		assert.Contains(t, migration.NewFiles[dir+"backend.hcl"], `key            = "`+environment+`/terraform.tfstate"`)
+		assert.Contains(t, migration.NewFiles[dir+"backend.hcl"], "dynamodb_table")
+		assert.Contains(t, migration.NewFiles[dir+"terraform.tfvars"], `environment  = "`+environment+`"`)
+		// Outputs of every terraform/*.tf are forwarded, the guardrail's too.
+		assert.Contains(t, migration.NewFiles[dir+"outputs.tf"], "value = module.app.ecr_repository_url")
+		assert.Contains(t, migration.NewFiles[dir+"outputs.tf"], "value = module.app.guardrail_id")
+	}
+
+	workflow := migration.NewFiles[".github/workflows/deploy.yml"]
+	assert.Contains(t, workflow, "  deploy-dev:\n    needs: test\n")
+	assert.Contains(t, workflow, "  deploy-staging:\n    needs: deploy-dev\n    runs-on: ubuntu-latest\n    environment: staging\n")
+	assert.Contains(t, workflow, "  deploy-prod:\n    needs: deploy-staging\n")
+	assert.Contains(t, workflow, "working-directory: terraform/envs/prod")
+	assert.Contains(t, workflow, "terraform init -backend-config=backend.hcl")
+	var config map[string]interface{}
+	require.NoError(t, yaml.Unmarshal([]byte(workflow), &config))
+}
+
+func TestTransformCDK(t *testing.T) {
+	generate := func(options map[string]string, project *models.Project) (*models.Migration, error) {
+		transformer := New(&Config{
+			SourceProvider: "gcp",
+			TargetProvider: "aws",
+			Options:        options,
+		})
+		migration := &models.Migration{
+			Project:  project,
+			Changes:  make([]*models.Change, 0),
+			NewFiles: make(map[string]string),
+			Commands: make([]string, 0),
+		}
+		return migration, transformer.generateDeploymentFiles(migration)
+	}
+	project := func(deployment *models.Deployment) *models.Project {
+		return &models.Project{
+			Flows:      []*models.Flow{{Name: "summarize"}},
+			Models:     []*models.Model{{Name: "googleai/gemini-1.5-pro"}},
+			Deployment: deployment,
+		}
+	}
+
+	migration, err := generate(map[string]string{aws.IaCOption: "cdk-go"}, project(nil))
+	require.NoError(t, err)
+	assert.NotContains(t, migration.NewFiles, "terraform/main.tf")
+	assert.Contains(t, migration.NewFiles["cdk/cdk.json"], `"environment": "dev"`)
+	assert.Contains(t, migration.NewFiles["cdk/go.mod"], "github.com/aws/aws-cdk-go/awscdk/v2")
+	app := migration.NewFiles["cdk/app.go"]
+	assert.Contains(t, app, "awslambda.Runtime_PROVIDED_AL2023()")
+	assert.Contains(t, app, `"summarize",`)
+	assert.Contains(t, app, `"cloudwatch:namespace": "GenKit/`)
+	assert.Contains(t, app, `jsii.Strings("bedrock:InvokeModel")`)
+	assert.Contains(t, migration.NewFiles["cdk/app_test.go"], "assertions.Template_FromStack(stack, nil)")
+	assert.Contains(t, migration.NewFiles["serve_flows_lambda.go"], "//go:build lambda")
+	assert.Contains(t, migration.NewFiles[".github/workflows/deploy.yml"], "cdk deploy --require-approval never")
+	assert.Contains(t, migration.Commands, "(cd cdk && go mod tidy && go test ./...)")
+
+	// App Runner is not generated for CDK; Fargate stands in.
+	migration, err = generate(map[string]string{aws.IaCOption: "cdk-go"}, project(&models.Deployment{Platform: "cloud-run", Timeout: "60s"}))
+	require.NoError(t, err)
+	app = migration.NewFiles["cdk/app.go"]
+	assert.Contains(t, app, "awsecspatterns.NewApplicationLoadBalancedFargateService")
+	assert.Contains(t, app, "IdleTimeout: awscdk.Duration_Seconds(jsii.Number(60))")
+	assert.Contains(t, migration.NewFiles, "Dockerfile")
+
+	_, err = generate(map[string]string{aws.IaCOption: "cdk-go", aws.DeployTargetOption: "eks"}, project(nil))
+	assert.ErrorContains(t, err, `deploy target "eks" cannot be generated with AWS CDK`)
+
+	_, err = generate(map[string]string{aws.IaCOption: "pulumi"}, project(nil))
+	assert.ErrorContains(t, err, `unsupported infrastructure format "pulumi"`)
+}
+
+func TestTransformLambdaHandler(t *testing.T) {
+	sourceDir := t.TempDir()
+
+	mainContent := `package main
+
+import (
+	"context"
+	"log"
+	"net/http"
+
+	"github.com/firebase/genkit/go/genkit"
+	"github.com/firebase/genkit/go/plugins/server"
+)
+
+func main() {
+	ctx := context.Background()
+	g := genkit.Init(ctx)
+
+	mux := http.NewServeMux()
+	for _, flow := range genkit.ListFlows(g) {
+		mux.HandleFunc("POST /"+flow.Name(), genkit.Handler(flow))
+	}
+	log.Fatal(server.Start(ctx, "127.0.0.1:8080", mux))
+}
+
+func debug() {
+	log.Fatal(http.ListenAndServe(":6060", nil))
+}
+`
+	sourcePath := filepath.Join(sourceDir, "main.go")
+	err := os.WriteFile(sourcePath, []byte(mainContent), 0644)
+	require.NoError(t, err)
+
+	transformer := New(&Config{
+		SourceProvider: "gcp",
+		TargetProvider: "aws",
+		Options:        map[string]string{aws.DeployTargetOption: "lambda"},
+	})
+	project := &models.Project{
+		Path:   sourceDir,
+		Flows:  []*models.Flow{{Name: "summarize"}},
+		Models: []*models.Model{{Name: "googleai/gemini-1.5-pro"}},
+		Files: map[string]*models.SourceFile{
+			"main.go": {Path: sourcePath, PackageName: "main", HasGenKit: true},
+		},
+	}
+
+	content, changes, err := transformer.transformGoFile(project, project.Files["main.go"])
+	require.NoError(t, err)
+	assert.Contains(t, content, `log.Fatal(serveFlows(ctx, "127.0.0.1:8080", mux))`)
+	assert.Contains(t, content, `log.Fatal(serveFlows(context.Background(), ":6060", http.DefaultServeMux))`)
+	assert.NotContains(t, content, "plugins/server")
+
+	var rewritten []int
+	for _, change := range changes {
+		if change.NewValue == "serveFlows" {
+			rewritten = append(rewritten, change.Line)
+		}
+	}
+	assert.Equal(t, []int{20, 24}, rewritten)
+
+	migration := &models.Migration{
+		Project:  project,
+		Changes:  make([]*models.Change, 0),
+		NewFiles: map[string]string{"main.go": content},
+	}
+	require.NoError(t, transformer.generateDeploymentFiles(migration))
+
+	assert.Contains(t, migration.NewFiles["serve_flows.go"], "//go:build !lambda")
+	assert.Contains(t, migration.NewFiles["serve_flows.go"], `os.Getenv("PORT")`)
+	assert.Contains(t, migration.NewFiles["serve_flows_lambda.go"], "lambda.StartWithOptions(httpadapter.NewV2(handler).ProxyWithContext")
+	assert.NotContains(t, migration.NewFiles, "cmd/lambda/main.go")
+	assert.Contains(t, migration.NewFiles[".github/workflows/deploy.yml"], "go build -tags lambda,lambda.norpc -o build/bootstrap .")
+
+	// The guide explains why the entry point is main rather than cmd/lambda.
+	guide := transformer.target.Guide(migration)
+	assert.Contains(t, guide, "### Lambda Entry Point")
+	assert.Contains(t, guide, "`serve_flows_lambda.go` hands the mux")
+	for _, change := range migration.Changes {
+		assert.NotContains(t, change.Description, "No flow server was found")
+	}
+}
+
+func TestTransformSAM(t *testing.T) {
+	transformer := New(&Config{
+		SourceProvider: "gcp",
+		TargetProvider: "aws",
+		Options:        map[string]string{aws.IaCOption: "sam"},
+	})
+	project := &models.Project{
+		Flows: []*models.Flow{
+			{Name: "summarize"},
+			{Name: "chat-stream", Streaming: true},
+		},
+		Models:         []*models.Model{{Name: "googleai/gemini-1.5-pro"}},
+		SafetySettings: []*models.SafetySetting{{Category: "HARM_CATEGORY_HARASSMENT", Threshold: "BLOCK_LOW_AND_ABOVE"}},
+	}
+	migration := &models.Migration{
+		Project:  project,
+		Changes:  make([]*models.Change, 0),
+		NewFiles: make(map[string]string),
+	}
+	require.NoError(t, transformer.generateDeploymentFiles(migration))
+
+	// The guardrail is a Terraform module granting the role SAM creates.
+	assert.Contains(t, migration.NewFiles["terraform/main.tf"], `data "aws_iam_role" "app_role"`)
+	assert.NotContains(t, migration.NewFiles["terraform/main.tf"], "aws_lambda_function")
+	assert.Contains(t, migration.NewFiles["terraform/envs/prod/outputs.tf"], "module.app.guardrail_id")
+	assert.Contains(t, migration.NewFiles["serve_flows_lambda.go"], "//go:build lambda")
+
+	var template struct {
+		Parameters map[string]interface{} `yaml:"Parameters"`
+		Resources  map[string]struct {
+			Type       string `yaml:"Type"`
+			Properties struct {
+				Handler  string `yaml:"Handler"`
+				Runtime  string `yaml:"Runtime"`
+				Role     any    `yaml:"Role"`
+				Policies []struct {
+					PolicyDocument struct {
+						Statement []struct {
+							Action interface{} `yaml:"Action"`
+						} `yaml:"Statement"`
+					} `yaml:"PolicyDocument"`
+				} `yaml:"Policies"`
+				Events map[string]struct {
+					Type       string            `yaml:"Type"`
+					Properties map[string]string `yaml:"Properties"`
+				} `yaml:"Events"`
+			} `yaml:"Properties"`
+		} `yaml:"Resources"`
+	}
+	require.NoError(t, yaml.Unmarshal([]byte(migration.NewFiles["template.yaml"]), &template))
+	assert.Contains(t, template.Parameters, "BedrockGuardrailId")
+
+	function := template.Resources["GenKitFunction"]
+	assert.Equal(t, "AWS::Serverless::Function", function.Type)
+	assert.Equal(t, "bootstrap", function.Properties.Handler)
+	assert.Equal(t, "provided.al2023", function.Properties.Runtime)
+	assert.Equal(t, map[string]string{"Path": "/summarize", "Method": "POST"}, function.Properties.Events["Summarize"].Properties)
+	assert.Equal(t, map[string]string{"Path": "/chat-stream", "Method": "POST"}, function.Properties.Events["ChatStream"].Properties)
+	assert.NotNil(t, function.Properties.Role)
+
+	role := template.Resources["GenKitAppRole"]
+	assert.Equal(t, "AWS::IAM::Role", role.Type)
+	require.Len(t, role.Properties.Policies, 1)
+	actions := make([]interface{}, 0)
+	for _, statement := range role.Properties.Policies[0].PolicyDocument.Statement {
+		actions = append(actions, statement.Action)
+	}
+	assert.Equal(t, []interface{}{
+		[]interface{}{"bedrock:InvokeModel", "bedrock:InvokeModelWithResponseStream"},
+		"bedrock:ApplyGuardrail",
+		"cloudwatch:PutMetricData",
+	}, actions)
+
+	config := migration.NewFiles["samconfig.toml"]
+	for _, environment := range []string{"dev", "staging", "prod"} {
+		assert.Contains(t, config, "["+environment+".deploy.parameters]")
+		assert.Contains(t, config, `parameter_overrides = "Environment=`+environment+`"`)
+	}
+
+	// SAM only generates Lambda, so streaming is flagged instead of moving
+	// to Fargate.
+	var streaming bool
+	for _, change := range migration.Changes {
+		if strings.Contains(change.Description, "Flow chat-stream streams") {
+			streaming = change.ManualReview
+		}
+	}
+	assert.True(t, streaming)
+}
+
+func TestTransformPulumi(t *testing.T) {
+	generate := func(options map[string]string, project *models.Project) (*models.Migration, error) {
+		transformer := New(&Config{
+			SourceProvider: "gcp",
+			TargetProvider: "aws",
+			Options:        options,
+		})
+		migration := &models.Migration{
+			Project:  project,
+			Changes:  make([]*models.Change, 0),
+			NewFiles: make(map[string]string),
+			Commands: make([]string, 0),
+		}
+		return migration, transformer.generateDeploymentFiles(migration)
+	}
+	project := func(deployment *models.Deployment) *models.Project {
+		return &models.Project{
+			Flows:      []*models.Flow{{Name: "summarize"}},
+			Models:     []*models.Model{{Name: "googleai/gemini-1.5-pro"}},
+			Deployment: deployment,
+		}
+	}
+
+	migration, err := generate(map[string]string{aws.IaCOption: "pulumi-go"}, project(nil))
+	require.NoError(t, err)
+	assert.NotContains(t, migration.NewFiles, "terraform/main.tf")
+	assert.Contains(t, migration.NewFiles["pulumi/Pulumi.yaml"], "runtime: go")
+	assert.Contains(t, migration.NewFiles["pulumi/go.mod"], "github.com/pulumi/pulumi-aws/sdk/v6")
+	for _, environment := range []string{"dev", "staging", "prod"} {
+		assert.Contains(t, migration.NewFiles["pulumi/Pulumi."+environment+".yaml"], "aws:region: us-east-1")
+	}
+	program := migration.NewFiles["pulumi/main.go"]
+	assert.Contains(t, program, `config.Require(ctx, "aws:region")`)
+	assert.Contains(t, program, `cfg.Get("projectName")`)
+	assert.Contains(t, program, `pulumi.String("provided.al2023")`)
+	assert.Contains(t, program, `"summarize",`)
+	assert.Contains(t, program, `ctx.Export("apiUrl", stage.InvokeUrl)`)
+	assert.Contains(t, program, `ctx.Export("functionArn", function.Arn)`)
+	assert.Contains(t, program, `"cloudwatch:namespace": "GenKit/`)
+	assert.NotContains(t, program, "aws/ecs")
+	assert.Contains(t, migration.NewFiles["serve_flows_lambda.go"], "//go:build lambda")
+	assert.Contains(t, migration.NewFiles[".github/workflows/deploy.yml"], "pulumi up --stack prod --yes")
+	assert.Contains(t, migration.Commands, "(cd pulumi && go mod tidy)")
+
+	// App Runner is not generated for Pulumi; Fargate stands in.
+	migration, err = generate(map[string]string{aws.IaCOption: "pulumi-go"}, project(&models.Deployment{Platform: "cloud-run", Timeout: "60s"}))
+	require.NoError(t, err)
+	program = migration.NewFiles["pulumi/main.go"]
+	assert.Contains(t, program, "ecs.NewService")
+	assert.Contains(t, program, "IdleTimeout:      pulumi.Int(60)")
+	assert.Contains(t, program, `ctx.Export("ecrRepositoryUrl", repository.RepositoryUrl)`)
+	assert.NotContains(t, program, "aws/lambda")
+	assert.Contains(t, migration.NewFiles, "Dockerfile")
+	assert.Contains(t, migration.NewFiles[".github/workflows/deploy.yml"], "--config imageTag=")
+
+	_, err = generate(map[string]string{aws.IaCOption: "pulumi-go", aws.DeployTargetOption: "app-runner"}, project(nil))
+	assert.ErrorContains(t, err, `deploy target "app-runner" cannot be generated with Pulumi`)
+}
+
+func TestTransformUserRules(t *testing.T) {
+	sourceDir := t.TempDir()
+
+	mainContent := `package main
+
+import "github.com/acme/platform/ourai"
+
+var model = ourai.NewClient("googleai/gemini-1.5-pro")
+`
+	sourcePath := filepath.Join(sourceDir, "main.go")
+	err := os.WriteFile(sourcePath, []byte(mainContent), 0644)
+	require.NoError(t, err)
+
+	rules, err := rewrite.ParseRules([]byte(`
+rules:
+  - name: ourai-client
+    match: ourai.NewClient($model)
+    replace: genkit.LookupModel(g, $model)
+    imports:
+      add: {genkit: github.com/firebase/genkit/go/genkit}
+      remove: {ourai: github.com/acme/platform/ourai}
+`))
+	require.NoError(t, err)
+
+	transformer := New(&Config{
+		SourceProvider: "gcp",
+		TargetProvider: "aws",
+		Rules:          rules,
+	})
+
+	content, changes, err := transformer.transformGoFile(&models.Project{Path: sourceDir}, &models.SourceFile{Path: sourcePath})
+	require.NoError(t, err)
+
+	assert.Contains(t, content, `genkit.LookupModel(g, "bedrock/anthropic.claude-3-sonnet-20240229-v1:0")`)
+	assert.NotContains(t, content, "ourai")
+
+	require.NotEmpty(t, changes)
+	assert.Equal(t, "rule", changes[0].Type)
+	assert.Equal(t, "ourai-client", changes[0].Rule)
+	assert.Equal(t, 5, changes[0].Line)
+}
+
+func TestTransformDirectCalls(t *testing.T) {
+	sourceDir := t.TempDir()
+
+	genaiContent := `package main
+
+import (
+	"context"
+
+	"github.com/firebase/genkit/go/genkit"
+	"google.golang.org/genai"
+)
+
+func summarize(ctx context.Context, g *genkit.Genkit, client *genai.Client, text string) (string, error) {
+	resp, err := client.Models.GenerateContent(ctx, "gemini-1.5-pro", genai.Text(text), nil)
+	if err != nil {
+		return "", err
+	}
+	return resp.Text(), nil
+}
+`
+	vertexContent := `package main
+
+import (
+	"context"
+
+	"cloud.google.com/go/vertexai/genai"
+)
+
+func ask(ctx context.Context, client *genai.Client, question string) error {
+	model := client.GenerativeModel("gemini-1.5-flash")
+	_, err := model.GenerateContent(ctx, genai.Text(question))
+	return err
+}
+`
+	genaiPath := filepath.Join(sourceDir, "summarize.go")
+	err := os.WriteFile(genaiPath, []byte(genaiContent), 0644)
+	require.NoError(t, err)
+	vertexPath := filepath.Join(sourceDir, "vertex.go")
+	err = os.WriteFile(vertexPath, []byte(vertexContent), 0644)
+	require.NoError(t, err)
+
+	transformer := New(&Config{
+		SourceProvider: "gcp",
+		TargetProvider: "aws",
+	})
+	project := &models.Project{Path: sourceDir}
+
+	content, changes, err := transformer.transformGoFile(project, &models.SourceFile{Path: genaiPath})
+	require.NoError(t, err)
+
+	assert.Contains(t, content, `resp, err := genkit.Generate(ctx, g, ai.WithModelName("bedrock/anthropic.claude-3-sonnet-20240229-v1:0"), ai.WithPrompt(text))`)
+	assert.Contains(t, content, `"github.com/firebase/genkit/go/ai"`)
+	assert.Equal(t, 11, changes[0].Line)
+	assert.False(t, changes[0].ManualReview)
+	require.Len(t, changes, 3)
+	assert.Equal(t, "No automatic translation for genai.Client", changes[1].Description)
+	assert.Equal(t, 10, changes[1].Line)
+
+	// Without a *genkit.Genkit in scope the call is flagged where it is.
+	content, changes, err = transformer.transformGoFile(project, &models.SourceFile{Path: vertexPath})
+	require.NoError(t, err)
+
+	assert.Contains(t, content, `model.GenerateContent(ctx, genai.Text(question))`)
+	var flagged []string
+	for _, change := range changes {
+		if change.ManualReview {
+			flagged = append(flagged, fmt.Sprintf("%d:%d", change.Line, change.Column))
+		}
+	}
+	assert.Equal(t, []string{"11:12", "9:39", "10:11", "11:39"}, flagged)
+
+	// A per-call generation without GenKit goes to Bedrock Converse with
+	// the mapped model.
+	standaloneContent := `package main
+
+import (
+	"context"
+
+	"google.golang.org/genai"
+)
+
+func classify(ctx context.Context, client *genai.Client, text string) (string, error) {
+	resp, err := client.Models.GenerateContent(ctx, "gemini-1.5-flash", genai.Text(text), nil)
+	if err != nil {
+		return "", err
+	}
+	return resp.Text(), nil
+}
+`
+	standalonePath := filepath.Join(sourceDir, "classify.go")
+	require.NoError(t, os.WriteFile(standalonePath, []byte(standaloneContent), 0644))
+
+	content, changes, err = transformer.transformGoFile(project, &models.SourceFile{Path: standalonePath})
+	require.NoError(t, err)
+
+	assert.Contains(t, content, `resp, err := converseText(ctx, "anthropic.claude-3-haiku-20240307-v1:0", text)`)
+	require.NotEmpty(t, changes)
+	assert.Equal(t, 10, changes[0].Line)
+	assert.Equal(t, "anthropic.claude-3-haiku-20240307-v1:0", changes[0].NewValue)
+	assert.False(t, changes[0].ManualReview)
+}
+
+func TestTransformPrompts(t *testing.T) {
+	sourceDir := t.TempDir()
+
+	body := `Summarize the following text:
+
+{{#if context}}
+---
+{{context}}
+{{/if}}
+`
+	promptContent := `---
+model: googleai/gemini-1.5-flash
+config:
+  temperature: 0.2
+  maxOutputTokens: 1024
+  candidateCount: 2
+---
+` + body
+
+	err := os.MkdirAll(filepath.Join(sourceDir, "prompts"), 0755)
+	require.NoError(t, err)
+	err = os.WriteFile(filepath.Join(sourceDir, "prompts", "summarize.prompt"), []byte(promptContent), 0644)
+	require.NoError(t, err)
+
+	transformer := New(&Config{
+		SourceProvider: "gcp",
+		TargetProvider: "aws",
+	})
+
+	migration := &models.Migration{
+		Project: &models.Project{
+			Path: sourceDir,
+			Prompts: []*models.Prompt{
+				{Path: filepath.Join("prompts", "summarize.prompt")},
+			},
+		},
+		Changes:  make([]*models.Change, 0),
+		NewFiles: make(map[string]string),
+	}
+
+	err = transformer.transformPrompts(migration)
+	require.NoError(t, err)
+
+	content := migration.NewFiles[filepath.Join("prompts", "summarize.prompt")]
+	assert.Equal(t, `---
+model: bedrock/anthropic.claude-3-haiku-20240307-v1:0
+config:
+  temperature: 0.2
+  maxTokens: 1024
+---
+`+body, content)
+
+	require.Len(t, migration.Changes, 2)
+	assert.Equal(t, "maxTokens", migration.Changes[0].NewValue)
+	assert.Equal(t, "candidateCount", migration.Changes[1].OldValue)
+}
+
+func TestTransformPromptsClamp(t *testing.T) {
+	sourceDir := t.TempDir()
+
+	promptContent := `---
+model: googleai/gemini-1.5-pro
+config:
+  temperature: 1.5
+  maxOutputTokens: 8192
+---
+Answer the question.
+`
+	err := os.WriteFile(filepath.Join(sourceDir, "answer.prompt"), []byte(promptContent), 0644)
+	require.NoError(t, err)
+
+	transformer := New(&Config{
+		SourceProvider: "gcp",
+		TargetProvider: "aws",
+	})
+
+	migration := &models.Migration{
+		Project: &models.Project{
+			Path:    sourceDir,
+			Prompts: []*models.Prompt{{Path: "answer.prompt"}},
+		},
+		Changes:  make([]*models.Change, 0),
+		NewFiles: make(map[string]string),
+	}
+
+	err = transformer.transformPrompts(migration)
+	require.NoError(t, err)
+
+	content := migration.NewFiles["answer.prompt"]
+	assert.Contains(t, content, "  temperature: 1\n")
+	assert.Contains(t, content, "  maxTokens: 4096\n")
+	assert.NotContains(t, content, "!!")
+}
+
+func TestTransformEmbedders(t *testing.T) {
+	sourceDir := t.TempDir()
+
+	transformer := New(&Config{
+		SourceProvider: "gcp",
+		TargetProvider: "aws",
+	})
+
+	migration := &models.Migration{
+		Project: &models.Project{
+			Path: sourceDir,
+			Embedders: []*models.Embedder{
+				{
+					Name:       "googleai/text-embedding-004",
+					Dimensions: 768,
+					Position:   token.Position{Filename: filepath.Join(sourceDir, "rag", "rag.go"), Line: 12},
+				},
+				{
+					Name:       "googleai/text-embedding-004",
+					Dimensions: 768,
+					Position:   token.Position{Filename: filepath.Join(sourceDir, "rag", "rag.go"), Line: 30},
+				},
+			},
+			Indexers: []*models.Indexer{
+				{
+					Provider: "pgvector",
+					Name:     "docs",
+					Package:  "rag",
+					Position: token.Position{Filename: filepath.Join(sourceDir, "rag", "rag.go"), Line: 13},
+				},
+			},
+		},
+		Changes:  make([]*models.Change, 0),
+		NewFiles: make(map[string]string),
+	}
+
+	err := transformer.transformEmbedders(migration)
+	require.NoError(t, err)
+
+	require.Len(t, migration.Changes, 2)
+	blocking := migration.Changes[0]
+	assert.True(t, blocking.Blocking)
+	assert.Equal(t, "embedder", blocking.Type)
+	assert.Equal(t, "amazon.titan-embed-text-v2:0", blocking.NewValue)
+	assert.Equal(t, 12, blocking.Line)
+	assert.Contains(t, blocking.Description, "from 768 to 1024")
+
+	reindex := migration.NewFiles[filepath.Join("rag", "reindex.go")]
+	assert.Contains(t, reindex, "package rag")
+	assert.Contains(t, reindex, `{"pgvector", "docs"},`)
+	assert.Contains(t, reindex, "googleai/text-embedding-004 (768 dimensions) -> amazon.titan-embed-text-v2:0 (1024 dimensions)")
+	assert.Equal(t, filepath.Join("rag", "reindex.go"), migration.Changes[1].File)
+}
+
+func TestTransformVectorStores(t *testing.T) {
+	sourceDir := t.TempDir()
+
+	mainContent := `package main
+
+import (
+	"context"
+
+	"cloud.google.com/go/firestore"
+	"github.com/firebase/genkit/go/genkit"
+	"github.com/firebase/genkit/go/plugins/firebase"
+)
+
+func defineRetriever(ctx context.Context, g *genkit.Genkit, client *firestore.Client) {
+	firebase.DefineRetriever(ctx, g, firebase.RetrieverOptions{
+		Name:            "menuRetriever",
+		Client:          client,
+		Collection:      "menu",
+		ContentField:    "text",
+		Limit:           5,
+		DistanceMeasure: firestore.DistanceMeasureCosine,
+	})
+}
+`
+	sourcePath := filepath.Join(sourceDir, "main.go")
+	err := os.WriteFile(sourcePath, []byte(mainContent), 0644)
+	require.NoError(t, err)
+
+	transformer := New(&Config{
+		SourceProvider: "gcp",
+		TargetProvider: "aws",
+		Options:        map[string]string{"vector-store": "aurora-pgvector"},
+	})
+
+	project := &models.Project{
+		Path: sourceDir,
+		VectorStores: []*models.VectorStore{
+			{
+				Kind:         "firestore",
+				Package:      "github.com/firebase/genkit/go/plugins/firebase",
+				Name:         "menuRetriever",
+				Collection:   "menu",
+				ContentField: "text",
+				Position:     token.Position{Filename: sourcePath, Line: 12},
+			},
+		},
+	}
+
+	content, changes, err := transformer.transformGoFile(project, &models.SourceFile{Path: sourcePath})
+	require.NoError(t, err)
+
+	assert.Contains(t, content, "pgvector.DefineRetriever(ctx, g, pgvector.RetrieverOptions{")
+	assert.Contains(t, content, `ConnectionString: os.Getenv("DATABASE_URL"),`)
+	assert.Contains(t, content, `Table:            "menu",`)
+	assert.Contains(t, content, `ContentColumn:    "text",`)
+	assert.NotContains(t, content, "DistanceMeasure")
+	assert.NotContains(t, content, "plugins/firebase")
+
+	var dropped *models.Change
+	for _, change := range changes {
+		if change.OldValue == "DistanceMeasure" {
+			dropped = change
+		}
+	}
+	require.NotNil(t, dropped)
+	assert.True(t, dropped.ManualReview)
+
+	migration := &models.Migration{
+		Project:  project,
+		Changes:  make([]*models.Change, 0),
+		NewFiles: make(map[string]string),
+	}
+
+	err = transformer.generateDeploymentFiles(migration)
+	require.NoError(t, err)
+
+	assert.Contains(t, migration.NewFiles["terraform/vectorstore.tf"], `engine                      = "aurora-postgresql"`)
+	assert.Contains(t, migration.NewFiles["scripts/migrate-vectors/schema.sql"], "embedding vector(1024) NOT NULL")
+	assert.Contains(t, migration.NewFiles["scripts/migrate-vectors/main.go"], "pgvector.DefineIndexer(")
+	assert.Contains(t, migration.NewFiles["scripts/migrate-vectors/main.go"], `"collection", "menu", "Firestore collection"`)
+
+	assert.Contains(t, migration.NewFiles["terraform/main.tf"], "DATABASE_URL")
+
+	transformer.config.Options["vector-store"] = "pinecone"
+	err = transformer.generateDeploymentFiles(migration)
+	assert.Error(t, err)
+}
+
+func TestTransformVectorStoresUnusedClient(t *testing.T) {
+	sourceDir := t.TempDir()
+
+	mainContent := `package main
+
+import (
+	"context"
+	"log"
+
+	"cloud.google.com/go/firestore"
+	"github.com/firebase/genkit/go/genkit"
+	"github.com/firebase/genkit/go/plugins/firebase"
+)
+
+func main() {
+	ctx := context.Background()
+	g, err := genkit.Init(ctx)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	client, err := firestore.NewClient(ctx, "my-project")
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	firebase.DefineRetriever(ctx, g, firebase.RetrieverOptions{
+		Name:       "menuRetriever",
+		Client:     client,
+		Collection: "menu",
+	})
+}
+`
+	sourcePath := filepath.Join(sourceDir, "main.go")
+	err := os.WriteFile(sourcePath, []byte(mainContent), 0644)
+	require.NoError(t, err)
+
+	transformer := New(&Config{
+		SourceProvider: "gcp",
+		TargetProvider: "aws",
+	})
+
+	project := &models.Project{
+		Path: sourceDir,
+		VectorStores: []*models.VectorStore{
+			{Kind: "firestore", Package: "github.com/firebase/genkit/go/plugins/firebase", Collection: "menu"},
+		},
+	}
+
+	content, changes, err := transformer.transformGoFile(project, &models.SourceFile{Path: sourcePath})
+	require.NoError(t, err)
+
+	assert.Contains(t, content, `	if err != nil {
+		log.Fatal(err)
+	}
+	_ = client // TODO: remove the Firestore client if nothing else uses it.
+
+	opensearch.DefineRetriever(ctx, g, opensearch.RetrieverOptions{`)
+
+	var kept *models.Change
+	for _, change := range changes {
+		if change.OldValue == "client" {
+			kept = change
+		}
+	}
+	require.NotNil(t, kept)
+	assert.Equal(t, 19, kept.Line)
+	assert.True(t, kept.ManualReview)
 }
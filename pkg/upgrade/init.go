@@ -0,0 +1,539 @@
+package upgrade
+
+import (
+	"fmt"
+	"go/ast"
+	"go/token"
+	"slices"
+	"strings"
+
+	"github.com/genkit-migrate/genkit-migrate/pkg/models"
+	"github.com/genkit-migrate/genkit-migrate/pkg/rewrite"
+)
+
+const (
+	googleGenAIPackage = "github.com/firebase/genkit/go/plugins/googlegenai"
+	ollamaPackage      = "github.com/firebase/genkit/go/plugins/ollama"
+
+	// instanceName is the *genkit.Genkit variable the call rules pass.
+	instanceName = "g"
+)
+
+// pluginUpgrade is the 1.0 plugin struct a pre-1.0 plugin Init call is
+// registered as; the fields of its Config carry over by name.
+type pluginUpgrade struct {
+	path, name, typ string
+}
+
+var pluginUpgrades = map[string]pluginUpgrade{
+	"github.com/firebase/genkit/go/plugins/googleai": {googleGenAIPackage, "googlegenai", "GoogleAI"},
+	"github.com/firebase/genkit/go/plugins/vertexai": {googleGenAIPackage, "googlegenai", "VertexAI"},
+	ollamaPackage: {ollamaPackage, "ollama", "Ollama"},
+}
+
+// initOptions maps the fields of the pre-1.0 genkit.Options (or Config)
+// struct to 1.0 Init options; an empty option has no 1.0 equivalent.
+var initOptions = map[string]string{
+	"DefaultModel": "WithDefaultModel",
+	"PromptDir":    "WithPromptDir",
+	"Plugins":      "WithPlugins",
+	"FlowAddr":     "",
+	"Flows":        "",
+}
+
+// legacyCalls have no automatic translation, keyed by package and name.
+var legacyCalls = map[string]string{
+	genkitPackage + ".Start":                          "serve flows over HTTP with genkit.Handler and server.Start",
+	genkitPackage + ".StartFlowServer":                "serve flows over HTTP with genkit.Handler and server.Start",
+	genkitPackage + ".LookupFlow":                     "keep the flow returned by genkit.DefineFlow instead of looking it up",
+	"github.com/firebase/genkit/go/ai.WithCandidates": "GenKit 1.0 always generates a single candidate",
+}
+
+// initRule rewrites the pre-1.0 initialization, where genkit.Init returned
+// only an error and plugins were initialized by their own Init functions,
+// into g := genkit.Init(ctx, genkit.WithPlugins(...)).
+type initRule struct {
+	// removed are the imports the dropped error checks referred to, such
+	// as log, which may be unused now.
+	removed map[string]bool
+}
+
+func (r *initRule) Name() string {
+	return "genkit-init"
+}
+
+func (r *initRule) Apply(file *rewrite.File) error {
+	if _, imported := file.ImportName(genkitPackage); !imported {
+		return nil
+	}
+
+	r.removed = make(map[string]bool)
+	ast.Inspect(file.AST, func(n ast.Node) bool {
+		if block, ok := n.(*ast.BlockStmt); ok {
+			r.upgradeBlock(file, block)
+		}
+		return true
+	})
+
+	for oldPath := range pluginUpgrades {
+		file.DeleteUnusedImport(oldPath)
+	}
+	for importPath := range r.removed {
+		file.DeleteUnusedImport(importPath)
+	}
+	return nil
+}
+
+// remove records the imports a dropped statement refers to.
+func (r *initRule) remove(file *rewrite.File, stmt ast.Stmt) {
+	ast.Inspect(stmt, func(n ast.Node) bool {
+		if sel, ok := n.(*ast.SelectorExpr); ok {
+			if pkg, _, ok := file.SelectorPackage(sel); ok {
+				r.removed[pkg] = true
+			}
+		}
+		return true
+	})
+}
+
+func (r *initRule) upgradeBlock(file *rewrite.File, block *ast.BlockStmt) {
+	list := block.List
+	block.List = r.upgradeStmts(file, list)
+
+	// Close the gap left by statements removed from the end of the block.
+	if n := len(block.List); n > 0 && len(list) > 0 && block.List[n-1] != list[len(list)-1] {
+		block.Rbrace = block.List[n-1].End()
+	}
+}
+
+func (r *initRule) upgradeStmts(file *rewrite.File, list []ast.Stmt) []ast.Stmt {
+	hasInit := false
+	for _, stmt := range list {
+		if call, _, _ := initCall(stmt); call != nil {
+			if pkg, _, ok := file.SelectorPackage(call.Fun); ok && pkg == genkitPackage {
+				hasInit = true
+			}
+		}
+	}
+
+	out := make([]ast.Stmt, 0, len(list))
+	var plugins []ast.Expr
+	var genkitInit *ast.CallExpr
+	initSlot, pluginSlot := -1, -1
+	for i := 0; i < len(list); i++ {
+		stmt := list[i]
+		call, lhs, checked := initCall(stmt)
+		if call == nil {
+			out = append(out, stmt)
+			continue
+		}
+		pkg, _, _ := file.SelectorPackage(call.Fun)
+		upgrade, isPlugin := pluginUpgrades[pkg]
+		if pkg != genkitPackage && !isPlugin {
+			out = append(out, stmt)
+			continue
+		}
+
+		// The error the old Init returned is gone, and so is its check.
+		errChecked := !checked && i+1 < len(list) && isErrCheck(list[i+1], lhs)
+		if len(lhs) == 1 && !checked && !errChecked && !isErr(lhs[0]) {
+			// Already g := genkit.Init(ctx, ...).
+			out = append(out, stmt)
+			continue
+		}
+		if checked {
+			r.remove(file, stmt.(*ast.IfStmt).Body)
+		}
+		if errChecked {
+			i++
+			r.remove(file, list[i])
+		}
+
+		if isPlugin {
+			if !hasInit {
+				file.Report(call, &models.Change{
+					Type:         "import",
+					Description:  fmt.Sprintf("Register the %s plugin with genkit.WithPlugins in the genkit.Init call", upgrade.typ),
+					ManualReview: true,
+				})
+				out = append(out, stmt)
+				continue
+			}
+			if pluginSlot < 0 {
+				pluginSlot = len(out)
+			}
+			plugins = append(plugins, r.plugin(file, upgrade, call))
+			continue
+		}
+
+		name := instanceName
+		if len(lhs) == 2 {
+			if ident, ok := lhs[0].(*ast.Ident); ok && ident.Name != "_" {
+				name = ident.Name
+			}
+		}
+		genkitInit = &ast.CallExpr{Fun: call.Fun, Lparen: call.Lparen, Args: r.options(file, call), Rparen: call.Rparen}
+		file.Report(call, &models.Change{
+			Type:        "import",
+			Description: fmt.Sprintf("genkit.Init returns the GenKit instance in 1.0; assigned it to %s", name),
+		})
+		initSlot = len(out)
+		out = append(out, &ast.AssignStmt{
+			Lhs:    []ast.Expr{&ast.Ident{NamePos: stmt.Pos(), Name: name}},
+			Tok:    token.DEFINE,
+			TokPos: stmt.Pos(),
+			Rhs:    []ast.Expr{genkitInit},
+		})
+	}
+
+	if genkitInit == nil {
+		return out
+	}
+	if len(plugins) > 0 {
+		addPlugins(file, genkitInit, plugins)
+	}
+
+	// Pre-1.0 programs registered their flows before genkit.Init, which then
+	// started serving them; the instance now has to exist before its first use.
+	to := initSlot
+	if pluginSlot >= 0 && pluginSlot < to {
+		to = pluginSlot
+	}
+	for i := 0; i < to; i++ {
+		if usesGenkit(file, out[i]) {
+			to = i
+			break
+		}
+	}
+	if to < initSlot {
+		stmt := out[initSlot]
+		rewrite.ClearPositions(stmt)
+		out = slices.Insert(slices.Delete(out, initSlot, initSlot+1), to, stmt)
+	}
+	return out
+}
+
+// usesGenkit reports whether a statement refers to any GenKit package.
+func usesGenkit(file *rewrite.File, stmt ast.Stmt) bool {
+	uses := false
+	ast.Inspect(stmt, func(n ast.Node) bool {
+		if sel, ok := n.(*ast.SelectorExpr); ok {
+			if pkg, _, ok := file.SelectorPackage(sel); ok && strings.HasPrefix(pkg, GenkitModule+"/") {
+				uses = true
+			}
+		}
+		return !uses
+	})
+	return uses
+}
+
+// initCall returns the Init call a statement makes and what it assigns, for
+// the shapes Init(...), err := Init(...), g, err := Init(...) and
+// if err := Init(...); err != nil {...}; checked reports the last one.
+func initCall(stmt ast.Stmt) (*ast.CallExpr, []ast.Expr, bool) {
+	var expr ast.Expr
+	var lhs []ast.Expr
+	checked := false
+
+	switch node := stmt.(type) {
+	case *ast.ExprStmt:
+		expr = node.X
+	case *ast.AssignStmt:
+		if len(node.Rhs) != 1 {
+			return nil, nil, false
+		}
+		expr, lhs = node.Rhs[0], node.Lhs
+	case *ast.IfStmt:
+		assign, ok := node.Init.(*ast.AssignStmt)
+		if !ok || node.Else != nil || len(assign.Lhs) != 1 || len(assign.Rhs) != 1 || !isErrCheck(&ast.IfStmt{Cond: node.Cond}, assign.Lhs) {
+			return nil, nil, false
+		}
+		expr, lhs, checked = assign.Rhs[0], assign.Lhs, true
+	default:
+		return nil, nil, false
+	}
+
+	call, ok := expr.(*ast.CallExpr)
+	if !ok {
+		return nil, nil, false
+	}
+	sel, ok := call.Fun.(*ast.SelectorExpr)
+	if !ok || sel.Sel.Name != "Init" {
+		return nil, nil, false
+	}
+	return call, lhs, checked
+}
+
+func isErr(expr ast.Expr) bool {
+	ident, ok := expr.(*ast.Ident)
+	return ok && ident.Name == "err"
+}
+
+// isErrCheck reports whether stmt is if err != nil {...} on the error the
+// last of lhs assigned.
+func isErrCheck(stmt ast.Stmt, lhs []ast.Expr) bool {
+	if len(lhs) == 0 {
+		return false
+	}
+	errIdent, ok := lhs[len(lhs)-1].(*ast.Ident)
+	if !ok {
+		return false
+	}
+	check, ok := stmt.(*ast.IfStmt)
+	if !ok || check.Init != nil || check.Else != nil {
+		return false
+	}
+	cond, ok := check.Cond.(*ast.BinaryExpr)
+	if !ok || cond.Op != token.NEQ {
+		return false
+	}
+	x, ok := cond.X.(*ast.Ident)
+	y, isIdent := cond.Y.(*ast.Ident)
+	return ok && isIdent && x.Name == errIdent.Name && y.Name == "nil"
+}
+
+// options translates the arguments of a pre-1.0 genkit.Init. The 0.x option
+// functions carry over; the Options struct is split into 1.0 options.
+func (r *initRule) options(file *rewrite.File, call *ast.CallExpr) []ast.Expr {
+	if len(call.Args) == 0 {
+		return nil
+	}
+	args := []ast.Expr{call.Args[0]}
+	if len(call.Args) != 2 {
+		return append(args, call.Args[1:]...)
+	}
+
+	config := call.Args[1]
+	if ident, ok := config.(*ast.Ident); ok && ident.Name == "nil" {
+		return args
+	}
+	if unary, ok := config.(*ast.UnaryExpr); ok && unary.Op == token.AND {
+		config = unary.X
+	}
+	lit, ok := config.(*ast.CompositeLit)
+	if !ok {
+		return append(args, call.Args[1:]...)
+	}
+	if pkg, _, ok := file.SelectorPackage(lit.Type); !ok || pkg != genkitPackage {
+		return append(args, call.Args[1:]...)
+	}
+
+	genkitName, _ := file.ImportName(genkitPackage)
+	for _, elt := range lit.Elts {
+		kv, ok := elt.(*ast.KeyValueExpr)
+		if !ok {
+			continue
+		}
+		key, ok := kv.Key.(*ast.Ident)
+		if !ok {
+			continue
+		}
+
+		option, known := initOptions[key.Name]
+		if option == "" {
+			description := fmt.Sprintf("genkit.Init has no %s option in GenKit 1.0", key.Name)
+			if known {
+				description += "; serve flows over HTTP with genkit.Handler and server.Start"
+			}
+			file.Report(kv, &models.Change{
+				Type:         "config",
+				Description:  description,
+				OldValue:     key.Name,
+				ManualReview: true,
+			})
+			continue
+		}
+
+		values := []ast.Expr{kv.Value}
+		if plugins, ok := kv.Value.(*ast.CompositeLit); ok && option == "WithPlugins" {
+			values = plugins.Elts
+		}
+		args = append(args, &ast.CallExpr{
+			Fun:  &ast.SelectorExpr{X: ast.NewIdent(genkitName), Sel: ast.NewIdent(option)},
+			Args: values,
+		})
+	}
+	return args
+}
+
+// plugin returns the 1.0 plugin struct for a plugin Init call, carrying over
+// the fields of a literal config.
+func (r *initRule) plugin(file *rewrite.File, upgrade pluginUpgrade, call *ast.CallExpr) ast.Expr {
+	name, exists := file.ImportName(upgrade.path)
+	if !exists {
+		file.AddImport(upgrade.name, upgrade.path)
+		name = upgrade.name
+	}
+
+	plugin := &ast.CompositeLit{Type: &ast.SelectorExpr{X: ast.NewIdent(name), Sel: ast.NewIdent(upgrade.typ)}}
+	if len(call.Args) == 2 {
+		config := call.Args[1]
+		if unary, ok := config.(*ast.UnaryExpr); ok && unary.Op == token.AND {
+			config = unary.X
+		}
+		switch node := config.(type) {
+		case *ast.CompositeLit:
+			plugin.Elts = node.Elts
+		case *ast.Ident:
+			if node.Name != "nil" {
+				file.Report(call, &models.Change{
+					Type:         "import",
+					Description:  fmt.Sprintf("Move the settings in %s to the %s plugin struct", node.Name, upgrade.typ),
+					ManualReview: true,
+				})
+			}
+		default:
+			file.Report(call, &models.Change{
+				Type:         "import",
+				Description:  fmt.Sprintf("Move the plugin settings to the %s plugin struct", upgrade.typ),
+				ManualReview: true,
+			})
+		}
+	}
+
+	file.Report(call, &models.Change{
+		Type:        "import",
+		Description: fmt.Sprintf("Registered the %s plugin with genkit.WithPlugins instead of its Init function", upgrade.typ),
+	})
+	return &ast.UnaryExpr{Op: token.AND, X: plugin}
+}
+
+// addPlugins appends plugins to the WithPlugins option of a genkit.Init call,
+// adding the option when there is none.
+func addPlugins(file *rewrite.File, call *ast.CallExpr, plugins []ast.Expr) {
+	for _, arg := range call.Args {
+		option, ok := arg.(*ast.CallExpr)
+		if !ok {
+			continue
+		}
+		if pkg, name, ok := file.SelectorPackage(option.Fun); ok && pkg == genkitPackage && name == "WithPlugins" {
+			option.Args = append(option.Args, plugins...)
+			return
+		}
+	}
+
+	genkitName, _ := file.ImportName(genkitPackage)
+	call.Args = append(call.Args, &ast.CallExpr{
+		Fun:  &ast.SelectorExpr{X: ast.NewIdent(genkitName), Sel: ast.NewIdent("WithPlugins")},
+		Args: plugins,
+	})
+}
+
+// legacyCallRule flags pre-1.0 calls that have no automatic translation.
+type legacyCallRule struct{}
+
+func (r *legacyCallRule) Name() string {
+	return "legacy-call"
+}
+
+func (r *legacyCallRule) Apply(file *rewrite.File) error {
+	ast.Inspect(file.AST, func(n ast.Node) bool {
+		call, ok := n.(*ast.CallExpr)
+		if !ok {
+			return true
+		}
+		pkg, name, ok := file.SelectorPackage(call.Fun)
+		if !ok {
+			return true
+		}
+		if note, exists := legacyCalls[pkg+"."+name]; exists {
+			file.Report(call, &models.Change{
+				Type:         "import",
+				Description:  fmt.Sprintf("%s has no GenKit 1.0 equivalent: %s", name, note),
+				ManualReview: true,
+			})
+		}
+		return true
+	})
+	return nil
+}
+
+// instanceRule flags functions that now use the GenKit instance without it
+// being in scope, such as flows registered outside main.
+type instanceRule struct{}
+
+func (r *instanceRule) Name() string {
+	return "genkit-instance"
+}
+
+func (r *instanceRule) Apply(file *rewrite.File) error {
+	for _, decl := range file.AST.Decls {
+		if gen, ok := decl.(*ast.GenDecl); ok && gen.Tok == token.VAR {
+			for _, spec := range gen.Specs {
+				for _, ident := range spec.(*ast.ValueSpec).Names {
+					if ident.Name == instanceName {
+						return nil
+					}
+				}
+			}
+		}
+	}
+
+	for _, decl := range file.AST.Decls {
+		fn, ok := decl.(*ast.FuncDecl)
+		if !ok || fn.Body == nil || !usesInstance(fn) || declaresInstance(fn) {
+			continue
+		}
+		file.Report(fn.Name, &models.Change{
+			Type:         "import",
+			Description:  fmt.Sprintf("%s uses the GenKit instance %s; pass it in as a *genkit.Genkit parameter", fn.Name.Name, instanceName),
+			ManualReview: true,
+		})
+	}
+	return nil
+}
+
+func usesInstance(fn *ast.FuncDecl) bool {
+	uses := false
+	ast.Inspect(fn.Body, func(n ast.Node) bool {
+		switch node := n.(type) {
+		case *ast.SelectorExpr:
+			// Only the operand can refer to the instance.
+			ast.Inspect(node.X, func(n ast.Node) bool {
+				if ident, ok := n.(*ast.Ident); ok && ident.Name == instanceName {
+					uses = true
+				}
+				return true
+			})
+			return false
+		case *ast.Ident:
+			if node.Name == instanceName {
+				uses = true
+			}
+		}
+		return true
+	})
+	return uses
+}
+
+func declaresInstance(fn *ast.FuncDecl) bool {
+	declares := false
+	declare := func(ident *ast.Ident) {
+		if ident.Name == instanceName {
+			declares = true
+		}
+	}
+
+	ast.Inspect(fn, func(n ast.Node) bool {
+		switch node := n.(type) {
+		case *ast.Field:
+			for _, ident := range node.Names {
+				declare(ident)
+			}
+		case *ast.AssignStmt:
+			if node.Tok == token.DEFINE {
+				for _, expr := range node.Lhs {
+					if ident, ok := expr.(*ast.Ident); ok {
+						declare(ident)
+					}
+				}
+			}
+		case *ast.ValueSpec:
+			for _, ident := range node.Names {
+				declare(ident)
+			}
+		}
+		return true
+	})
+	return declares
+}
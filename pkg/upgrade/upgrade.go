@@ -0,0 +1,161 @@
+// Package upgrade rewrites projects written against the pre-1.0 GenKit Go
+// API to the 1.0 style, in which a *genkit.Genkit instance is passed to every
+// registration and generation call.
+package upgrade
+
+import (
+	"context"
+	_ "embed"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/genkit-migrate/genkit-migrate/pkg/models"
+	"github.com/genkit-migrate/genkit-migrate/pkg/rewrite"
+	"github.com/otiai10/copy"
+)
+
+const (
+	GenkitModule  = "github.com/firebase/genkit/go"
+	GenkitVersion = "v1.0.2"
+
+	genkitPackage = "github.com/firebase/genkit/go/genkit"
+)
+
+//go:embed rules.yaml
+var callRules []byte
+
+// Legacy reports whether a GenKit module version predates 1.0.
+func Legacy(version string) bool {
+	return strings.HasPrefix(version, "v0.")
+}
+
+// Rules returns the upgrade rules: the statement-level init rule first, so
+// that the instance the call rules pass is declared, then the call shapes.
+func Rules() ([]rewrite.Rule, error) {
+	calls, err := rewrite.ParseRules(callRules)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse upgrade rules: %w", err)
+	}
+
+	rules := []rewrite.Rule{&initRule{}, &legacyCallRule{}}
+	rules = append(rules, calls...)
+	return append(rules, &instanceRule{}), nil
+}
+
+type Upgrader struct {
+	config *Config
+}
+
+type Config struct {
+	TargetPath string
+	DryRun     bool
+}
+
+func New(config *Config) *Upgrader {
+	return &Upgrader{config: config}
+}
+
+// UpgradeProject rewrites the project's GenKit sources and go.mod. Projects
+// already on GenKit 1.0 come back without changes.
+func (u *Upgrader) UpgradeProject(ctx context.Context, project *models.Project) (*models.Migration, error) {
+	migration := &models.Migration{
+		Project:     project,
+		Changes:     make([]*models.Change, 0),
+		NewFiles:    make(map[string]string),
+		DeleteFiles: make([]string, 0),
+		Commands:    make([]string, 0),
+	}
+
+	if !Legacy(project.Dependencies[GenkitModule]) {
+		return migration, nil
+	}
+
+	rules, err := Rules()
+	if err != nil {
+		return nil, err
+	}
+
+	for filePath, sourceFile := range project.Files {
+		if !sourceFile.HasGenKit {
+			continue
+		}
+
+		file, err := rewrite.ParseFile(sourceFile.Path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse %s: %w", filePath, err)
+		}
+		if err := rewrite.Apply(file, rules); err != nil {
+			return nil, fmt.Errorf("failed to upgrade %s: %w", filePath, err)
+		}
+		if len(file.Changes) == 0 {
+			continue
+		}
+
+		content, err := file.Format()
+		if err != nil {
+			return nil, fmt.Errorf("failed to format %s: %w", filePath, err)
+		}
+		migration.NewFiles[filePath] = content
+		migration.Changes = append(migration.Changes, file.Changes...)
+	}
+
+	if err := u.upgradeGoMod(migration); err != nil {
+		return nil, fmt.Errorf("failed to upgrade go.mod: %w", err)
+	}
+	migration.Commands = append(migration.Commands, "go mod tidy")
+
+	return migration, nil
+}
+
+var genkitRequire = regexp.MustCompile(`(?m)^(\s*(?:require\s+)?` + regexp.QuoteMeta(GenkitModule) + `\s+)v0\.\S+`)
+
+func (u *Upgrader) upgradeGoMod(migration *models.Migration) error {
+	content, err := os.ReadFile(filepath.Join(migration.Project.Path, "go.mod"))
+	if err != nil {
+		return err
+	}
+
+	version := migration.Project.Dependencies[GenkitModule]
+	migration.NewFiles["go.mod"] = genkitRequire.ReplaceAllString(string(content), "${1}"+GenkitVersion)
+	migration.Changes = append(migration.Changes, &models.Change{
+		Type:        "dependency",
+		Description: fmt.Sprintf("Upgraded %s %s -> %s", GenkitModule, version, GenkitVersion),
+		File:        "go.mod",
+		OldValue:    version,
+		NewValue:    GenkitVersion,
+	})
+	return nil
+}
+
+// WriteProject writes the upgraded files to the target path, copying the rest
+// of the project there first when it is not upgraded in place.
+func (u *Upgrader) WriteProject(migration *models.Migration) error {
+	if u.config.DryRun {
+		return nil
+	}
+
+	source, err := filepath.Abs(migration.Project.Path)
+	if err != nil {
+		return err
+	}
+	target, err := filepath.Abs(u.config.TargetPath)
+	if err != nil {
+		return err
+	}
+	if source != target {
+		if err := copy.Copy(source, target); err != nil {
+			return fmt.Errorf("failed to copy project: %w", err)
+		}
+	}
+
+	for filePath, content := range migration.NewFiles {
+		fullPath := filepath.Join(target, filePath)
+		if err := os.WriteFile(fullPath, []byte(content), 0600); err != nil {
+			return fmt.Errorf("failed to write file %s: %w", fullPath, err)
+		}
+	}
+	return nil
+}
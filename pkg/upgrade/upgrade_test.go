@@ -0,0 +1,239 @@
+package upgrade
+
+import (
+	"context"
+	"go/ast"
+	"go/importer"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"text/template"
+
+	"github.com/genkit-migrate/genkit-migrate/pkg/models"
+	"github.com/genkit-migrate/genkit-migrate/pkg/rewrite"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLegacy(t *testing.T) {
+	assert.True(t, Legacy("v0.1.2"))
+	assert.True(t, Legacy("v0.5.0"))
+	assert.False(t, Legacy("v1.0.2"))
+	assert.False(t, Legacy(""))
+}
+
+func upgradeSource(t *testing.T, src string) (string, []*models.Change) {
+	rules, err := Rules()
+	require.NoError(t, err)
+
+	file, err := rewrite.ParseSource("main.go", []byte(src))
+	require.NoError(t, err)
+	require.NoError(t, rewrite.Apply(file, rules))
+
+	content, err := file.Format()
+	require.NoError(t, err)
+	return content, file.Changes
+}
+
+// typeCheck type-checks upgraded source against empty GenKit packages, so
+// only their members and the names the snippet leaves out are undefined.
+func typeCheck(t *testing.T, content string) {
+	t.Helper()
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, "main.go", content, 0)
+	require.NoError(t, err)
+
+	std := importer.ForCompiler(fset, "source", nil)
+	var errs []string
+	config := types.Config{
+		Importer: importerFunc(func(path string) (*types.Package, error) {
+			if !strings.HasPrefix(path, GenkitModule+"/") {
+				return std.Import(path)
+			}
+			pkg := types.NewPackage(path, rewrite.DefaultImportName(path))
+			pkg.MarkComplete()
+			return pkg, nil
+		}),
+		Error: func(err error) {
+			if !strings.HasPrefix(err.(types.Error).Msg, "undefined: ") {
+				errs = append(errs, err.Error())
+			}
+		},
+	}
+	config.Check("main", fset, []*ast.File{file}, nil)
+	assert.Empty(t, errs)
+}
+
+type importerFunc func(path string) (*types.Package, error)
+
+func (f importerFunc) Import(path string) (*types.Package, error) {
+	return f(path)
+}
+
+func TestRules(t *testing.T) {
+	src := `package main
+
+import (
+	"context"
+	"log"
+
+	"github.com/firebase/genkit/go/ai"
+	"github.com/firebase/genkit/go/genkit"
+	"github.com/firebase/genkit/go/plugins/googleai"
+)
+
+func main() {
+	ctx := context.Background()
+	if err := googleai.Init(ctx, &googleai.Config{APIKey: key}); err != nil {
+		log.Fatal(err)
+	}
+
+	// Flows are registered before genkit.Init starts serving them.
+	genkit.DefineFlow("jokeFlow", func(ctx context.Context, topic string) (string, error) {
+		resp, err := ai.Generate(ctx, googleai.Model("gemini-1.5-flash"), ai.WithTextPrompt(topic))
+		if err != nil {
+			return "", err
+		}
+		return resp.Text(), nil
+	})
+
+	err := genkit.Init(ctx, &genkit.Options{FlowAddr: ":3400"})
+	if err != nil {
+		log.Fatal(err)
+	}
+}
+
+func registerTools() {
+	ai.DefineTool("lookup", "Looks things up", lookup)
+}
+`
+	content, changes := upgradeSource(t, src)
+
+	assert.Contains(t, content, `g := genkit.Init(ctx, genkit.WithPlugins(&googlegenai.GoogleAI{APIKey: key}))`)
+	assert.Contains(t, content, "// Flows are registered before genkit.Init starts serving them.\n\tgenkit.DefineFlow(g, \"jokeFlow\",")
+	assert.Less(t, strings.Index(content, "g := genkit.Init"), strings.Index(content, "genkit.DefineFlow"))
+	assert.Contains(t, content, `genkit.Generate(ctx, g, ai.WithModel(googlegenai.GoogleAIModel(g, "gemini-1.5-flash")), ai.WithPrompt(topic))`)
+	assert.Contains(t, content, `genkit.DefineTool(g, "lookup", "Looks things up", lookup)`)
+	assert.Contains(t, content, `"github.com/firebase/genkit/go/plugins/googlegenai"`)
+	assert.NotContains(t, content, `"github.com/firebase/genkit/go/plugins/googleai"`)
+	assert.NotContains(t, content, "googleai.Init")
+	assert.Equal(t, 1, strings.Count(content, "if err != nil"))
+	// log was only used by the dropped error checks.
+	assert.NotContains(t, content, `"log"`)
+	typeCheck(t, content)
+
+	var review []string
+	for _, change := range changes {
+		assert.NotEmpty(t, change.Rule)
+		if change.ManualReview {
+			review = append(review, change.Rule)
+		}
+	}
+	// FlowAddr has no 1.0 option, and registerTools has no instance in scope.
+	assert.ElementsMatch(t, []string{"genkit-init", "genkit-instance"}, review)
+}
+
+func TestRulesKeepsInstanceName(t *testing.T) {
+	src := `package main
+
+import (
+	"context"
+	"log"
+
+	"github.com/firebase/genkit/go/genkit"
+	"github.com/firebase/genkit/go/plugins/vertexai"
+)
+
+func main() {
+	ctx := context.Background()
+	kit, err := genkit.Init(ctx, genkit.WithPlugins(&vertexai.VertexAI{}))
+	if err != nil {
+		log.Fatal(err)
+	}
+	if err := genkit.StartFlowServer(kit, ""); err != nil {
+		log.Fatal(err)
+	}
+}
+`
+	content, changes := upgradeSource(t, src)
+
+	assert.Contains(t, content, `kit := genkit.Init(ctx, genkit.WithPlugins(&vertexai.VertexAI{}))`)
+	assert.NotContains(t, content, "if err != nil")
+	typeCheck(t, content)
+
+	var descriptions []string
+	for _, change := range changes {
+		descriptions = append(descriptions, change.Description)
+	}
+	assert.Contains(t, descriptions, "StartFlowServer has no GenKit 1.0 equivalent: serve flows over HTTP with genkit.Handler and server.Start")
+}
+
+func TestTemplatesUseCurrentAPI(t *testing.T) {
+	tmpl, err := template.ParseFiles(filepath.Join("..", "..", "templates", "aws", "main.go.tmpl"))
+	require.NoError(t, err)
+
+	for _, target := range []string{"aws", "local"} {
+		var out strings.Builder
+		require.NoError(t, tmpl.Execute(&out, map[string]any{
+			"TargetProvider": target,
+			"ProjectName":    "chatbot",
+			"AWS":            map[string]string{"Region": "us-east-1"},
+			"Flows":          []map[string]string{{"Name": "Chat"}},
+		}))
+
+		content, changes := upgradeSource(t, out.String())
+		assert.Empty(t, changes, target)
+		assert.Contains(t, content, `genkit.DefineFlow(g, "Chat"`, target)
+	}
+}
+
+func TestUpgradeProject(t *testing.T) {
+	dir := t.TempDir()
+	goMod := "module example.com/bot\n\ngo 1.22\n\nrequire (\n\tgithub.com/firebase/genkit/go v0.1.2\n\tgithub.com/stretchr/testify v1.9.0\n)\n"
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "go.mod"), []byte(goMod), 0600))
+	src := `package main
+
+import (
+	"context"
+
+	"github.com/firebase/genkit/go/genkit"
+)
+
+func main() {
+	genkit.Init(context.Background(), nil)
+}
+`
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "main.go"), []byte(src), 0600))
+
+	project := &models.Project{
+		Path:         dir,
+		Dependencies: map[string]string{GenkitModule: "v0.1.2"},
+		Files: map[string]*models.SourceFile{
+			"main.go": {Path: filepath.Join(dir, "main.go"), HasGenKit: true},
+		},
+	}
+
+	upgrader := New(&Config{TargetPath: dir})
+	migration, err := upgrader.UpgradeProject(context.Background(), project)
+	require.NoError(t, err)
+
+	assert.Contains(t, migration.NewFiles["go.mod"], "github.com/firebase/genkit/go v1.0.2")
+	assert.Contains(t, migration.NewFiles["go.mod"], "github.com/stretchr/testify v1.9.0")
+	assert.Contains(t, migration.NewFiles["main.go"], "g := genkit.Init(context.Background())")
+	assert.Equal(t, []string{"go mod tidy"}, migration.Commands)
+
+	require.NoError(t, upgrader.WriteProject(migration))
+	written, err := os.ReadFile(filepath.Join(dir, "go.mod"))
+	require.NoError(t, err)
+	assert.Contains(t, string(written), "v1.0.2")
+
+	project.Dependencies[GenkitModule] = "v1.0.2"
+	migration, err = upgrader.UpgradeProject(context.Background(), project)
+	require.NoError(t, err)
+	assert.Empty(t, migration.Changes)
+	assert.Empty(t, migration.NewFiles)
+}